@@ -2,9 +2,12 @@ package main
 
 import (
 	"github.com/docker/machine/drivers/amazonec2"
+	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/drivers/plugin"
 )
 
 func main() {
-	plugin.RegisterDriver(amazonec2.NewDriver("", ""))
+	plugin.RegisterDriverFactory(func() drivers.Driver {
+		return amazonec2.NewDriver("", "")
+	})
 }
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"github.com/docker/machine/drivers/fake"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/drivers/plugin"
+)
+
+func main() {
+	plugin.RegisterDriverFactory(func() drivers.Driver {
+		return fake.NewDriver("", "")
+	})
+}
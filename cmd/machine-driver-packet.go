@@ -2,9 +2,12 @@ package main
 
 import (
 	"github.com/docker/machine/drivers/packet"
+	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/drivers/plugin"
 )
 
 func main() {
-	plugin.RegisterDriver(new(packet.Driver))
+	plugin.RegisterDriverFactory(func() drivers.Driver {
+		return new(packet.Driver)
+	})
 }
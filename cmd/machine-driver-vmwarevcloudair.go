@@ -2,9 +2,12 @@ package main
 
 import (
 	"github.com/docker/machine/drivers/vmwarevcloudair"
+	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/drivers/plugin"
 )
 
 func main() {
-	plugin.RegisterDriver(vmwarevcloudair.NewDriver("", ""))
+	plugin.RegisterDriverFactory(func() drivers.Driver {
+		return vmwarevcloudair.NewDriver("", "")
+	})
 }
@@ -3,14 +3,23 @@ package main
 import (
 	"fmt"
 	"os"
+	"os/signal"
 	"path"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/docker/docker/pkg/term"
 	"github.com/docker/machine/cli"
 	"github.com/docker/machine/commands"
 	"github.com/docker/machine/commands/mcndirs"
+	"github.com/docker/machine/libmachine/drivers/plugin/localbinary"
+	"github.com/docker/machine/libmachine/drivers/rpc"
+	"github.com/docker/machine/libmachine/events"
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/mcnutils"
+	"github.com/docker/machine/libmachine/persist"
 	"github.com/docker/machine/libmachine/ssh"
 	"github.com/docker/machine/version"
 )
@@ -46,6 +55,8 @@ Options:
    {{.}}{{end}}{{ end }}
 `
 
+var defaultBackoff = mcnutils.DefaultBackoffPolicy()
+
 func setDebugOutputLevel() {
 	// TODO: I'm not really a fan of this method and really would rather
 	// use -v / --verbose TBQH
@@ -68,6 +79,7 @@ func setDebugOutputLevel() {
 
 func main() {
 	setDebugOutputLevel()
+	events.Subscribe(commands.NewProgressPrinter(os.Stderr, term.IsTerminal(os.Stderr.Fd())))
 	cli.AppHelpTemplate = AppHelpTemplate
 	cli.CommandHelpTemplate = CommandHelpTemplate
 	app := cli.NewApp()
@@ -82,7 +94,8 @@ func main() {
 		}
 		mcnutils.GithubAPIToken = c.GlobalString("github-api-token")
 		mcndirs.BaseDir = c.GlobalString("storage-path")
-		return nil
+		localbinary.PluginDir = mcndirs.GetPluginDir()
+		return configureBackoff(c)
 	}
 
 	app.Commands = commands.Commands
@@ -138,12 +151,107 @@ func main() {
 			Name:   "native-ssh",
 			Usage:  "Use the native (Go-based) SSH implementation.",
 		},
+		cli.BoolFlag{
+			EnvVar: "MACHINE_TIMINGS",
+			Name:   "timings",
+			Usage:  "Print how long each operation took once the command is done",
+		},
+		cli.DurationFlag{
+			EnvVar: "MACHINE_WAIT_INITIAL_INTERVAL",
+			Name:   "wait-initial-interval",
+			Usage:  "Initial interval between attempts when waiting for a machine",
+			Value:  defaultBackoff.InitialInterval,
+		},
+		cli.DurationFlag{
+			EnvVar: "MACHINE_WAIT_MAX_INTERVAL",
+			Name:   "wait-max-interval",
+			Usage:  "Maximum interval between attempts when waiting for a machine",
+			Value:  defaultBackoff.MaxInterval,
+		},
+		cli.DurationFlag{
+			EnvVar: "MACHINE_WAIT_TIMEOUT",
+			Name:   "wait-timeout",
+			Usage:  "Give up waiting for a machine (state, SSH, daemon) after this long, 0 to wait forever",
+			Value:  defaultBackoff.MaxElapsedTime,
+		},
+		cli.StringSliceFlag{
+			EnvVar: "MACHINE_WAIT_TIMEOUT_DRIVER",
+			Name:   "wait-timeout-driver",
+			Usage:  "Override the wait timeout for a driver, in the form driver=duration, 0 to wait forever",
+			Value:  &cli.StringSlice{},
+		},
 	}
 
-	// TODO: Close plugin servers in case of client panic.
+	// Plugin servers exit by themselves once they stop getting heartbeats,
+	// but don't leave them behind any longer than necessary.
+	defer func() {
+		if r := recover(); r != nil {
+			rpcdriver.CloseAllPlugins()
+			panic(r)
+		}
+	}()
+	go closePluginsOnSignal()
+
 	if err := app.Run(os.Args); err != nil {
 		log.Error(err)
 	}
+
+	ssh.CloseAll()
+	rpcdriver.CloseAllPlugins()
+}
+
+func closePluginsOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	sig := <-signals
+	// Don't leave the machines being created or removed looking like
+	// they still are.
+	persist.InterruptTransitions()
+	// The ssh processes of the tunnels would outlive this one otherwise.
+	ssh.CloseAll()
+	rpcdriver.CloseAllPlugins()
+
+	if s, ok := sig.(syscall.Signal); ok {
+		os.Exit(128 + int(s))
+	}
+	os.Exit(1)
+}
+
+// configureBackoff sets up how long and how often we retry while waiting
+// for machines, both globally and for specific drivers.
+func configureBackoff(c *cli.Context) error {
+	policy := mcnutils.DefaultBackoffPolicy()
+
+	if interval := c.GlobalDuration("wait-initial-interval"); interval > 0 {
+		policy.InitialInterval = interval
+	}
+
+	if interval := c.GlobalDuration("wait-max-interval"); interval > 0 {
+		policy.MaxInterval = interval
+	}
+
+	policy.MaxElapsedTime = c.GlobalDuration("wait-timeout")
+
+	mcnutils.SetDefaultBackoffPolicy(policy)
+
+	for _, driverTimeout := range c.GlobalStringSlice("wait-timeout-driver") {
+		kv := strings.SplitN(driverTimeout, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("Invalid driver wait timeout %q, expected driver=duration", driverTimeout)
+		}
+
+		timeout, err := time.ParseDuration(kv[1])
+		if err != nil {
+			return fmt.Errorf("Invalid driver wait timeout %q: %s", driverTimeout, err)
+		}
+
+		driverPolicy := *policy
+		driverPolicy.MaxElapsedTime = timeout
+		mcnutils.SetDriverBackoffPolicy(kv[0], &driverPolicy)
+	}
+
+	return nil
 }
 
 func cmdNotFound(c *cli.Context, command string) {
@@ -40,7 +40,7 @@ func getActiveHost(store persist.Store) (*host.Host, error) {
 		return nil, err
 	}
 
-	hostListItems := getHostListItems(hosts)
+	hostListItems := getHostListItems(hosts, false)
 
 	for _, item := range hostListItems {
 		if item.Active {
@@ -13,14 +13,19 @@ import (
 	"github.com/docker/machine/drivers/errdriver"
 	"github.com/docker/machine/libmachine/cert"
 	"github.com/docker/machine/libmachine/drivers"
-	"github.com/docker/machine/libmachine/drivers/plugin/localbinary"
 	"github.com/docker/machine/libmachine/drivers/rpc"
 	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnerror"
 	"github.com/docker/machine/libmachine/persist"
+	"github.com/docker/machine/libmachine/ssh"
+	"github.com/docker/machine/libmachine/timing"
 )
 
 var (
+	// osExit is a variable so that tests can check the exit code.
+	osExit = os.Exit
+
 	ErrUnknownShell       = errors.New("Error: Unknown shell")
 	ErrNoMachineSpecified = errors.New("Error: Expected to get one or more machine names as arguments")
 	ErrExpectedOneMachine = errors.New("Error: Expected one machine name as an argument")
@@ -42,8 +47,12 @@ type CommandLine interface {
 
 	GlobalString(name string) string
 
+	GlobalBool(name string) bool
+
 	FlagNames() (names []string)
 
+	IsSet(name string) bool
+
 	Generic(name string) interface{}
 }
 
@@ -62,10 +71,6 @@ func (c *contextCommandLine) Application() *cli.App {
 func newPluginDriver(driverName string, rawContent []byte) (drivers.Driver, error) {
 	d, err := rpcdriver.NewRPCClientDriver(rawContent, driverName)
 	if err != nil {
-		// Not being able to find a driver binary is a "known error"
-		if _, ok := err.(localbinary.ErrPluginBinaryNotFound); ok {
-			return errdriver.NewDriver(driverName), nil
-		}
 		return nil, err
 	}
 
@@ -76,14 +81,61 @@ func newPluginDriver(driverName string, rawContent []byte) (drivers.Driver, erro
 	return d, nil
 }
 
+// newHostPluginDriver is like newPluginDriver, but hosts whose driver
+// binary is missing get a driver which fails every call, so that they can
+// still be listed and removed.
+func newHostPluginDriver(h *host.Host) (drivers.Driver, error) {
+	d, err := newPluginDriver(h.DriverName, h.RawDriver)
+	if _, ok := err.(mcnerror.ErrDriverUnavailable); ok {
+		log.Warnf("Driver %q used by host %q was not found, use \"%s driver install\" to install it", h.DriverName, h.Name, os.Args[0])
+		return errdriver.NewDriver(h.DriverName), nil
+	}
+	return d, err
+}
+
 func fatalOnError(command func(commandLine CommandLine) error) func(context *cli.Context) {
 	return func(context *cli.Context) {
-		if err := command(&contextCommandLine{context}); err != nil {
-			log.Fatal(err)
+		err := command(&contextCommandLine{context})
+
+		if context.GlobalBool("timings") {
+			if err := timing.WriteSummary(os.Stderr); err != nil {
+				log.Debugf("Error printing timings: %s", err)
+			}
+		}
+
+		if err != nil {
+			log.Error(err)
+			ssh.CloseAll()
+			rpcdriver.CloseAllPlugins()
+			osExit(exitCode(err))
 		}
 	}
 }
 
+// exitCode maps an error returned by a command to the exit code of the
+// process.  The codes are documented in docs/reference/index.md.
+func exitCode(err error) int {
+	switch mcnerror.Cause(err) {
+	case ErrUnknownShell,
+		ErrNoMachineSpecified,
+		ErrExpectedOneMachine,
+		errNoMachineName,
+		errJumpKeyWithoutHost,
+		errTooManyArguments,
+		errImproperEnvArgs,
+		errImproperUnsetEnvArgs,
+		errTunnelSwarm,
+		errWrongNumberArguments,
+		errUnsupportedFilter,
+		errMissingMachineName,
+		errExpectedOneDriver,
+		errExpectedOneSource:
+		return mcnerror.ExitCodeUsage
+	}
+
+	return mcnerror.ExitCode(err)
+}
+
 func confirmInput(msg string) (bool, error) {
 	fmt.Printf("%s (y/n): ", msg)
 
@@ -111,13 +163,13 @@ func listHosts(store persist.Store) ([]*host.Host, error) {
 
 	hosts, err := store.List()
 	if err != nil {
-		return nil, fmt.Errorf("Error attempting to list hosts from store: %s", err)
+		return nil, mcnerror.Wrapf(err, "Error attempting to list hosts from store")
 	}
 
 	for _, h := range hosts {
-		d, err := newPluginDriver(h.DriverName, h.RawDriver)
+		d, err := newHostPluginDriver(h)
 		if err != nil {
-			return nil, fmt.Errorf("Error attempting to invoke binary for plugin '%s': %s", h.DriverName, err)
+			return nil, mcnerror.Wrapf(err, "Error attempting to invoke binary for plugin '%s'", h.DriverName)
 		}
 
 		h.Driver = d
@@ -131,12 +183,12 @@ func listHosts(store persist.Store) ([]*host.Host, error) {
 func loadHost(store persist.Store, hostName string) (*host.Host, error) {
 	h, err := store.Load(hostName)
 	if err != nil {
-		return nil, fmt.Errorf("Loading host from store failed: %s", err)
+		return nil, mcnerror.Wrapf(err, "Loading host from store failed")
 	}
 
-	d, err := newPluginDriver(h.DriverName, h.RawDriver)
+	d, err := newHostPluginDriver(h)
 	if err != nil {
-		return nil, fmt.Errorf("Error attempting to invoke binary for plugin: %s", err)
+		return nil, mcnerror.Wrapf(err, "Error attempting to invoke binary for plugin")
 	}
 
 	h.Driver = d
@@ -158,7 +210,7 @@ func getFirstArgHost(c CommandLine) (*host.Host, error) {
 
 	h, err := loadHost(store, hostName)
 	if err != nil {
-		return nil, fmt.Errorf("Error trying to get host %q: %s", hostName, err)
+		return nil, mcnerror.Wrapf(err, "Error trying to get host %q", hostName)
 	}
 
 	return h, nil
@@ -171,7 +223,7 @@ func getHostsFromContext(c CommandLine) ([]*host.Host, error) {
 	for _, hostName := range c.Args() {
 		h, err := loadHost(store, hostName)
 		if err != nil {
-			return nil, fmt.Errorf("Could not load host %q: %s", hostName, err)
+			return nil, mcnerror.Wrapf(err, "Could not load host %q", hostName)
 		}
 		hosts = append(hosts, h)
 	}
@@ -204,6 +256,46 @@ var Commands = []cli.Command{
 		Action:          fatalOnError(cmdCreateOuter),
 		SkipFlagParsing: true,
 	},
+	{
+		Name:   "driver",
+		Usage:  "Manage driver plugins and run the commands they offer",
+		Action: fatalOnError(cmdDriverCommand),
+		Subcommands: []cli.Command{
+			{
+				Name:   "ls",
+				Usage:  "List the installed drivers",
+				Action: fatalOnError(cmdDriverLs),
+			},
+			{
+				Name:        "info",
+				Usage:       "Show the API version and create flags of a driver",
+				Description: "Argument is a driver name.",
+				Action:      fatalOnError(cmdDriverInfo),
+			},
+			{
+				Name:        "install",
+				Usage:       "Install a driver plugin binary",
+				Description: "Argument is the path or URL of a docker-machine-driver-NAME binary.",
+				Action:      fatalOnError(cmdDriverInstall),
+				Flags: []cli.Flag{
+					cli.StringFlag{
+						Name:  "name",
+						Usage: "Name of the driver, if it can't be told from the binary's file name",
+					},
+					cli.StringFlag{
+						Name:  "sha256",
+						Usage: "Expected SHA-256 checksum of the binary (required when installing from a URL)",
+					},
+				},
+			},
+			{
+				Name:        "remove",
+				Usage:       "Remove a driver installed with 'driver install'",
+				Description: "Argument is a driver name.",
+				Action:      fatalOnError(cmdDriverRemove),
+			},
+		},
+	},
 	{
 		Name:        "env",
 		Usage:       "Display the commands to set up the environment for the Docker client",
@@ -226,6 +318,10 @@ var Commands = []cli.Command{
 				Name:  "no-proxy",
 				Usage: "Add machine IP to NO_PROXY environment variable",
 			},
+			cli.BoolFlag{
+				Name:  "tunnel",
+				Usage: "Reach the Docker daemon through an SSH tunnel started in the background",
+			},
 		},
 	},
 	{
@@ -259,6 +355,10 @@ var Commands = []cli.Command{
 				Name:  "quiet, q",
 				Usage: "Enable quiet mode",
 			},
+			cli.BoolFlag{
+				Name:  "capabilities",
+				Usage: "Show the actions supported by each machine's driver",
+			},
 			cli.StringSliceFlag{
 				Name:  "filter",
 				Usage: "Filter output based on conditions provided",
@@ -281,6 +381,12 @@ var Commands = []cli.Command{
 			},
 		},
 	},
+	{
+		Name:        "reset-host-key",
+		Usage:       "Forget the SSH host key recorded for a machine",
+		Description: "Argument(s) are one or more machine names.",
+		Action:      fatalOnError(cmdResetHostKey),
+	},
 	{
 		Name:        "restart",
 		Usage:       "Restart a machine",
@@ -302,7 +408,7 @@ var Commands = []cli.Command{
 	{
 		Name:            "ssh",
 		Usage:           "Log into or run a command on a machine with SSH.",
-		Description:     "Arguments are [machine-name] [command]",
+		Description:     "Arguments are [-A] [machine-name] [command]. -A forwards the SSH agent.",
 		Action:          fatalOnError(cmdSSH),
 		SkipFlagParsing: true,
 	},
@@ -336,6 +442,18 @@ var Commands = []cli.Command{
 		Description: "Argument(s) are one or more machine names.",
 		Action:      fatalOnError(cmdStop),
 	},
+	{
+		Name:        "tunnel",
+		Usage:       "Forward a local port or socket to the Docker daemon of a machine over SSH",
+		Description: "Argument is a machine name.",
+		Action:      fatalOnError(cmdTunnel),
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "local",
+				Usage: "Local address to listen on, as tcp://host:port or unix:///path (port 0 picks a free one), a socket in the directory of the machine by default",
+			},
+		},
+	},
 	{
 		Name:        "upgrade",
 		Usage:       "Upgrade a machine to the latest version of Docker",
@@ -373,6 +491,7 @@ func machineCommand(actionName string, host *host.Host, errorChan chan<- error)
 		"kill":          host.Kill,
 		"upgrade":       host.Upgrade,
 		"ip":            printIP(host),
+		"resetHostKey":  host.ResetHostKey,
 	}
 
 	log.Debugf("command=%s machine=%s", actionName, host.Name)
@@ -417,27 +536,39 @@ func consolidateErrs(errs []error) error {
 }
 
 func runActionWithContext(actionName string, c CommandLine) error {
-	store := getStore(c)
-
 	hosts, err := getHostsFromContext(c)
 	if err != nil {
 		return err
 	}
 
+	return runActionForHosts(actionName, c, hosts)
+}
+
+// runActionForHosts runs an action on the hosts of the command line, for
+// the commands which first need the hosts themselves.
+func runActionForHosts(actionName string, c CommandLine, hosts []*host.Host) error {
+	store := getStore(c)
+
 	if len(hosts) == 0 {
 		return ErrNoMachineSpecified
 	}
 
-	if errs := runActionForeachMachine(actionName, hosts); len(errs) > 0 {
-		return consolidateErrs(errs)
-	}
+	errs := runActionForeachMachine(actionName, hosts)
 
+	// Save the hosts even if the action failed on some of them, so that
+	// the state transitions they went through are recorded.
 	for _, h := range hosts {
 		if err := saveHost(store, h); err != nil {
 			return fmt.Errorf("Error saving host to store: %s", err)
 		}
 	}
 
+	if len(errs) == 1 {
+		return errs[0]
+	} else if len(errs) > 1 {
+		return consolidateErrs(errs)
+	}
+
 	return nil
 }
 
@@ -2,12 +2,16 @@ package commands
 
 import (
 	"errors"
+	"flag"
+	"os"
 	"strings"
 	"testing"
 
+	"github.com/docker/machine/cli"
 	"github.com/docker/machine/drivers/fakedriver"
 	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/hosttest"
+	"github.com/docker/machine/libmachine/mcnerror"
 	"github.com/docker/machine/libmachine/state"
 	"github.com/stretchr/testify/assert"
 )
@@ -152,3 +156,39 @@ func TestConsolidateError(t *testing.T) {
 		assert.Equal(t, c.expectedErr, consolidateErrs(c.inputErrs))
 	}
 }
+
+func TestExitCode(t *testing.T) {
+	cases := []struct {
+		err      error
+		expected int
+	}{
+		{errors.New("Something went wrong"), 1},
+		{ErrNoMachineSpecified, 2},
+		{mcnerror.Wrapf(errNoMachineName, "Error creating machine"), 2},
+		{mcnerror.Wrapf(mcnerror.ErrInvalidHostname, "Error creating machine"), 2},
+		{mcnerror.Wrapf(mcnerror.ErrHostDoesNotExist{Name: "foo"}, "Could not load host %q", "foo"), 3},
+		{mcnerror.ErrHostNotRunning{Name: "foo"}, 5},
+		{ErrCertInvalid{wrappedErr: errors.New("bad cert"), hostURL: "tcp://1.2.3.4:2376"}, 10},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.expected, exitCode(c.err))
+	}
+}
+
+func TestFatalOnErrorExitCode(t *testing.T) {
+	defer func() {
+		osExit = os.Exit
+	}()
+
+	code := -1
+	osExit = func(c int) {
+		code = c
+	}
+
+	fatalOnError(func(c CommandLine) error {
+		return mcnerror.ErrHostDoesNotExist{Name: "foo"}
+	})(cli.NewContext(cli.NewApp(), flag.NewFlagSet("test", 0), nil))
+
+	assert.Equal(t, mcnerror.ExitCodeHostDoesNotExist, code)
+}
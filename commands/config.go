@@ -10,6 +10,7 @@ import (
 	"github.com/docker/machine/libmachine/cert"
 	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnerror"
 	"github.com/docker/machine/libmachine/state"
 )
 
@@ -26,6 +27,10 @@ Be advised that this will trigger a Docker daemon restart which will stop runnin
 `, e.hostURL, e.wrappedErr)
 }
 
+func (e ErrCertInvalid) ExitCode() int {
+	return mcnerror.ExitCodeCertInvalid
+}
+
 func cmdConfig(c CommandLine) error {
 	// Ensure that log messages always go to stderr when this command is
 	// being run (it is intended to be run in a subshell)
@@ -42,7 +47,7 @@ func cmdConfig(c CommandLine) error {
 
 	dockerHost, authOptions, err := runConnectionBoilerplate(host, c)
 	if err != nil {
-		return fmt.Errorf("Error running connection boilerplate: %s", err)
+		return mcnerror.Wrapf(err, "Error running connection boilerplate")
 	}
 
 	log.Debug(dockerHost)
@@ -58,15 +63,15 @@ func runConnectionBoilerplate(h *host.Host, c CommandLine) (string, *auth.Option
 	if err != nil {
 		// TODO: This is a common operation and should have a commonly
 		// defined error.
-		return "", &auth.Options{}, fmt.Errorf("Error trying to get host state: %s", err)
+		return "", &auth.Options{}, mcnerror.Wrapf(err, "Error trying to get host state")
 	}
 	if hostState != state.Running {
-		return "", &auth.Options{}, fmt.Errorf("%s is not running. Please start it in order to use the connection settings", h.Name)
+		return "", &auth.Options{}, mcnerror.Wrapf(mcnerror.ErrHostNotRunning{Name: h.Name}, "Please start the host in order to use the connection settings")
 	}
 
 	dockerHost, err := h.Driver.GetURL()
 	if err != nil {
-		return "", &auth.Options{}, fmt.Errorf("Error getting driver URL: %s", err)
+		return "", &auth.Options{}, mcnerror.Wrapf(err, "Error getting driver URL")
 	}
 
 	if c.Bool("swarm") {
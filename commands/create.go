@@ -14,7 +14,6 @@ import (
 
 	"github.com/docker/machine/cli"
 	"github.com/docker/machine/commands/mcndirs"
-	"github.com/docker/machine/drivers/errdriver"
 	"github.com/docker/machine/libmachine"
 	"github.com/docker/machine/libmachine/auth"
 	"github.com/docker/machine/libmachine/drivers"
@@ -25,11 +24,14 @@ import (
 	"github.com/docker/machine/libmachine/mcnerror"
 	"github.com/docker/machine/libmachine/mcnflag"
 	"github.com/docker/machine/libmachine/persist"
+	"github.com/docker/machine/libmachine/ssh"
 	"github.com/docker/machine/libmachine/swarm"
+	"github.com/docker/machine/libmachine/timing"
 )
 
 var (
-	errNoMachineName = errors.New("Error: No machine name specified")
+	errNoMachineName      = errors.New("Error: No machine name specified")
+	errJumpKeyWithoutHost = errors.New("Error: --ssh-jump-key needs --ssh-jump-host")
 )
 
 var (
@@ -115,6 +117,21 @@ var (
 			Usage: "addr to advertise for Swarm (default: detect and use the machine IP)",
 			Value: "",
 		},
+		cli.StringFlag{
+			Name:  "ssh-jump-host",
+			Usage: "Reach the machine over SSH through this host, as [user@]host[:port] (default user: the one of the machine)",
+			Value: "",
+		},
+		cli.StringFlag{
+			Name:  "ssh-jump-key",
+			Usage: "SSH private key for the jump host (default: the key of the machine)",
+			Value: "",
+		},
+		cli.StringFlag{
+			Name:  "ssh-key-type",
+			Usage: "Type of the SSH key generated for the machine: rsa[-bits], ecdsa[-256|-384|-521] or ed25519 (default: rsa-2048)",
+			Value: "",
+		},
 	}
 )
 
@@ -142,17 +159,42 @@ func cmdCreateInner(c CommandLine) error {
 
 	validName := host.ValidateHostName(name)
 	if !validName {
-		return fmt.Errorf("Error creating machine: %s", mcnerror.ErrInvalidHostname)
+		return mcnerror.Wrapf(mcnerror.ErrInvalidHostname, "Error creating machine")
 	}
 
 	if err := validateSwarmDiscovery(c.String("swarm-discovery")); err != nil {
 		return fmt.Errorf("Error parsing swarm discovery: %s", err)
 	}
 
+	if jumpHost := c.String("ssh-jump-host"); jumpHost != "" {
+		if _, err := ssh.ParseJumpHost(jumpHost); err != nil {
+			return err
+		}
+	} else if c.String("ssh-jump-key") != "" {
+		return errJumpKeyWithoutHost
+	}
+
+	// The key is used from wherever the machine is used later.
+	jumpKeyPath := c.String("ssh-jump-key")
+	if jumpKeyPath != "" {
+		absPath, err := filepath.Abs(jumpKeyPath)
+		if err != nil {
+			return fmt.Errorf("Error getting the path of the jump host key: %s", err)
+		}
+		jumpKeyPath = absPath
+	}
+
+	if _, err := ssh.ParseKeyType(c.String("ssh-key-type")); err != nil {
+		return err
+	}
+
 	// TODO: Fix hacky JSON solution
 	bareDriverData, err := json.Marshal(&drivers.BaseDriver{
-		MachineName: name,
-		StorePath:   c.GlobalString("storage-path"),
+		MachineName:    name,
+		StorePath:      c.GlobalString("storage-path"),
+		SSHJumpHost:    c.String("ssh-jump-host"),
+		SSHJumpKeyPath: jumpKeyPath,
+		SSHKeyType:     c.String("ssh-key-type"),
 	})
 	if err != nil {
 		return fmt.Errorf("Error attempting to marshal bare driver data: %s", err)
@@ -160,7 +202,7 @@ func cmdCreateInner(c CommandLine) error {
 
 	driver, err := newPluginDriver(driverName, bareDriverData)
 	if err != nil {
-		return fmt.Errorf("Error loading driver %q: %s", driverName, err)
+		return mcnerror.Wrapf(err, "Error loading driver %q", driverName)
 	}
 
 	h, err := store.NewHost(driver)
@@ -217,12 +259,31 @@ func cmdCreateInner(c CommandLine) error {
 	mcnFlags := driver.GetCreateFlags()
 	driverOpts := getDriverOpts(c, mcnFlags)
 
+	if err := mcnflag.Validate(mcnFlags, driverOpts.Values); err != nil {
+		return err
+	}
+
+	// The API keys and passwords given to the driver are kept out of the
+	// logs.
+	for _, f := range mcnFlags {
+		if secret, ok := driverOpts.Values[f.String()].(string); ok && mcnflag.IsSecret(f) {
+			log.AddSecret(secret)
+		}
+	}
+
 	if err := h.Driver.SetConfigFromFlags(driverOpts); err != nil {
 		return fmt.Errorf("Error setting machine configuration from flags provided: %s", err)
 	}
 
-	if err := libmachine.Create(store, h); err != nil {
-		return fmt.Errorf("Error creating machine: %s", err)
+	err = libmachine.Create(store, h)
+
+	timingsPath := filepath.Join(h.HostOptions.AuthOptions.StorePath, "timings.json")
+	if err := timing.WriteSummaryFile(timingsPath); err != nil {
+		log.Debugf("Error writing timings to %s: %s", timingsPath, err)
+	}
+
+	if err != nil {
+		return mcnerror.Wrapf(err, "Error creating machine")
 	}
 
 	if err := saveHost(store, h); err != nil {
@@ -292,11 +353,7 @@ func cmdCreateOuter(c CommandLine) error {
 
 	driver, err := newPluginDriver(driverName, bareDriverData)
 	if err != nil {
-		return fmt.Errorf("Error loading driver %q: %s", driverName, err)
-	}
-
-	if _, ok := driver.(*errdriver.Driver); ok {
-		return errdriver.NotLoadable{driverName}
+		return mcnerror.Wrapf(err, "Error loading driver %q", driverName)
 	}
 
 	// TODO: So much flag manipulation and voodoo here, it seems to be
@@ -333,7 +390,7 @@ func cmdCreateOuter(c CommandLine) error {
 	return c.Application().Run(os.Args)
 }
 
-func getDriverOpts(c CommandLine, mcnflags []mcnflag.Flag) drivers.DriverOptions {
+func getDriverOpts(c CommandLine, mcnflags []mcnflag.Flag) rpcdriver.RPCFlags {
 	// TODO: This function is pretty damn YOLO and would benefit from some
 	// sanity checking around types and assertions.
 	//
@@ -358,16 +415,55 @@ func getDriverOpts(c CommandLine, mcnflags []mcnflag.Flag) drivers.DriverOptions
 		if !ok {
 			// TODO: This is pretty hacky.  StringSlice is the only
 			// type so far we have to worry about which is not a
-			// Getter, though.
+			// Getter, though (durations and floats are).
 			driverOpts.Values[name] = c.StringSlice(name)
 			continue
 		}
 		driverOpts.Values[name] = getter.Get()
 	}
 
+	// Required flags which weren't given and have no default are left out,
+	// for Validate to tell them apart from flags set to 0.
+	for _, f := range mcnflags {
+		if mcnflag.IsRequired(f) && !mcnflag.HasDefault(f) && !isFlagGiven(c, f) {
+			delete(driverOpts.Values, f.String())
+		}
+	}
+
 	return driverOpts
 }
 
+// isFlagGiven tells whether a flag was given on the command line or with
+// its environment variable.
+func isFlagGiven(c CommandLine, f mcnflag.Flag) bool {
+	if c.IsSet(f.String()) {
+		return true
+	}
+
+	for _, envVar := range strings.Split(mcnflag.EnvVar(f), ",") {
+		if envVar = strings.TrimSpace(envVar); envVar != "" && os.Getenv(envVar) != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// flagUsage adds what the help of a flag must say about its value to the
+// usage given by the driver.
+func flagUsage(f mcnflag.Flag, usage string) string {
+	if f, ok := f.(*mcnflag.EnumFlag); ok && len(f.Allowed) > 0 {
+		usage += fmt.Sprintf(" (one of: %s)", strings.Join(f.Allowed, ", "))
+	}
+	if _, ok := f.(*mcnflag.SecretFlag); ok {
+		usage += " (secret)"
+	}
+	if mcnflag.IsRequired(f) {
+		usage += " (required)"
+	}
+	return usage
+}
+
 func convertMcnFlagsToCliFlags(mcnFlags []mcnflag.Flag) ([]cli.Flag, error) {
 	cliFlags := []cli.Flag{}
 	for _, f := range mcnFlags {
@@ -387,7 +483,7 @@ func convertMcnFlagsToCliFlags(mcnFlags []mcnflag.Flag) ([]cli.Flag, error) {
 			cliFlags = append(cliFlags, cli.IntFlag{
 				Name:   f.Name,
 				EnvVar: f.EnvVar,
-				Usage:  f.Usage,
+				Usage:  flagUsage(f, f.Usage),
 				Value:  f.Value,
 			})
 		case *mcnflag.StringFlag:
@@ -395,18 +491,57 @@ func convertMcnFlagsToCliFlags(mcnFlags []mcnflag.Flag) ([]cli.Flag, error) {
 			cliFlags = append(cliFlags, cli.StringFlag{
 				Name:   f.Name,
 				EnvVar: f.EnvVar,
-				Usage:  f.Usage,
+				Usage:  flagUsage(f, f.Usage),
 				Value:  f.Value,
 			})
 		case *mcnflag.StringSliceFlag:
 			f := f.(*mcnflag.StringSliceFlag)
+			value := cli.StringSlice(f.Value)
 			cliFlags = append(cliFlags, cli.StringSliceFlag{
 				Name:   f.Name,
 				EnvVar: f.EnvVar,
-				Usage:  f.Usage,
-
-				//TODO: Is this used with defaults? Can we convert the literal []string to cli.StringSlice properly?
-				Value: &cli.StringSlice{},
+				Usage:  flagUsage(f, f.Usage),
+				Value:  &value,
+			})
+		case *mcnflag.DurationFlag:
+			f := f.(*mcnflag.DurationFlag)
+			cliFlags = append(cliFlags, cli.DurationFlag{
+				Name:   f.Name,
+				EnvVar: f.EnvVar,
+				Usage:  flagUsage(f, f.Usage),
+				Value:  f.Value,
+			})
+		case *mcnflag.FloatFlag:
+			f := f.(*mcnflag.FloatFlag)
+			cliFlags = append(cliFlags, cli.Float64Flag{
+				Name:   f.Name,
+				EnvVar: f.EnvVar,
+				Usage:  flagUsage(f, f.Usage),
+				Value:  f.Value,
+			})
+		case *mcnflag.EnumFlag:
+			f := f.(*mcnflag.EnumFlag)
+			cliFlags = append(cliFlags, cli.StringFlag{
+				Name:   f.Name,
+				EnvVar: f.EnvVar,
+				Usage:  flagUsage(f, f.Usage),
+				Value:  f.Value,
+			})
+		case *mcnflag.SecretFlag:
+			// No value, so that nothing gives the secret away in the help.
+			f := f.(*mcnflag.SecretFlag)
+			cliFlags = append(cliFlags, cli.StringFlag{
+				Name:   f.Name,
+				EnvVar: f.EnvVar,
+				Usage:  flagUsage(f, f.Usage),
+			})
+		case *mcnflag.FilePathFlag:
+			f := f.(*mcnflag.FilePathFlag)
+			cliFlags = append(cliFlags, cli.StringFlag{
+				Name:   f.Name,
+				EnvVar: f.EnvVar,
+				Usage:  flagUsage(f, f.Usage),
+				Value:  f.Value,
 			})
 		default:
 			log.Warn("Flag is ", f)
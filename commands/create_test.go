@@ -1,8 +1,13 @@
 package commands
 
 import (
+	"flag"
+	"os"
 	"testing"
+	"time"
 
+	"github.com/docker/machine/cli"
+	"github.com/docker/machine/libmachine/mcnflag"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -20,3 +25,88 @@ func TestValidateSwarmDiscoveryAcceptsValidFormat(t *testing.T) {
 	err := validateSwarmDiscovery("token://deadbeefcafe")
 	assert.NoError(t, err)
 }
+
+func TestConvertMcnFlagsToCliFlags(t *testing.T) {
+	cliFlags, err := convertMcnFlagsToCliFlags([]mcnflag.Flag{
+		&mcnflag.StringSliceFlag{Name: "tags", Value: []string{"a", "b"}},
+		&mcnflag.DurationFlag{Name: "timeout", Usage: "Timeout", Value: time.Minute},
+		&mcnflag.FloatFlag{Name: "ratio", Value: 0.5},
+		&mcnflag.EnumFlag{Name: "size", Usage: "Size", Value: "small", Allowed: []string{"small", "large"}},
+		&mcnflag.SecretFlag{Name: "token", Usage: "Token", Required: true},
+		&mcnflag.FilePathFlag{Name: "key", Usage: "Key"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []cli.Flag{
+		cli.StringSliceFlag{Name: "tags", Value: &cli.StringSlice{"a", "b"}},
+		cli.DurationFlag{Name: "timeout", Usage: "Timeout", Value: time.Minute},
+		cli.Float64Flag{Name: "ratio", Value: 0.5},
+		cli.StringFlag{Name: "size", Usage: "Size (one of: small, large)", Value: "small"},
+		cli.StringFlag{Name: "token", Usage: "Token (secret) (required)"},
+		cli.StringFlag{Name: "key", Usage: "Key"},
+	}, cliFlags)
+}
+
+func TestGetDriverOptsValidation(t *testing.T) {
+	mcnFlags := []mcnflag.Flag{
+		&mcnflag.EnumFlag{Name: "size", Value: "small", Allowed: []string{"small", "large"}},
+		&mcnflag.DurationFlag{Name: "timeout", Value: time.Minute},
+		&mcnflag.StringFlag{Name: "region", Required: true},
+	}
+
+	cliFlags, err := convertMcnFlagsToCliFlags(mcnFlags)
+	assert.NoError(t, err)
+
+	set := flag.NewFlagSet("create", flag.ContinueOnError)
+	for _, f := range cliFlags {
+		f.Apply(set)
+	}
+	assert.NoError(t, set.Parse([]string{"--size", "medium", "--timeout", "90s"}))
+
+	c := &contextCommandLine{cli.NewContext(nil, set, nil)}
+	c.Command.Flags = cliFlags
+
+	driverOpts := getDriverOpts(c, mcnFlags)
+	assert.Equal(t, 90*time.Second, driverOpts.Duration("timeout"))
+
+	assert.EqualError(t, mcnflag.Validate(mcnFlags, driverOpts.Values), `Invalid value for --size: "medium" is not one of small, large`)
+
+	driverOpts.Values["size"] = "large"
+	assert.EqualError(t, mcnflag.Validate(mcnFlags, driverOpts.Values), "Invalid value for --region: the flag is required")
+
+	driverOpts.Values["region"] = "eu"
+	assert.NoError(t, mcnflag.Validate(mcnFlags, driverOpts.Values))
+}
+
+func TestGetDriverOptsRequiredNumbers(t *testing.T) {
+	mcnFlags := []mcnflag.Flag{
+		&mcnflag.IntFlag{Name: "cpus", EnvVar: "MACHINE_TEST_CPUS", Required: true},
+		&mcnflag.FloatFlag{Name: "ratio", Required: true},
+	}
+
+	cliFlags, err := convertMcnFlagsToCliFlags(mcnFlags)
+	assert.NoError(t, err)
+
+	parse := func(args ...string) map[string]interface{} {
+		set := flag.NewFlagSet("create", flag.ContinueOnError)
+		for _, f := range cliFlags {
+			f.Apply(set)
+		}
+		assert.NoError(t, set.Parse(args))
+
+		c := &contextCommandLine{cli.NewContext(nil, set, nil)}
+		c.Command.Flags = cliFlags
+
+		return getDriverOpts(c, mcnFlags).Values
+	}
+
+	assert.EqualError(t, mcnflag.Validate(mcnFlags, parse("--ratio", "0.5")), "Invalid value for --cpus: the flag is required")
+	assert.EqualError(t, mcnflag.Validate(mcnFlags, parse("--cpus", "2")), "Invalid value for --ratio: the flag is required")
+
+	// 0 is a value like any other.
+	assert.NoError(t, mcnflag.Validate(mcnFlags, parse("--cpus", "0", "--ratio", "0")))
+
+	os.Setenv("MACHINE_TEST_CPUS", "0")
+	defer os.Unsetenv("MACHINE_TEST_CPUS")
+	assert.NoError(t, mcnflag.Validate(mcnFlags, parse("--ratio", "0")))
+}
@@ -0,0 +1,316 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/docker/machine/cli"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/drivers/plugin/localbinary"
+	"github.com/docker/machine/libmachine/drivers/rpc"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/mcnflag"
+	"github.com/docker/machine/libmachine/version"
+)
+
+// driverCommandMachineName is the machine name given to the driver which runs
+// driver commands, which isn't about any machine.
+const driverCommandMachineName = "driver-command"
+
+var (
+	errExpectedOneDriver = errors.New("Error: Expected one driver name as an argument")
+	errExpectedOneSource = errors.New("Error: Expected one driver binary path or URL as an argument")
+)
+
+func cmdDriverLs(c CommandLine) error {
+	w := tabwriter.NewWriter(os.Stdout, 5, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tAPI VERSION\tPATH")
+
+	for _, plugin := range localbinary.ListPlugins() {
+		apiVersion := "-"
+		if info, err := rpcdriver.GetPluginInfo(plugin.DriverName); err != nil {
+			log.Debugf("Error getting info for driver %q: %s", plugin.DriverName, err)
+		} else {
+			apiVersion = fmt.Sprintf("%d", info.APIVersion)
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\n", plugin.DriverName, apiVersion, plugin.Path)
+	}
+
+	return w.Flush()
+}
+
+func cmdDriverInfo(c CommandLine) error {
+	if len(c.Args()) != 1 {
+		c.ShowHelp()
+		return errExpectedOneDriver
+	}
+
+	info, err := rpcdriver.GetPluginInfo(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Name:        %s\n", info.DriverName)
+	fmt.Printf("Path:        %s\n", info.Path)
+	fmt.Printf("API version: %d\n", info.APIVersion)
+
+	if !version.IsAPIVersionSupported(info.APIVersion) {
+		fmt.Printf("\nThis driver is not compatible with this version of %s, which supports API versions %d to %d.\n", os.Args[0], version.MinAPIVersion, version.APIVersion)
+		return nil
+	}
+
+	fmt.Println("\nCreate flags:")
+
+	w := tabwriter.NewWriter(os.Stdout, 5, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "FLAG\tTYPE\tDEFAULT\tENV\tUSAGE")
+	for _, flag := range info.CreateFlags {
+		flagType, usage, envVar := describeFlag(flag)
+		defaultValue := ""
+		if value := flag.Default(); value != nil {
+			defaultValue = fmt.Sprintf("%v", value)
+		}
+		fmt.Fprintf(w, "--%s\t%s\t%s\t%s\t%s\n", flag, flagType, defaultValue, envVar, usage)
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(info.Commands) == 0 {
+		return nil
+	}
+
+	fmt.Printf("\nCommands (run with '%s driver %s COMMAND'):\n", os.Args[0], info.DriverName)
+
+	w = tabwriter.NewWriter(os.Stdout, 5, 1, 3, ' ', 0)
+	for _, command := range info.Commands {
+		fmt.Fprintf(w, "%s\t%s\n", command.Name, command.Usage)
+	}
+
+	return w.Flush()
+}
+
+func describeFlag(flag mcnflag.Flag) (flagType, usage, envVar string) {
+	switch f := flag.(type) {
+	case *mcnflag.StringFlag:
+		return "string", flagUsage(f, f.Usage), f.EnvVar
+	case *mcnflag.StringSliceFlag:
+		return "string list", flagUsage(f, f.Usage), f.EnvVar
+	case *mcnflag.IntFlag:
+		return "int", flagUsage(f, f.Usage), f.EnvVar
+	case *mcnflag.BoolFlag:
+		return "bool", f.Usage, f.EnvVar
+	case *mcnflag.DurationFlag:
+		return "duration", flagUsage(f, f.Usage), f.EnvVar
+	case *mcnflag.FloatFlag:
+		return "float", flagUsage(f, f.Usage), f.EnvVar
+	case *mcnflag.EnumFlag:
+		return "enum", flagUsage(f, f.Usage), f.EnvVar
+	case *mcnflag.SecretFlag:
+		return "secret", flagUsage(f, f.Usage), f.EnvVar
+	case *mcnflag.FilePathFlag:
+		return "file path", flagUsage(f, f.Usage), f.EnvVar
+	}
+	return fmt.Sprintf("%T", flag), "", ""
+}
+
+// cmdDriverCommand runs a command offered by a driver, e.g.
+// "driver virtualbox hostonlyifs".  The commands of the driver are turned
+// into the commands of a CLI app of their own, so that they get their help
+// and flag parsing for free.
+func cmdDriverCommand(c CommandLine) error {
+	if len(c.Args()) == 0 {
+		c.ShowHelp()
+		return nil
+	}
+
+	driverName := c.Args().First()
+
+	// The driver isn't configured with SetConfigFromFlags: commands get
+	// the values of their own flags from RunCommand.
+	bareDriverData, err := json.Marshal(&drivers.BaseDriver{
+		MachineName: driverCommandMachineName,
+		StorePath:   c.GlobalString("storage-path"),
+	})
+	if err != nil {
+		return fmt.Errorf("Error attempting to marshal bare driver data: %s", err)
+	}
+
+	d, err := rpcdriver.NewRPCClientDriver(bareDriverData, driverName)
+	if err != nil {
+		return mcnerror.Wrapf(err, "Error loading driver %q", driverName)
+	}
+	defer d.Close()
+
+	commands := d.GetCommands()
+	if len(commands) == 0 {
+		return fmt.Errorf("Driver %q doesn't offer any commands", driverName)
+	}
+
+	var cmdErr error
+
+	app := cli.NewApp()
+	app.Name = fmt.Sprintf("%s %s", c.Application().Name, driverName)
+	app.Usage = fmt.Sprintf("Run the commands of the %s driver", driverName)
+	app.Writer = c.Application().Writer
+	app.Version = c.Application().Version
+	app.HideVersion = true
+	app.Action = func(context *cli.Context) {
+		if context.Args().Present() {
+			cmdErr = fmt.Errorf("Driver %q has no command %q", driverName, context.Args().First())
+			return
+		}
+		cli.ShowAppHelp(context)
+	}
+
+	for _, command := range commands {
+		cliFlags, err := convertMcnFlagsToCliFlags(command.Flags)
+		if err != nil {
+			return fmt.Errorf("Error trying to convert the flags of command %q: %s", command.Name, err)
+		}
+
+		command := command
+		app.Commands = append(app.Commands, cli.Command{
+			Name:  command.Name,
+			Usage: command.Usage,
+			Flags: cliFlags,
+			Action: func(context *cli.Context) {
+				cmdErr = runDriverCommand(d, command, &contextCommandLine{context})
+			},
+		})
+	}
+
+	if err := app.Run(append([]string{app.Name}, c.Args().Tail()...)); err != nil {
+		return err
+	}
+
+	return cmdErr
+}
+
+func runDriverCommand(d drivers.CommandRunner, command drivers.Command, c CommandLine) error {
+	driverOpts := getDriverOpts(c, command.Flags)
+
+	if err := mcnflag.Validate(command.Flags, driverOpts.Values); err != nil {
+		return err
+	}
+
+	output, err := d.RunCommand(command.Name, driverOpts, c.Args())
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(output)
+
+	return nil
+}
+
+func isURL(source string) bool {
+	return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// driverNameFromSource guesses the driver name from the file name of a
+// plugin binary, e.g. docker-machine-driver-foo.
+func driverNameFromSource(source string) string {
+	if isURL(source) {
+		source = strings.SplitN(source, "?", 2)[0]
+		return localbinary.DriverNameFromBinary(source[strings.LastIndex(source, "/")+1:])
+	}
+	return localbinary.DriverNameFromBinary(filepath.Base(source))
+}
+
+func openDriverSource(source string) (io.ReadCloser, error) {
+	if !isURL(source) {
+		return os.Open(source)
+	}
+
+	resp, err := http.Get(source)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Error downloading %s: %s", source, resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+func cmdDriverInstall(c CommandLine) error {
+	if len(c.Args()) != 1 {
+		c.ShowHelp()
+		return errExpectedOneSource
+	}
+
+	source := c.Args().First()
+	checksum := c.String("sha256")
+
+	driverName := c.String("name")
+	if driverName == "" {
+		driverName = driverNameFromSource(source)
+	}
+	if driverName == "" {
+		return fmt.Errorf("Cannot tell the driver name from %q, please use --name", source)
+	}
+
+	if isURL(source) && checksum == "" {
+		return fmt.Errorf("Refusing to install a driver downloaded from %s without a checksum, please use --sha256", source)
+	}
+
+	r, err := openDriverSource(source)
+	if err != nil {
+		return fmt.Errorf("Error reading driver binary: %s", err)
+	}
+	defer r.Close()
+
+	// Make sure we install something we can actually use, the driver
+	// installed before is kept otherwise.
+	var (
+		info      *rpcdriver.PluginInfo
+		verifyErr error
+	)
+	path, err := localbinary.InstallPlugin(driverName, r, checksum, func(path string) error {
+		info, verifyErr = rpcdriver.GetPluginInfoFromPath(driverName, path)
+		return verifyErr
+	})
+	if verifyErr != nil {
+		return fmt.Errorf("%s is not a working driver plugin: %s", source, verifyErr)
+	}
+	if err != nil {
+		return fmt.Errorf("Error installing driver %q: %s", driverName, err)
+	}
+
+	if !version.IsAPIVersionSupported(info.APIVersion) {
+		log.Warnf("Driver %q uses API version %d, but this version of %s supports API versions %d to %d", driverName, info.APIVersion, os.Args[0], version.MinAPIVersion, version.APIVersion)
+	}
+
+	log.Infof("Installed driver %q to %s", driverName, path)
+
+	return nil
+}
+
+func cmdDriverRemove(c CommandLine) error {
+	if len(c.Args()) != 1 {
+		c.ShowHelp()
+		return errExpectedOneDriver
+	}
+
+	driverName := c.Args().First()
+
+	if err := localbinary.RemovePlugin(driverName); err != nil {
+		return mcnerror.Wrapf(err, "Error removing driver %q", driverName)
+	}
+
+	log.Infof("Removed driver %q", driverName)
+
+	return nil
+}
@@ -0,0 +1,22 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDriverNameFromSource(t *testing.T) {
+	cases := map[string]string{
+		"/tmp/docker-machine-driver-foo":                                  "foo",
+		"docker-machine-driver-foo":                                       "foo",
+		"https://example.com/releases/docker-machine-driver-bar":          "bar",
+		"https://example.com/releases/docker-machine-driver-bar?raw=true": "bar",
+		"https://example.com/releases/latest":                             "",
+		"/tmp/foo":                                                        "",
+	}
+
+	for source, expected := range cases {
+		assert.Equal(t, expected, driverNameFromSource(source), source)
+	}
+}
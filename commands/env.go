@@ -55,11 +55,6 @@ func set(c CommandLine) error {
 		return err
 	}
 
-	dockerHost, _, err := runConnectionBoilerplate(host, c)
-	if err != nil {
-		return fmt.Errorf("Error running connection boilerplate: %s", err)
-	}
-
 	userShell, err := getShell(c)
 	if err != nil {
 		return err
@@ -67,12 +62,33 @@ func set(c CommandLine) error {
 
 	shellCfg := &ShellConfig{
 		DockerCertPath:  filepath.Join(mcndirs.GetMachineDir(), host.Name),
-		DockerHost:      dockerHost,
 		DockerTLSVerify: "1",
 		UsageHint:       generateUsageHint(userShell, os.Args),
 		MachineName:     host.Name,
 	}
 
+	if c.Bool("tunnel") {
+		if c.Bool("swarm") {
+			return errTunnelSwarm
+		}
+
+		// The tunnel goes to the socket of the daemon, which doesn't use
+		// TLS: the variables are emptied for the Docker client not to.
+		addr, err := startTunnel(c, host)
+		if err != nil {
+			return err
+		}
+		warnTCPTunnel(addr, host.Name)
+		shellCfg.DockerHost = addr.URL()
+		shellCfg.DockerCertPath = ""
+		shellCfg.DockerTLSVerify = ""
+	} else {
+		shellCfg.DockerHost, _, err = runConnectionBoilerplate(host, c)
+		if err != nil {
+			return fmt.Errorf("Error running connection boilerplate: %s", err)
+		}
+	}
+
 	if c.Bool("no-proxy") {
 		ip, err := host.Driver.GetIP()
 		if err != nil {
@@ -135,6 +151,16 @@ func unset(c CommandLine) error {
 		shellCfg.NoProxyVar, shellCfg.NoProxyValue = findNoProxyFromEnv()
 	}
 
+	// The tunnel started by env for the machine goes with the variables
+	// pointing at it.
+	if name := os.Getenv("DOCKER_MACHINE_NAME"); name != "" {
+		if _, addr, err := runningTunnel(name); err == nil && addr.URL() == os.Getenv("DOCKER_HOST") {
+			if err := stopTunnel(name); err != nil {
+				log.Warnf("Error stopping the SSH tunnel of %s: %s", name, err)
+			}
+		}
+	}
+
 	switch userShell {
 	case "fish":
 		shellCfg.Prefix = "set -e "
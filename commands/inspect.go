@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"text/template"
+
+	"github.com/docker/machine/libmachine/drivers"
 )
 
 var funcMap = template.FuncMap{
@@ -29,6 +31,20 @@ func cmdInspect(c CommandLine) error {
 		return err
 	}
 
+	jsonHost, err := json.Marshal(host)
+	if err != nil {
+		return err
+	}
+
+	obj := make(map[string]interface{})
+	if err := json.Unmarshal(jsonHost, &obj); err != nil {
+		return err
+	}
+
+	// Capabilities are not part of the stored configuration, they are
+	// queried from the driver.
+	obj["Capabilities"] = drivers.GetCapabilities(host.Driver)
+
 	tmplString := c.String("format")
 	if tmplString != "" {
 		var tmpl *template.Template
@@ -37,23 +53,13 @@ func cmdInspect(c CommandLine) error {
 			return fmt.Errorf("Template parsing error: %v\n", err)
 		}
 
-		jsonHost, err := json.Marshal(host)
-		if err != nil {
-			return err
-		}
-
-		obj := make(map[string]interface{})
-		if err := json.Unmarshal(jsonHost, &obj); err != nil {
-			return err
-		}
-
 		if err := tmpl.Execute(os.Stdout, obj); err != nil {
 			return err
 		}
 
 		os.Stdout.Write([]byte{'\n'})
 	} else {
-		prettyJSON, err := json.MarshalIndent(host, "", "    ")
+		prettyJSON, err := json.MarshalIndent(obj, "", "    ")
 		if err != nil {
 			return err
 		}
@@ -1,5 +1,12 @@
 package commands
 
 func cmdKill(c CommandLine) error {
-	return runActionWithContext("kill", c)
+	hosts, err := getHostsFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	stopTunnels(hosts)
+
+	return runActionForHosts("kill", c, hosts)
 }
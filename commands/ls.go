@@ -13,6 +13,7 @@ import (
 	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnerror"
 	"github.com/docker/machine/libmachine/state"
 	"github.com/docker/machine/libmachine/swarm"
 	"github.com/skarademir/naturalsort"
@@ -20,6 +21,8 @@ import (
 
 var (
 	stateTimeoutDuration = 10 * time.Second
+
+	errUnsupportedFilter = errors.New("Unsupported filter syntax.")
 )
 
 // FilterOptions -
@@ -37,10 +40,12 @@ type HostListItem struct {
 	State        state.State
 	URL          string
 	SwarmOptions *swarm.Options
+	Capabilities drivers.Capabilities
 }
 
 func cmdLs(c CommandLine) error {
 	quiet := c.Bool("quiet")
+	showCapabilities := c.Bool("capabilities")
 	filters, err := parseFilters(c.StringSlice("filter"))
 	if err != nil {
 		return err
@@ -66,7 +71,11 @@ func cmdLs(c CommandLine) error {
 	swarmInfo := make(map[string]string)
 
 	w := tabwriter.NewWriter(os.Stdout, 5, 1, 3, ' ', 0)
-	fmt.Fprintln(w, "NAME\tACTIVE\tDRIVER\tSTATE\tURL\tSWARM")
+	header := "NAME\tACTIVE\tDRIVER\tSTATE\tURL\tSWARM"
+	if showCapabilities {
+		header += "\tCAPABILITIES"
+	}
+	fmt.Fprintln(w, header)
 
 	for _, host := range hostList {
 		swarmOptions := host.HostOptions.SwarmOptions
@@ -79,7 +88,7 @@ func cmdLs(c CommandLine) error {
 		}
 	}
 
-	items := getHostListItems(hostList)
+	items := getHostListItems(hostList, showCapabilities)
 
 	sortHostListItemsByName(items)
 
@@ -97,8 +106,12 @@ func cmdLs(c CommandLine) error {
 				swarmInfo = fmt.Sprintf("%s (master)", swarmInfo)
 			}
 		}
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s",
 			item.Name, activeString, item.DriverName, item.State, item.URL, swarmInfo)
+		if showCapabilities {
+			fmt.Fprintf(w, "\t%s", item.Capabilities)
+		}
+		fmt.Fprintln(w)
 	}
 
 	w.Flush()
@@ -111,7 +124,7 @@ func parseFilters(filters []string) (FilterOptions, error) {
 	for _, f := range filters {
 		kv := strings.SplitN(f, "=", 2)
 		if len(kv) != 2 {
-			return options, errors.New("Unsupported filter syntax.")
+			return options, errUnsupportedFilter
 		}
 		key, value := kv[0], kv[1]
 
@@ -201,7 +214,7 @@ func matchesState(host *host.Host, states []string) bool {
 		return true
 	}
 	for _, n := range states {
-		s, err := host.Driver.GetState()
+		s, err := host.GetState()
 		if err != nil {
 			log.Warn(err)
 		}
@@ -229,10 +242,26 @@ func matchesName(host *host.Host, names []string) bool {
 	return false
 }
 
-func attemptGetHostState(h *host.Host, stateQueryChan chan<- HostListItem) {
+func attemptGetHostState(h *host.Host, withCapabilities bool, stateQueryChan chan<- HostListItem) {
 	stateCh := make(chan state.State)
 	urlCh := make(chan string)
 
+	// The machine may not exist at the provider yet (or any more), so
+	// don't bother asking the driver about it.
+	if lifecycleState := h.LifecycleState(); lifecycleState != state.None {
+		item := HostListItem{
+			Name:         h.Name,
+			DriverName:   h.Driver.DriverName(),
+			State:        lifecycleState,
+			SwarmOptions: h.HostOptions.SwarmOptions,
+		}
+		if withCapabilities {
+			item.Capabilities = drivers.GetCapabilities(h.Driver)
+		}
+		stateQueryChan <- item
+		return
+	}
+
 	go func() {
 		currentState, err := h.Driver.GetState()
 		if err != nil {
@@ -245,7 +274,7 @@ func attemptGetHostState(h *host.Host, stateQueryChan chan<- HostListItem) {
 	go func() {
 		url, err := h.GetURL()
 		if err != nil {
-			if err.Error() == drivers.ErrHostIsNotRunning.Error() {
+			if _, ok := mcnerror.Cause(err).(mcnerror.ErrHostNotRunning); ok {
 				url = ""
 			} else {
 				log.Errorf("error getting URL for host %s: %s", h.Name, err)
@@ -267,7 +296,7 @@ func attemptGetHostState(h *host.Host, stateQueryChan chan<- HostListItem) {
 			h.Name, err)
 	}
 
-	stateQueryChan <- HostListItem{
+	item := HostListItem{
 		Name:         h.Name,
 		Active:       active,
 		DriverName:   h.Driver.DriverName(),
@@ -275,14 +304,22 @@ func attemptGetHostState(h *host.Host, stateQueryChan chan<- HostListItem) {
 		URL:          url,
 		SwarmOptions: h.HostOptions.SwarmOptions,
 	}
+
+	// Asking a plugin for its capabilities is another round trip, so only
+	// do it when they're shown.
+	if withCapabilities {
+		item.Capabilities = drivers.GetCapabilities(h.Driver)
+	}
+
+	stateQueryChan <- item
 }
 
-func getHostState(h *host.Host, hostListItemsChan chan<- HostListItem) {
+func getHostState(h *host.Host, withCapabilities bool, hostListItemsChan chan<- HostListItem) {
 	// This channel is used to communicate the properties we are querying
 	// about the host in the case of a successful read.
 	stateQueryChan := make(chan HostListItem)
 
-	go attemptGetHostState(h, stateQueryChan)
+	go attemptGetHostState(h, withCapabilities, stateQueryChan)
 
 	select {
 	// If we get back useful information, great.  Forward it straight to
@@ -300,12 +337,12 @@ func getHostState(h *host.Host, hostListItemsChan chan<- HostListItem) {
 	}
 }
 
-func getHostListItems(hostList []*host.Host) []HostListItem {
+func getHostListItems(hostList []*host.Host, withCapabilities bool) []HostListItem {
 	hostListItems := []HostListItem{}
 	hostListItemsChan := make(chan HostListItem)
 
 	for _, h := range hostList {
-		go getHostState(h, hostListItemsChan)
+		go getHostState(h, withCapabilities, hostListItemsChan)
 	}
 
 	for range hostList {
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/state"
 	"github.com/docker/machine/libmachine/swarm"
@@ -363,7 +364,7 @@ func TestGetHostListItems(t *testing.T) {
 
 	items := []HostListItem{}
 	for _, host := range hosts {
-		go getHostState(host, hostListItemsChan)
+		go getHostState(host, false, hostListItemsChan)
 	}
 
 	for i := 0; i < len(hosts); i++ {
@@ -377,6 +378,45 @@ func TestGetHostListItems(t *testing.T) {
 	}
 }
 
+func TestGetHostListItemsShowsLifecycleState(t *testing.T) {
+	h := &host.Host{
+		Name:       "foo",
+		DriverName: "fakedriver",
+		Driver: &fakedriver.Driver{
+			MockState: state.Error,
+		},
+		HostOptions: &host.Options{
+			SwarmOptions: &swarm.Options{},
+		},
+	}
+	h.SetTransition(state.Creating, "Creating machine")
+
+	items := getHostListItems([]*host.Host{h}, false)
+
+	assert.Equal(t, 1, len(items))
+	assert.Equal(t, state.Creating, items[0].State)
+	assert.Equal(t, "", items[0].URL)
+}
+
+func TestGetHostListItemsCapabilities(t *testing.T) {
+	h := &host.Host{
+		Name:       "foo",
+		DriverName: "fakedriver",
+		Driver: &fakedriver.Driver{
+			MockState: state.Running,
+		},
+		HostOptions: &host.Options{
+			SwarmOptions: &swarm.Options{},
+		},
+	}
+
+	items := getHostListItems([]*host.Host{h}, false)
+	assert.Nil(t, items[0].Capabilities)
+
+	items = getHostListItems([]*host.Host{h}, true)
+	assert.Equal(t, drivers.DefaultCapabilities, items[0].Capabilities)
+}
+
 // issue #1908
 func TestGetHostListItemsEnvDockerHostUnset(t *testing.T) {
 	orgDockerHost := os.Getenv("DOCKER_HOST")
@@ -449,7 +489,7 @@ func TestGetHostListItemsEnvDockerHostUnset(t *testing.T) {
 
 	items := []HostListItem{}
 	for _, host := range hosts {
-		go getHostState(host, hostListItemsChan)
+		go getHostState(host, false, hostListItemsChan)
 	}
 
 	for i := 0; i < len(hosts); i++ {
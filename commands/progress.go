@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/docker/machine/libmachine/events"
+)
+
+const progressBarWidth = 30
+
+// progressPrinter renders the progress events of drivers.  On a terminal,
+// a phase gets a progress bar which is redrawn in place; elsewhere, a line
+// is printed every 10%.
+type progressPrinter struct {
+	lock     sync.Mutex
+	out      io.Writer
+	terminal bool
+
+	// phase is the phase last printed, and percent how far it was.
+	phase   string
+	percent int
+	drawing bool
+}
+
+// NewProgressPrinter returns the events handler which renders the progress
+// of drivers to out.
+func NewProgressPrinter(out io.Writer, terminal bool) events.Handler {
+	p := &progressPrinter{
+		out:      out,
+		terminal: terminal,
+	}
+	return p.handle
+}
+
+func (p *progressPrinter) handle(e events.Event) {
+	if e.Kind != events.KindProgress {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// Downloads to the cache aren't done for any machine in particular.
+	phase := e.Phase
+	if e.MachineName != "" {
+		phase = fmt.Sprintf("(%s) %s", e.MachineName, e.Phase)
+	}
+	samePhase := phase == p.phase
+	if !samePhase && p.drawing {
+		fmt.Fprintln(p.out)
+		p.drawing = false
+	}
+
+	switch {
+	case e.Percent == events.UnknownPercent:
+		if !samePhase || e.Message != "" {
+			fmt.Fprintln(p.out, withMessage(phase+"...", e.Message))
+		}
+	case p.terminal:
+		fmt.Fprintf(p.out, "\r%s", withMessage(fmt.Sprintf("%s %s %3d%%", phase, progressBar(e.Percent), e.Percent), e.Message))
+		p.drawing = e.Percent < 100
+		if !p.drawing {
+			fmt.Fprintln(p.out)
+		}
+	case !samePhase || e.Percent/10 > p.percent/10:
+		fmt.Fprintln(p.out, withMessage(fmt.Sprintf("%s: %d%%", phase, e.Percent), e.Message))
+	}
+
+	p.phase, p.percent = phase, e.Percent
+}
+
+func withMessage(s, message string) string {
+	if message == "" {
+		return s
+	}
+	return s + " " + message
+}
+
+func progressBar(percent int) string {
+	if percent > 100 {
+		percent = 100
+	}
+	if percent < 0 {
+		percent = 0
+	}
+
+	done := percent * progressBarWidth / 100
+	bar := strings.Repeat("=", done)
+	if done < progressBarWidth {
+		bar += ">" + strings.Repeat(" ", progressBarWidth-done-1)
+	}
+
+	return "[" + bar + "]"
+}
@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/docker/machine/libmachine/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func progress(handle events.Handler, phase string, percent int, message string) {
+	handle(events.Event{
+		Kind:        events.KindProgress,
+		MachineName: "default",
+		Phase:       phase,
+		Percent:     percent,
+		Message:     message,
+	})
+}
+
+func TestProgressPrinter(t *testing.T) {
+	out := &bytes.Buffer{}
+	handle := NewProgressPrinter(out, false)
+
+	for percent := 0; percent <= 100; percent += 2 {
+		progress(handle, "Copying boot2docker.iso", percent, "")
+	}
+	handle(events.Event{Kind: events.KindLog, Message: "Created"})
+	progress(handle, "Waiting for an IP", events.UnknownPercent, "")
+	progress(handle, "Waiting for an IP", events.UnknownPercent, "")
+
+	assert.Equal(t, `(default) Copying boot2docker.iso: 0%
+(default) Copying boot2docker.iso: 10%
+(default) Copying boot2docker.iso: 20%
+(default) Copying boot2docker.iso: 30%
+(default) Copying boot2docker.iso: 40%
+(default) Copying boot2docker.iso: 50%
+(default) Copying boot2docker.iso: 60%
+(default) Copying boot2docker.iso: 70%
+(default) Copying boot2docker.iso: 80%
+(default) Copying boot2docker.iso: 90%
+(default) Copying boot2docker.iso: 100%
+(default) Waiting for an IP...
+`, out.String())
+}
+
+func TestProgressPrinterTerminal(t *testing.T) {
+	out := &bytes.Buffer{}
+	handle := NewProgressPrinter(out, true)
+
+	progress(handle, "Booting", 0, "")
+	progress(handle, "Booting", 50, "kernel")
+	progress(handle, "Copying", 100, "")
+
+	assert.Equal(t, "\r(default) Booting [>                             ]   0%"+
+		"\r(default) Booting [===============>              ]  50% kernel\n"+
+		"\r(default) Copying [==============================] 100%\n", out.String())
+}
+
+func TestProgressPrinterWithoutMachine(t *testing.T) {
+	out := &bytes.Buffer{}
+	handle := NewProgressPrinter(out, false)
+
+	handle(events.Event{Kind: events.KindProgress, Phase: "Downloading boot2docker.iso", Percent: 0})
+
+	assert.Equal(t, "Downloading boot2docker.iso: 0%\n", out.String())
+}
@@ -0,0 +1,5 @@
+package commands
+
+func cmdResetHostKey(c CommandLine) error {
+	return runActionWithContext("resetHostKey", c)
+}
@@ -2,15 +2,20 @@ package commands
 
 import (
 	"errors"
-	"fmt"
 
 	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/state"
+)
+
+var (
+	errMissingMachineName = errors.New("You must specify a machine name")
 )
 
 func cmdRm(c CommandLine) error {
 	if len(c.Args()) == 0 {
 		c.ShowHelp()
-		return errors.New("You must specify a machine name")
+		return errMissingMachineName
 	}
 
 	force := c.Bool("force")
@@ -19,12 +24,25 @@ func cmdRm(c CommandLine) error {
 	for _, hostName := range c.Args() {
 		h, err := loadHost(store, hostName)
 		if err != nil {
-			return fmt.Errorf("Error removing host %q: %s", hostName, err)
+			return mcnerror.Wrapf(err, "Error removing host %q", hostName)
+		}
+
+		if err := stopTunnel(hostName); err != nil {
+			log.Warnf("Error stopping the SSH tunnel of %s: %s", hostName, err)
+		}
+
+		h.SetTransition(state.Removing, "Removing machine")
+		if err := saveHost(store, h); err != nil {
+			log.Debugf("Error recording removal of machine %q: %s", hostName, err)
 		}
 
 		if err := h.Driver.Remove(); err != nil {
 			if !force {
 				log.Errorf("Provider error removing machine %q: %s", hostName, err)
+				h.SetTransition(state.Error, err.Error())
+				if err := saveHost(store, h); err != nil {
+					log.Debugf("Error recording failed removal of machine %q: %s", hostName, err)
+				}
 				continue
 			}
 		}
@@ -1,24 +1,31 @@
 package commands
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"os/exec"
+	"path"
+	"path/filepath"
 	"strings"
 
+	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/persist"
+	"github.com/docker/machine/libmachine/ssh"
 )
 
 var (
 	errWrongNumberArguments = errors.New("Improper number of arguments")
+	errRecursiveCopy        = errors.New("Error: copying directories needs the scp binary")
+	errDifferentJumpHosts   = errors.New("Error: scp can't copy between machines behind different jump hosts")
 
 	// TODO: possibly move this to ssh package
 	baseSSHArgs = []string{
 		"-o", "IdentitiesOnly=yes",
-		"-o", "StrictHostKeyChecking=no",
-		"-o", "UserKnownHostsFile=/dev/null",
 		"-o", "LogLevel=quiet", // suppress "Warning: Permanently added '[localhost]:2022' (ECDSA) to the list of known hosts."
 	}
 )
@@ -49,6 +56,10 @@ func (s *storeHostInfoLoader) load(name string) (HostInfo, error) {
 		return nil, fmt.Errorf("Error loading host: %s", err)
 	}
 
+	if err := host.SupportsAction(drivers.CapabilitySSH); err != nil {
+		return nil, err
+	}
+
 	return host.Driver, nil
 }
 
@@ -65,6 +76,11 @@ func cmdScp(c CommandLine) error {
 	store := getStore(c)
 	hostInfoLoader := &storeHostInfoLoader{store}
 
+	if _, err := exec.LookPath("scp"); err != nil {
+		log.Debug("scp binary not found, copying over SSH")
+		return copyOverSSH(src, dest, c.Bool("recursive"), hostInfoLoader)
+	}
+
 	cmd, err := getScpCmd(src, dest, c.Bool("recursive"), hostInfoLoader)
 	if err != nil {
 		return err
@@ -93,9 +109,21 @@ func getScpCmd(src, dest string, recursive bool, hostInfoLoader HostInfoLoader)
 		return nil, err
 	}
 
+	jumpArgs, err := getScpJumpArgs(srcHost, destHost)
+	if err != nil {
+		return nil, err
+	}
+
+	knownHostsArgs, err := getScpKnownHostsArgs(srcHost, destHost)
+	if err != nil {
+		return nil, err
+	}
+
 	// TODO: Check that "-3" flag is available in user's version of scp.
 	// It is on every system I've checked, but the manual mentioned it's "newer"
-	sshArgs := baseSSHArgs
+	sshArgs := append([]string{}, baseSSHArgs...)
+	sshArgs = append(sshArgs, knownHostsArgs...)
+	sshArgs = append(sshArgs, jumpArgs...)
 	sshArgs = append(sshArgs, "-3")
 	if recursive {
 		sshArgs = append(sshArgs, "-r")
@@ -123,6 +151,96 @@ func getScpCmd(src, dest string, recursive bool, hostInfoLoader HostInfoLoader)
 	return cmd, nil
 }
 
+// getScpJumpArgs returns the options for scp to reach the machines through
+// their jump host.  Both machines of a copy between machines must have the
+// same, scp can only be given one.  The key of the jump host is checked
+// against the known_hosts files of the machines, where it's recorded.
+func getScpJumpArgs(hosts ...HostInfo) ([]string, error) {
+	var (
+		jumpHost   *ssh.JumpHost
+		knownHosts []string
+		seen       bool
+	)
+
+	for _, hostInfo := range hosts {
+		d, ok := hostInfo.(drivers.Driver)
+		if !ok {
+			continue
+		}
+
+		jump, err := drivers.GetSSHJumpHost(d)
+		if err != nil {
+			return nil, err
+		}
+
+		if seen && jumpHostID(jump) != jumpHostID(jumpHost) {
+			return nil, errDifferentJumpHosts
+		}
+
+		jumpHost, seen = jump, true
+		if path := drivers.KnownHostsPath(d); path != "" {
+			knownHosts = append(knownHosts, path)
+		}
+	}
+
+	if jumpHost == nil {
+		return nil, nil
+	}
+
+	sshBinaryPath, err := exec.LookPath("ssh")
+	if err != nil {
+		sshBinaryPath = "ssh"
+	}
+
+	return ssh.ProxyCommandArgs(sshBinaryPath, jumpHost, knownHosts...), nil
+}
+
+// jumpHostID tells jump hosts apart, by their address and the keys used to
+// log in to them.
+func jumpHostID(jump *ssh.JumpHost) string {
+	if jump == nil {
+		return ""
+	}
+	return jump.String() + " " + strings.Join(jump.Keys, " ")
+}
+
+// getScpKnownHostsArgs returns the options for scp to check the host keys
+// of the machines, once they are recorded in their known_hosts files.
+func getScpKnownHostsArgs(hosts ...HostInfo) ([]string, error) {
+	knownHosts := []string{}
+
+	for _, hostInfo := range hosts {
+		d, ok := hostInfo.(drivers.Driver)
+		if !ok {
+			continue
+		}
+
+		path := drivers.KnownHostsPath(d)
+		if path == "" {
+			continue
+		}
+
+		ip, err := d.GetIP()
+		if err != nil {
+			return nil, err
+		}
+
+		jump, err := drivers.GetSSHJumpHost(d)
+		if err != nil {
+			return nil, err
+		}
+
+		// scp connects to the default port of the IP of the machine.
+		if err := ssh.RecordHostKey(path, drivers.HostKeyAlias(d), net.JoinHostPort(ip, "22"), jump); err != nil {
+			return nil, err
+		}
+
+		knownHosts = append(knownHosts, path)
+	}
+
+	return ssh.KnownHostsArgs(knownHosts...), nil
+}
+
 func getInfoForScpArg(hostAndPath string, hostInfoLoader HostInfoLoader) (HostInfo, string, []string, error) {
 	// Local path.  e.g. "/tmp/foo"
 	if !strings.Contains(hostAndPath, ":") {
@@ -164,6 +282,101 @@ func generateLocationArg(hostInfo HostInfo, path string) (string, error) {
 	return location, nil
 }
 
+// getSSHClient returns an SSH client of a machine to copy files with.
+var getSSHClient = func(hostInfo HostInfo) (ssh.Client, error) {
+	d, ok := hostInfo.(drivers.Driver)
+	if !ok {
+		return nil, fmt.Errorf("Error connecting to %q over SSH", hostInfo.GetMachineName())
+	}
+	return drivers.GetSSHClientFromDriver(d)
+}
+
+// copyOverSSH copies a file like scp would, with the SSH clients of the
+// machines rather than the scp binary.  Files copied from a machine are
+// read in memory first.
+func copyOverSSH(src, dest string, recursive bool, hostInfoLoader HostInfoLoader) error {
+	if recursive {
+		return errRecursiveCopy
+	}
+
+	srcHost, srcPath, _, err := getInfoForScpArg(src, hostInfoLoader)
+	if err != nil {
+		return err
+	}
+
+	destHost, destPath, _, err := getInfoForScpArg(dest, hostInfoLoader)
+	if err != nil {
+		return err
+	}
+
+	var (
+		content io.Reader
+		size    int64
+		mode    os.FileMode = 0644
+	)
+
+	if srcHost == nil {
+		file, err := os.Open(srcPath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		info, err := file.Stat()
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return errRecursiveCopy
+		}
+
+		content, size, mode = file, info.Size(), info.Mode().Perm()
+	} else {
+		client, err := getSSHClient(srcHost)
+		if err != nil {
+			return err
+		}
+
+		buf := &bytes.Buffer{}
+		if err := client.Download(srcPath, buf); err != nil {
+			return err
+		}
+
+		content, size = buf, int64(buf.Len())
+	}
+
+	if destHost == nil {
+		if info, err := os.Stat(destPath); err == nil && info.IsDir() {
+			destPath = filepath.Join(destPath, path.Base(srcPath))
+		}
+
+		file, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.Copy(file, content); err != nil {
+			file.Close()
+			return err
+		}
+
+		return file.Close()
+	}
+
+	// Like scp, copy into the home directory or a directory ending with
+	// a slash under the name of the source.
+	if destPath == "" || strings.HasSuffix(destPath, "/") {
+		destPath += path.Base(filepath.ToSlash(srcPath))
+	}
+
+	client, err := getSSHClient(destHost)
+	if err != nil {
+		return err
+	}
+
+	return client.Upload(content, size, destPath, mode)
+}
+
 func runCmdWithStdIo(cmd exec.Cmd) error {
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
@@ -1,9 +1,18 @@
 package commands
 
 import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/ssh"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -99,8 +108,10 @@ func TestGetScpCmd(t *testing.T) {
 
 	cmd, err := getScpCmd("/tmp/foo", "myfunhost:/home/docker/foo", true, &hostInfoLoader)
 
-	expectedArgs := append(
-		baseSSHArgs,
+	expectedArgs := append([]string{}, baseSSHArgs...)
+	expectedArgs = append(expectedArgs, ssh.KnownHostsArgs()...)
+	expectedArgs = append(
+		expectedArgs,
 		"-3",
 		"-r",
 		"-i",
@@ -113,3 +124,151 @@ func TestGetScpCmd(t *testing.T) {
 	assert.Equal(t, expectedCmd, cmd)
 	assert.NoError(t, err)
 }
+
+type MockSSHClient struct {
+	files map[string]string
+	modes map[string]os.FileMode
+}
+
+func (c *MockSSHClient) Output(command string) (string, error) {
+	return "", nil
+}
+
+func (c *MockSSHClient) Shell(args ...string) error {
+	return nil
+}
+
+func (c *MockSSHClient) Run(command string) (*ssh.Result, error) {
+	return &ssh.Result{}, nil
+}
+
+func (c *MockSSHClient) Stream(command string, stdout, stderr io.Writer) error {
+	return nil
+}
+
+func (c *MockSSHClient) Upload(src io.Reader, size int64, dest string, mode os.FileMode) error {
+	data, err := ioutil.ReadAll(src)
+	if err != nil {
+		return err
+	}
+	c.files[dest] = string(data)
+	c.modes[dest] = mode
+	return nil
+}
+
+func (c *MockSSHClient) Download(src string, dest io.Writer) error {
+	data, ok := c.files[src]
+	if !ok {
+		return fmt.Errorf("scp: %s: No such file or directory", src)
+	}
+	_, err := io.WriteString(dest, data)
+	return err
+}
+
+func (c *MockSSHClient) Forward(local, remote ssh.Addr) (ssh.Tunnel, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (c *MockSSHClient) Close() error {
+	return nil
+}
+
+func TestCopyOverSSH(t *testing.T) {
+	client := &MockSSHClient{files: map[string]string{}, modes: map[string]os.FileMode{}}
+	defer func(get func(HostInfo) (ssh.Client, error)) { getSSHClient = get }(getSSHClient)
+	getSSHClient = func(hostInfo HostInfo) (ssh.Client, error) {
+		return client, nil
+	}
+
+	hostInfoLoader := &MockHostInfoLoader{MockHostInfo{}}
+
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	local := filepath.Join(dir, "foo")
+	assert.NoError(t, ioutil.WriteFile(local, []byte("bar"), 0600))
+
+	assert.NoError(t, copyOverSSH(local, "myfunhost:/home/docker/foo", false, hostInfoLoader))
+	assert.Equal(t, "bar", client.files["/home/docker/foo"])
+	assert.Equal(t, os.FileMode(0600), client.modes["/home/docker/foo"])
+
+	assert.NoError(t, copyOverSSH(local, "myfunhost:", false, hostInfoLoader))
+	assert.Equal(t, "bar", client.files["foo"])
+
+	assert.NoError(t, copyOverSSH(local, "myfunhost:/tmp/", false, hostInfoLoader))
+	assert.Equal(t, "bar", client.files["/tmp/foo"])
+
+	assert.NoError(t, copyOverSSH("myfunhost:/home/docker/foo", dir+string(filepath.Separator), false, hostInfoLoader))
+	data, err := ioutil.ReadFile(local)
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", string(data))
+
+	assert.NoError(t, copyOverSSH("myfunhost:/home/docker/foo", "otherhost:/tmp/copy", false, hostInfoLoader))
+	assert.Equal(t, "bar", client.files["/tmp/copy"])
+
+	assert.EqualError(t, copyOverSSH("myfunhost:/nope", local, false, hostInfoLoader), "scp: /nope: No such file or directory")
+	assert.Equal(t, errRecursiveCopy, copyOverSSH(dir, "myfunhost:/tmp/", false, hostInfoLoader))
+	assert.Equal(t, errRecursiveCopy, copyOverSSH(local, "myfunhost:/tmp/", true, hostInfoLoader))
+}
+
+func TestGetScpJumpArgs(t *testing.T) {
+	direct := &fakedriver.Driver{BaseDriver: &drivers.BaseDriver{MachineName: "direct", SSHUser: "docker"}}
+	behindBastion := &fakedriver.Driver{MockName: "private", BaseDriver: &drivers.BaseDriver{MachineName: "private", StorePath: "/store", SSHJumpHost: "ubuntu@bastion:2222", SSHJumpKeyPath: "/store/id_rsa"}}
+	alsoBehindBastion := &fakedriver.Driver{BaseDriver: &drivers.BaseDriver{MachineName: "private2", SSHJumpHost: "ubuntu@bastion:2222", SSHJumpKeyPath: "/store/id_rsa"}}
+
+	args, err := getScpJumpArgs(nil, &MockHostInfo{})
+	assert.NoError(t, err)
+	assert.Empty(t, args)
+
+	args, err = getScpJumpArgs(direct, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, args)
+
+	args, err = getScpJumpArgs(nil, behindBastion)
+	assert.NoError(t, err)
+	assert.Len(t, args, 2)
+	assert.Equal(t, "-o", args[0])
+	assert.True(t, strings.HasSuffix(args[1], "-i /store/id_rsa -p 2222 -W %h:%p ubuntu@bastion"), args[1])
+	assert.Contains(t, args[1], "-o UserKnownHostsFile="+filepath.Join("/store", "machines", "private", "known_hosts"))
+
+	args2, err := getScpJumpArgs(behindBastion, alsoBehindBastion)
+	assert.NoError(t, err)
+	assert.Equal(t, args, args2)
+
+	otherKey := &fakedriver.Driver{BaseDriver: &drivers.BaseDriver{MachineName: "private3", SSHJumpHost: "ubuntu@bastion:2222", SSHJumpKeyPath: "/store/other_rsa"}}
+	_, err = getScpJumpArgs(behindBastion, otherKey)
+	assert.Equal(t, errDifferentJumpHosts, err)
+
+	_, err = getScpJumpArgs(behindBastion, direct)
+	assert.Equal(t, errDifferentJumpHosts, err)
+}
+
+func TestGetScpKnownHostsArgs(t *testing.T) {
+	storePath, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(storePath)
+
+	d := &fakedriver.Driver{MockName: "dev", BaseDriver: &drivers.BaseDriver{MachineName: "dev", StorePath: storePath}}
+
+	// The key is already recorded, so the machine isn't connected to.
+	knownHosts := drivers.KnownHostsPath(d)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(knownHosts), 0700))
+	keyPath := filepath.Join(storePath, "id_rsa")
+	assert.NoError(t, ssh.GenerateSSHKey(keyPath))
+	publicKey, err := ioutil.ReadFile(keyPath + ".pub")
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(knownHosts, append([]byte("docker-machine:dev,1.2.3.4 "), publicKey...), 0600))
+
+	args, err := getScpKnownHostsArgs(nil, d)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"-o", "StrictHostKeyChecking=yes",
+		"-o", "CheckHostIP=no",
+		"-o", "UserKnownHostsFile=" + knownHosts,
+	}, args)
+
+	args, err = getScpKnownHostsArgs(nil, &MockHostInfo{})
+	assert.NoError(t, err)
+	assert.Equal(t, ssh.KnownHostsArgs(), args)
+}
@@ -1,8 +1,9 @@
 package commands
 
 import (
-	"fmt"
-
+	"github.com/docker/machine/cli"
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/ssh"
 	"github.com/docker/machine/libmachine/state"
 )
 
@@ -15,7 +16,9 @@ func cmdSSH(c CommandLine) error {
 		}
 	}
 
-	name := c.Args().First()
+	forwardAgent, args := parseSSHArgs(c.Args())
+
+	name := args.First()
 	if name == "" {
 		return ErrExpectedOneMachine
 	}
@@ -32,13 +35,28 @@ func cmdSSH(c CommandLine) error {
 	}
 
 	if currentState != state.Running {
-		return fmt.Errorf("Error: Cannot run SSH command: Host %q is not running", host.Name)
+		return mcnerror.Wrapf(mcnerror.ErrHostNotRunning{Name: host.Name}, "Error: Cannot run SSH command")
 	}
 
-	client, err := host.CreateSSHClient()
+	var client ssh.Client
+	if forwardAgent {
+		client, err = host.CreateSSHClientForwardingAgent()
+	} else {
+		client, err = host.CreateSSHClient()
+	}
 	if err != nil {
 		return err
 	}
 
-	return client.Shell(c.Args().Tail()...)
+	return client.Shell(args.Tail()...)
+}
+
+// parseSSHArgs reads the -A option, given before the machine name to
+// forward the SSH agent like ssh does.  The flags aren't parsed, so that the
+// ones of the command run on the machine are left alone.
+func parseSSHArgs(args cli.Args) (bool, cli.Args) {
+	if len(args) > 0 && args[0] == "-A" {
+		return true, args[1:]
+	}
+	return false, args
 }
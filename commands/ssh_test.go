@@ -0,0 +1,22 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/docker/machine/cli"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSSHArgs(t *testing.T) {
+	forwardAgent, args := parseSSHArgs(cli.Args{"-A", "dev", "ls", "-A"})
+	assert.True(t, forwardAgent)
+	assert.Equal(t, cli.Args{"dev", "ls", "-A"}, args)
+
+	forwardAgent, args = parseSSHArgs(cli.Args{"dev", "ls", "-A"})
+	assert.False(t, forwardAgent)
+	assert.Equal(t, cli.Args{"dev", "ls", "-A"}, args)
+
+	forwardAgent, args = parseSSHArgs(cli.Args{})
+	assert.False(t, forwardAgent)
+	assert.Empty(t, args)
+}
@@ -1,5 +1,13 @@
 package commands
 
 func cmdStop(c CommandLine) error {
-	return runActionWithContext("stop", c)
+	hosts, err := getHostsFromContext(c)
+	if err != nil {
+		return err
+	}
+
+	// The tunnels started by env would be left forwarding to nothing.
+	stopTunnels(hosts)
+
+	return runActionForHosts("stop", c, hosts)
 }
@@ -0,0 +1,318 @@
+package commands
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/docker/machine/commands/mcndirs"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/ssh"
+	"github.com/docker/machine/libmachine/state"
+)
+
+// tunnelPidFile records the tunnel started in the background by env, in the
+// machine's directory: its pid on the first line, and its address on the
+// second.
+const tunnelPidFile = "tunnel.pid"
+
+var errTunnelSwarm = errors.New("Error: --tunnel can't be used with --swarm, the tunnel goes to the Docker daemon of the machine")
+
+func cmdTunnel(c CommandLine) error {
+	// The address of the tunnel is the only thing printed on stdout, for
+	// scripts to read it.
+	log.SetOutWriter(os.Stderr)
+
+	if len(c.Args()) != 1 {
+		return ErrExpectedOneMachine
+	}
+
+	host, err := getFirstArgHost(c)
+	if err != nil {
+		return err
+	}
+
+	local, err := machineTunnelAddr(host.Name)
+	if err != nil {
+		return err
+	}
+	if c.String("local") != "" {
+		if local, err = ssh.ParseAddr(c.String("local")); err != nil {
+			return err
+		}
+	}
+
+	tunnel, err := openTunnel(host, local)
+	if err != nil {
+		return err
+	}
+	defer tunnel.Close()
+
+	fmt.Println(tunnel.Addr().URL())
+	warnTCPTunnel(tunnel.Addr(), host.Name)
+	log.Infof("Forwarding %s to the Docker daemon of %s, press Ctrl-C to stop", tunnel.Addr().URL(), host.Name)
+
+	return tunnel.Wait()
+}
+
+// warnTCPTunnel warns that a tunnel listening on a TCP port lets every user
+// of the computer in: the daemon is reached through its socket, without TLS.
+func warnTCPTunnel(addr ssh.Addr, name string) {
+	if addr.Net == "tcp" {
+		log.Warnf("Every user of this computer can reach the Docker daemon of %s through %s, which doesn't use TLS", name, addr.URL())
+	}
+}
+
+// openTunnel forwards a local address to the Docker socket of a machine,
+// over SSH.
+func openTunnel(h *host.Host, local ssh.Addr) (ssh.Tunnel, error) {
+	currentState, err := h.Driver.GetState()
+	if err != nil {
+		return nil, err
+	}
+
+	if currentState != state.Running {
+		return nil, mcnerror.Wrapf(mcnerror.ErrHostNotRunning{Name: h.Name}, "Error: Cannot open SSH tunnel")
+	}
+
+	client, err := h.CreateSSHClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Forward(local, ssh.Addr{Net: "unix", Address: ssh.DockerSocket})
+}
+
+// maxSocketPath is the longest path a Unix socket can be bound to on all
+// systems: sun_path holds 104 bytes on macOS and the BSDs, 108 on Linux,
+// with the terminating NUL.
+const maxSocketPath = 103
+
+// machineTunnelAddr is where a tunnel listens by default: a socket of the
+// machine's directory, which only the user can connect to, or a port picked
+// by the tunnel on Windows.  When the path of the machine's directory is
+// too long for a socket, the socket goes to a directory of the user's
+// instead, see shortTunnelDir.
+func machineTunnelAddr(name string) (ssh.Addr, error) {
+	if runtime.GOOS == "windows" {
+		return ssh.Addr{Net: "tcp", Address: "127.0.0.1:0"}, nil
+	}
+
+	path := filepath.Join(mcndirs.GetMachineDir(), name, "docker.sock")
+	if len(path) <= maxSocketPath {
+		return ssh.Addr{Net: "unix", Address: path}, nil
+	}
+
+	dir, err := shortTunnelDir()
+	if err != nil {
+		return ssh.Addr{}, err
+	}
+
+	// The name is derived from the long path, to tell the machines of
+	// different storage paths apart.
+	hash := fnv.New32a()
+	hash.Write([]byte(path))
+	short := filepath.Join(dir, fmt.Sprintf("%s-%08x.sock", truncate(name, 16), hash.Sum32()))
+
+	log.Debugf("The path of the tunnel socket of %s is too long, using %s instead", name, short)
+
+	return ssh.Addr{Net: "unix", Address: short}, nil
+}
+
+// shortTunnelDir is where the tunnel sockets whose path would be too long
+// go: the runtime directory of the user, or a directory of the temporary
+// one which only the user can use.
+func shortTunnelDir() (string, error) {
+	base := os.Getenv("XDG_RUNTIME_DIR")
+	if base == "" {
+		base = os.TempDir()
+	}
+	dir := filepath.Join(base, fmt.Sprintf("docker-machine-%d", os.Getuid()))
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	// Chmod fails if another user created the directory first.
+	if err := os.Chmod(dir, 0700); err != nil {
+		return "", fmt.Errorf("Error securing the directory of the tunnel sockets: %s", err)
+	}
+
+	return dir, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// startTunnel runs the tunnel command in the background for env, unless the
+// tunnel it started before still runs, and returns its address.  What the
+// tunnel logs goes to tunnel.log, in the machine's directory.
+func startTunnel(c CommandLine, h *host.Host) (ssh.Addr, error) {
+	if _, addr, err := runningTunnel(h.Name); err == nil {
+		return addr, nil
+	}
+
+	logPath := filepath.Join(mcndirs.GetMachineDir(), h.Name, "tunnel.log")
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		return ssh.Addr{}, err
+	}
+	defer logFile.Close()
+
+	local, err := machineTunnelAddr(h.Name)
+	if err != nil {
+		return ssh.Addr{}, err
+	}
+
+	cmd, err := tunnelCommand(c, h.Name, local)
+	if err != nil {
+		return ssh.Addr{}, err
+	}
+	cmd.Stderr = logFile
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return ssh.Addr{}, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return ssh.Addr{}, fmt.Errorf("Error starting the SSH tunnel: %s", err)
+	}
+
+	// The tunnel prints its address once it's open, or exits.
+	addr, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		cmd.Wait()
+		output, _ := ioutil.ReadFile(logPath)
+		return ssh.Addr{}, fmt.Errorf("Error starting the SSH tunnel: %s", strings.TrimSpace(string(output)))
+	}
+	local, err = ssh.ParseAddr(strings.TrimSpace(addr))
+	if err != nil {
+		cmd.Process.Kill()
+		return ssh.Addr{}, err
+	}
+
+	log.Debugf("SSH tunnel to %s running with pid %d", h.Name, cmd.Process.Pid)
+
+	pidPath := filepath.Join(mcndirs.GetMachineDir(), h.Name, tunnelPidFile)
+	if err := ioutil.WriteFile(pidPath, []byte(fmt.Sprintf("%d\n%s\n", cmd.Process.Pid, local.URL())), 0600); err != nil {
+		cmd.Process.Kill()
+		return ssh.Addr{}, err
+	}
+
+	return local, cmd.Process.Release()
+}
+
+// runningTunnel returns the pid and address of the tunnel started by env,
+// if it still accepts connections.
+func runningTunnel(name string) (int, ssh.Addr, error) {
+	data, err := ioutil.ReadFile(filepath.Join(mcndirs.GetMachineDir(), name, tunnelPidFile))
+	if err != nil {
+		return 0, ssh.Addr{}, err
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		return 0, ssh.Addr{}, fmt.Errorf("Invalid tunnel pid file %q", data)
+	}
+
+	pid, err := strconv.Atoi(lines[0])
+	if err != nil {
+		return 0, ssh.Addr{}, err
+	}
+
+	addr, err := ssh.ParseAddr(lines[1])
+	if err != nil {
+		return 0, ssh.Addr{}, err
+	}
+
+	conn, err := net.Dial(addr.Net, addr.Address)
+	if err != nil {
+		return 0, ssh.Addr{}, err
+	}
+	conn.Close()
+
+	return pid, addr, nil
+}
+
+// stopTunnels stops the tunnels started by env for the hosts an action is
+// about to stop.
+func stopTunnels(hosts []*host.Host) {
+	for _, h := range hosts {
+		if err := stopTunnel(h.Name); err != nil {
+			log.Warnf("Error stopping the SSH tunnel of %s: %s", h.Name, err)
+		}
+	}
+}
+
+// stopTunnel kills the tunnel started by env for a machine, if it runs.
+// The tunnel is only killed while it accepts connections, so that another
+// process which got the pid of a tunnel gone is left alone.
+func stopTunnel(name string) error {
+	pidPath := filepath.Join(mcndirs.GetMachineDir(), name, tunnelPidFile)
+	if _, err := os.Stat(pidPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	if pid, addr, err := runningTunnel(name); err == nil {
+		log.Debugf("Stopping the SSH tunnel to %s, pid %d", name, pid)
+
+		process, err := os.FindProcess(pid)
+		if err != nil {
+			return err
+		}
+		if err := process.Kill(); err != nil {
+			return err
+		}
+
+		if addr.Net == "unix" {
+			if err := os.Remove(addr.Address); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
+	return os.Remove(pidPath)
+}
+
+// tunnelCommand is the tunnel command started by env, with the global
+// options it needs to load the machine as env did.
+func tunnelCommand(c CommandLine, name string, local ssh.Addr) (*exec.Cmd, error) {
+	binary, err := machineBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"--storage-path", c.GlobalString("storage-path")}
+	if c.GlobalBool("native-ssh") {
+		args = append(args, "--native-ssh")
+	}
+	args = append(args, "tunnel", "--local", local.URL(), name)
+
+	return exec.Command(binary, args...), nil
+}
+
+// machineBinary returns the path of the binary running, to run it again.
+func machineBinary() (string, error) {
+	binary, err := exec.LookPath(os.Args[0])
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Abs(binary)
+}
@@ -0,0 +1,161 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/docker/machine/cli"
+	"github.com/docker/machine/commands/mcndirs"
+	"github.com/docker/machine/libmachine/ssh"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTunnelCommand(t *testing.T) {
+	globalSet := flag.NewFlagSet("machine", flag.ContinueOnError)
+	globalSet.String("storage-path", "", "")
+	globalSet.Bool("native-ssh", false, "")
+	assert.NoError(t, globalSet.Parse([]string{"--storage-path", "/store", "--native-ssh"}))
+
+	c := &contextCommandLine{cli.NewContext(nil, flag.NewFlagSet("env", flag.ContinueOnError), cli.NewContext(nil, globalSet, nil))}
+
+	cmd, err := tunnelCommand(c, "default", ssh.Addr{Net: "unix", Address: "/store/machines/default/docker.sock"})
+	assert.NoError(t, err)
+
+	binary, err := machineBinary()
+	assert.NoError(t, err)
+	assert.True(t, filepath.IsAbs(binary))
+	assert.Equal(t, []string{binary, "--storage-path", "/store", "--native-ssh", "tunnel", "--local", "unix:///store/machines/default/docker.sock", "default"}, cmd.Args)
+}
+
+func TestMachineTunnelAddr(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		addr, err := machineTunnelAddr("default")
+		assert.NoError(t, err)
+		assert.Equal(t, ssh.Addr{Net: "tcp", Address: "127.0.0.1:0"}, addr)
+		return
+	}
+
+	defer func(dir string) { mcndirs.BaseDir = dir }(mcndirs.BaseDir)
+	mcndirs.BaseDir = "/store"
+
+	addr, err := machineTunnelAddr("default")
+	assert.NoError(t, err)
+	assert.Equal(t, ssh.Addr{Net: "unix", Address: filepath.Join("/store", "machines", "default", "docker.sock")}, addr)
+}
+
+func TestMachineTunnelAddrTooLong(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The tunnel listens on a port on Windows")
+	}
+
+	runtimeDir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(runtimeDir)
+	defer os.Setenv("XDG_RUNTIME_DIR", os.Getenv("XDG_RUNTIME_DIR"))
+	os.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+
+	defer func(dir string) { mcndirs.BaseDir = dir }(mcndirs.BaseDir)
+	mcndirs.BaseDir = "/store/" + strings.Repeat("a", 100)
+
+	addr, err := machineTunnelAddr("a-machine-with-a-long-name")
+	assert.NoError(t, err)
+	assert.Equal(t, "unix", addr.Net)
+	assert.True(t, len(addr.Address) <= maxSocketPath)
+	assert.True(t, strings.HasPrefix(addr.Address, filepath.Join(runtimeDir, fmt.Sprintf("docker-machine-%d", os.Getuid()), "a-machine-with-a")))
+
+	// The socket of a machine of another storage path goes elsewhere.
+	mcndirs.BaseDir = "/store/" + strings.Repeat("b", 100)
+	other, err := machineTunnelAddr("a-machine-with-a-long-name")
+	assert.NoError(t, err)
+	assert.NotEqual(t, addr, other)
+
+	info, err := os.Stat(filepath.Dir(addr.Address))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+}
+
+func TestStopTunnel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The tunnel is faked with sleep and a Unix socket")
+	}
+
+	defer func(dir string) { mcndirs.BaseDir = dir }(mcndirs.BaseDir)
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	mcndirs.BaseDir = dir
+
+	machineDir := filepath.Join(mcndirs.GetMachineDir(), "default")
+	assert.NoError(t, os.MkdirAll(machineDir, 0700))
+	pidPath := filepath.Join(machineDir, tunnelPidFile)
+
+	assert.NoError(t, stopTunnel("default"))
+
+	addr, err := machineTunnelAddr("default")
+	assert.NoError(t, err)
+	listener, err := net.Listen(addr.Net, addr.Address)
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	tunnel := exec.Command("sleep", "60")
+	assert.NoError(t, tunnel.Start())
+	defer tunnel.Process.Kill()
+
+	assert.NoError(t, ioutil.WriteFile(pidPath, []byte(fmt.Sprintf("%d\n%s\n", tunnel.Process.Pid, addr.URL())), 0600))
+
+	pid, running, err := runningTunnel("default")
+	assert.NoError(t, err)
+	assert.Equal(t, tunnel.Process.Pid, pid)
+	assert.Equal(t, addr, running)
+
+	assert.NoError(t, stopTunnel("default"))
+	assert.Error(t, tunnel.Wait())
+
+	_, err = os.Stat(pidPath)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(addr.Address)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestStopTunnelGone(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("The process is faked with sleep")
+	}
+
+	defer func(dir string) { mcndirs.BaseDir = dir }(mcndirs.BaseDir)
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	mcndirs.BaseDir = dir
+
+	machineDir := filepath.Join(mcndirs.GetMachineDir(), "default")
+	assert.NoError(t, os.MkdirAll(machineDir, 0700))
+	pidPath := filepath.Join(machineDir, tunnelPidFile)
+
+	// The tunnel is gone and another process got its pid: it's left alone.
+	other := exec.Command("sleep", "60")
+	assert.NoError(t, other.Start())
+	defer other.Process.Kill()
+
+	addr, err := machineTunnelAddr("default")
+	assert.NoError(t, err)
+	assert.NoError(t, ioutil.WriteFile(pidPath, []byte(fmt.Sprintf("%d\n%s\n", other.Process.Pid, addr.URL())), 0600))
+
+	_, _, err = runningTunnel("default")
+	assert.Error(t, err)
+
+	assert.NoError(t, stopTunnel("default"))
+	assert.NoError(t, other.Process.Signal(syscall.Signal(0)))
+
+	_, err = os.Stat(pidPath)
+	assert.True(t, os.IsNotExist(err))
+}
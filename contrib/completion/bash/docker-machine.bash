@@ -43,7 +43,7 @@ _docker-machine-env() {
             ;;
         *)
             if [[ "${cur}" == -* ]]; then
-                COMPREPLY=($(compgen -W "--swarm --shell --unset --help" -- "${cur}"))
+                COMPREPLY=($(compgen -W "--swarm --shell --unset --tunnel --help" -- "${cur}"))
             else
                 COMPREPLY=($(compgen -W "$(docker-machine ls -q)" -- "${cur}"))
             fi
@@ -169,6 +169,20 @@ _docker-machine-stop() {
     fi
 }
 
+_docker-machine-tunnel() {
+    case "${prev}" in
+        --local)
+            COMPREPLY=()
+            ;;
+        *)
+            if [[ "${cur}" == -* ]]; then
+                COMPREPLY=($(compgen -W "--local --help" -- "${cur}"))
+            else
+                COMPREPLY=($(compgen -W "$(docker-machine ls -q)" -- "${cur}"))
+            fi
+    esac
+}
+
 _docker-machine-upgrade() {
     if [[ "${cur}" == -* ]]; then
         COMPREPLY=($(compgen -W "--help" -- "${cur}"))
@@ -207,7 +221,7 @@ _docker-machine-docker-machine() {
 
 _docker-machine() {
     COMPREPLY=()
-    local commands=(active config create env inspect ip kill ls regenerate-certs restart rm ssh scp start status stop upgrade url help)
+    local commands=(active config create env inspect ip kill ls regenerate-certs restart rm ssh scp start status stop tunnel upgrade url help)
 
     local flags=(--debug --native-ssh --help --version)
     local wants_dir=(--storage-path)
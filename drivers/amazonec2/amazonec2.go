@@ -75,12 +75,12 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Usage:  "AWS Access Key",
 			EnvVar: "AWS_ACCESS_KEY_ID",
 		},
-		mcnflag.StringFlag{
+		mcnflag.SecretFlag{
 			Name:   "amazonec2-secret-key",
 			Usage:  "AWS Secret Key",
 			EnvVar: "AWS_SECRET_ACCESS_KEY",
 		},
-		mcnflag.StringFlag{
+		mcnflag.SecretFlag{
 			Name:   "amazonec2-session-token",
 			Usage:  "AWS Session Token",
 			EnvVar: "AWS_SESSION_TOKEN",
@@ -184,6 +184,10 @@ func NewDriver(hostName, storePath string) drivers.Driver {
 	}
 }
 
+func (d *Driver) GetSecrets() []string {
+	return []string{d.SecretKey, d.SessionToken}
+}
+
 func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	region, err := validateAwsRegion(flags.String("amazonec2-region"))
 	if err != nil {
@@ -273,6 +277,15 @@ func (d *Driver) DriverName() string {
 }
 
 func (d *Driver) checkPrereqs() error {
+	// EC2 imports RSA and Ed25519 keys only
+	keyType, err := ssh.ParseKeyType(d.SSHKeyType)
+	if err != nil {
+		return err
+	}
+	if keyType.Algorithm == ssh.KeyTypeECDSA {
+		return fmt.Errorf("The amazonec2 driver doesn't support %s SSH keys, use %s or %s", keyType.Algorithm, ssh.KeyTypeRSA, ssh.KeyTypeEd25519)
+	}
+
 	// check for existing keypair
 	key, err := d.getClient().GetKeyPair(d.MachineName)
 	if err != nil {
@@ -567,7 +580,7 @@ func (d *Driver) waitForInstance() error {
 
 func (d *Driver) createKeyPair() error {
 
-	if err := ssh.GenerateSSHKey(d.GetSSHKeyPath()); err != nil {
+	if err := ssh.GenerateSSHKeyOfType(d.GetSSHKeyPath(), d.SSHKeyType); err != nil {
 		return err
 	}
 
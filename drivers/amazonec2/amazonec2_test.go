@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/docker/machine/commands/mcndirs"
 	"github.com/docker/machine/drivers/amazonec2/amz"
@@ -50,6 +51,14 @@ func (d DriverOptionsMock) Bool(key string) bool {
 	return d.Data[key].(bool)
 }
 
+func (d DriverOptionsMock) Duration(key string) time.Duration {
+	return d.Data[key].(time.Duration)
+}
+
+func (d DriverOptionsMock) Float(key string) float64 {
+	return d.Data[key].(float64)
+}
+
 func cleanup() error {
 	return os.RemoveAll(testStoreDir)
 }
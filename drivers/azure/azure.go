@@ -65,7 +65,7 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Usage:  "Azure location",
 			Value:  defaultLocation,
 		},
-		mcnflag.StringFlag{
+		mcnflag.SecretFlag{
 			Name:  "azure-password",
 			Usage: "Azure user password",
 		},
@@ -136,6 +136,10 @@ func (d *Driver) DriverName() string {
 	return "azure"
 }
 
+func (d *Driver) GetSecrets() []string {
+	return []string{d.UserPassword}
+}
+
 func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.SubscriptionID = flags.String("azure-subscription-id")
 
@@ -188,6 +192,15 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 }
 
 func (d *Driver) PreCreateCheck() error {
+	// The SSH key is also the one of the management certificate
+	keyType, err := ssh.ParseKeyType(d.SSHKeyType)
+	if err != nil {
+		return err
+	}
+	if keyType.Algorithm != ssh.KeyTypeRSA {
+		return fmt.Errorf("The azure driver only supports %s SSH keys", ssh.KeyTypeRSA)
+	}
+
 	if err := d.setUserSubscription(); err != nil {
 		return err
 	}
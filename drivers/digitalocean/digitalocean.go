@@ -38,7 +38,7 @@ const (
 // "docker hosts create"
 func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 	return []mcnflag.Flag{
-		mcnflag.StringFlag{
+		mcnflag.SecretFlag{
 			EnvVar: "DIGITALOCEAN_ACCESS_TOKEN",
 			Name:   "digitalocean-access-token",
 			Usage:  "Digital Ocean access token",
@@ -106,6 +106,10 @@ func (d *Driver) DriverName() string {
 	return "digitalocean"
 }
 
+func (d *Driver) GetSecrets() []string {
+	return []string{d.AccessToken}
+}
+
 func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.AccessToken = flags.String("digitalocean-access-token")
 	d.Image = flags.String("digitalocean-image")
@@ -201,7 +205,7 @@ func (d *Driver) Create() error {
 }
 
 func (d *Driver) createSSHKey() (*godo.Key, error) {
-	if err := ssh.GenerateSSHKey(d.GetSSHKeyPath()); err != nil {
+	if err := ssh.GenerateSSHKeyOfType(d.GetSSHKeyPath(), d.SSHKeyType); err != nil {
 		return nil, err
 	}
 
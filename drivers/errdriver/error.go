@@ -1,9 +1,8 @@
 package errdriver
 
 import (
-	"fmt"
-
 	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/mcnerror"
 	"github.com/docker/machine/libmachine/mcnflag"
 	"github.com/docker/machine/libmachine/state"
 )
@@ -12,14 +11,6 @@ type Driver struct {
 	Name string
 }
 
-type NotLoadable struct {
-	Name string
-}
-
-func (e NotLoadable) Error() string {
-	return fmt.Sprintf("Driver %q not found. Do you have the plugin binary accessible in your PATH?", e.Name)
-}
-
 func NewDriver(Name string) drivers.Driver {
 	return &Driver{
 		Name: Name,
@@ -31,6 +22,10 @@ func (d *Driver) DriverName() string {
 	return "not-found"
 }
 
+func (d *Driver) GetCapabilities() drivers.Capabilities {
+	return drivers.Capabilities{}
+}
+
 func (d *Driver) PreCreateCheck() error {
 	return nil
 }
@@ -40,11 +35,11 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 }
 
 func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
-	return NotLoadable{d.Name}
+	return mcnerror.ErrDriverUnavailable{DriverName: d.Name}
 }
 
 func (d *Driver) GetURL() (string, error) {
-	return "", NotLoadable{d.Name}
+	return "", mcnerror.ErrDriverUnavailable{DriverName: d.Name}
 }
 
 func (d *Driver) GetMachineName() string {
@@ -52,11 +47,11 @@ func (d *Driver) GetMachineName() string {
 }
 
 func (d *Driver) GetIP() (string, error) {
-	return "1.2.3.4", NotLoadable{d.Name}
+	return "1.2.3.4", mcnerror.ErrDriverUnavailable{DriverName: d.Name}
 }
 
 func (d *Driver) GetSSHHostname() (string, error) {
-	return "", NotLoadable{d.Name}
+	return "", mcnerror.ErrDriverUnavailable{DriverName: d.Name}
 }
 
 func (d *Driver) GetSSHKeyPath() string {
@@ -64,7 +59,7 @@ func (d *Driver) GetSSHKeyPath() string {
 }
 
 func (d *Driver) GetSSHPort() (int, error) {
-	return 0, NotLoadable{d.Name}
+	return 0, mcnerror.ErrDriverUnavailable{DriverName: d.Name}
 }
 
 func (d *Driver) GetSSHUsername() string {
@@ -72,33 +67,33 @@ func (d *Driver) GetSSHUsername() string {
 }
 
 func (d *Driver) GetState() (state.State, error) {
-	return state.Error, NotLoadable{d.Name}
+	return state.Error, mcnerror.ErrDriverUnavailable{DriverName: d.Name}
 }
 
 func (d *Driver) Create() error {
-	return NotLoadable{d.Name}
+	return mcnerror.ErrDriverUnavailable{DriverName: d.Name}
 }
 
 func (d *Driver) Remove() error {
-	return NotLoadable{d.Name}
+	return mcnerror.ErrDriverUnavailable{DriverName: d.Name}
 }
 
 func (d *Driver) Start() error {
-	return NotLoadable{d.Name}
+	return mcnerror.ErrDriverUnavailable{DriverName: d.Name}
 }
 
 func (d *Driver) Stop() error {
-	return NotLoadable{d.Name}
+	return mcnerror.ErrDriverUnavailable{DriverName: d.Name}
 }
 
 func (d *Driver) Restart() error {
-	return NotLoadable{d.Name}
+	return mcnerror.ErrDriverUnavailable{DriverName: d.Name}
 }
 
 func (d *Driver) Kill() error {
-	return NotLoadable{d.Name}
+	return mcnerror.ErrDriverUnavailable{DriverName: d.Name}
 }
 
 func (d *Driver) Upgrade() error {
-	return NotLoadable{d.Name}
+	return mcnerror.ErrDriverUnavailable{DriverName: d.Name}
 }
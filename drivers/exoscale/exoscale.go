@@ -12,6 +12,7 @@ import (
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/mcnflag"
 	"github.com/docker/machine/libmachine/mcnutils"
+	"github.com/docker/machine/libmachine/ssh"
 	"github.com/docker/machine/libmachine/state"
 	"github.com/pyr/egoscale/src/egoscale"
 )
@@ -52,7 +53,7 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Name:   "exoscale-api-key",
 			Usage:  "exoscale API key",
 		},
-		mcnflag.StringFlag{
+		mcnflag.SecretFlag{
 			EnvVar: "EXOSCALE_API_SECRET",
 			Name:   "exoscale-api-secret-key",
 			Usage:  "exoscale API secret key",
@@ -116,7 +117,20 @@ func (d *Driver) DriverName() string {
 	return "exoscale"
 }
 
+func (d *Driver) GetSecrets() []string {
+	return []string{d.APISecretKey}
+}
+
 func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
+	// The key of the machine is created by the API, which picks its type.
+	keyType, err := ssh.ParseKeyType(d.SSHKeyType)
+	if err != nil {
+		return err
+	}
+	if keyType != ssh.DefaultKeyType {
+		return fmt.Errorf("The exoscale driver doesn't support choosing the type of the SSH key, it's always %s", ssh.DefaultKeyType)
+	}
+
 	d.URL = flags.String("exoscale-endpoint")
 	d.APIKey = flags.String("exoscale-api-key")
 	d.APISecretKey = flags.String("exoscale-api-secret-key")
@@ -23,3 +23,21 @@ func TestSetConfigFromFlags(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Empty(t, checkFlags.InvalidFlags)
 }
+
+func TestSetConfigFromFlagsKeyType(t *testing.T) {
+	driver := NewDriver("default", "path").(*Driver)
+	driver.SSHKeyType = "ed25519"
+
+	checkFlags := &drivers.CheckDriverOptions{
+		FlagsValues: map[string]interface{}{
+			"exoscale-api-key":        "API_KEY",
+			"exoscale-api-secret-key": "API_SECRET_KEY",
+		},
+		CreateFlags: driver.GetCreateFlags(),
+	}
+
+	assert.EqualError(t, driver.SetConfigFromFlags(checkFlags), "The exoscale driver doesn't support choosing the type of the SSH key, it's always rsa-2048")
+
+	driver.SSHKeyType = "rsa-2048"
+	assert.NoError(t, driver.SetConfigFromFlags(checkFlags))
+}
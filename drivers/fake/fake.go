@@ -0,0 +1,421 @@
+// Package fake is a driver whose machines don't exist: what they do is
+// scripted, so that the CLI and automation around it can be tested without
+// a hypervisor or a cloud account.
+package fake
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/events"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnflag"
+	"github.com/docker/machine/libmachine/state"
+)
+
+const (
+	driverName        = "fake"
+	defaultIP         = "127.0.0.1"
+	defaultDockerPort = 2376
+	backendFile       = "fake-machine.json"
+
+	// progressSteps is how many times the progress of a delay is reported.
+	progressSteps = 10
+)
+
+type Driver struct {
+	*drivers.BaseDriver
+	ScriptPath string
+	Script     Script
+}
+
+// machine is what the fake backend knows of a machine.  It is kept in the
+// machine's directory so that it outlives the plugin process.
+type machine struct {
+	State state.State
+	Calls map[string]int
+}
+
+func NewDriver(hostName, storePath string) drivers.Driver {
+	return &Driver{
+		BaseDriver: &drivers.BaseDriver{
+			MachineName: hostName,
+			StorePath:   storePath,
+		},
+		Script: Script{
+			IP:         defaultIP,
+			DockerPort: defaultDockerPort,
+		},
+	}
+}
+
+func (d *Driver) GetCreateFlags() []mcnflag.Flag {
+	return []mcnflag.Flag{
+		mcnflag.FilePathFlag{
+			Name:   "fake-script",
+			Usage:  "JSON file scripting the machine, read again on every call",
+			EnvVar: "FAKE_SCRIPT",
+		},
+		mcnflag.StringFlag{
+			Name:  "fake-ip",
+			Usage: "IP address of the machine",
+			Value: defaultIP,
+		},
+		mcnflag.IntFlag{
+			Name:  "fake-docker-port",
+			Usage: "Port of the Docker daemon",
+			Value: defaultDockerPort,
+		},
+		mcnflag.StringFlag{
+			Name:  "fake-ssh-hostname",
+			Usage: "SSH host name, e.g. of a local sshd (default: the IP address)",
+		},
+		mcnflag.IntFlag{
+			Name:  "fake-ssh-port",
+			Usage: "SSH port; the machine can only be reached over SSH if it is set",
+		},
+		mcnflag.StringFlag{
+			Name:  "fake-ssh-user",
+			Usage: "SSH user",
+			Value: drivers.DefaultSSHUser,
+		},
+		mcnflag.StringFlag{
+			Name:  "fake-ssh-key",
+			Usage: "SSH private key path",
+		},
+		mcnflag.DurationFlag{
+			Name:  "fake-delay",
+			Usage: "How long every call takes",
+		},
+		mcnflag.StringSliceFlag{
+			Name:  "fake-fail",
+			Usage: fmt.Sprintf("Phase which fails, one of %v", phaseNames),
+		},
+	}
+}
+
+// DriverName returns the name of the driver
+func (d *Driver) DriverName() string {
+	return driverName
+}
+
+func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
+	d.ScriptPath = flags.String("fake-script")
+	d.Script = Script{
+		IP:          flags.String("fake-ip"),
+		DockerPort:  flags.Int("fake-docker-port"),
+		SSHHostname: flags.String("fake-ssh-hostname"),
+		SSHPort:     flags.Int("fake-ssh-port"),
+		SSHUser:     flags.String("fake-ssh-user"),
+		SSHKeyPath:  flags.String("fake-ssh-key"),
+		Delay:       Duration(drivers.GetDuration(flags, "fake-delay")),
+		Phases:      make(map[string]Phase),
+	}
+
+	for _, name := range flags.StringSlice("fake-fail") {
+		d.Script.Phases[name] = Phase{Error: fmt.Sprintf("Fake failure during %s", name)}
+	}
+
+	if err := d.Script.validate(); err != nil {
+		return err
+	}
+
+	if d.ScriptPath != "" {
+		path, err := filepath.Abs(d.ScriptPath)
+		if err != nil {
+			return err
+		}
+		d.ScriptPath = path
+	}
+
+	_, err := d.script()
+	return err
+}
+
+// script returns the current script of the machine.
+func (d *Driver) script() (Script, error) {
+	return loadScript(d.Script, d.ScriptPath)
+}
+
+func (d *Driver) backendPath() string {
+	return d.ResolveStorePath(backendFile)
+}
+
+func (d *Driver) loadMachine() (*machine, error) {
+	m := &machine{
+		Calls: make(map[string]int),
+	}
+
+	data, err := ioutil.ReadFile(d.backendPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return m, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (d *Driver) saveMachine(m *machine) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(d.backendPath()), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(d.backendPath(), data, 0600)
+}
+
+// play plays a phase of the script: the call takes the scripted time and
+// fails if the script says so.
+func (d *Driver) play(name string) (Script, Phase, error) {
+	script, err := d.script()
+	if err != nil {
+		return script, Phase{}, err
+	}
+
+	phase := script.Phases[name]
+
+	delay := phase.Delay
+	if delay == 0 {
+		delay = script.Delay
+	}
+	if delay > 0 {
+		d.wait(name, time.Duration(delay))
+	}
+
+	if phase.Error == "" {
+		return script, phase, nil
+	}
+
+	m, err := d.loadMachine()
+	if err != nil {
+		return script, phase, err
+	}
+
+	m.Calls[name]++
+	if err := d.saveMachine(m); err != nil {
+		return script, phase, err
+	}
+
+	if phase.Failures == 0 || m.Calls[name] <= phase.Failures {
+		return script, phase, errors.New(phase.Error)
+	}
+
+	return script, phase, nil
+}
+
+// wait reports the progress of a phase which takes some time.
+func (d *Driver) wait(name string, delay time.Duration) {
+	phase := "Fake " + name
+	for step := 0; step < progressSteps; step++ {
+		events.Progress(d.MachineName, phase, step*100/progressSteps, "")
+		time.Sleep(delay / progressSteps)
+	}
+	events.Progress(d.MachineName, phase, 100, "")
+}
+
+// transition plays a phase which leaves an existing machine in a new
+// state.
+func (d *Driver) transition(name string, to state.State) error {
+	_, phase, err := d.play(name)
+	if err != nil {
+		return err
+	}
+
+	if phase.State != state.None {
+		to = phase.State
+	}
+
+	m, err := d.loadMachine()
+	if err != nil {
+		return err
+	}
+
+	if m.State == state.None {
+		return fmt.Errorf("Fake machine %q doesn't exist", d.MachineName)
+	}
+
+	m.State = to
+
+	return d.saveMachine(m)
+}
+
+// GetCapabilities returns the actions the machine supports, which can be
+// scripted.  It can only be reached over SSH if an SSH port is given.
+func (d *Driver) GetCapabilities() drivers.Capabilities {
+	script, err := d.script()
+	if err != nil {
+		log.Debugf("Error loading fake driver script: %s", err)
+		script = d.Script
+	}
+
+	if script.Capabilities != nil {
+		return drivers.Capabilities(script.Capabilities)
+	}
+
+	capabilities := drivers.Capabilities{
+		drivers.CapabilityStart,
+		drivers.CapabilityStop,
+		drivers.CapabilityKill,
+		drivers.CapabilityRestart,
+	}
+
+	if script.SSHPort != 0 {
+		capabilities = append(capabilities, drivers.CapabilitySSH)
+	}
+
+	return capabilities
+}
+
+func (d *Driver) PreCreateCheck() error {
+	_, _, err := d.play("precreatecheck")
+	return err
+}
+
+func (d *Driver) Create() error {
+	_, phase, err := d.play("create")
+	if err != nil {
+		return err
+	}
+
+	m, err := d.loadMachine()
+	if err != nil {
+		return err
+	}
+
+	m.State = state.Running
+	if phase.State != state.None {
+		m.State = phase.State
+	}
+
+	return d.saveMachine(m)
+}
+
+func (d *Driver) Start() error {
+	return d.transition("start", state.Running)
+}
+
+func (d *Driver) Stop() error {
+	return d.transition("stop", state.Stopped)
+}
+
+func (d *Driver) Restart() error {
+	return d.transition("restart", state.Running)
+}
+
+func (d *Driver) Kill() error {
+	return d.transition("kill", state.Stopped)
+}
+
+func (d *Driver) Remove() error {
+	if _, _, err := d.play("remove"); err != nil {
+		return err
+	}
+
+	if err := os.Remove(d.backendPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (d *Driver) GetState() (state.State, error) {
+	_, phase, err := d.play("getstate")
+	if err != nil {
+		return state.Error, err
+	}
+
+	if phase.State != state.None {
+		return phase.State, nil
+	}
+
+	m, err := d.loadMachine()
+	if err != nil {
+		return state.Error, err
+	}
+
+	if m.State == state.None {
+		return state.Error, fmt.Errorf("Fake machine %q doesn't exist", d.MachineName)
+	}
+
+	return m.State, nil
+}
+
+func (d *Driver) GetIP() (string, error) {
+	script, _, err := d.play("getip")
+	if err != nil {
+		return "", err
+	}
+
+	if script.IP == "" {
+		return "", errors.New("IP address is not set")
+	}
+
+	return script.IP, nil
+}
+
+func (d *Driver) GetURL() (string, error) {
+	script, _, err := d.play("geturl")
+	if err != nil {
+		return "", err
+	}
+
+	if script.IP == "" {
+		return "", errors.New("IP address is not set")
+	}
+
+	return fmt.Sprintf("tcp://%s:%d", script.IP, script.DockerPort), nil
+}
+
+// currentScript is the script for the getters which can't fail.
+func (d *Driver) currentScript() Script {
+	script, err := d.script()
+	if err != nil {
+		log.Debugf("Error loading fake driver script: %s", err)
+		return d.Script
+	}
+	return script
+}
+
+func (d *Driver) GetSSHHostname() (string, error) {
+	if hostname := d.currentScript().SSHHostname; hostname != "" {
+		return hostname, nil
+	}
+	return d.GetIP()
+}
+
+func (d *Driver) GetSSHPort() (int, error) {
+	if port := d.currentScript().SSHPort; port != 0 {
+		return port, nil
+	}
+	return drivers.DefaultSSHPort, nil
+}
+
+func (d *Driver) GetSSHUsername() string {
+	if user := d.currentScript().SSHUser; user != "" {
+		return user
+	}
+	return drivers.DefaultSSHUser
+}
+
+func (d *Driver) GetSSHKeyPath() string {
+	if path := d.currentScript().SSHKeyPath; path != "" {
+		return path
+	}
+	return d.ResolveStorePath("id_rsa")
+}
@@ -0,0 +1,182 @@
+package fake_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/machine/drivers/fake"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/drivers/drivertest"
+	"github.com/docker/machine/libmachine/drivers/rpc"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func newDriver(t *testing.T, values map[string]interface{}) (drivers.Driver, func()) {
+	dir, err := ioutil.TempDir("", "fake-driver-")
+	assert.NoError(t, err)
+
+	d := fake.NewDriver("default", dir)
+
+	flags := make(map[string]interface{})
+	for _, flag := range d.GetCreateFlags() {
+		flags[flag.String()] = flag.Default()
+	}
+	for name, value := range values {
+		flags[name] = value
+	}
+
+	assert.NoError(t, d.SetConfigFromFlags(rpcdriver.RPCFlags{Values: flags}))
+
+	return d, func() { os.RemoveAll(dir) }
+}
+
+func writeScript(t *testing.T, script string) (string, func()) {
+	file, err := ioutil.TempFile("", "fake-script-")
+	assert.NoError(t, err)
+	defer file.Close()
+
+	_, err = file.WriteString(script)
+	assert.NoError(t, err)
+
+	return file.Name(), func() { os.Remove(file.Name()) }
+}
+
+func TestConformance(t *testing.T) {
+	drivertest.Run(t, drivertest.Config{
+		NewDriver: fake.NewDriver,
+		Flags: map[string]interface{}{
+			"fake-ip":          "10.0.0.7",
+			"fake-docker-port": 12376,
+		},
+	})
+}
+
+func TestDefaults(t *testing.T) {
+	d, cleanup := newDriver(t, nil)
+	defer cleanup()
+
+	url, err := d.GetURL()
+	assert.NoError(t, err)
+	assert.Equal(t, "tcp://127.0.0.1:2376", url)
+	assert.False(t, drivers.GetCapabilities(d).Has(drivers.CapabilitySSH))
+
+	_, err = d.GetState()
+	assert.EqualError(t, err, `Fake machine "default" doesn't exist`)
+}
+
+func TestSSH(t *testing.T) {
+	d, cleanup := newDriver(t, map[string]interface{}{
+		"fake-ssh-port": 2222,
+		"fake-ssh-user": "tester",
+	})
+	defer cleanup()
+
+	assert.True(t, drivers.GetCapabilities(d).Has(drivers.CapabilitySSH))
+
+	hostname, err := d.GetSSHHostname()
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", hostname)
+
+	port, err := d.GetSSHPort()
+	assert.NoError(t, err)
+	assert.Equal(t, 2222, port)
+	assert.Equal(t, "tester", d.GetSSHUsername())
+}
+
+func TestFailFlag(t *testing.T) {
+	d, cleanup := newDriver(t, map[string]interface{}{
+		"fake-fail": []string{"stop"},
+	})
+	defer cleanup()
+
+	assert.NoError(t, d.Create())
+	assert.EqualError(t, d.Stop(), "Fake failure during stop")
+	assert.EqualError(t, d.Stop(), "Fake failure during stop")
+
+	s, err := d.GetState()
+	assert.NoError(t, err)
+	assert.Equal(t, state.Running, s)
+}
+
+func TestUnknownPhase(t *testing.T) {
+	d := fake.NewDriver("default", "")
+
+	err := d.SetConfigFromFlags(rpcdriver.RPCFlags{Values: map[string]interface{}{
+		"fake-fail": []string{"boot"},
+	}})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `Unknown phase "boot"`)
+}
+
+func TestScript(t *testing.T) {
+	path, cleanupScript := writeScript(t, `{
+		"IP": "192.168.0.12",
+		"Phases": {
+			"create": {"Error": "Out of capacity", "Failures": 2},
+			"start": {"State": "Error"},
+			"getip": {"Delay": "10ms"}
+		}
+	}`)
+	defer cleanupScript()
+
+	d, cleanup := newDriver(t, map[string]interface{}{
+		"fake-script": path,
+	})
+	defer cleanup()
+
+	assert.EqualError(t, d.Create(), "Out of capacity")
+	assert.EqualError(t, d.Create(), "Out of capacity")
+	assert.NoError(t, d.Create())
+
+	assert.NoError(t, d.Stop())
+	assert.NoError(t, d.Start())
+
+	s, err := d.GetState()
+	assert.NoError(t, err)
+	assert.Equal(t, state.Error, s)
+
+	start := time.Now()
+	ip, err := d.GetIP()
+	assert.NoError(t, err)
+	assert.Equal(t, "192.168.0.12", ip)
+	assert.True(t, time.Since(start) >= 10*time.Millisecond)
+}
+
+func TestScriptIsReadOnEveryCall(t *testing.T) {
+	path, cleanupScript := writeScript(t, `{}`)
+	defer cleanupScript()
+
+	d, cleanup := newDriver(t, map[string]interface{}{
+		"fake-script": path,
+	})
+	defer cleanup()
+
+	assert.NoError(t, d.Create())
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"Phases": {"getstate": {"State": "Saved"}}}`), 0600))
+
+	s, err := d.GetState()
+	assert.NoError(t, err)
+	assert.Equal(t, state.Saved, s)
+}
+
+func TestInvalidScript(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fake-driver-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "script.json")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"Delay": 10}`), 0600))
+
+	d := fake.NewDriver("default", dir)
+	err = d.SetConfigFromFlags(rpcdriver.RPCFlags{Values: map[string]interface{}{
+		"fake-script": path,
+	}})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Error parsing fake driver script")
+}
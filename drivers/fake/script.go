@@ -0,0 +1,123 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/state"
+)
+
+// The phases of a script are named after the driver methods they script.
+var phaseNames = []string{
+	"precreatecheck",
+	"create",
+	"start",
+	"stop",
+	"restart",
+	"kill",
+	"remove",
+	"getstate",
+	"getip",
+	"geturl",
+}
+
+// Duration is a time.Duration written as a string such as "1m30s" in
+// scripts.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("Durations must be strings such as \"1m30s\": %s", err)
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+
+	return nil
+}
+
+// Phase scripts what happens when a driver method is called.
+type Phase struct {
+	// Delay is how long the call takes, instead of the script's Delay.
+	Delay Duration `json:",omitempty"`
+
+	// Error makes the call fail with this message.
+	Error string `json:",omitempty"`
+
+	// Failures is how many calls fail before the next ones succeed, or 0
+	// for all of them.
+	Failures int `json:",omitempty"`
+
+	// State is the state the machine is left in, or the one reported by
+	// the getstate phase.
+	State state.State `json:",omitempty"`
+}
+
+// Script is the behavior of the fake machine.  Scripts are given with
+// flags or, for the fields they set, a JSON file.
+type Script struct {
+	IP           string
+	DockerPort   int
+	SSHHostname  string               `json:",omitempty"`
+	SSHPort      int                  `json:",omitempty"`
+	SSHUser      string               `json:",omitempty"`
+	SSHKeyPath   string               `json:",omitempty"`
+	Delay        Duration             `json:",omitempty"`
+	Capabilities []drivers.Capability `json:",omitempty"`
+	Phases       map[string]Phase     `json:",omitempty"`
+}
+
+func isPhase(name string) bool {
+	for _, phase := range phaseNames {
+		if phase == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Script) validate() error {
+	for name := range s.Phases {
+		if !isPhase(name) {
+			return fmt.Errorf("Unknown phase %q, phases are %v", name, phaseNames)
+		}
+	}
+	return nil
+}
+
+// loadScript returns the script with the fields set in the file at path
+// replaced.
+func loadScript(script Script, path string) (Script, error) {
+	phases := make(map[string]Phase)
+	for name, phase := range script.Phases {
+		phases[name] = phase
+	}
+	script.Phases = phases
+
+	if path == "" {
+		return script, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return script, fmt.Errorf("Error reading fake driver script: %s", err)
+	}
+
+	if err := json.Unmarshal(data, &script); err != nil {
+		return script, fmt.Errorf("Error parsing fake driver script %s: %s", path, err)
+	}
+
+	return script, script.validate()
+}
@@ -47,6 +47,10 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Usage: "SSH private key path",
 			Value: defaultSourceSSHKey,
 		},
+		mcnflag.BoolFlag{
+			Name:  "generic-ssh-agent",
+			Usage: "Authenticate with the running SSH agent, and use the SSH key where it is rather than importing it",
+		},
 		mcnflag.IntFlag{
 			Name:  "generic-ssh-port",
 			Usage: "SSH port",
@@ -84,6 +88,7 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.SSHUser = flags.String("generic-ssh-user")
 	d.SSHKey = flags.String("generic-ssh-key")
 	d.SSHPort = flags.Int("generic-ssh-port")
+	d.SSHAgent = flags.Bool("generic-ssh-agent")
 
 	if d.IPAddress == "" {
 		return errors.New("generic driver requires the --generic-ip-address option")
@@ -93,10 +98,21 @@ func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 		return errors.New("generic driver requires the --generic-ssh-key option")
 	}
 
+	// The key, which may be encrypted, stays where it is.
+	if d.SSHAgent {
+		d.SSHKeyPath = d.SSHKey
+	}
+
 	return nil
 }
 
 func (d *Driver) Create() error {
+	if d.SSHAgent {
+		log.Debugf("Using the SSH agent and the SSH key %s", d.SSHKey)
+		log.Debugf("IP: %s", d.IPAddress)
+		return nil
+	}
+
 	log.Info("Importing SSH key...")
 
 	// TODO: validate the key is a valid key
@@ -134,6 +150,16 @@ func (d *Driver) GetState() (state.State, error) {
 	return st, nil
 }
 
+// GetCapabilities returns the actions the driver supports.  Machines are
+// not managed by the driver, so they can only be reached over SSH.
+func (d *Driver) GetCapabilities() drivers.Capabilities {
+	return drivers.Capabilities{
+		drivers.CapabilityKill,
+		drivers.CapabilityRestart,
+		drivers.CapabilitySSH,
+	}
+}
+
 func (d *Driver) Start() error {
 	return errors.New("generic driver does not support start")
 }
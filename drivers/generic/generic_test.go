@@ -23,3 +23,23 @@ func TestSetConfigFromFlags(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Empty(t, checkFlags.InvalidFlags)
 }
+
+func TestSetConfigFromFlagsWithSSHAgent(t *testing.T) {
+	driver := NewDriver("default", "path")
+
+	checkFlags := &drivers.CheckDriverOptions{
+		FlagsValues: map[string]interface{}{
+			"generic-ip-address": "localhost",
+			"generic-ssh-key":    "/home/docker/.ssh/id_rsa",
+			"generic-ssh-agent":  true,
+		},
+		CreateFlags: driver.GetCreateFlags(),
+	}
+
+	err := driver.SetConfigFromFlags(checkFlags)
+
+	assert.NoError(t, err)
+	assert.Empty(t, checkFlags.InvalidFlags)
+	assert.True(t, drivers.UseSSHAgent(driver))
+	assert.Equal(t, "/home/docker/.ssh/id_rsa", driver.GetSSHKeyPath())
+}
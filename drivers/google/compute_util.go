@@ -8,8 +8,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/log"
-	"github.com/docker/machine/libmachine/ssh"
 	raw "google.golang.org/api/compute/v1"
 
 	"golang.org/x/oauth2"
@@ -326,17 +326,15 @@ func (c *ComputeUtil) startInstance() error {
 	return c.waitForRegionalOp(op.Name)
 }
 
-func (c *ComputeUtil) executeCommands(commands []string, ip, sshKeyPath string) error {
-	for _, command := range commands {
-		auth := &ssh.Auth{
-			Keys: []string{sshKeyPath},
-		}
-
-		client, err := ssh.NewClient(c.userName, ip, 22, auth)
-		if err != nil {
-			return err
-		}
+// executeCommands runs commands on the instance over SSH, checking its host
+// key as every other connection to the machine does.
+func (c *ComputeUtil) executeCommands(d *Driver, commands []string) error {
+	client, err := drivers.GetSSHClientFromDriver(d)
+	if err != nil {
+		return err
+	}
 
+	for _, command := range commands {
 		if _, err := client.Output(command); err != nil {
 			return err
 		}
@@ -203,7 +203,7 @@ func (d *Driver) PreCreateCheck() error {
 func (d *Driver) Create() error {
 	log.Infof("Generating SSH Key")
 
-	if err := ssh.GenerateSSHKey(d.GetSSHKeyPath()); err != nil {
+	if err := ssh.GenerateSSHKeyOfType(d.GetSSHKeyPath(), d.SSHKeyType); err != nil {
 		return err
 	}
 
@@ -153,7 +153,7 @@ func (d *Driver) Create() error {
 
 	log.Infof("Creating SSH key...")
 
-	if err := ssh.GenerateSSHKey(d.GetSSHKeyPath()); err != nil {
+	if err := ssh.GenerateSSHKeyOfType(d.GetSSHKeyPath(), d.SSHKeyType); err != nil {
 		return err
 	}
 
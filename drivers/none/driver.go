@@ -75,6 +75,11 @@ func (d *Driver) GetState() (state.State, error) {
 	return state.Running, nil
 }
 
+// GetCapabilities returns the actions the driver supports, which is none.
+func (d *Driver) GetCapabilities() drivers.Capabilities {
+	return drivers.Capabilities{}
+}
+
 func (d *Driver) Kill() error {
 	return fmt.Errorf("hosts without a driver cannot be killed")
 }
@@ -0,0 +1,20 @@
+package none_test
+
+import (
+	"testing"
+
+	"github.com/docker/machine/drivers/none"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/drivers/drivertest"
+)
+
+func TestConformance(t *testing.T) {
+	drivertest.Run(t, drivertest.Config{
+		NewDriver: func(machineName, storePath string) drivers.Driver {
+			return none.NewDriver(machineName, storePath)
+		},
+		Flags: map[string]interface{}{
+			"url": "tcp://1.2.3.4:2376",
+		},
+	})
+}
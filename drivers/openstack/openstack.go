@@ -81,11 +81,10 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Usage:  "OpenStack username",
 			Value:  "",
 		},
-		mcnflag.StringFlag{
+		mcnflag.SecretFlag{
 			EnvVar: "OS_PASSWORD",
 			Name:   "openstack-password",
 			Usage:  "OpenStack password",
-			Value:  "",
 		},
 		mcnflag.StringFlag{
 			EnvVar: "OS_TENANT_NAME",
@@ -227,6 +226,10 @@ func (d *Driver) DriverName() string {
 	return "openstack"
 }
 
+func (d *Driver) GetSecrets() []string {
+	return []string{d.Password}
+}
+
 func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.AuthUrl = flags.String("openstack-auth-url")
 	d.ActiveTimeout = flags.Int("openstack-active-timeout")
@@ -576,7 +579,7 @@ func (d *Driver) initNetwork() error {
 
 func (d *Driver) createSSHKey() error {
 	log.WithField("Name", d.KeyPairName).Debug("Creating Key Pair...")
-	if err := ssh.GenerateSSHKey(d.GetSSHKeyPath()); err != nil {
+	if err := ssh.GenerateSSHKeyOfType(d.GetSSHKeyPath(), d.SSHKeyType); err != nil {
 		return err
 	}
 	publicKey, err := ioutil.ReadFile(d.publicSSHKeyPath())
@@ -50,7 +50,7 @@ func NewDriver(hostName, storePath string) Driver {
 
 func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 	return []mcnflag.Flag{
-		mcnflag.StringFlag{
+		mcnflag.SecretFlag{
 			Name:   "packet-api-key",
 			Usage:  "Packet api key",
 			EnvVar: "PACKET_API_KEY",
@@ -91,6 +91,10 @@ func (d *Driver) DriverName() string {
 	return "packet"
 }
 
+func (d *Driver) GetSecrets() []string {
+	return []string{d.ApiKey}
+}
+
 func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	if strings.Contains(flags.String("packet-os"), "coreos") {
 		d.SSHUser = "core"
@@ -220,7 +224,7 @@ func (d *Driver) Create() error {
 }
 
 func (d *Driver) createSSHKey() (*packngo.SSHKey, error) {
-	if err := ssh.GenerateSSHKey(d.sshKeyPath()); err != nil {
+	if err := ssh.GenerateSSHKeyOfType(d.sshKeyPath(), d.SSHKeyType); err != nil {
 		return nil, err
 	}
 
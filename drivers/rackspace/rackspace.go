@@ -34,11 +34,10 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Usage:  "Rackspace account username",
 			Value:  "",
 		},
-		mcnflag.StringFlag{
+		mcnflag.SecretFlag{
 			EnvVar: "OS_API_KEY",
 			Name:   "rackspace-api-key",
 			Usage:  "Rackspace API key",
-			Value:  "",
 		},
 		mcnflag.StringFlag{
 			EnvVar: "OS_REGION_NAME",
@@ -111,6 +110,10 @@ func missingEnvOrOption(setting, envVar, opt string) error {
 	)
 }
 
+func (d *Driver) GetSecrets() []string {
+	return append(d.Driver.GetSecrets(), d.APIKey)
+}
+
 // SetConfigFromFlags assigns and verifies the command-line arguments presented to the driver.
 func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.Username = flags.String("rackspace-username")
@@ -100,7 +100,7 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Name:   "softlayer-user",
 			Usage:  "softlayer user account name",
 		},
-		mcnflag.StringFlag{
+		mcnflag.SecretFlag{
 			EnvVar: "SOFTLAYER_API_KEY",
 			Name:   "softlayer-api-key",
 			Usage:  "softlayer user API key",
@@ -208,6 +208,13 @@ func validateClientConfig(c *Client) error {
 	return nil
 }
 
+func (d *Driver) GetSecrets() []string {
+	if d.Client == nil {
+		return []string{}
+	}
+	return []string{d.Client.ApiKey}
+}
+
 func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 
 	d.Client = &Client{
@@ -434,7 +441,7 @@ func (d *Driver) buildHostSpec() *HostSpec {
 }
 
 func (d *Driver) createSSHKey() (*SSHKey, error) {
-	if err := ssh.GenerateSSHKey(d.GetSSHKeyPath()); err != nil {
+	if err := ssh.GenerateSSHKeyOfType(d.GetSSHKeyPath(), d.SSHKeyType); err != nil {
 		return nil, err
 	}
 
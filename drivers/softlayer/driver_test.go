@@ -4,6 +4,7 @@ import (
 	"io/ioutil"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/docker/machine/commands/mcndirs"
 	"github.com/docker/machine/libmachine/drivers"
@@ -49,6 +50,20 @@ func (d DriverOptionsMock) Bool(key string) bool {
 	return false
 }
 
+func (d DriverOptionsMock) Duration(key string) time.Duration {
+	if value, ok := d.Data[key]; ok {
+		return value.(time.Duration)
+	}
+	return 0
+}
+
+func (d DriverOptionsMock) Float(key string) float64 {
+	if value, ok := d.Data[key]; ok {
+		return value.(float64)
+	}
+	return 0
+}
+
 func cleanup() error {
 	return os.RemoveAll(testStoreDir)
 }
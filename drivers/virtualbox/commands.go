@@ -0,0 +1,53 @@
+package virtualbox
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/docker/machine/libmachine/drivers"
+)
+
+func (d *Driver) GetCommands() []drivers.Command {
+	return []drivers.Command{
+		{
+			Name:  "hostonlyifs",
+			Usage: "List the host-only networks of VirtualBox",
+		},
+	}
+}
+
+func (d *Driver) RunCommand(name string, flags drivers.DriverOptions, args []string) (string, error) {
+	switch name {
+	case "hostonlyifs":
+		nets, err := listHostOnlyNetworks()
+		if err != nil {
+			return "", err
+		}
+		return formatHostOnlyNetworks(nets), nil
+	}
+
+	return "", fmt.Errorf("Unknown command %q", name)
+}
+
+func formatHostOnlyNetworks(nets map[string]*hostOnlyNetwork) string {
+	names := []string{}
+	for name := range nets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+
+	w := tabwriter.NewWriter(&buf, 5, 1, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tIP\tNETMASK\tDHCP\tSTATUS")
+	for _, name := range names {
+		n := nets[name]
+		fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%s\n", n.Name, n.IPv4.IP, net.IP(n.IPv4.Mask), n.DHCP, n.Status)
+	}
+	w.Flush()
+
+	return buf.String()
+}
@@ -0,0 +1,28 @@
+package virtualbox
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatHostOnlyNetworks(t *testing.T) {
+	nets := map[string]*hostOnlyNetwork{
+		"HostInterfaceNetworking-vboxnet1": {
+			Name:   "vboxnet1",
+			IPv4:   net.IPNet{IP: net.ParseIP("192.168.100.1"), Mask: net.CIDRMask(24, 32)},
+			Status: "Down",
+		},
+		"HostInterfaceNetworking-vboxnet0": {
+			Name:   "vboxnet0",
+			DHCP:   true,
+			IPv4:   net.IPNet{IP: net.ParseIP("192.168.99.1"), Mask: net.CIDRMask(24, 32)},
+			Status: "Up",
+		},
+	}
+
+	assert.Equal(t, "NAME       IP              NETMASK         DHCP    STATUS\n"+
+		"vboxnet0   192.168.99.1    255.255.255.0   true    Up\n"+
+		"vboxnet1   192.168.100.1   255.255.255.0   false   Down\n", formatHostOnlyNetworks(nets))
+}
@@ -69,7 +69,7 @@ func (v *VBoxCmdManager) vbmOutErr(args ...string) (string, string, error) {
 		// VBoxManage will sometimes not set the return code, but has a fatal error
 		// such as VBoxManage.exe: error: VT-x is not available. (VERR_VMX_NO_VMX)
 		if strings.Contains(stderrStr, "error:") {
-			err = fmt.Errorf("%v %v failed:\n%v", vboxManageCmd, strings.Join(args, " "), stderrStr)
+			err = errors.New(log.Redact(fmt.Sprintf("%v %v failed:\n%v", vboxManageCmd, strings.Join(args, " "), stderrStr)))
 		}
 	}
 
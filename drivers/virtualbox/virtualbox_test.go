@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/drivers/drivertest"
 	"github.com/docker/machine/libmachine/state"
 	"github.com/stretchr/testify/assert"
 )
@@ -129,7 +130,7 @@ func TestGetIPErrors(t *testing.T) {
 		err      error
 		finalErr error
 	}{
-		{`VMState="poweroff"`, nil, errors.New("Host is not running")},
+		{`VMState="poweroff"`, nil, drivers.ErrHostIsNotRunning},
 		{"", errors.New("Unable to get state"), errors.New("Unable to get state")},
 	}
 
@@ -195,3 +196,12 @@ func TestSetConfigFromFlags(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Empty(t, checkFlags.InvalidFlags)
 }
+
+func TestConformance(t *testing.T) {
+	drivertest.Run(t, drivertest.Config{
+		NewDriver: func(machineName, storePath string) drivers.Driver {
+			return NewDriver(machineName, storePath)
+		},
+		SkipLifecycle: true,
+	})
+}
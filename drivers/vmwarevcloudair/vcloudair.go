@@ -54,7 +54,7 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Name:   "vmwarevcloudair-username",
 			Usage:  "vCloud Air username",
 		},
-		mcnflag.StringFlag{
+		mcnflag.SecretFlag{
 			EnvVar: "VCLOUDAIR_PASSWORD",
 			Name:   "vmwarevcloudair-password",
 			Usage:  "vCloud Air password",
@@ -147,6 +147,10 @@ func (d *Driver) DriverName() string {
 	return "vmwarevcloudair"
 }
 
+func (d *Driver) GetSecrets() []string {
+	return []string{d.UserPassword}
+}
+
 func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 
 	d.UserName = flags.String("vmwarevcloudair-username")
@@ -648,7 +652,7 @@ func generateVMName() string {
 }
 
 func (d *Driver) createSSHKey() (string, error) {
-	if err := ssh.GenerateSSHKey(d.GetSSHKeyPath()); err != nil {
+	if err := ssh.GenerateSSHKeyOfType(d.GetSSHKeyPath(), d.SSHKeyType); err != nil {
 		return "", err
 	}
 
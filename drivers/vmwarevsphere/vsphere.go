@@ -90,7 +90,7 @@ func (d *Driver) GetCreateFlags() []mcnflag.Flag {
 			Name:   "vmwarevsphere-username",
 			Usage:  "vSphere username",
 		},
-		mcnflag.StringFlag{
+		mcnflag.SecretFlag{
 			EnvVar: "VSPHERE_PASSWORD",
 			Name:   "vmwarevsphere-password",
 			Usage:  "vSphere password",
@@ -152,6 +152,10 @@ func (d *Driver) DriverName() string {
 	return "vmwarevsphere"
 }
 
+func (d *Driver) GetSecrets() []string {
+	return []string{d.Password}
+}
+
 func (d *Driver) SetConfigFromFlags(flags drivers.DriverOptions) error {
 	d.SSHUser = "docker"
 	d.SSHPort = 22
@@ -229,7 +233,7 @@ func (d *Driver) Create() error {
 	}
 
 	log.Infof("Generating SSH Keypair...")
-	if err := ssh.GenerateSSHKey(d.GetSSHKeyPath()); err != nil {
+	if err := ssh.GenerateSSHKeyOfType(d.GetSSHKeyPath(), d.SSHKeyType); err != nil {
 		return err
 	}
 
@@ -24,6 +24,19 @@ type BaseDriver struct {
 	SwarmMaster    bool
 	SwarmHost      string
 	SwarmDiscovery string
+
+	// SSHJumpHost is the host, as [user@]host[:port], the machine is
+	// reached through over SSH, with the key at SSHJumpKeyPath.
+	SSHJumpHost    string
+	SSHJumpKeyPath string
+
+	// SSHKeyType is the type of the SSH key generated for the machine, as
+	// ssh.ParseKeyType expects it, the default type if it's empty.
+	SSHKeyType string
+
+	// SSHAgent tells to authenticate with the keys of the running SSH agent
+	// too, before the key at SSHKeyPath.
+	SSHAgent bool
 }
 
 // DriverName returns the name of the driver
@@ -78,6 +91,18 @@ func (d *BaseDriver) PreCreateCheck() error {
 	return nil
 }
 
+// GetSSHJumpHost returns the jump host the machine is reached through, if
+// any, and the key to connect to it with.
+func (d *BaseDriver) GetSSHJumpHost() (string, string) {
+	return d.SSHJumpHost, d.SSHJumpKeyPath
+}
+
+// UseSSHAgent tells if the machine is logged in to with the keys of the
+// running SSH agent.
+func (d *BaseDriver) UseSSHAgent() bool {
+	return d.SSHAgent
+}
+
 // ResolveStorePath returns the store path where the machine is
 func (d *BaseDriver) ResolveStorePath(file string) string {
 	return filepath.Join(d.StorePath, "machines", d.MachineName, file)
@@ -0,0 +1,62 @@
+package drivers
+
+import "strings"
+
+// Capability is an action which a driver may or may not be able to perform
+// on the machines it manages.
+type Capability string
+
+const (
+	CapabilityStart   Capability = "start"
+	CapabilityStop    Capability = "stop"
+	CapabilityKill    Capability = "kill"
+	CapabilityRestart Capability = "restart"
+	CapabilitySSH     Capability = "ssh"
+)
+
+// Capabilities is the set of actions supported by a driver.
+type Capabilities []Capability
+
+// DefaultCapabilities are the capabilities assumed for drivers which do not
+// report their own, i.e. everything the Driver interface promises.
+var DefaultCapabilities = Capabilities{
+	CapabilityStart,
+	CapabilityStop,
+	CapabilityKill,
+	CapabilityRestart,
+	CapabilitySSH,
+}
+
+// CapabilityReporter is implemented by drivers which cannot perform every
+// action of the Driver interface.
+type CapabilityReporter interface {
+	// GetCapabilities returns the actions the driver supports.
+	GetCapabilities() Capabilities
+}
+
+// GetCapabilities returns the capabilities reported by the driver, or
+// DefaultCapabilities if it does not report any.
+func GetCapabilities(d Driver) Capabilities {
+	if reporter, ok := d.(CapabilityReporter); ok {
+		return reporter.GetCapabilities()
+	}
+	return DefaultCapabilities
+}
+
+// Has returns whether the capability is part of the set.
+func (c Capabilities) Has(capability Capability) bool {
+	for _, supported := range c {
+		if supported == capability {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Capabilities) String() string {
+	names := []string{}
+	for _, capability := range c {
+		names = append(names, string(capability))
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,46 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type capabilityDriver struct {
+	*MockDriver
+	capabilities Capabilities
+}
+
+func (d *capabilityDriver) GetCapabilities() Capabilities {
+	return d.capabilities
+}
+
+func TestGetCapabilitiesDefault(t *testing.T) {
+	capabilities := GetCapabilities(&MockDriver{})
+
+	assert.Equal(t, DefaultCapabilities, capabilities)
+	assert.True(t, capabilities.Has(CapabilityStart))
+	assert.False(t, capabilities.Has(Capability("snapshot")))
+}
+
+func TestGetCapabilitiesReported(t *testing.T) {
+	driver := &capabilityDriver{
+		MockDriver:   &MockDriver{},
+		capabilities: Capabilities{CapabilitySSH, CapabilityKill},
+	}
+
+	capabilities := GetCapabilities(driver)
+
+	assert.True(t, capabilities.Has(CapabilitySSH))
+	assert.False(t, capabilities.Has(CapabilityStart))
+	assert.Equal(t, "ssh,kill", capabilities.String())
+}
+
+func TestSerialDriverForwardsCapabilities(t *testing.T) {
+	driver := newSerialDriverWithLock(&capabilityDriver{
+		MockDriver:   &MockDriver{},
+		capabilities: Capabilities{CapabilitySSH},
+	}, &MockLocker{calls: &CallRecorder{}})
+
+	assert.Equal(t, Capabilities{CapabilitySSH}, GetCapabilities(driver))
+}
@@ -1,6 +1,10 @@
 package drivers
 
-import "github.com/docker/machine/libmachine/mcnflag"
+import (
+	"time"
+
+	"github.com/docker/machine/libmachine/mcnflag"
+)
 
 // CheckDriverOptions implements DriverOptions and is used to validate flag parsing
 type CheckDriverOptions struct {
@@ -9,21 +13,32 @@ type CheckDriverOptions struct {
 	InvalidFlags []string
 }
 
+// value returns the value given to a flag or, like the CLI, its default.
+func (o *CheckDriverOptions) value(key string) interface{} {
+	if value, present := o.FlagsValues[key]; present {
+		return value
+	}
+	for _, flag := range o.CreateFlags {
+		if flag.String() == key {
+			return flag.Default()
+		}
+	}
+	return nil
+}
+
 func (o *CheckDriverOptions) String(key string) string {
 	for _, flag := range o.CreateFlags {
 		if flag.String() == key {
-			_, ok := flag.(mcnflag.StringFlag)
-			if !ok {
+			switch flag.(type) {
+			case mcnflag.StringFlag, mcnflag.EnumFlag, mcnflag.SecretFlag, mcnflag.FilePathFlag:
+			default:
 				o.InvalidFlags = append(o.InvalidFlags, flag.String())
 			}
 		}
 	}
 
-	value, present := o.FlagsValues[key].(string)
-	if present {
-		return value
-	}
-	return ""
+	value, _ := o.value(key).(string)
+	return value
 }
 
 func (o *CheckDriverOptions) StringSlice(key string) []string {
@@ -36,11 +51,8 @@ func (o *CheckDriverOptions) StringSlice(key string) []string {
 		}
 	}
 
-	value, present := o.FlagsValues[key].([]string)
-	if present {
-		return value
-	}
-	return nil
+	value, _ := o.value(key).([]string)
+	return value
 }
 
 func (o *CheckDriverOptions) Int(key string) int {
@@ -53,11 +65,8 @@ func (o *CheckDriverOptions) Int(key string) int {
 		}
 	}
 
-	value, present := o.FlagsValues[key].(int)
-	if present {
-		return value
-	}
-	return 42
+	value, _ := o.value(key).(int)
+	return value
 }
 
 func (o *CheckDriverOptions) Bool(key string) bool {
@@ -70,9 +79,34 @@ func (o *CheckDriverOptions) Bool(key string) bool {
 		}
 	}
 
-	value, present := o.FlagsValues[key].(bool)
-	if present {
-		return value
+	value, _ := o.value(key).(bool)
+	return value
+}
+
+func (o *CheckDriverOptions) Duration(key string) time.Duration {
+	for _, flag := range o.CreateFlags {
+		if flag.String() == key {
+			_, ok := flag.(mcnflag.DurationFlag)
+			if !ok {
+				o.InvalidFlags = append(o.InvalidFlags, flag.String())
+			}
+		}
 	}
-	return false
+
+	value, _ := o.value(key).(time.Duration)
+	return value
+}
+
+func (o *CheckDriverOptions) Float(key string) float64 {
+	for _, flag := range o.CreateFlags {
+		if flag.String() == key {
+			_, ok := flag.(mcnflag.FloatFlag)
+			if !ok {
+				o.InvalidFlags = append(o.InvalidFlags, flag.String())
+			}
+		}
+	}
+
+	value, _ := o.value(key).(float64)
+	return value
 }
@@ -0,0 +1,62 @@
+package drivers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/docker/machine/libmachine/mcnflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckDriverOptionsDefaults(t *testing.T) {
+	opts := &CheckDriverOptions{
+		FlagsValues: map[string]interface{}{
+			"cpus": 4,
+		},
+		CreateFlags: []mcnflag.Flag{
+			mcnflag.IntFlag{Name: "cpus", Value: 1},
+			mcnflag.IntFlag{Name: "disks", Value: 2},
+			mcnflag.DurationFlag{Name: "timeout", Value: time.Minute},
+			mcnflag.FloatFlag{Name: "ratio", Value: 0.5},
+			mcnflag.EnumFlag{Name: "size", Value: "small", Allowed: []string{"small"}},
+		},
+	}
+
+	assert.Equal(t, 4, opts.Int("cpus"))
+	assert.Equal(t, 2, opts.Int("disks"))
+	assert.Equal(t, 0, opts.Int("unknown"))
+	assert.Equal(t, time.Minute, opts.Duration("timeout"))
+	assert.Equal(t, 0.5, opts.Float("ratio"))
+	assert.Equal(t, "small", opts.String("size"))
+	assert.Empty(t, opts.InvalidFlags)
+
+	opts.String("cpus")
+	assert.Equal(t, []string{"cpus"}, opts.InvalidFlags)
+}
+
+// stringOptions only implements DriverOptions, like drivers built against
+// older versions of machine do.
+type stringOptions map[string]string
+
+func (o stringOptions) String(key string) string        { return o[key] }
+func (o stringOptions) StringSlice(key string) []string { return nil }
+func (o stringOptions) Int(key string) int              { return 0 }
+func (o stringOptions) Bool(key string) bool            { return false }
+
+func TestGetDurationAndFloat(t *testing.T) {
+	typed := &CheckDriverOptions{
+		FlagsValues: map[string]interface{}{"timeout": time.Minute, "ratio": 0.5},
+		CreateFlags: []mcnflag.Flag{
+			mcnflag.DurationFlag{Name: "timeout"},
+			mcnflag.FloatFlag{Name: "ratio"},
+		},
+	}
+	assert.Equal(t, time.Minute, GetDuration(typed, "timeout"))
+	assert.Equal(t, 0.5, GetFloat(typed, "ratio"))
+
+	untyped := stringOptions{"timeout": "1m", "ratio": "0.5", "bad": "soon"}
+	assert.Equal(t, time.Minute, GetDuration(untyped, "timeout"))
+	assert.Equal(t, 0.5, GetFloat(untyped, "ratio"))
+	assert.Equal(t, time.Duration(0), GetDuration(untyped, "bad"))
+	assert.Equal(t, 0.0, GetFloat(untyped, "bad"))
+}
@@ -0,0 +1,43 @@
+package drivers
+
+import "github.com/docker/machine/libmachine/mcnflag"
+
+// Command is an operation a driver offers besides those of the Driver
+// interface, e.g. listing the regions of a cloud provider.  Users run it
+// with "docker-machine driver NAME COMMAND".
+type Command struct {
+	Name  string
+	Usage string
+	Flags []mcnflag.Flag
+}
+
+// CommandRunner is implemented by drivers which offer commands.  The driver
+// running a command knows its store path but is otherwise unconfigured:
+// SetConfigFromFlags isn't called, and the command gets the values of its
+// own flags only.
+type CommandRunner interface {
+	// GetCommands returns the commands the driver offers.
+	GetCommands() []Command
+
+	// RunCommand runs a command with the values of its flags and its
+	// arguments, and returns its output.
+	RunCommand(name string, flags DriverOptions, args []string) (string, error)
+}
+
+// GetCommands returns the commands offered by the driver, if any.
+func GetCommands(d Driver) []Command {
+	if runner, ok := d.(CommandRunner); ok {
+		return runner.GetCommands()
+	}
+	return []Command{}
+}
+
+// FindCommand returns the command of the given name, or nil.
+func FindCommand(commands []Command, name string) *Command {
+	for i := range commands {
+		if commands[i].Name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
@@ -1,10 +1,14 @@
 package drivers
 
 import (
-	"errors"
+	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnerror"
 	"github.com/docker/machine/libmachine/mcnflag"
+	"github.com/docker/machine/libmachine/mcnutils"
 	"github.com/docker/machine/libmachine/state"
 )
 
@@ -72,7 +76,7 @@ type Driver interface {
 	Stop() error
 }
 
-var ErrHostIsNotRunning = errors.New("Host is not running")
+var ErrHostIsNotRunning = mcnerror.ErrHostNotRunning{}
 
 type DriverOptions interface {
 	String(key string) string
@@ -81,6 +85,43 @@ type DriverOptions interface {
 	Bool(key string) bool
 }
 
+// TypedDriverOptions are DriverOptions which can also hold the values of
+// duration and float flags.  Drivers should read those with GetDuration and
+// GetFloat, which work with any DriverOptions.
+type TypedDriverOptions interface {
+	DriverOptions
+	Duration(key string) time.Duration
+	Float(key string) float64
+}
+
+// GetDuration returns the value of a duration flag, parsing it from a
+// string if the options don't hold durations.
+func GetDuration(opts DriverOptions, key string) time.Duration {
+	if typed, ok := opts.(TypedDriverOptions); ok {
+		return typed.Duration(key)
+	}
+
+	d, err := time.ParseDuration(opts.String(key))
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+// GetFloat returns the value of a float flag, parsing it from a string if
+// the options don't hold floats.
+func GetFloat(opts DriverOptions, key string) float64 {
+	if typed, ok := opts.(TypedDriverOptions); ok {
+		return typed.Float(key)
+	}
+
+	f, err := strconv.ParseFloat(opts.String(key), 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
 func MachineInState(d Driver, desiredState state.State) func() bool {
 	return func() bool {
 		currentState, err := d.GetState()
@@ -93,3 +134,23 @@ func MachineInState(d Driver, desiredState state.State) func() bool {
 		return false
 	}
 }
+
+func machineInStateErr(d Driver, desiredState state.State) func() error {
+	return func() error {
+		currentState, err := d.GetState()
+		if err != nil {
+			log.Debugf("Error getting machine state: %s", err)
+			return err
+		}
+		if currentState != desiredState {
+			return fmt.Errorf("Machine is %s, waiting for it to be %s", currentState, desiredState)
+		}
+		return nil
+	}
+}
+
+// WaitForState waits until the machine is in the desired state, following
+// the backoff policy configured for the driver.
+func WaitForState(d Driver, desiredState state.State) error {
+	return mcnutils.RetryWithDriverPolicy(d.DriverName(), machineInStateErr(d, desiredState))
+}
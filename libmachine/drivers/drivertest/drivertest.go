@@ -0,0 +1,504 @@
+// Package drivertest checks that a driver behaves the way Machine expects it
+// to.  Driver authors run the checks from a test of their own, against a
+// fake backend:
+//
+//	func TestConformance(t *testing.T) {
+//		drivertest.Run(t, drivertest.Config{
+//			NewDriver: func(machineName, storePath string) drivers.Driver {
+//				return NewDriver(machineName, storePath)
+//			},
+//			Flags: map[string]interface{}{
+//				"mydriver-token": "fake",
+//			},
+//		})
+//	}
+//
+// The checks can go through RPC like Machine does, by using the driver's
+// plugin binary with NewPluginDriver.
+package drivertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/drivers/rpc"
+	"github.com/docker/machine/libmachine/mcnflag"
+	"github.com/docker/machine/libmachine/mcnutils"
+	"github.com/docker/machine/libmachine/persist"
+	"github.com/docker/machine/libmachine/state"
+)
+
+const (
+	// MachineName is the name of the machines created by the checks.
+	MachineName = "drivertest"
+
+	defaultStateTimeout = time.Minute
+	stateCheckInterval  = 100 * time.Millisecond
+)
+
+// T is the part of *testing.T the checks use.
+type T interface {
+	Errorf(format string, args ...interface{})
+	Logf(format string, args ...interface{})
+}
+
+// Config describes the driver under test.
+type Config struct {
+	// NewDriver returns an unconfigured driver for a machine, like the
+	// NewDriver function of a driver package.
+	NewDriver func(machineName, storePath string) drivers.Driver
+
+	// Flags are the values given to create flags.  The other flags take
+	// their default value.
+	Flags map[string]interface{}
+
+	// SkipLifecycle skips creating a machine, changing its state and
+	// removing it, for drivers which can't do that without a real
+	// backend.
+	SkipLifecycle bool
+
+	// StateTimeout is how long a machine gets to reach a state.  It
+	// defaults to a minute.
+	StateTimeout time.Duration
+}
+
+// NewPluginDriver returns a NewDriver function which gives clients of the
+// plugin binary of the driver, docker-machine-driver-NAME.
+func NewPluginDriver(t T, driverName string) func(machineName, storePath string) drivers.Driver {
+	return func(machineName, storePath string) drivers.Driver {
+		data, err := json.Marshal(&drivers.BaseDriver{
+			MachineName: machineName,
+			StorePath:   storePath,
+		})
+		if err != nil {
+			t.Errorf("Error marshalling bare driver data: %s", err)
+			return nil
+		}
+
+		d, err := rpcdriver.NewRPCClientDriver(data, driverName)
+		if err != nil {
+			t.Errorf("Error starting the plugin for driver %q: %s", driverName, err)
+			return nil
+		}
+
+		return d
+	}
+}
+
+type checker struct {
+	t         T
+	config    Config
+	storePath string
+	closers   []interface {
+		Close() error
+	}
+}
+
+// Run runs every check on the driver.  Failures are reported with t.Errorf;
+// a check stops at its first failure, but the others still run.
+func Run(t T, config Config) {
+	if config.StateTimeout == 0 {
+		config.StateTimeout = defaultStateTimeout
+	}
+
+	storePath, err := ioutil.TempDir("", "drivertest-")
+	if err != nil {
+		t.Errorf("Error creating the store: %s", err)
+		return
+	}
+	defer os.RemoveAll(storePath)
+
+	c := &checker{
+		t:         t,
+		config:    config,
+		storePath: storePath,
+	}
+	defer c.close()
+
+	checks := []struct {
+		name  string
+		check func() error
+	}{
+		{"flags", c.checkFlags},
+		{"configuration", c.checkConfigRoundTrip},
+		{"persistence", c.checkPersistence},
+		{"lifecycle", c.checkLifecycle},
+	}
+
+	for _, check := range checks {
+		if check.name == "lifecycle" && config.SkipLifecycle {
+			t.Logf("Skipping the %s check", check.name)
+			continue
+		}
+		if err := check.check(); err != nil {
+			t.Errorf("Driver failed the %s check: %s", check.name, err)
+		}
+	}
+}
+
+func (c *checker) close() {
+	for _, closer := range c.closers {
+		if err := closer.Close(); err != nil {
+			c.t.Logf("Error closing driver: %s", err)
+		}
+	}
+}
+
+func (c *checker) newDriver() (drivers.Driver, error) {
+	d := c.config.NewDriver(MachineName, c.storePath)
+	if d == nil {
+		return nil, fmt.Errorf("NewDriver returned no driver")
+	}
+
+	if closer, ok := d.(interface {
+		Close() error
+	}); ok {
+		c.closers = append(c.closers, closer)
+	}
+
+	return d, nil
+}
+
+// flagValues returns the values of all the create flags, like the create
+// command sends them.
+func (c *checker) flagValues(flags []mcnflag.Flag) map[string]interface{} {
+	values := make(map[string]interface{})
+
+	// Required flags without a default are left out unless configured,
+	// as the create command does.
+	for _, f := range flags {
+		if mcnflag.IsRequired(f) && !mcnflag.HasDefault(f) {
+			continue
+		}
+		values[f.String()] = f.Default()
+		if f.Default() == nil {
+			values[f.String()] = false
+		}
+	}
+
+	for name, value := range c.config.Flags {
+		values[name] = value
+	}
+
+	return values
+}
+
+// configuredDriver returns a new driver configured from the flags.
+func (c *checker) configuredDriver() (drivers.Driver, error) {
+	d, err := c.newDriver()
+	if err != nil {
+		return nil, err
+	}
+
+	flags := d.GetCreateFlags()
+	values := c.flagValues(flags)
+
+	if err := mcnflag.Validate(flags, values); err != nil {
+		return nil, fmt.Errorf("The flags given in the configuration aren't valid: %s", err)
+	}
+
+	if _, ok := d.(*rpcdriver.RPCClientDriver); ok {
+		if err := d.SetConfigFromFlags(rpcdriver.RPCFlags{Values: values}); err != nil {
+			return nil, fmt.Errorf("SetConfigFromFlags failed: %s", err)
+		}
+		return d, nil
+	}
+
+	opts := &drivers.CheckDriverOptions{
+		FlagsValues: values,
+		CreateFlags: flags,
+	}
+
+	if err := d.SetConfigFromFlags(opts); err != nil {
+		return nil, fmt.Errorf("SetConfigFromFlags failed: %s", err)
+	}
+
+	if len(opts.InvalidFlags) > 0 {
+		return nil, fmt.Errorf("SetConfigFromFlags read flags with a type other than their own: %v", opts.InvalidFlags)
+	}
+
+	return d, nil
+}
+
+func (c *checker) checkFlags() error {
+	d, err := c.newDriver()
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+
+	for _, f := range d.GetCreateFlags() {
+		name := f.String()
+		if name == "" {
+			return fmt.Errorf("A create flag has no name: %+v", f)
+		}
+		if seen[name] {
+			return fmt.Errorf("The create flag %q is declared twice", name)
+		}
+		seen[name] = true
+	}
+
+	for name := range c.config.Flags {
+		if !seen[name] {
+			return fmt.Errorf("The configuration gives a value to %q, which isn't a create flag", name)
+		}
+	}
+
+	_, err = c.configuredDriver()
+	return err
+}
+
+type configRawGetter interface {
+	GetConfigRaw() ([]byte, error)
+}
+
+type configRawSetter interface {
+	SetConfigRaw(data []byte) error
+}
+
+// getConfigRaw returns the configuration Machine stores for the driver.
+func getConfigRaw(d drivers.Driver) ([]byte, error) {
+	if getter, ok := d.(configRawGetter); ok {
+		return getter.GetConfigRaw()
+	}
+	return json.Marshal(d)
+}
+
+func setConfigRaw(d drivers.Driver, data []byte) error {
+	if setter, ok := d.(configRawSetter); ok {
+		return setter.SetConfigRaw(data)
+	}
+	return json.Unmarshal(data, d)
+}
+
+func sameConfig(expected, actual []byte) error {
+	var expectedValue, actualValue interface{}
+
+	if err := json.Unmarshal(expected, &expectedValue); err != nil {
+		return fmt.Errorf("The configuration isn't valid JSON: %s", err)
+	}
+	if err := json.Unmarshal(actual, &actualValue); err != nil {
+		return fmt.Errorf("The configuration isn't valid JSON: %s", err)
+	}
+
+	if !reflect.DeepEqual(expectedValue, actualValue) {
+		return fmt.Errorf("The configuration changed from %s to %s", bytes.TrimSpace(expected), bytes.TrimSpace(actual))
+	}
+
+	return nil
+}
+
+func (c *checker) checkConfigRoundTrip() error {
+	d, err := c.configuredDriver()
+	if err != nil {
+		return err
+	}
+
+	if d.DriverName() == "" {
+		return fmt.Errorf("DriverName returned an empty name")
+	}
+
+	data, err := getConfigRaw(d)
+	if err != nil {
+		return fmt.Errorf("Error getting the configuration: %s", err)
+	}
+
+	var base drivers.BaseDriver
+	if err := json.Unmarshal(data, &base); err != nil {
+		return fmt.Errorf("The configuration isn't a JSON object: %s", err)
+	}
+	if base.MachineName != MachineName {
+		return fmt.Errorf("The configuration has MachineName %q instead of %q", base.MachineName, MachineName)
+	}
+	if base.StorePath != c.storePath {
+		return fmt.Errorf("The configuration has StorePath %q instead of %q", base.StorePath, c.storePath)
+	}
+
+	loaded, err := c.newDriver()
+	if err != nil {
+		return err
+	}
+
+	if err := setConfigRaw(loaded, data); err != nil {
+		return fmt.Errorf("Error setting the configuration: %s", err)
+	}
+
+	if name := loaded.GetMachineName(); name != MachineName {
+		return fmt.Errorf("GetMachineName returned %q instead of %q", name, MachineName)
+	}
+
+	reloaded, err := getConfigRaw(loaded)
+	if err != nil {
+		return fmt.Errorf("Error getting the configuration: %s", err)
+	}
+
+	return sameConfig(data, reloaded)
+}
+
+func (c *checker) checkPersistence() error {
+	d, err := c.configuredDriver()
+	if err != nil {
+		return err
+	}
+
+	store := persist.Filestore{Path: c.storePath}
+
+	h, err := store.NewHost(d)
+	if err != nil {
+		return fmt.Errorf("Error creating the host: %s", err)
+	}
+
+	// The store only does this by itself for plugins.
+	data, err := getConfigRaw(d)
+	if err != nil {
+		return fmt.Errorf("Error getting the configuration: %s", err)
+	}
+	h.RawDriver = data
+
+	if err := store.Save(h); err != nil {
+		return fmt.Errorf("Error saving the host: %s", err)
+	}
+	defer store.Remove(h.Name)
+
+	saved, err := store.Load(h.Name)
+	if err != nil {
+		return fmt.Errorf("Error loading the host: %s", err)
+	}
+
+	if saved.DriverName != d.DriverName() {
+		return fmt.Errorf("The host was saved with driver %q instead of %q", saved.DriverName, d.DriverName())
+	}
+
+	loaded, err := c.newDriver()
+	if err != nil {
+		return err
+	}
+
+	if err := setConfigRaw(loaded, saved.RawDriver); err != nil {
+		return fmt.Errorf("Error setting the saved configuration: %s", err)
+	}
+
+	reloaded, err := getConfigRaw(loaded)
+	if err != nil {
+		return fmt.Errorf("Error getting the configuration: %s", err)
+	}
+
+	return sameConfig(data, reloaded)
+}
+
+func (c *checker) waitForState(d drivers.Driver, desiredState state.State) error {
+	attempts := int(c.config.StateTimeout / stateCheckInterval)
+
+	if err := mcnutils.WaitForSpecific(drivers.MachineInState(d, desiredState), attempts, stateCheckInterval); err != nil {
+		current, _ := d.GetState()
+		return fmt.Errorf("The machine is %s instead of %s after %s", current, desiredState, c.config.StateTimeout)
+	}
+
+	return nil
+}
+
+func checkURL(d drivers.Driver) error {
+	rawURL, err := d.GetURL()
+	if err != nil {
+		return fmt.Errorf("GetURL failed: %s", err)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("GetURL returned an invalid URL %q: %s", rawURL, err)
+	}
+
+	if u.Scheme != "tcp" {
+		return fmt.Errorf("GetURL returned %q, which isn't a tcp:// URL", rawURL)
+	}
+
+	host, port, err := net.SplitHostPort(u.Host)
+	if err != nil || host == "" {
+		return fmt.Errorf("GetURL returned %q, which has no host and port", rawURL)
+	}
+
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return fmt.Errorf("GetURL returned %q, whose port isn't a number", rawURL)
+	}
+
+	return nil
+}
+
+func (c *checker) checkLifecycle() error {
+	d, err := c.configuredDriver()
+	if err != nil {
+		return err
+	}
+
+	if err := d.PreCreateCheck(); err != nil {
+		return fmt.Errorf("PreCreateCheck failed: %s", err)
+	}
+
+	if err := d.Create(); err != nil {
+		return fmt.Errorf("Create failed: %s", err)
+	}
+
+	removed := false
+	defer func() {
+		if !removed {
+			if err := d.Remove(); err != nil {
+				c.t.Logf("Error removing the machine: %s", err)
+			}
+		}
+	}()
+
+	if err := c.waitForState(d, state.Running); err != nil {
+		return err
+	}
+
+	if err := checkURL(d); err != nil {
+		return err
+	}
+
+	capabilities := drivers.GetCapabilities(d)
+
+	transitions := []struct {
+		capability drivers.Capability
+		action     func() error
+		state      state.State
+	}{
+		{drivers.CapabilityStop, d.Stop, state.Stopped},
+		{drivers.CapabilityStart, d.Start, state.Running},
+		{drivers.CapabilityRestart, d.Restart, state.Running},
+		{drivers.CapabilityKill, d.Kill, state.Stopped},
+	}
+
+	for _, transition := range transitions {
+		if !capabilities.Has(transition.capability) {
+			continue
+		}
+		if err := transition.action(); err != nil {
+			return fmt.Errorf("%s failed: %s", transition.capability, err)
+		}
+		if err := c.waitForState(d, transition.state); err != nil {
+			return fmt.Errorf("After %s: %s", transition.capability, err)
+		}
+	}
+
+	removed = true
+
+	if err := d.Remove(); err != nil {
+		return fmt.Errorf("Remove failed: %s", err)
+	}
+
+	if err := d.Remove(); err != nil {
+		return fmt.Errorf("Remove failed on a machine which was already removed: %s", err)
+	}
+
+	return nil
+}
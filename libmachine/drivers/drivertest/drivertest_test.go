@@ -0,0 +1,220 @@
+package drivertest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/drivers/plugin"
+	"github.com/docker/machine/libmachine/drivers/plugin/localbinary"
+	"github.com/docker/machine/libmachine/mcnflag"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/stretchr/testify/assert"
+)
+
+const pluginTestEnvKey = "MACHINE_TEST_DRIVERTEST_PLUGIN"
+
+// backend is the fake cloud of memoryDriver: the state of each machine.
+var (
+	backendLock = &sync.Mutex{}
+	backend     = make(map[string]state.State)
+)
+
+type memoryDriver struct {
+	*drivers.BaseDriver
+	Size    string
+	Timeout time.Duration
+
+	// removeOnce makes Remove fail on a machine which doesn't exist.
+	removeOnce bool
+}
+
+func newMemoryDriver(machineName, storePath string) drivers.Driver {
+	return &memoryDriver{
+		BaseDriver: &drivers.BaseDriver{
+			MachineName: machineName,
+			StorePath:   storePath,
+		},
+	}
+}
+
+func (d *memoryDriver) setState(s state.State) {
+	backendLock.Lock()
+	defer backendLock.Unlock()
+	backend[d.MachineName] = s
+}
+
+func (d *memoryDriver) DriverName() string {
+	return "memory"
+}
+
+func (d *memoryDriver) GetCreateFlags() []mcnflag.Flag {
+	return []mcnflag.Flag{
+		mcnflag.EnumFlag{Name: "memory-size", Value: "small", Allowed: []string{"small", "large"}},
+		mcnflag.DurationFlag{Name: "memory-timeout", Value: time.Minute},
+	}
+}
+
+func (d *memoryDriver) SetConfigFromFlags(flags drivers.DriverOptions) error {
+	d.Size = flags.String("memory-size")
+	d.Timeout = drivers.GetDuration(flags, "memory-timeout")
+	return nil
+}
+
+func (d *memoryDriver) GetSSHHostname() (string, error) {
+	return d.GetIP()
+}
+
+func (d *memoryDriver) GetURL() (string, error) {
+	return "tcp://1.2.3.4:2376", nil
+}
+
+func (d *memoryDriver) GetState() (state.State, error) {
+	backendLock.Lock()
+	defer backendLock.Unlock()
+	s, ok := backend[d.MachineName]
+	if !ok {
+		return state.None, fmt.Errorf("machine %q doesn't exist", d.MachineName)
+	}
+	return s, nil
+}
+
+func (d *memoryDriver) PreCreateCheck() error {
+	return nil
+}
+
+func (d *memoryDriver) Create() error {
+	d.setState(state.Running)
+	return nil
+}
+
+func (d *memoryDriver) Start() error {
+	d.setState(state.Running)
+	return nil
+}
+
+func (d *memoryDriver) Stop() error {
+	d.setState(state.Stopped)
+	return nil
+}
+
+func (d *memoryDriver) Restart() error {
+	d.setState(state.Running)
+	return nil
+}
+
+func (d *memoryDriver) Kill() error {
+	d.setState(state.Stopped)
+	return nil
+}
+
+func (d *memoryDriver) Remove() error {
+	backendLock.Lock()
+	defer backendLock.Unlock()
+	if _, ok := backend[d.MachineName]; !ok && d.removeOnce {
+		return fmt.Errorf("machine %q doesn't exist", d.MachineName)
+	}
+	delete(backend, d.MachineName)
+	return nil
+}
+
+// recorder is a T which keeps the failures.
+type recorder struct {
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Logf(format string, args ...interface{}) {}
+
+// TestMain lets the test binary double as the plugin of memoryDriver.
+func TestMain(m *testing.M) {
+	if os.Getenv(pluginTestEnvKey) != "" && os.Getenv(localbinary.PluginEnvKey) == localbinary.PluginEnvVal {
+		plugin.RegisterDriverFactory(func() drivers.Driver {
+			return newMemoryDriver("", "")
+		})
+		return
+	}
+
+	os.Exit(m.Run())
+}
+
+func TestRun(t *testing.T) {
+	Run(t, Config{
+		NewDriver: newMemoryDriver,
+		Flags: map[string]interface{}{
+			"memory-size": "large",
+		},
+	})
+}
+
+func TestRunThroughPlugin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test binary can't be linked as a plugin on Windows")
+	}
+
+	dir, err := ioutil.TempDir("", "drivertest-plugin-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	binary, err := filepath.Abs(os.Args[0])
+	assert.NoError(t, err)
+	assert.NoError(t, os.Symlink(binary, filepath.Join(dir, localbinary.BinaryName("memory"))))
+
+	localbinary.PluginDir = dir
+	os.Setenv(pluginTestEnvKey, "1")
+	defer func() {
+		localbinary.PluginDir = ""
+		os.Unsetenv(pluginTestEnvKey)
+	}()
+
+	Run(t, Config{
+		NewDriver: NewPluginDriver(t, "memory"),
+		Flags: map[string]interface{}{
+			"memory-timeout": 90 * time.Second,
+		},
+	})
+}
+
+func TestRunReportsFailures(t *testing.T) {
+	r := &recorder{}
+
+	Run(r, Config{
+		NewDriver: func(machineName, storePath string) drivers.Driver {
+			d := newMemoryDriver(machineName, storePath).(*memoryDriver)
+			d.removeOnce = true
+			return d
+		},
+		Flags: map[string]interface{}{
+			"memory-size": "huge",
+		},
+	})
+
+	assert.Len(t, r.errors, 4)
+	for _, err := range r.errors {
+		assert.Contains(t, err, `Invalid value for --memory-size: "huge" is not one of small, large`)
+	}
+
+	r = &recorder{}
+
+	Run(r, Config{
+		NewDriver: func(machineName, storePath string) drivers.Driver {
+			d := newMemoryDriver(machineName, storePath).(*memoryDriver)
+			d.removeOnce = true
+			return d
+		},
+	})
+
+	if assert.Len(t, r.errors, 1) {
+		assert.True(t, strings.HasPrefix(r.errors[0], "Driver failed the lifecycle check: Remove failed on a machine which was already removed"), r.errors[0])
+	}
+}
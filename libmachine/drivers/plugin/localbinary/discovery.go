@@ -0,0 +1,225 @@
+package localbinary
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/docker/machine/libmachine/mcnerror"
+)
+
+const pluginBinaryPrefix = "docker-machine-driver-"
+
+var (
+	// PluginDir is where drivers installed with "docker-machine driver
+	// install" are kept.  It is searched before the PATH.
+	PluginDir string
+
+	validDriverName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_\-\.]*$`)
+)
+
+// PluginBinary is a driver plugin binary found on disk.
+type PluginBinary struct {
+	DriverName string
+	Path       string
+}
+
+type ErrInvalidDriverName struct {
+	driverName string
+}
+
+func (e ErrInvalidDriverName) Error() string {
+	return fmt.Sprintf("Invalid driver name %q. Allowed driver name chars are: 0-9a-zA-Z _ - .", e.driverName)
+}
+
+type ErrChecksumMismatch struct {
+	Expected string
+	Actual   string
+}
+
+func (e ErrChecksumMismatch) Error() string {
+	return fmt.Sprintf("Checksum mismatch: expected sha256 %s, got %s", e.Expected, e.Actual)
+}
+
+// BinaryName returns the name of the plugin binary for a driver.
+func BinaryName(driverName string) string {
+	if runtime.GOOS == "windows" {
+		return pluginBinaryPrefix + driverName + ".exe"
+	}
+	return pluginBinaryPrefix + driverName
+}
+
+// DriverNameFromBinary returns the name of the driver for a plugin binary
+// name, or "" if the name isn't one of a plugin binary.
+func DriverNameFromBinary(binaryName string) string {
+	if runtime.GOOS == "windows" {
+		binaryName = strings.TrimSuffix(binaryName, ".exe")
+	}
+	if !strings.HasPrefix(binaryName, pluginBinaryPrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(binaryName, pluginBinaryPrefix)
+}
+
+func isExecutable(info os.FileInfo) bool {
+	if info.IsDir() {
+		return false
+	}
+	return runtime.GOOS == "windows" || info.Mode()&0111 != 0
+}
+
+// LookupPlugin returns the path of the plugin binary for a driver, looking
+// in PluginDir first and then in the PATH.
+func LookupPlugin(driverName string) (string, error) {
+	if PluginDir != "" {
+		path := filepath.Join(PluginDir, BinaryName(driverName))
+		if info, err := os.Stat(path); err == nil && isExecutable(info) {
+			return path, nil
+		}
+	}
+
+	path, err := exec.LookPath(BinaryName(driverName))
+	if err != nil {
+		return "", mcnerror.ErrDriverUnavailable{DriverName: driverName}
+	}
+
+	return path, nil
+}
+
+// ListPlugins returns the plugin binaries in PluginDir and the PATH, sorted
+// by driver name.  If several binaries exist for a driver, only the one
+// LookupPlugin would use is returned.
+func ListPlugins() []PluginBinary {
+	dirs := []string{}
+	if PluginDir != "" {
+		dirs = append(dirs, PluginDir)
+	}
+	dirs = append(dirs, filepath.SplitList(os.Getenv("PATH"))...)
+
+	plugins := []PluginBinary{}
+	seen := make(map[string]bool)
+
+	for _, dir := range dirs {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, file := range files {
+			driverName := DriverNameFromBinary(file.Name())
+			if driverName == "" || seen[driverName] || !isExecutable(file) {
+				continue
+			}
+
+			seen[driverName] = true
+			plugins = append(plugins, PluginBinary{
+				DriverName: driverName,
+				Path:       filepath.Join(dir, file.Name()),
+			})
+		}
+	}
+
+	sort.Sort(byDriverName(plugins))
+
+	return plugins
+}
+
+type byDriverName []PluginBinary
+
+func (p byDriverName) Len() int           { return len(p) }
+func (p byDriverName) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p byDriverName) Less(i, j int) bool { return p[i].DriverName < p[j].DriverName }
+
+// InstallPlugin copies a plugin binary for the driver into PluginDir.  If
+// sha256sum isn't empty, the binary is only installed if its checksum
+// matches.  If verify isn't nil, it's given the path of the new binary
+// before it replaces the installed one, which is kept if verify fails.  The
+// path of the installed binary is returned.
+func InstallPlugin(driverName string, r io.Reader, sha256sum string, verify func(path string) error) (string, error) {
+	if !validDriverName.MatchString(driverName) {
+		return "", ErrInvalidDriverName{driverName}
+	}
+
+	if PluginDir == "" {
+		return "", fmt.Errorf("No plugin directory configured")
+	}
+
+	if err := os.MkdirAll(PluginDir, 0700); err != nil {
+		return "", err
+	}
+
+	// Write to a temporary file first so that a failed download or a bad
+	// checksum never leaves a broken driver behind.
+	tmp, err := ioutil.TempFile(PluginDir, ".install-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, hash), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("Error writing driver binary: %s", err)
+	}
+
+	if sha256sum != "" {
+		actual := hex.EncodeToString(hash.Sum(nil))
+		if !strings.EqualFold(actual, sha256sum) {
+			return "", ErrChecksumMismatch{
+				Expected: strings.ToLower(sha256sum),
+				Actual:   actual,
+			}
+		}
+	}
+
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return "", err
+	}
+
+	if verify != nil {
+		if err := verify(tmp.Name()); err != nil {
+			return "", err
+		}
+	}
+
+	path := filepath.Join(PluginDir, BinaryName(driverName))
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// RemovePlugin deletes the plugin binary for the driver from PluginDir.
+// Binaries in the PATH are never touched.
+func RemovePlugin(driverName string) error {
+	if !validDriverName.MatchString(driverName) {
+		return ErrInvalidDriverName{driverName}
+	}
+
+	if PluginDir == "" {
+		return mcnerror.ErrDriverUnavailable{DriverName: driverName}
+	}
+
+	path := filepath.Join(PluginDir, BinaryName(driverName))
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return mcnerror.ErrDriverUnavailable{DriverName: driverName}
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package localbinary
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/stretchr/testify/assert"
+)
+
+func withPluginDirs(t *testing.T, f func(pluginDir, pathDir string)) {
+	pluginDir, err := ioutil.TempDir("", "plugins")
+	assert.NoError(t, err)
+	defer os.RemoveAll(pluginDir)
+
+	pathDir, err := ioutil.TempDir("", "path")
+	assert.NoError(t, err)
+	defer os.RemoveAll(pathDir)
+
+	oldPath := os.Getenv("PATH")
+	os.Setenv("PATH", pathDir)
+	PluginDir = pluginDir
+	defer func() {
+		os.Setenv("PATH", oldPath)
+		PluginDir = ""
+	}()
+
+	f(pluginDir, pathDir)
+}
+
+func writeBinary(t *testing.T, dir, name string, mode os.FileMode) string {
+	path := filepath.Join(dir, name)
+	assert.NoError(t, ioutil.WriteFile(path, []byte("#!/bin/sh\n"), mode))
+	return path
+}
+
+func TestDriverNameFromBinary(t *testing.T) {
+	assert.Equal(t, "foo", DriverNameFromBinary(BinaryName("foo")))
+	assert.Equal(t, "", DriverNameFromBinary("docker-machine"))
+}
+
+func TestLookupPluginPrefersPluginDir(t *testing.T) {
+	withPluginDirs(t, func(pluginDir, pathDir string) {
+		writeBinary(t, pathDir, BinaryName("foo"), 0755)
+		installed := writeBinary(t, pluginDir, BinaryName("foo"), 0755)
+		inPath := writeBinary(t, pathDir, BinaryName("bar"), 0755)
+
+		path, err := LookupPlugin("foo")
+		assert.NoError(t, err)
+		assert.Equal(t, installed, path)
+
+		path, err = LookupPlugin("bar")
+		assert.NoError(t, err)
+		assert.Equal(t, inPath, path)
+
+		_, err = LookupPlugin("baz")
+		assert.Equal(t, mcnerror.ErrDriverUnavailable{DriverName: "baz"}, err)
+	})
+}
+
+func TestListPlugins(t *testing.T) {
+	withPluginDirs(t, func(pluginDir, pathDir string) {
+		writeBinary(t, pathDir, BinaryName("foo"), 0755)
+		installed := writeBinary(t, pluginDir, BinaryName("foo"), 0755)
+		inPath := writeBinary(t, pathDir, BinaryName("bar"), 0755)
+		writeBinary(t, pathDir, "docker-machine", 0755)
+		writeBinary(t, pathDir, BinaryName("notexecutable"), 0644)
+
+		assert.Equal(t, []PluginBinary{
+			{DriverName: "bar", Path: inPath},
+			{DriverName: "foo", Path: installed},
+		}, ListPlugins())
+	})
+}
+
+func TestInstallAndRemovePlugin(t *testing.T) {
+	withPluginDirs(t, func(pluginDir, pathDir string) {
+		// sha256 of "#!/bin/sh\n"
+		checksum := "a8076d3d28d21e02012b20eaf7dbf75409a6277134439025f282e368e3305abf"
+
+		_, err := InstallPlugin("foo", strings.NewReader("#!/bin/bash\n"), checksum, nil)
+		assert.IsType(t, ErrChecksumMismatch{}, err)
+
+		files, _ := ioutil.ReadDir(pluginDir)
+		assert.Empty(t, files)
+
+		_, err = InstallPlugin("../foo", strings.NewReader("#!/bin/sh\n"), "", nil)
+		assert.IsType(t, ErrInvalidDriverName{}, err)
+
+		path, err := InstallPlugin("foo", strings.NewReader("#!/bin/sh\n"), strings.ToUpper(checksum), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, filepath.Join(pluginDir, BinaryName("foo")), path)
+
+		found, err := LookupPlugin("foo")
+		assert.NoError(t, err)
+		assert.Equal(t, path, found)
+
+		// A binary which fails verification doesn't replace the installed
+		// one.
+		var verified string
+		_, err = InstallPlugin("foo", strings.NewReader("#!/bin/false\n"), "", func(path string) error {
+			verified = path
+			return errors.New("not a plugin")
+		})
+		assert.EqualError(t, err, "not a plugin")
+		assert.Equal(t, pluginDir, filepath.Dir(verified))
+		assert.NotEqual(t, path, verified)
+
+		data, err := ioutil.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "#!/bin/sh\n", string(data))
+
+		files, _ = ioutil.ReadDir(pluginDir)
+		assert.Len(t, files, 1)
+
+		assert.NoError(t, RemovePlugin("foo"))
+		assert.Equal(t, mcnerror.ErrDriverUnavailable{DriverName: "foo"}, RemovePlugin("foo"))
+	})
+}
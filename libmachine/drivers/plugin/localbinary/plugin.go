@@ -2,11 +2,18 @@ package localbinary
 
 import (
 	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/docker/machine/libmachine/log"
@@ -16,6 +23,9 @@ var (
 	// Timeout where we will bail if we're not able to properly contact the
 	// plugin server.
 	defaultTimeout = 10 * time.Second
+
+	// How many of the last lines a plugin wrote to stderr are kept.
+	stderrTailLines = 10
 )
 
 const (
@@ -23,6 +33,15 @@ const (
 	pluginErrPrefix = "(%s) DBG | "
 	PluginEnvKey    = "MACHINE_PLUGIN_TOKEN"
 	PluginEnvVal    = "42"
+
+	// PluginAuthEnvKey holds the random token the plugin must find in
+	// every call.
+	PluginAuthEnvKey = "MACHINE_PLUGIN_AUTH_TOKEN"
+
+	// PluginSocketEnvKey holds the path of the Unix socket the plugin
+	// should listen on.  If it's not set, the plugin listens on a local TCP
+	// port.
+	PluginSocketEnvKey = "MACHINE_PLUGIN_SOCKET"
 )
 
 type PluginStreamer interface {
@@ -53,6 +72,15 @@ type McnBinaryExecutor interface {
 
 	// Stop reading from the plugins in question.
 	Close() error
+
+	// Wait blocks until the plugin binary has exited and returns why.
+	Wait() error
+
+	// Kill forcibly stops the plugin binary.
+	Kill() error
+
+	// Stderr returns the last lines the plugin binary wrote to stderr.
+	Stderr() []string
 }
 
 // DriverPlugin interface wraps the underlying mechanics of starting a driver
@@ -66,71 +94,190 @@ type Plugin struct {
 	Executor    McnBinaryExecutor
 	Addr        string
 	MachineName string
-	addrCh      chan string
-	stopCh      chan bool
+
+	// Token must be sent with every call to the plugin.
+	Token string
+
+	socketDir string
+	addrCh    chan string
+	stopCh    chan bool
 }
 
 type Executor struct {
 	pluginStdout, pluginStderr io.ReadCloser
 	DriverName                 string
+	Token                      string
+	SocketPath                 string
 	binaryPath                 string
+	cmd                        *exec.Cmd
+	stderrTail                 *lineTail
+	exitCh                     chan struct{}
+	exitErr                    error
 }
 
-type ErrPluginBinaryNotFound struct {
-	driverName string
+// lineTail is a writer which remembers the last lines written to it.
+type lineTail struct {
+	lock    sync.Mutex
+	lines   []string
+	partial string
+	max     int
 }
 
-func (e ErrPluginBinaryNotFound) Error() string {
-	return fmt.Sprintf("Driver %q not found. Do you have the plugin binary accessible in your PATH?", e.driverName)
+func newLineTail(max int) *lineTail {
+	return &lineTail{max: max}
+}
+
+func (t *lineTail) Write(p []byte) (int, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	lines := strings.Split(t.partial+string(p), "\n")
+	t.partial = lines[len(lines)-1]
+
+	t.lines = append(t.lines, lines[:len(lines)-1]...)
+	if len(t.lines) > t.max {
+		t.lines = t.lines[len(t.lines)-t.max:]
+	}
+
+	return len(p), nil
+}
+
+func (t *lineTail) Lines() []string {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	lines := append([]string{}, t.lines...)
+	if t.partial != "" {
+		lines = append(lines, t.partial)
+	}
+	if len(lines) > t.max {
+		lines = lines[len(lines)-t.max:]
+	}
+
+	return lines
 }
 
 func NewPlugin(driverName string) (*Plugin, error) {
-	binaryPath, err := exec.LookPath(fmt.Sprintf("docker-machine-driver-%s", driverName))
+	binaryPath, err := LookupPlugin(driverName)
 	if err != nil {
-		return nil, ErrPluginBinaryNotFound{driverName}
+		return nil, err
 	}
 
 	log.Debugf("Found binary path at %s", binaryPath)
 
+	return NewPluginFromPath(driverName, binaryPath)
+}
+
+// NewPluginFromPath returns a plugin for the driver which runs the binary
+// at binaryPath, such as one which isn't installed yet.
+func NewPluginFromPath(driverName, binaryPath string) (*Plugin, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, fmt.Errorf("Error generating plugin token: %s", err)
+	}
+
+	// Unix sockets live in a directory of their own which only the current
+	// user can enter.  There are no Unix sockets on Windows, where plugins
+	// fall back to TCP.
+	socketDir, socketPath := "", ""
+	if runtime.GOOS != "windows" {
+		socketDir, err = ioutil.TempDir("", "docker-machine-plugin-")
+		if err != nil {
+			return nil, fmt.Errorf("Error creating plugin socket directory: %s", err)
+		}
+		socketPath = filepath.Join(socketDir, "plugin.sock")
+	}
+
 	return &Plugin{
-		stopCh: make(chan bool),
-		addrCh: make(chan string, 1),
+		Token:     token,
+		socketDir: socketDir,
+		stopCh:    make(chan bool),
+		addrCh:    make(chan string, 1),
 		Executor: &Executor{
 			DriverName: driverName,
+			Token:      token,
+			SocketPath: socketPath,
 			binaryPath: binaryPath,
+			stderrTail: newLineTail(stderrTailLines),
+			exitCh:     make(chan struct{}),
 		},
 	}, nil
 }
 
-func (lbe *Executor) Start() (*bufio.Scanner, *bufio.Scanner, error) {
-	var err error
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
 
+func (lbe *Executor) Start() (*bufio.Scanner, *bufio.Scanner, error) {
 	log.Debugf("Launching plugin server for driver %s", lbe.DriverName)
 
 	cmd := exec.Command(lbe.binaryPath)
 
-	lbe.pluginStdout, err = cmd.StdoutPipe()
-	if err != nil {
-		return nil, nil, fmt.Errorf("Error getting cmd stdout pipe: %s", err)
-	}
+	// Rather than the pipes of the command, use our own so that Wait only
+	// returns once everything the plugin wrote has been read, and the
+	// last lines of stderr are known.
+	stdoutReader, stdoutWriter := io.Pipe()
+	stderrReader, stderrWriter := io.Pipe()
 
-	lbe.pluginStderr, err = cmd.StderrPipe()
-	if err != nil {
-		return nil, nil, fmt.Errorf("Error getting cmd stderr pipe: %s", err)
-	}
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = io.MultiWriter(lbe.stderrTail, stderrWriter)
+
+	lbe.pluginStdout = stdoutReader
+	lbe.pluginStderr = stderrReader
 
 	outScanner := bufio.NewScanner(lbe.pluginStdout)
 	errScanner := bufio.NewScanner(lbe.pluginStderr)
 
-	os.Setenv(PluginEnvKey, PluginEnvVal)
+	cmd.Env = append(os.Environ(),
+		PluginEnvKey+"="+PluginEnvVal,
+		PluginAuthEnvKey+"="+lbe.Token,
+	)
+	if lbe.SocketPath != "" {
+		cmd.Env = append(cmd.Env, PluginSocketEnvKey+"="+lbe.SocketPath)
+	}
 
 	if err := cmd.Start(); err != nil {
 		return nil, nil, fmt.Errorf("Error starting plugin binary: %s", err)
 	}
 
+	lbe.cmd = cmd
+
+	go func() {
+		lbe.exitErr = cmd.Wait()
+		stdoutWriter.Close()
+		stderrWriter.Close()
+		close(lbe.exitCh)
+	}()
+
 	return outScanner, errScanner, nil
 }
 
+func (lbe *Executor) Wait() error {
+	<-lbe.exitCh
+	return lbe.exitErr
+}
+
+func (lbe *Executor) Kill() error {
+	if lbe.cmd == nil || lbe.cmd.Process == nil {
+		return nil
+	}
+
+	select {
+	case <-lbe.exitCh:
+		return nil
+	default:
+		return lbe.cmd.Process.Kill()
+	}
+}
+
+func (lbe *Executor) Stderr() []string {
+	return lbe.stderrTail.Lines()
+}
+
 func (lbe *Executor) Close() error {
 	if err := lbe.pluginStdout.Close(); err != nil {
 		return err
@@ -149,6 +296,9 @@ func stream(scanner *bufio.Scanner, streamOutCh chan<- string, stopCh <-chan boo
 		for scanner.Scan() {
 			lines <- scanner.Text()
 		}
+		if err := scanner.Err(); err != nil && err != io.ErrClosedPipe {
+			log.Warnf("Scanning stream: %s", err)
+		}
 	}()
 	for {
 		select {
@@ -157,13 +307,20 @@ func stream(scanner *bufio.Scanner, streamOutCh chan<- string, stopCh <-chan boo
 			return
 		case line := <-lines:
 			streamOutCh <- strings.Trim(line, "\n")
-			if err := scanner.Err(); err != nil {
-				log.Warnf("Scanning stream: %s", err)
-			}
 		}
 	}
 }
 
+// PluginOutPrefix is the prefix of the output of a plugin.
+func PluginOutPrefix(machineName string) string {
+	return fmt.Sprintf(pluginOutPrefix, machineName)
+}
+
+// PluginErrPrefix is the prefix of the debug output of a plugin.
+func PluginErrPrefix(machineName string) string {
+	return fmt.Sprintf(pluginErrPrefix, machineName)
+}
+
 func (lbp *Plugin) AttachStream(scanner *bufio.Scanner) (<-chan string, chan<- bool) {
 	streamOutCh := make(chan string)
 	stopCh := make(chan bool)
@@ -185,7 +342,11 @@ func (lbp *Plugin) execServer() error {
 		return fmt.Errorf("Reading plugin address failed: %s", err)
 	}
 
-	lbp.addrCh <- strings.TrimSpace(addr)
+	addr = strings.TrimSpace(addr)
+	lbp.addrCh <- addr
+	if addr == "" {
+		return errors.New("Plugin binary exited before telling its address")
+	}
 
 	stdOutCh, stopStdoutCh := lbp.AttachStream(outScanner)
 	stdErrCh, stopStderrCh := lbp.AttachStream(errScanner)
@@ -193,9 +354,9 @@ func (lbp *Plugin) execServer() error {
 	for {
 		select {
 		case out := <-stdOutCh:
-			log.Info(fmt.Sprintf(pluginOutPrefix, lbp.MachineName), out)
+			log.Info(PluginOutPrefix(lbp.MachineName), out)
 		case err := <-stdErrCh:
-			log.Debug(fmt.Sprintf(pluginErrPrefix, lbp.MachineName), err)
+			log.Debug(PluginErrPrefix(lbp.MachineName), err)
 		case _ = <-lbp.stopCh:
 			stopStdoutCh <- true
 			stopStderrCh <- true
@@ -214,7 +375,11 @@ func (lbp *Plugin) Serve() error {
 func (lbp *Plugin) Address() (string, error) {
 	if lbp.Addr == "" {
 		select {
-		case lbp.Addr = <-lbp.addrCh:
+		case addr := <-lbp.addrCh:
+			if addr == "" {
+				return "", errors.New("Plugin binary didn't tell its server address")
+			}
+			lbp.Addr = addr
 			log.Debugf("Plugin server listening at address %s", lbp.Addr)
 			close(lbp.addrCh)
 			return lbp.Addr, nil
@@ -225,7 +390,39 @@ func (lbp *Plugin) Address() (string, error) {
 	return lbp.Addr, nil
 }
 
+// Wait blocks until the plugin binary has exited and returns why.
+func (lbp *Plugin) Wait() error {
+	return lbp.Executor.Wait()
+}
+
+// Kill forcibly stops the plugin binary.
+func (lbp *Plugin) Kill() error {
+	return lbp.Executor.Kill()
+}
+
+// Stderr returns the last lines the plugin binary wrote to stderr.
+func (lbp *Plugin) Stderr() []string {
+	return lbp.Executor.Stderr()
+}
+
+// Abort kills a plugin binary which never became usable and removes its
+// socket directory.  Unlike Close, it doesn't wait for Serve to stop.
+func (lbp *Plugin) Abort() error {
+	lbp.Kill()
+
+	if lbp.socketDir != "" {
+		return os.RemoveAll(lbp.socketDir)
+	}
+
+	return nil
+}
+
 func (lbp *Plugin) Close() error {
 	lbp.stopCh <- true
+
+	if lbp.socketDir != "" {
+		return os.RemoveAll(lbp.socketDir)
+	}
+
 	return nil
 }
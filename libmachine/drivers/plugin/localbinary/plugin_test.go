@@ -4,7 +4,11 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
 	"testing"
 	"time"
 
@@ -14,6 +18,7 @@ import (
 type FakeExecutor struct {
 	stdout, stderr io.ReadCloser
 	closed         bool
+	killed         bool
 }
 
 func (fe *FakeExecutor) Start() (*bufio.Scanner, *bufio.Scanner, error) {
@@ -25,6 +30,19 @@ func (fe *FakeExecutor) Close() error {
 	return nil
 }
 
+func (fe *FakeExecutor) Wait() error {
+	return nil
+}
+
+func (fe *FakeExecutor) Kill() error {
+	fe.killed = true
+	return nil
+}
+
+func (fe *FakeExecutor) Stderr() []string {
+	return nil
+}
+
 func TestLocalBinaryPluginAddress(t *testing.T) {
 	lbp := &Plugin{}
 	expectedAddr := "127.0.0.1:12345"
@@ -66,6 +84,41 @@ func TestLocalBinaryPluginAddressTimeout(t *testing.T) {
 	time.Sleep(defaultTimeout + 1)
 }
 
+func TestLocalBinaryPluginEmptyAddress(t *testing.T) {
+	lbp := &Plugin{}
+	lbp.addrCh = make(chan string, 1)
+	lbp.addrCh <- ""
+
+	if _, err := lbp.Address(); err == nil {
+		t.Fatal("Expected an error for an empty address")
+	}
+}
+
+func TestLocalBinaryPluginAbort(t *testing.T) {
+	socketDir, err := ioutil.TempDir("", "docker-machine-plugin-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(socketDir)
+
+	fe := &FakeExecutor{}
+	lbp := &Plugin{
+		Executor:  fe,
+		socketDir: socketDir,
+		stopCh:    make(chan bool),
+	}
+
+	if err := lbp.Abort(); err != nil {
+		t.Fatalf("Expected no error, instead got %s", err)
+	}
+	if !fe.killed {
+		t.Fatal("Expected the plugin binary to be killed")
+	}
+	if _, err := os.Stat(socketDir); !os.IsNotExist(err) {
+		t.Fatal("Expected the socket directory to be removed")
+	}
+}
+
 func TestLocalBinaryPluginClose(t *testing.T) {
 	lbp := &Plugin{}
 	lbp.stopCh = make(chan bool, 1)
@@ -153,3 +206,66 @@ func TestExecServer(t *testing.T) {
 		t.Fatalf("Error serving: %s", err)
 	}
 }
+
+func TestLineTail(t *testing.T) {
+	tail := newLineTail(2)
+
+	io.WriteString(tail, "one\ntw")
+	io.WriteString(tail, "o\nthree\nfou")
+
+	if lines := tail.Lines(); !reflect.DeepEqual(lines, []string{"three", "fou"}) {
+		t.Fatalf("Unexpected lines: %q", lines)
+	}
+}
+
+func TestExecutorWaitAndStderr(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses a shell script")
+	}
+
+	dir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	binaryPath := filepath.Join(dir, "docker-machine-driver-crash")
+	script := "#!/bin/sh\necho 127.0.0.1:12345\necho first >&2\necho last words >&2\nexit 3\n"
+	if err := ioutil.WriteFile(binaryPath, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	executor := &Executor{
+		DriverName: "crash",
+		binaryPath: binaryPath,
+		stderrTail: newLineTail(1),
+		exitCh:     make(chan struct{}),
+	}
+
+	outScanner, errScanner, err := executor.Start()
+	if err != nil {
+		t.Fatalf("Error starting plugin: %s", err)
+	}
+
+	// Drain the output, as a running plugin server would.
+	go func() {
+		for outScanner.Scan() {
+		}
+	}()
+	go func() {
+		for errScanner.Scan() {
+		}
+	}()
+
+	if err := executor.Wait(); err == nil || err.Error() != "exit status 3" {
+		t.Fatalf("Expected the plugin to exit with status 3, got %v", err)
+	}
+
+	if lines := executor.Stderr(); !reflect.DeepEqual(lines, []string{"last words"}) {
+		t.Fatalf("Unexpected stderr: %q", lines)
+	}
+
+	if err := executor.Kill(); err != nil {
+		t.Fatalf("Killing an exited plugin should do nothing, got %s", err)
+	}
+}
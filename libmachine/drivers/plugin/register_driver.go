@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/rpc"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/docker/machine/libmachine"
@@ -19,7 +20,41 @@ var (
 	heartbeatTimeout = 500 * time.Millisecond
 )
 
+// RegisterDriver serves the driver over RPC.  The plugin process serves a
+// single machine.
 func RegisterDriver(d drivers.Driver) {
+	serve(d, nil)
+}
+
+// RegisterDriverFactory serves drivers created with newDriver over RPC.
+// The plugin process serves as many machines as the client asks for.
+func RegisterDriverFactory(newDriver func() drivers.Driver) {
+	serve(newDriver(), newDriver)
+}
+
+// listen opens the listener the client asked for.
+func listen(token string) (net.Listener, string, error) {
+	if token == "" {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, "", err
+		}
+		return listener, listener.Addr().String(), nil
+	}
+
+	return rpcdriver.Listen(os.Getenv(localbinary.PluginSocketEnvKey))
+}
+
+// cleanup removes the socket and the directory the client made for it.
+// os.Exit skips deferred calls, so it has to be called explicitly.
+func cleanup(listener net.Listener) {
+	listener.Close()
+	if socketPath := os.Getenv(localbinary.PluginSocketEnvKey); socketPath != "" {
+		os.Remove(filepath.Dir(socketPath))
+	}
+}
+
+func serve(d drivers.Driver, newDriver func() drivers.Driver) {
 	if os.Getenv(localbinary.PluginEnvKey) != localbinary.PluginEnvVal {
 		fmt.Fprintf(os.Stderr, `This is a Docker Machine plugin binary.
 Plugin binaries are not intended to be invoked directly.
@@ -33,26 +68,42 @@ Please use this plugin through the main 'docker-machine' binary.
 
 	rpcd := rpcdriver.NewRPCServerDriver(d)
 	rpc.Register(rpcd)
-	rpc.HandleHTTP()
 
-	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if newDriver != nil {
+		rpcd.APIVersion = version.APIVersion
+		rpc.Register(rpcdriver.NewRPCPluginServer(rpc.DefaultServer, rpcd, newDriver))
+	}
+
+	// The token is only meant for us, not for whatever the driver runs.
+	token := os.Getenv(localbinary.PluginAuthEnvKey)
+	os.Unsetenv(localbinary.PluginAuthEnvKey)
+
+	listener, addr, err := listen(token)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading RPC server: %s\n", err)
 		os.Exit(1)
 	}
 	defer listener.Close()
 
-	fmt.Println(listener.Addr())
+	fmt.Println(addr)
 
-	go http.Serve(listener, nil)
+	if token == "" {
+		// Clients which predate authentication speak HTTP RPC over TCP.
+		rpc.HandleHTTP()
+		go http.Serve(listener, nil)
+	} else {
+		go rpcdriver.ServeAuthenticated(listener, rpc.DefaultServer, token)
+	}
 
 	for {
 		select {
 		case <-rpcd.CloseCh:
+			cleanup(listener)
 			os.Exit(0)
 		case <-rpcd.HeartbeatCh:
 			continue
 		case <-time.After(heartbeatTimeout):
+			cleanup(listener)
 			os.Exit(1)
 		}
 	}
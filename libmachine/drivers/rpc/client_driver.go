@@ -1,15 +1,20 @@
 package rpcdriver
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/rpc"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/drivers/plugin/localbinary"
 	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnerror"
 	"github.com/docker/machine/libmachine/mcnflag"
 	"github.com/docker/machine/libmachine/state"
+	"github.com/docker/machine/libmachine/timing"
 	"github.com/docker/machine/libmachine/version"
 )
 
@@ -17,10 +22,34 @@ var (
 	heartbeatInterval = 200 * time.Millisecond
 )
 
+// multiplexAPIVersion is the first API version in which a single plugin
+// process can serve several machines.
+const multiplexAPIVersion = 2
+
+var (
+	pluginsLock = &sync.Mutex{}
+
+	// plugins are the running plugin processes which can serve more than
+	// one machine, by driver name.
+	plugins = make(map[string]*pluginProcess)
+
+	// starting are the plugins being started, by driver name.
+	starting = make(map[string]*pluginStart)
+)
+
 type RPCClientDriver struct {
-	plugin          localbinary.DriverPlugin
-	heartbeatDoneCh chan bool
-	Client          *InternalClient
+	process    *pluginProcess
+	Client     *InternalClient
+	driverName string
+
+	// config is the last known configuration of the driver, for when the
+	// plugin has to be restarted.
+	config []byte
+
+	// lock guards process, Client and config, which change when the
+	// plugin is restarted.
+	lock        sync.Mutex
+	restartLock sync.Mutex
 }
 
 type RPCCall struct {
@@ -32,13 +61,25 @@ type RPCCall struct {
 type InternalClient struct {
 	MachineName string
 	RPCClient   *rpc.Client
+
+	// ServiceName is the RPC service of the machine's driver if the
+	// plugin serves several machines, or "" for the default service.
+	ServiceName string
 }
 
 func (ic *InternalClient) Call(serviceMethod string, args interface{}, reply interface{}) error {
-	if serviceMethod != "RPCServerDriver.Heartbeat" {
+	method := strings.TrimPrefix(serviceMethod, "RPCServerDriver.")
+
+	if method != "Heartbeat" && method != "WatchEvents" {
 		log.Debugf("(%s) Calling %+v", ic.MachineName, serviceMethod)
+		defer timing.Track("rpc." + method)()
 	}
-	return ic.RPCClient.Call(serviceMethod, args, reply)
+
+	if ic.ServiceName != "" {
+		serviceMethod = ic.ServiceName + "." + method
+	}
+
+	return mcnerror.Decode(ic.RPCClient.Call(serviceMethod, args, reply))
 }
 
 func NewInternalClient(rpcclient *rpc.Client) *InternalClient {
@@ -47,10 +88,46 @@ func NewInternalClient(rpcclient *rpc.Client) *InternalClient {
 	}
 }
 
-func NewRPCClientDriver(rawDriverData []byte, driverName string) (*RPCClientDriver, error) {
-	mcnName := ""
+// pluginProcess is a running plugin binary and our connection to it.
+type pluginProcess struct {
+	driverName      string
+	plugin          *localbinary.Plugin
+	client          *InternalClient
+	apiVersion      int
+	heartbeatDoneCh chan bool
+	closeOnce       sync.Once
+	closeErr        error
+
+	// exitedCh is closed once the plugin process has exited.  exitErr
+	// tells why if it wasn't asked to.
+	exitedCh chan struct{}
+	exitErr  error
+
+	// eventsDoneCh is closed once we stop fetching the events of the
+	// plugin, or is nil if it doesn't send any.
+	eventsDoneCh chan struct{}
 
-	p, err := localbinary.NewPlugin(driverName)
+	// lock guards closing and exitErr.
+	lock    sync.Mutex
+	closing bool
+
+	// refs counts the machines using the process; guarded by pluginsLock.
+	refs int
+}
+
+// startPlugin launches the plugin binary for a driver, connects to it and
+// agrees on an API version.
+func startPlugin(driverName string) (*pluginProcess, error) {
+	path, err := localbinary.LookupPlugin(driverName)
+	if err != nil {
+		return nil, err
+	}
+
+	return startPluginFromPath(driverName, path)
+}
+
+func startPluginFromPath(driverName, path string) (*pluginProcess, error) {
+	p, err := localbinary.NewPluginFromPath(driverName, path)
 	if err != nil {
 		return nil, err
 	}
@@ -65,57 +142,341 @@ func NewRPCClientDriver(rawDriverData []byte, driverName string) (*RPCClientDriv
 
 	addr, err := p.Address()
 	if err != nil {
+		p.Abort()
 		return nil, fmt.Errorf("Error attempting to get plugin server address for RPC: %s", err)
 	}
 
-	rpcclient, err := rpc.DialHTTP("tcp", addr)
+	rpcclient, err := DialPlugin(addr, p.Token)
 	if err != nil {
+		p.Kill()
+		p.Close()
 		return nil, err
 	}
 
-	c := &RPCClientDriver{
-		Client:          NewInternalClient(rpcclient),
+	process := &pluginProcess{
+		driverName:      driverName,
+		plugin:          p,
+		client:          NewInternalClient(rpcclient),
 		heartbeatDoneCh: make(chan bool),
+		exitedCh:        make(chan struct{}),
 	}
 
-	go func(c *RPCClientDriver) {
-		for {
-			select {
-			case <-c.heartbeatDoneCh:
-				return
-			default:
-				if err := c.Client.Call("RPCServerDriver.Heartbeat", struct{}{}, nil); err != nil {
-					log.Warnf("Error attempting heartbeat call to plugin server: %s", err)
-					c.Close()
+	runningLock.Lock()
+	running[process] = true
+	runningLock.Unlock()
+
+	go process.watch()
+	go process.heartbeat()
+
+	// Plugins which predate version negotiation can only tell us their
+	// version.
+	if err := process.client.Call("RPCServerDriver.NegotiateVersion", version.APIVersion, &process.apiVersion); err != nil {
+		log.Debugf("Plugin for driver %q cannot negotiate its API version: %s", driverName, err)
+		if err := process.client.Call("RPCServerDriver.GetVersion", struct{}{}, &process.apiVersion); err != nil {
+			process.close()
+			return nil, err
+		}
+	}
+
+	process.startWatchingEvents()
+
+	return process, nil
+}
+
+func (p *pluginProcess) heartbeat() {
+	for {
+		select {
+		case <-p.heartbeatDoneCh:
+			return
+		case <-p.exitedCh:
+			return
+		default:
+			if err := p.client.Call("RPCServerDriver.Heartbeat", struct{}{}, nil); err != nil {
+				p.lock.Lock()
+				closing := p.closing
+				p.lock.Unlock()
+				if closing {
 					return
 				}
-				time.Sleep(heartbeatInterval)
+				// Calls will fail and tell why once the plugin is gone.
+				log.Debugf("Error attempting heartbeat call to plugin server, killing it: %s", err)
+				p.plugin.Kill()
+				return
 			}
+			time.Sleep(heartbeatInterval)
 		}
-	}(c)
+	}
+}
 
-	var serverVersion int
-	if err := c.Client.Call("RPCServerDriver.GetVersion", struct{}{}, &serverVersion); err != nil {
-		return nil, err
+// forget removes the process from the pool, so that no more machines are
+// served by it.
+func (p *pluginProcess) forget() {
+	pluginsLock.Lock()
+	if plugins[p.driverName] == p {
+		delete(plugins, p.driverName)
+	}
+	pluginsLock.Unlock()
+
+	runningLock.Lock()
+	delete(running, p)
+	runningLock.Unlock()
+}
+
+// close asks the plugin process to exit, and kills it if it doesn't.
+func (p *pluginProcess) close() error {
+	p.closeOnce.Do(func() {
+		p.lock.Lock()
+		p.closing = true
+		p.lock.Unlock()
+
+		close(p.heartbeatDoneCh)
+
+		log.Debug("Making call to close connection to plugin binary")
+
+		if err := p.plugin.Close(); err != nil {
+			p.closeErr = err
+		}
+
+		select {
+		case <-p.exitedCh:
+			return
+		default:
+		}
+
+		// The plugin may not have handed all its events over yet.
+		if p.eventsDoneCh != nil {
+			p.flushEvents()
+		}
+
+		log.Debug("Making call to close driver server")
+
+		// The plugin exits as soon as it gets the call, so whether the
+		// answer makes it back doesn't matter.
+		if err := p.client.Call("RPCServerDriver.Close", struct{}{}, nil); err != nil {
+			log.Debugf("Error closing driver server: %s", err)
+		}
+
+		select {
+		case <-p.exitedCh:
+			log.Debug("Successfully made call to close driver server")
+		case <-time.After(pluginExitTimeout):
+			log.Debugf("Plugin for driver %q didn't exit, killing it", p.driverName)
+			if err := p.plugin.Kill(); err != nil {
+				p.closeErr = err
+			}
+		}
+
+		if p.eventsDoneCh != nil {
+			select {
+			case <-p.eventsDoneCh:
+			case <-time.After(pluginExitTimeout):
+			}
+		}
+	})
+
+	return p.closeErr
+}
+
+// pluginStart is a plugin process being started, which the machines using
+// the same driver wait for rather than starting their own.
+type pluginStart struct {
+	done chan struct{}
+	err  error
+}
+
+// acquirePlugin returns a plugin process for the driver, reusing a running
+// one if the plugin can serve several machines.  Plugins are started
+// without holding pluginsLock, so that the plugins of other drivers don't
+// wait for them.
+func acquirePlugin(driverName string) (*pluginProcess, error) {
+	pluginsLock.Lock()
+
+	if p, ok := plugins[driverName]; ok {
+		p.refs++
+		pluginsLock.Unlock()
+		return p, nil
+	}
+
+	if start, ok := starting[driverName]; ok {
+		pluginsLock.Unlock()
+
+		<-start.done
+		if start.err != nil {
+			return nil, start.err
+		}
+
+		// The plugin is shared if it can serve several machines, a plugin
+		// of our own is started otherwise.
+		return acquirePlugin(driverName)
+	}
+
+	start := &pluginStart{done: make(chan struct{})}
+	starting[driverName] = start
+	pluginsLock.Unlock()
+
+	p, err := startPlugin(driverName)
+
+	pluginsLock.Lock()
+	delete(starting, driverName)
+	if err == nil {
+		if p.apiVersion >= multiplexAPIVersion {
+			p.plugin.MachineName = driverName
+			plugins[driverName] = p
+		}
+		p.refs++
+	}
+	pluginsLock.Unlock()
+
+	start.err = err
+	close(start.done)
+
+	return p, err
+}
+
+// release closes the plugin process once no machine uses it any more.
+func (p *pluginProcess) release() error {
+	pluginsLock.Lock()
+	p.refs--
+	done := p.refs <= 0
+	if done && plugins[p.driverName] == p {
+		delete(plugins, p.driverName)
+	}
+	pluginsLock.Unlock()
+
+	if !done {
+		return nil
 	}
 
-	if serverVersion != version.APIVersion {
-		return nil, fmt.Errorf("Driver binary uses an incompatible API version (%d)", serverVersion)
+	return p.close()
+}
+
+// PluginInfo describes the plugin binary for a driver.
+type PluginInfo struct {
+	DriverName  string
+	Path        string
+	APIVersion  int
+	CreateFlags []mcnflag.Flag
+	Commands    []drivers.Command
+}
+
+// GetPluginInfo launches the plugin for a driver just long enough to ask it
+// which API version it speaks and, if we speak it too, which create flags
+// it takes and which commands it offers.
+func GetPluginInfo(driverName string) (*PluginInfo, error) {
+	path, err := localbinary.LookupPlugin(driverName)
+	if err != nil {
+		return nil, err
 	}
-	log.Debug("Using API Version ", serverVersion)
 
-	if err := c.SetConfigRaw(rawDriverData); err != nil {
+	return GetPluginInfoFromPath(driverName, path)
+}
+
+// GetPluginInfoFromPath is GetPluginInfo for the plugin binary at path,
+// such as one which isn't installed yet.
+func GetPluginInfoFromPath(driverName, path string) (*PluginInfo, error) {
+	p, err := startPluginFromPath(driverName, path)
+	if err != nil {
 		return nil, err
 	}
+	defer p.close()
 
-	mcnName = c.GetMachineName()
-	p.MachineName = mcnName
-	c.Client.MachineName = mcnName
-	c.plugin = p
+	info := &PluginInfo{
+		DriverName: driverName,
+		Path:       path,
+		APIVersion: p.apiVersion,
+	}
+
+	if version.IsAPIVersionSupported(info.APIVersion) {
+		c := &RPCClientDriver{
+			process:    p,
+			Client:     p.client,
+			driverName: driverName,
+		}
+		info.CreateFlags = c.GetCreateFlags()
+		info.Commands = c.GetCommands()
+	}
+
+	return info, nil
+}
+
+// machineNameFromConfig digs the machine name out of raw driver data, all
+// drivers embed BaseDriver.
+func machineNameFromConfig(rawDriverData []byte) string {
+	var base struct {
+		MachineName string
+	}
+	if err := json.Unmarshal(rawDriverData, &base); err != nil {
+		return ""
+	}
+	return base.MachineName
+}
+
+func NewRPCClientDriver(rawDriverData []byte, driverName string) (*RPCClientDriver, error) {
+	c := &RPCClientDriver{
+		driverName: driverName,
+	}
+
+	if err := c.connect(rawDriverData); err != nil {
+		return nil, err
+	}
 
 	return c, nil
 }
 
+// connect gets a plugin process for the driver and hands it the driver's
+// configuration.
+func (c *RPCClientDriver) connect(rawDriverData []byte) error {
+	p, err := acquirePlugin(c.driverName)
+	if err != nil {
+		return err
+	}
+
+	if !version.IsAPIVersionSupported(p.apiVersion) {
+		p.release()
+		return fmt.Errorf("Driver binary uses an incompatible API version (%d)", p.apiVersion)
+	}
+	log.Debug("Using API Version ", p.apiVersion)
+
+	client := NewInternalClient(p.client.RPCClient)
+
+	if p.apiVersion >= multiplexAPIVersion {
+		if err := p.client.Call("RPCPluginServer.NewDriver", machineNameFromConfig(rawDriverData), &client.ServiceName); err != nil {
+			p.release()
+			return err
+		}
+	}
+
+	if err := client.Call("RPCServerDriver.SetConfigRaw", rawDriverData, nil); err != nil {
+		closeDriver(p, client)
+		p.release()
+		return err
+	}
+
+	if err := client.Call("RPCServerDriver.GetMachineName", struct{}{}, &client.MachineName); err != nil {
+		log.Warnf("Error attempting call to get machine name: %s", err)
+	}
+
+	// The secrets of the machine are masked whether it's being created or
+	// not.  Plugins which predate GetSecrets rely on the create flags.
+	var secrets []string
+	if err := client.Call("RPCServerDriver.GetSecrets", struct{}{}, &secrets); err != nil {
+		log.Debugf("Error attempting call to get secrets: %s", err)
+	}
+	log.AddSecret(secrets...)
+
+	// The output of a shared plugin is labeled with the driver name, set
+	// when it was started.
+	if p.apiVersion < multiplexAPIVersion {
+		p.plugin.MachineName = client.MachineName
+	}
+
+	c.lock.Lock()
+	c.process, c.Client, c.config = p, client, rawDriverData
+	c.lock.Unlock()
+
+	return nil
+}
+
 func (c *RPCClientDriver) MarshalJSON() ([]byte, error) {
 	return c.GetConfigRaw()
 }
@@ -124,25 +485,28 @@ func (c *RPCClientDriver) UnmarshalJSON(data []byte) error {
 	return c.SetConfigRaw(data)
 }
 
+// Close stops using the plugin.  The plugin process is shut down once no
+// machine is using it any more.
 func (c *RPCClientDriver) Close() error {
-	c.heartbeatDoneCh <- true
-	close(c.heartbeatDoneCh)
+	c.lock.Lock()
+	p, client := c.process, c.Client
+	c.lock.Unlock()
 
-	log.Debug("Making call to close connection to plugin binary")
+	closeDriver(p, client)
 
-	if err := c.plugin.Close(); err != nil {
-		return err
-	}
-
-	log.Debug("Making call to close driver server")
+	return p.release()
+}
 
-	if err := c.Client.Call("RPCServerDriver.Close", struct{}{}, nil); err != nil {
-		return err
+// closeDriver lets a plugin serving several machines drop the driver of the
+// client's machine.
+func closeDriver(p *pluginProcess, client *InternalClient) {
+	if client.ServiceName == "" {
+		return
 	}
 
-	log.Debug("Successfully made call to close driver server")
-
-	return nil
+	if err := p.client.Call("RPCPluginServer.CloseDriver", client.ServiceName, nil); err != nil {
+		log.Debugf("Error closing the driver of %s: %s", client.ServiceName, err)
+	}
 }
 
 // Helper method to make requests which take no arguments and return simply a
@@ -150,7 +514,7 @@ func (c *RPCClientDriver) Close() error {
 func (c *RPCClientDriver) rpcStringCall(method string) (string, error) {
 	var info string
 
-	if err := c.Client.Call(method, struct{}{}, &info); err != nil {
+	if err := c.call(method, struct{}{}, &info); err != nil {
 		return "", err
 	}
 
@@ -160,21 +524,62 @@ func (c *RPCClientDriver) rpcStringCall(method string) (string, error) {
 func (c *RPCClientDriver) GetCreateFlags() []mcnflag.Flag {
 	var flags []mcnflag.Flag
 
-	if err := c.Client.Call("RPCServerDriver.GetCreateFlags", struct{}{}, &flags); err != nil {
+	if err := c.call("RPCServerDriver.GetCreateFlags", struct{}{}, &flags); err != nil {
 		log.Warnf("Error attempting call to get create flags: %s", err)
 	}
 
 	return flags
 }
 
+// GetCapabilities returns the actions the driver supports.  Plugins which
+// predate capability discovery are assumed to support everything.
+func (c *RPCClientDriver) GetCapabilities() drivers.Capabilities {
+	var capabilities drivers.Capabilities
+
+	if err := c.call("RPCServerDriver.GetCapabilities", struct{}{}, &capabilities); err != nil {
+		log.Debugf("Error attempting call to get capabilities, assuming defaults: %s", err)
+		return drivers.DefaultCapabilities
+	}
+
+	return capabilities
+}
+
+// GetCommands returns the commands the driver offers.  Plugins which predate
+// driver commands offer none.
+func (c *RPCClientDriver) GetCommands() []drivers.Command {
+	var commands []drivers.Command
+
+	if err := c.call("RPCServerDriver.GetCommands", struct{}{}, &commands); err != nil {
+		log.Debugf("Error attempting call to get commands: %s", err)
+		return []drivers.Command{}
+	}
+
+	return commands
+}
+
+func (c *RPCClientDriver) RunCommand(name string, flags drivers.DriverOptions, args []string) (string, error) {
+	rpcFlags, ok := flags.(RPCFlags)
+	if !ok {
+		return "", fmt.Errorf("Cannot send flags of type %T to a plugin", flags)
+	}
+
+	var output string
+
+	if err := c.call("RPCServerDriver.RunCommand", &RunCommandArgs{Name: name, Flags: rpcFlags, Args: args}, &output); err != nil {
+		return "", err
+	}
+
+	return output, nil
+}
+
 func (c *RPCClientDriver) SetConfigRaw(data []byte) error {
-	return c.Client.Call("RPCServerDriver.SetConfigRaw", data, nil)
+	return c.call("RPCServerDriver.SetConfigRaw", data, nil)
 }
 
 func (c *RPCClientDriver) GetConfigRaw() ([]byte, error) {
 	var data []byte
 
-	if err := c.Client.Call("RPCServerDriver.GetConfigRaw", struct{}{}, &data); err != nil {
+	if err := c.call("RPCServerDriver.GetConfigRaw", struct{}{}, &data); err != nil {
 		return nil, err
 	}
 
@@ -192,7 +597,7 @@ func (c *RPCClientDriver) DriverName() string {
 }
 
 func (c *RPCClientDriver) SetConfigFromFlags(flags drivers.DriverOptions) error {
-	return c.Client.Call("RPCServerDriver.SetConfigFromFlags", &flags, nil)
+	return c.call("RPCServerDriver.SetConfigFromFlags", &flags, nil)
 }
 
 func (c *RPCClientDriver) GetURL() (string, error) {
@@ -208,6 +613,44 @@ func (c *RPCClientDriver) GetMachineName() string {
 	return name
 }
 
+// baseDriver returns what the BaseDriver of the plugin holds, as last
+// configured.
+func (c *RPCClientDriver) baseDriver() *drivers.BaseDriver {
+	c.lock.Lock()
+	config := c.config
+	c.lock.Unlock()
+
+	base := &drivers.BaseDriver{}
+	if err := json.Unmarshal(config, base); err != nil {
+		log.Debugf("Error reading the configuration of the driver: %s", err)
+	}
+
+	return base
+}
+
+// ResolveStorePath returns the path of a file of the machine, as the
+// BaseDriver of the plugin would, without a call to the plugin.
+func (c *RPCClientDriver) ResolveStorePath(file string) string {
+	base := c.baseDriver()
+	if base.MachineName == "" {
+		return ""
+	}
+
+	return base.ResolveStorePath(file)
+}
+
+// GetSSHJumpHost returns the jump host the machine is reached through,
+// without a call to the plugin.
+func (c *RPCClientDriver) GetSSHJumpHost() (string, string) {
+	return c.baseDriver().GetSSHJumpHost()
+}
+
+// UseSSHAgent tells if the machine is logged in to with the keys of the
+// running SSH agent, without a call to the plugin.
+func (c *RPCClientDriver) UseSSHAgent() bool {
+	return c.baseDriver().UseSSHAgent()
+}
+
 func (c *RPCClientDriver) GetIP() (string, error) {
 	return c.rpcStringCall("RPCServerDriver.GetIP")
 }
@@ -230,7 +673,7 @@ func (c *RPCClientDriver) GetSSHKeyPath() string {
 func (c *RPCClientDriver) GetSSHPort() (int, error) {
 	var port int
 
-	if err := c.Client.Call("RPCServerDriver.GetSSHPort", struct{}{}, &port); err != nil {
+	if err := c.call("RPCServerDriver.GetSSHPort", struct{}{}, &port); err != nil {
 		return 0, err
 	}
 
@@ -249,7 +692,7 @@ func (c *RPCClientDriver) GetSSHUsername() string {
 func (c *RPCClientDriver) GetState() (state.State, error) {
 	var s state.State
 
-	if err := c.Client.Call("RPCServerDriver.GetState", struct{}{}, &s); err != nil {
+	if err := c.call("RPCServerDriver.GetState", struct{}{}, &s); err != nil {
 		return state.Error, err
 	}
 
@@ -257,37 +700,37 @@ func (c *RPCClientDriver) GetState() (state.State, error) {
 }
 
 func (c *RPCClientDriver) PreCreateCheck() error {
-	return c.Client.Call("RPCServerDriver.PreCreateCheck", struct{}{}, nil)
+	return c.call("RPCServerDriver.PreCreateCheck", struct{}{}, nil)
 }
 
 func (c *RPCClientDriver) Create() error {
-	return c.Client.Call("RPCServerDriver.Create", struct{}{}, nil)
+	return c.call("RPCServerDriver.Create", struct{}{}, nil)
 }
 
 func (c *RPCClientDriver) Remove() error {
-	return c.Client.Call("RPCServerDriver.Remove", struct{}{}, nil)
+	return c.call("RPCServerDriver.Remove", struct{}{}, nil)
 }
 
 func (c *RPCClientDriver) Start() error {
-	return c.Client.Call("RPCServerDriver.Start", struct{}{}, nil)
+	return c.call("RPCServerDriver.Start", struct{}{}, nil)
 }
 
 func (c *RPCClientDriver) Stop() error {
-	return c.Client.Call("RPCServerDriver.Stop", struct{}{}, nil)
+	return c.call("RPCServerDriver.Stop", struct{}{}, nil)
 }
 
 func (c *RPCClientDriver) Restart() error {
-	return c.Client.Call("RPCServerDriver.Restart", struct{}{}, nil)
+	return c.call("RPCServerDriver.Restart", struct{}{}, nil)
 }
 
 func (c *RPCClientDriver) Kill() error {
-	return c.Client.Call("RPCServerDriver.Kill", struct{}{}, nil)
+	return c.call("RPCServerDriver.Kill", struct{}{}, nil)
 }
 
 func (c *RPCClientDriver) LocalArtifactPath(file string) string {
 	var path string
 
-	if err := c.Client.Call("RPCServerDriver.LocalArtifactPath", file, &path); err != nil {
+	if err := c.call("RPCServerDriver.LocalArtifactPath", file, &path); err != nil {
 		log.Warnf("Error attempting call to get LocalArtifactPath: %s", err)
 	}
 
@@ -304,5 +747,5 @@ func (c *RPCClientDriver) GlobalArtifactPath() string {
 }
 
 func (c *RPCClientDriver) Upgrade() error {
-	return c.Client.Call("RPCServerDriver.Upgrade", struct{}{}, nil)
+	return c.call("RPCServerDriver.Upgrade", struct{}{}, nil)
 }
@@ -0,0 +1,187 @@
+package rpcdriver
+
+import (
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine/drivers/plugin/localbinary"
+	"github.com/docker/machine/libmachine/events"
+	"github.com/docker/machine/libmachine/log"
+)
+
+var (
+	// How long WatchEvents waits for events before replying without any.
+	watchEventsTimeout = time.Second
+
+	// How many events a plugin keeps for the client before dropping the
+	// oldest ones.
+	maxQueuedEvents = 1000
+)
+
+// EventQueue keeps the events published in a plugin until the client
+// fetches them with WatchEvents.
+type EventQueue struct {
+	lock     sync.Mutex
+	events   []events.Event
+	notifyCh chan struct{}
+	watched  bool
+}
+
+func NewEventQueue() *EventQueue {
+	return &EventQueue{
+		notifyCh: make(chan struct{}, 1),
+	}
+}
+
+func (q *EventQueue) push(event events.Event) {
+	q.lock.Lock()
+	q.events = append(q.events, event)
+	if len(q.events) > maxQueuedEvents {
+		q.events = q.events[len(q.events)-maxQueuedEvents:]
+	}
+	q.lock.Unlock()
+
+	select {
+	case q.notifyCh <- struct{}{}:
+	default:
+	}
+}
+
+// watch starts queuing events the first time the client asks for them.
+// From then on, log records are sent as events too rather than written to
+// stdout and stderr.  It returns whether the queue was already watched.
+func (q *EventQueue) watch() bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.watched {
+		return true
+	}
+
+	q.watched = true
+	events.Subscribe(q.push)
+	log.SetHandler(func(r log.Record) {
+		events.Publish(events.Event{
+			Kind:    events.KindLog,
+			Level:   r.Level,
+			Message: r.Message,
+		})
+	})
+
+	return false
+}
+
+func (q *EventQueue) take() []events.Event {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	taken := q.events
+	q.events = nil
+
+	return taken
+}
+
+// Next returns the events queued so far, waiting up to timeout for some if
+// there are none.  The first call returns straight away.
+func (q *EventQueue) Next(timeout time.Duration) []events.Event {
+	if !q.watch() {
+		return []events.Event{}
+	}
+
+	timeoutCh := time.After(timeout)
+	for {
+		if taken := q.take(); len(taken) > 0 {
+			return taken
+		}
+
+		select {
+		case <-q.notifyCh:
+		case <-timeoutCh:
+			return []events.Event{}
+		}
+	}
+}
+
+// WatchEventsArgs are the arguments of WatchEvents.
+type WatchEventsArgs struct {
+	// Flush asks for the events queued so far without waiting for more.
+	Flush bool
+}
+
+// startWatchingEvents asks the plugin to send its events, which it then
+// fetches until the plugin is closed.  Plugins which predate events keep
+// writing their logs to stdout and stderr.
+func (p *pluginProcess) startWatchingEvents() {
+	var received []events.Event
+	if err := p.client.Call("RPCServerDriver.WatchEvents", &WatchEventsArgs{}, &received); err != nil {
+		log.Debugf("Plugin for driver %q cannot send events: %s", p.driverName, err)
+		return
+	}
+
+	p.eventsDoneCh = make(chan struct{})
+	go p.watchEvents()
+}
+
+func (p *pluginProcess) watchEvents() {
+	defer close(p.eventsDoneCh)
+
+	for {
+		var received []events.Event
+		if err := p.client.Call("RPCServerDriver.WatchEvents", &WatchEventsArgs{}, &received); err != nil {
+			p.lock.Lock()
+			closing := p.closing
+			p.lock.Unlock()
+			if !closing {
+				log.Debugf("Error watching the events of the plugin for driver %q: %s", p.driverName, err)
+			}
+			return
+		}
+
+		for _, event := range received {
+			p.publish(event)
+		}
+
+		select {
+		case <-p.heartbeatDoneCh:
+			return
+		case <-p.exitedCh:
+			return
+		default:
+		}
+	}
+}
+
+// flushEvents publishes the events still queued in the plugin, so that
+// none is lost when it exits.
+func (p *pluginProcess) flushEvents() {
+	var received []events.Event
+	if err := p.client.Call("RPCServerDriver.WatchEvents", &WatchEventsArgs{Flush: true}, &received); err != nil {
+		log.Debugf("Error fetching the last events of the plugin for driver %q: %s", p.driverName, err)
+		return
+	}
+
+	for _, event := range received {
+		p.publish(event)
+	}
+}
+
+// publish publishes an event of the plugin in this process.  Log records
+// are written out the way the output of the plugin is.
+func (p *pluginProcess) publish(event events.Event) {
+	if event.MachineName == "" {
+		event.MachineName = p.plugin.MachineName
+	}
+
+	if event.Kind == events.KindLog {
+		switch event.Level {
+		case log.InfoLevel:
+			log.Info(localbinary.PluginOutPrefix(event.MachineName), event.Message)
+		case log.WarnLevel:
+			log.Warn(localbinary.PluginOutPrefix(event.MachineName), event.Message)
+		default:
+			log.Debug(localbinary.PluginErrPrefix(event.MachineName), event.Message)
+		}
+	}
+
+	events.Publish(event)
+}
@@ -0,0 +1,111 @@
+package rpcdriver_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/machine/libmachine/drivers/rpc"
+	"github.com/docker/machine/libmachine/events"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventQueue(t *testing.T) {
+	q := rpcdriver.NewEventQueue()
+	defer log.SetHandler(nil)
+
+	// Nothing is queued before the first call, which doesn't wait.
+	events.Progress("foo", "Copying ISO", 10, "")
+	assert.Empty(t, q.Next(time.Hour))
+
+	events.Progress("foo", "Copying ISO", 20, "")
+	log.Info("Copied")
+
+	received := q.Next(time.Hour)
+	if assert.Len(t, received, 2) {
+		assert.Equal(t, events.KindProgress, received[0].Kind)
+		assert.Equal(t, 20, received[0].Percent)
+		assert.Equal(t, events.KindLog, received[1].Kind)
+		assert.Equal(t, log.InfoLevel, received[1].Level)
+		assert.Equal(t, "Copied", received[1].Message)
+	}
+
+	start := time.Now()
+	assert.Empty(t, q.Next(10*time.Millisecond))
+	assert.True(t, time.Since(start) >= 10*time.Millisecond)
+
+	go events.Progress("foo", "Copying ISO", 30, "")
+	received = q.Next(time.Hour)
+	if assert.Len(t, received, 1) {
+		assert.Equal(t, 30, received[0].Percent)
+	}
+}
+
+func TestPluginEvents(t *testing.T) {
+	_, cleanup := setupCrashingPlugin(t)
+	defer cleanup()
+
+	lock := &sync.Mutex{}
+	received := []events.Event{}
+	unsubscribe := events.Subscribe(func(e events.Event) {
+		lock.Lock()
+		defer lock.Unlock()
+		received = append(received, e)
+	})
+	defer unsubscribe()
+
+	d, err := rpcdriver.NewRPCClientDriver([]byte(`{"MachineName":"foo"}`), "crashing")
+	assert.NoError(t, err)
+	defer d.Close()
+
+	assert.NoError(t, d.Create())
+
+	// Events may arrive after the call returned.
+	var progress, created *events.Event
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline) && (progress == nil || created == nil); {
+		time.Sleep(10 * time.Millisecond)
+
+		lock.Lock()
+		for i := range received {
+			switch {
+			case received[i].Kind == events.KindProgress:
+				progress = &received[i]
+			case received[i].Kind == events.KindLog && received[i].Message == "Created":
+				created = &received[i]
+			}
+		}
+		lock.Unlock()
+	}
+
+	if assert.NotNil(t, progress) {
+		assert.Equal(t, "foo", progress.MachineName)
+		assert.Equal(t, "Creating", progress.Phase)
+		assert.Equal(t, 50, progress.Percent)
+		assert.Equal(t, "Half way there", progress.Message)
+		assert.False(t, progress.Time.IsZero())
+	}
+
+	if assert.NotNil(t, created) {
+		assert.Equal(t, log.InfoLevel, created.Level)
+		assert.Equal(t, "crashing", created.MachineName)
+	}
+}
+
+func TestWatchEventsFlush(t *testing.T) {
+	r := rpcdriver.NewRPCServerDriver(nil)
+	defer log.SetHandler(nil)
+
+	var received []events.Event
+	assert.NoError(t, r.WatchEvents(&rpcdriver.WatchEventsArgs{}, &received))
+
+	events.Progress("foo", "Copying ISO", 10, "")
+	assert.NoError(t, r.WatchEvents(&rpcdriver.WatchEventsArgs{Flush: true}, &received))
+	assert.Len(t, received, 1)
+
+	// Flushing doesn't wait for more events.
+	start := time.Now()
+	assert.NoError(t, r.WatchEvents(&rpcdriver.WatchEventsArgs{Flush: true}, &received))
+	assert.Empty(t, received)
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+}
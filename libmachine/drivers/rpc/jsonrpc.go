@@ -0,0 +1,472 @@
+package rpcdriver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/rpc"
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/mcnflag"
+)
+
+// The JSON protocol lets plugins be written in any language.  It is
+// JSON-RPC 1.0 with the plugin token added to every request:
+//
+//	--> {"id": 1, "method": "RPCServerDriver.GetURL", "params": [{}], "token": "..."}
+//	<-- {"id": 1, "result": "tcp://1.2.3.4:2376", "error": null}
+//
+// Every message is a single JSON object followed by a newline.  See
+// docs/DRIVER_SPEC.md for how each method's arguments and results look.
+
+var errMissingParams = errors.New("jsonrpc: request body missing params")
+
+type jsonRequest struct {
+	ID     *json.RawMessage `json:"id"`
+	Method string           `json:"method"`
+	Params *json.RawMessage `json:"params"`
+	Token  string           `json:"token"`
+}
+
+type jsonResponse struct {
+	ID     *json.RawMessage `json:"id"`
+	Result interface{}      `json:"result"`
+	Error  interface{}      `json:"error"`
+}
+
+// jsonFlag is how create flags, which are an interface on the Go side,
+// look in JSON.  Durations are strings such as "1m30s".
+type jsonFlag struct {
+	Type     string
+	Name     string
+	Usage    string
+	EnvVar   string
+	Value    interface{} `json:",omitempty"`
+	Allowed  []string    `json:",omitempty"`
+	Required bool        `json:",omitempty"`
+}
+
+func toJSONFlags(flags []mcnflag.Flag) ([]jsonFlag, error) {
+	jsonFlags := []jsonFlag{}
+
+	for _, flag := range flags {
+		var f jsonFlag
+
+		// Drivers return their flags as values, but gob hands them to
+		// the client as pointers.
+		switch v := flag.(type) {
+		case mcnflag.StringFlag:
+			flag = &v
+		case mcnflag.StringSliceFlag:
+			flag = &v
+		case mcnflag.IntFlag:
+			flag = &v
+		case mcnflag.BoolFlag:
+			flag = &v
+		case mcnflag.DurationFlag:
+			flag = &v
+		case mcnflag.FloatFlag:
+			flag = &v
+		case mcnflag.EnumFlag:
+			flag = &v
+		case mcnflag.SecretFlag:
+			flag = &v
+		case mcnflag.FilePathFlag:
+			flag = &v
+		}
+
+		switch v := flag.(type) {
+		case *mcnflag.StringFlag:
+			f = jsonFlag{Type: "string", Name: v.Name, Usage: v.Usage, EnvVar: v.EnvVar, Value: v.Value, Required: v.Required}
+		case *mcnflag.StringSliceFlag:
+			f = jsonFlag{Type: "stringSlice", Name: v.Name, Usage: v.Usage, EnvVar: v.EnvVar, Value: v.Value, Required: v.Required}
+		case *mcnflag.IntFlag:
+			f = jsonFlag{Type: "int", Name: v.Name, Usage: v.Usage, EnvVar: v.EnvVar, Value: v.Value, Required: v.Required}
+		case *mcnflag.BoolFlag:
+			f = jsonFlag{Type: "bool", Name: v.Name, Usage: v.Usage, EnvVar: v.EnvVar}
+		case *mcnflag.DurationFlag:
+			f = jsonFlag{Type: "duration", Name: v.Name, Usage: v.Usage, EnvVar: v.EnvVar, Value: v.Value.String(), Required: v.Required}
+		case *mcnflag.FloatFlag:
+			f = jsonFlag{Type: "float", Name: v.Name, Usage: v.Usage, EnvVar: v.EnvVar, Value: v.Value, Required: v.Required}
+		case *mcnflag.EnumFlag:
+			f = jsonFlag{Type: "enum", Name: v.Name, Usage: v.Usage, EnvVar: v.EnvVar, Value: v.Value, Allowed: v.Allowed, Required: v.Required}
+		case *mcnflag.SecretFlag:
+			f = jsonFlag{Type: "secret", Name: v.Name, Usage: v.Usage, EnvVar: v.EnvVar, Required: v.Required}
+		case *mcnflag.FilePathFlag:
+			f = jsonFlag{Type: "filePath", Name: v.Name, Usage: v.Usage, EnvVar: v.EnvVar, Value: v.Value, Required: v.Required}
+		default:
+			return nil, fmt.Errorf("jsonrpc: unsupported flag type %T", flag)
+		}
+
+		jsonFlags = append(jsonFlags, f)
+	}
+
+	return jsonFlags, nil
+}
+
+func fromJSONFlags(jsonFlags []jsonFlag) ([]mcnflag.Flag, error) {
+	flags := []mcnflag.Flag{}
+
+	for _, f := range jsonFlags {
+		switch f.Type {
+		case "string":
+			value, _ := f.Value.(string)
+			flags = append(flags, &mcnflag.StringFlag{Name: f.Name, Usage: f.Usage, EnvVar: f.EnvVar, Value: value, Required: f.Required})
+		case "stringSlice":
+			flags = append(flags, &mcnflag.StringSliceFlag{Name: f.Name, Usage: f.Usage, EnvVar: f.EnvVar, Value: toStringSlice(f.Value), Required: f.Required})
+		case "int":
+			value, _ := f.Value.(float64)
+			flags = append(flags, &mcnflag.IntFlag{Name: f.Name, Usage: f.Usage, EnvVar: f.EnvVar, Value: int(value), Required: f.Required})
+		case "bool":
+			flags = append(flags, &mcnflag.BoolFlag{Name: f.Name, Usage: f.Usage, EnvVar: f.EnvVar})
+		case "duration":
+			value, err := toDuration(f.Value)
+			if err != nil {
+				return nil, fmt.Errorf("jsonrpc: invalid duration for flag %q: %s", f.Name, err)
+			}
+			flags = append(flags, &mcnflag.DurationFlag{Name: f.Name, Usage: f.Usage, EnvVar: f.EnvVar, Value: value, Required: f.Required})
+		case "float":
+			value, _ := f.Value.(float64)
+			flags = append(flags, &mcnflag.FloatFlag{Name: f.Name, Usage: f.Usage, EnvVar: f.EnvVar, Value: value, Required: f.Required})
+		case "enum":
+			value, _ := f.Value.(string)
+			flags = append(flags, &mcnflag.EnumFlag{Name: f.Name, Usage: f.Usage, EnvVar: f.EnvVar, Value: value, Allowed: f.Allowed, Required: f.Required})
+		case "secret":
+			flags = append(flags, &mcnflag.SecretFlag{Name: f.Name, Usage: f.Usage, EnvVar: f.EnvVar, Required: f.Required})
+		case "filePath":
+			value, _ := f.Value.(string)
+			flags = append(flags, &mcnflag.FilePathFlag{Name: f.Name, Usage: f.Usage, EnvVar: f.EnvVar, Value: value, Required: f.Required})
+		default:
+			return nil, fmt.Errorf("jsonrpc: unsupported type %q for flag %q", f.Type, f.Name)
+		}
+	}
+
+	return flags, nil
+}
+
+// jsonCommand is how driver commands look in JSON.
+type jsonCommand struct {
+	Name  string
+	Usage string
+	Flags []jsonFlag
+}
+
+func toJSONCommands(commands []drivers.Command) ([]jsonCommand, error) {
+	jsonCommands := []jsonCommand{}
+
+	for _, command := range commands {
+		flags, err := toJSONFlags(command.Flags)
+		if err != nil {
+			return nil, err
+		}
+		jsonCommands = append(jsonCommands, jsonCommand{Name: command.Name, Usage: command.Usage, Flags: flags})
+	}
+
+	return jsonCommands, nil
+}
+
+func fromJSONCommands(jsonCommands []jsonCommand) ([]drivers.Command, error) {
+	commands := []drivers.Command{}
+
+	for _, command := range jsonCommands {
+		flags, err := fromJSONFlags(command.Flags)
+		if err != nil {
+			return nil, err
+		}
+		commands = append(commands, drivers.Command{Name: command.Name, Usage: command.Usage, Flags: flags})
+	}
+
+	return commands, nil
+}
+
+func toDuration(value interface{}) (time.Duration, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case string:
+		return time.ParseDuration(v)
+	case float64:
+		return time.Duration(v), nil
+	}
+	return 0, fmt.Errorf("unexpected value %v", value)
+}
+
+func toStringSlice(value interface{}) []string {
+	values, _ := value.([]interface{})
+	strs := []string{}
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
+
+// unmarshalJSONValue decodes an argument or a result, taking care of the
+// types which are interfaces on the Go side.
+func unmarshalJSONValue(data []byte, x interface{}) error {
+	switch v := x.(type) {
+	case *drivers.DriverOptions:
+		flags := &RPCFlags{}
+		if err := json.Unmarshal(data, flags); err != nil {
+			return err
+		}
+		*v = flags
+		return nil
+	case *[]mcnflag.Flag:
+		jsonFlags := []jsonFlag{}
+		if err := json.Unmarshal(data, &jsonFlags); err != nil {
+			return err
+		}
+		flags, err := fromJSONFlags(jsonFlags)
+		if err != nil {
+			return err
+		}
+		*v = flags
+		return nil
+	case *[]drivers.Command:
+		jsonCommands := []jsonCommand{}
+		if err := json.Unmarshal(data, &jsonCommands); err != nil {
+			return err
+		}
+		commands, err := fromJSONCommands(jsonCommands)
+		if err != nil {
+			return err
+		}
+		*v = commands
+		return nil
+	}
+
+	return json.Unmarshal(data, x)
+}
+
+// toJSONValue is the counterpart of unmarshalJSONValue.
+func toJSONValue(x interface{}) (interface{}, error) {
+	switch v := x.(type) {
+	case *[]mcnflag.Flag:
+		return toJSONFlags(*v)
+	case *[]drivers.Command:
+		return toJSONCommands(*v)
+	case *RunCommandArgs:
+		return RunCommandArgs{Name: v.Name, Flags: toJSONFlagValues(v.Flags), Args: v.Args}, nil
+	case *drivers.DriverOptions:
+		if flags, ok := (*v).(RPCFlags); ok {
+			return toJSONFlagValues(flags), nil
+		}
+	}
+	return x, nil
+}
+
+// toJSONFlagValues writes durations the way they are written in flags.
+func toJSONFlagValues(flags RPCFlags) RPCFlags {
+	values := make(map[string]interface{}, len(flags.Values))
+	for key, value := range flags.Values {
+		if d, ok := value.(time.Duration); ok {
+			value = d.String()
+		}
+		values[key] = value
+	}
+	return RPCFlags{Values: values}
+}
+
+type jsonClientCodec struct {
+	dec   *json.Decoder
+	enc   *json.Encoder
+	c     io.Closer
+	token string
+
+	resp struct {
+		ID     uint64           `json:"id"`
+		Result *json.RawMessage `json:"result"`
+		Error  interface{}      `json:"error"`
+	}
+
+	mutex   sync.Mutex
+	pending map[uint64]string
+}
+
+// NewJSONClientCodec returns a codec which speaks the JSON protocol and
+// sends the token with every call.
+func NewJSONClientCodec(conn io.ReadWriteCloser, token string) rpc.ClientCodec {
+	return &jsonClientCodec{
+		dec:     json.NewDecoder(conn),
+		enc:     json.NewEncoder(conn),
+		c:       conn,
+		token:   token,
+		pending: make(map[uint64]string),
+	}
+}
+
+func (c *jsonClientCodec) WriteRequest(r *rpc.Request, param interface{}) error {
+	c.mutex.Lock()
+	c.pending[r.Seq] = r.ServiceMethod
+	c.mutex.Unlock()
+
+	value, err := toJSONValue(param)
+	if err != nil {
+		return err
+	}
+
+	params, err := json.Marshal([1]interface{}{value})
+	if err != nil {
+		return err
+	}
+	raw := json.RawMessage(params)
+
+	id, err := json.Marshal(r.Seq)
+	if err != nil {
+		return err
+	}
+	rawID := json.RawMessage(id)
+
+	return c.enc.Encode(&jsonRequest{
+		ID:     &rawID,
+		Method: r.ServiceMethod,
+		Params: &raw,
+		Token:  c.token,
+	})
+}
+
+func (c *jsonClientCodec) ReadResponseHeader(r *rpc.Response) error {
+	c.resp.Result = nil
+	c.resp.Error = nil
+
+	if err := c.dec.Decode(&c.resp); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	r.ServiceMethod = c.pending[c.resp.ID]
+	delete(c.pending, c.resp.ID)
+	c.mutex.Unlock()
+
+	r.Error = ""
+	r.Seq = c.resp.ID
+	if c.resp.Error != nil {
+		msg, ok := c.resp.Error.(string)
+		if !ok {
+			return fmt.Errorf("jsonrpc: invalid error %v", c.resp.Error)
+		}
+		if msg == "" {
+			msg = "unspecified error"
+		}
+		r.Error = msg
+	}
+
+	return nil
+}
+
+func (c *jsonClientCodec) ReadResponseBody(x interface{}) error {
+	if x == nil || c.resp.Result == nil {
+		return nil
+	}
+	return unmarshalJSONValue(*c.resp.Result, x)
+}
+
+func (c *jsonClientCodec) Close() error {
+	return c.c.Close()
+}
+
+type jsonServerCodec struct {
+	dec   *json.Decoder
+	enc   *json.Encoder
+	c     io.Closer
+	token string
+
+	req jsonRequest
+
+	mutex   sync.Mutex
+	seq     uint64
+	pending map[uint64]*json.RawMessage
+}
+
+// NewJSONServerCodec returns a codec which speaks the JSON protocol and
+// only accepts calls carrying the token.
+func NewJSONServerCodec(conn io.ReadWriteCloser, token string) rpc.ServerCodec {
+	return &jsonServerCodec{
+		dec:     json.NewDecoder(conn),
+		enc:     json.NewEncoder(conn),
+		c:       conn,
+		token:   token,
+		pending: make(map[uint64]*json.RawMessage),
+	}
+}
+
+func (c *jsonServerCodec) ReadRequestHeader(r *rpc.Request) error {
+	c.req = jsonRequest{}
+
+	if err := c.dec.Decode(&c.req); err != nil {
+		return err
+	}
+
+	if !validToken(c.req.Token, c.token) {
+		return errInvalidToken
+	}
+
+	r.ServiceMethod = c.req.Method
+
+	c.mutex.Lock()
+	c.seq++
+	c.pending[c.seq] = c.req.ID
+	c.req.ID = nil
+	r.Seq = c.seq
+	c.mutex.Unlock()
+
+	return nil
+}
+
+func (c *jsonServerCodec) ReadRequestBody(x interface{}) error {
+	if x == nil {
+		return nil
+	}
+
+	if c.req.Params == nil {
+		return errMissingParams
+	}
+
+	var params [1]json.RawMessage
+	if err := json.Unmarshal(*c.req.Params, &params); err != nil {
+		return err
+	}
+
+	return unmarshalJSONValue(params[0], x)
+}
+
+var null = json.RawMessage([]byte("null"))
+
+func (c *jsonServerCodec) WriteResponse(r *rpc.Response, x interface{}) error {
+	c.mutex.Lock()
+	id, ok := c.pending[r.Seq]
+	if !ok {
+		c.mutex.Unlock()
+		return errors.New("jsonrpc: invalid sequence number in response")
+	}
+	delete(c.pending, r.Seq)
+	c.mutex.Unlock()
+
+	if id == nil {
+		// Invalid request so no id.  Use JSON null.
+		id = &null
+	}
+
+	resp := jsonResponse{ID: id}
+	if r.Error == "" {
+		value, err := toJSONValue(x)
+		if err != nil {
+			resp.Error = err.Error()
+		} else {
+			resp.Result = value
+		}
+	} else {
+		resp.Error = r.Error
+	}
+
+	return c.enc.Encode(resp)
+}
+
+func (c *jsonServerCodec) Close() error {
+	return c.c.Close()
+}
@@ -0,0 +1,187 @@
+package rpcdriver
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/rpc"
+	"testing"
+	"time"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/mcnflag"
+	"github.com/docker/machine/libmachine/state"
+	"github.com/docker/machine/libmachine/version"
+	"github.com/stretchr/testify/assert"
+)
+
+type flagsDriver struct {
+	*fakedriver.Driver
+	flags drivers.DriverOptions
+}
+
+func (d *flagsDriver) GetCreateFlags() []mcnflag.Flag {
+	return []mcnflag.Flag{
+		mcnflag.IntFlag{Name: "fake-cpus", Usage: "CPUs", EnvVar: "FAKE_CPUS", Value: 2},
+		mcnflag.StringFlag{Name: "fake-image", Value: "ubuntu"},
+		mcnflag.StringSliceFlag{Name: "fake-tags", Value: []string{"a", "b"}},
+		mcnflag.BoolFlag{Name: "fake-debug"},
+		mcnflag.DurationFlag{Name: "fake-timeout", Value: time.Minute},
+		mcnflag.EnumFlag{Name: "fake-size", Value: "small", Allowed: []string{"small", "large"}, Required: true},
+		mcnflag.SecretFlag{Name: "fake-token"},
+	}
+}
+
+func (d *flagsDriver) SetConfigFromFlags(flags drivers.DriverOptions) error {
+	d.flags = flags
+	return nil
+}
+
+func newJSONTestClient(t *testing.T, d drivers.Driver) *rpc.Client {
+	server := rpc.NewServer()
+	rpcd := NewRPCServerDriver(d)
+	rpcd.APIVersion = version.APIVersion
+	assert.NoError(t, server.Register(rpcd))
+
+	serverConn, clientConn := net.Pipe()
+	go server.ServeCodec(NewJSONServerCodec(serverConn, "secret"))
+
+	client := rpc.NewClientWithCodec(NewJSONClientCodec(clientConn, "secret"))
+
+	// Machine agrees on the version first, as it does with every plugin.
+	var apiVersion int
+	assert.NoError(t, client.Call("RPCServerDriver.NegotiateVersion", version.APIVersion, &apiVersion))
+
+	return client
+}
+
+func TestJSONCodecCalls(t *testing.T) {
+	d := &flagsDriver{Driver: &fakedriver.Driver{MockName: "foo", MockState: state.Running}}
+	client := newJSONTestClient(t, d)
+	defer client.Close()
+
+	var name string
+	assert.NoError(t, client.Call("RPCServerDriver.GetMachineName", struct{}{}, &name))
+	assert.Equal(t, "foo", name)
+
+	var s state.State
+	assert.NoError(t, client.Call("RPCServerDriver.GetState", struct{}{}, &s))
+	assert.Equal(t, state.Running, s)
+
+	var flags []mcnflag.Flag
+	assert.NoError(t, client.Call("RPCServerDriver.GetCreateFlags", struct{}{}, &flags))
+	assert.Equal(t, []mcnflag.Flag{
+		&mcnflag.IntFlag{Name: "fake-cpus", Usage: "CPUs", EnvVar: "FAKE_CPUS", Value: 2},
+		&mcnflag.StringFlag{Name: "fake-image", Value: "ubuntu"},
+		&mcnflag.StringSliceFlag{Name: "fake-tags", Value: []string{"a", "b"}},
+		&mcnflag.BoolFlag{Name: "fake-debug"},
+		&mcnflag.DurationFlag{Name: "fake-timeout", Value: time.Minute},
+		&mcnflag.EnumFlag{Name: "fake-size", Value: "small", Allowed: []string{"small", "large"}, Required: true},
+		&mcnflag.SecretFlag{Name: "fake-token"},
+	}, flags)
+
+	var opts drivers.DriverOptions = RPCFlags{
+		Values: map[string]interface{}{
+			"fake-cpus":    4,
+			"fake-tags":    []string{"c"},
+			"fake-timeout": 90 * time.Second,
+		},
+	}
+	assert.NoError(t, client.Call("RPCServerDriver.SetConfigFromFlags", &opts, nil))
+	assert.Equal(t, 4, d.flags.Int("fake-cpus"))
+	assert.Equal(t, []string{"c"}, d.flags.StringSlice("fake-tags"))
+	assert.Equal(t, 90*time.Second, drivers.GetDuration(d.flags, "fake-timeout"))
+
+	assert.EqualError(t, client.Call("RPCServerDriver.NoSuchMethod", struct{}{}, nil), "rpc: can't find method RPCServerDriver.NoSuchMethod")
+}
+
+// TestJSONWireFormat talks to the server the way a plugin client written in
+// another language would.
+func TestJSONWireFormat(t *testing.T) {
+	server := rpc.NewServer()
+	assert.NoError(t, server.Register(NewRPCServerDriver(&fakedriver.Driver{MockURL: "tcp://1.2.3.4:2376"})))
+
+	serverConn, clientConn := net.Pipe()
+	go server.ServeCodec(NewJSONServerCodec(serverConn, "secret"))
+	defer clientConn.Close()
+
+	fmt.Fprintln(clientConn, `{"id": 7, "method": "RPCServerDriver.GetURL", "params": [{}], "token": "secret"}`)
+
+	line, err := bufio.NewReader(clientConn).ReadBytes('\n')
+	assert.NoError(t, err)
+
+	var resp map[string]interface{}
+	assert.NoError(t, json.Unmarshal(line, &resp))
+	assert.Equal(t, map[string]interface{}{
+		"id":     float64(7),
+		"result": "tcp://1.2.3.4:2376",
+		"error":  nil,
+	}, resp)
+}
+
+func TestJSONCodecDriverCommands(t *testing.T) {
+	client := newJSONTestClient(t, &commandsDriver{&fakedriver.Driver{}})
+	defer client.Close()
+
+	var commands []drivers.Command
+	assert.NoError(t, client.Call("RPCServerDriver.GetCommands", struct{}{}, &commands))
+	assert.Equal(t, []drivers.Command{
+		{
+			Name:  "echo",
+			Usage: "Print the arguments",
+			Flags: []mcnflag.Flag{
+				&mcnflag.StringFlag{Name: "prefix", Value: ">"},
+			},
+		},
+	}, commands)
+
+	var output string
+	args := &RunCommandArgs{
+		Name:  "echo",
+		Flags: RPCFlags{Values: map[string]interface{}{"prefix": "$"}},
+		Args:  []string{"hi"},
+	}
+	assert.NoError(t, client.Call("RPCServerDriver.RunCommand", args, &output))
+	assert.Equal(t, "$ hi", output)
+}
+
+func TestJSONCodecRejectsWrongToken(t *testing.T) {
+	server := rpc.NewServer()
+	assert.NoError(t, server.Register(NewRPCServerDriver(&fakedriver.Driver{})))
+
+	serverConn, clientConn := net.Pipe()
+	go server.ServeCodec(NewJSONServerCodec(serverConn, "secret"))
+
+	client := rpc.NewClientWithCodec(NewJSONClientCodec(clientConn, "guess"))
+	defer client.Close()
+
+	var config []byte
+	assert.Error(t, client.Call("RPCServerDriver.GetConfigRaw", struct{}{}, &config))
+}
+
+func TestDialPluginDetectsJSON(t *testing.T) {
+	listener, addr, err := Listen("")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	server := rpc.NewServer()
+	assert.NoError(t, server.Register(NewRPCServerDriver(&fakedriver.Driver{MockName: "foo"})))
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		server.ServeCodec(NewJSONServerCodec(conn, "secret"))
+	}()
+
+	client, err := DialPlugin("jsonrpc+"+addr, "secret")
+	assert.NoError(t, err)
+	defer client.Close()
+
+	var name string
+	assert.NoError(t, client.Call("RPCServerDriver.GetMachineName", struct{}{}, &name))
+	assert.Equal(t, "foo", name)
+}
@@ -3,9 +3,17 @@ package rpcdriver
 import (
 	"encoding/gob"
 	"encoding/json"
+	"fmt"
+	"net/rpc"
+	"strconv"
+	"sync"
+	"time"
 
+	"github.com/docker/machine/drivers/errdriver"
 	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/events"
 	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnerror"
 	"github.com/docker/machine/libmachine/mcnflag"
 	"github.com/docker/machine/libmachine/state"
 	"github.com/docker/machine/libmachine/version"
@@ -17,6 +25,12 @@ func init() {
 	gob.Register(new(mcnflag.StringFlag))
 	gob.Register(new(mcnflag.StringSliceFlag))
 	gob.Register(new(mcnflag.BoolFlag))
+	gob.Register(new(mcnflag.DurationFlag))
+	gob.Register(new(mcnflag.FloatFlag))
+	gob.Register(new(mcnflag.EnumFlag))
+	gob.Register(new(mcnflag.SecretFlag))
+	gob.Register(new(mcnflag.FilePathFlag))
+	gob.Register(time.Duration(0))
 }
 
 type RPCFlags struct {
@@ -42,6 +56,10 @@ func (r RPCFlags) String(key string) string {
 
 func (r RPCFlags) StringSlice(key string) []string {
 	val, ok := r.Get(key).([]string)
+	if values, isJSON := r.Get(key).([]interface{}); isJSON {
+		// Flags which came over the JSON protocol.
+		val, ok = toStringSlice(values), true
+	}
 	if !ok {
 		log.Warnf("Type assertion did not go smoothly to string slice for key %s", key)
 	}
@@ -50,6 +68,10 @@ func (r RPCFlags) StringSlice(key string) []string {
 
 func (r RPCFlags) Int(key string) int {
 	val, ok := r.Get(key).(int)
+	if f, isJSON := r.Get(key).(float64); isJSON {
+		// Numbers which came over the JSON protocol.
+		val, ok = int(f), true
+	}
 	if !ok {
 		log.Warnf("Type assertion did not go smoothly to int for key %s", key)
 	}
@@ -64,30 +86,190 @@ func (r RPCFlags) Bool(key string) bool {
 	return val
 }
 
+func (r RPCFlags) Duration(key string) time.Duration {
+	val, ok := r.Get(key).(time.Duration)
+	switch v := r.Get(key).(type) {
+	case float64:
+		// Nanoseconds which came over the JSON protocol.
+		val, ok = time.Duration(v), true
+	case string:
+		d, err := time.ParseDuration(v)
+		val, ok = d, err == nil
+	}
+	if !ok {
+		log.Warnf("Type assertion did not go smoothly to duration for key %s", key)
+	}
+	return val
+}
+
+func (r RPCFlags) Float(key string) float64 {
+	val, ok := r.Get(key).(float64)
+	if s, isString := r.Get(key).(string); isString {
+		// Clients of API version 1 send floats as strings.
+		f, err := strconv.ParseFloat(s, 64)
+		val, ok = f, err == nil
+	}
+	if !ok {
+		log.Warnf("Type assertion did not go smoothly to float for key %s", key)
+	}
+	return val
+}
+
 type RPCServerDriver struct {
 	ActualDriver drivers.Driver
 	CloseCh      chan bool
 	HeartbeatCh  chan bool
+
+	// Events are the events published in the plugin, for the client.
+	Events *EventQueue
+
+	// APIVersion is the highest API version the plugin speaks.
+	APIVersion int
+
+	// clientVersion is the API version agreed on with the client, version
+	// 1 until the client negotiates one.
+	clientVersion int
+	versionLock   sync.Mutex
 }
 
 func NewRPCServerDriver(d drivers.Driver) *RPCServerDriver {
 	return &RPCServerDriver{
-		ActualDriver: d,
-		CloseCh:      make(chan bool),
-		HeartbeatCh:  make(chan bool),
+		ActualDriver:  d,
+		CloseCh:       make(chan bool),
+		HeartbeatCh:   make(chan bool),
+		Events:        NewEventQueue(),
+		APIVersion:    version.MinAPIVersion,
+		clientVersion: version.MinAPIVersion,
 	}
 }
 
+// RPCPluginServer lets a single plugin process serve several machines
+// (API version 2).  Each machine gets its own RPCServerDriver, registered
+// as a separate service named after the machine.
+type RPCPluginServer struct {
+	server    *rpc.Server
+	base      *RPCServerDriver
+	newDriver func() drivers.Driver
+	lock      sync.Mutex
+
+	// services are the registered drivers by service name, and refs how
+	// many clients use each.  net/rpc can't unregister a service, so a
+	// driver no client uses is swapped for one which refuses the calls,
+	// until a client asks for the machine again.
+	services map[string]*RPCServerDriver
+	refs     map[string]int
+}
+
+// NewRPCPluginServer returns a plugin server which registers the drivers it
+// creates with newDriver on server.  base is the default RPCServerDriver of
+// the plugin, whose close and heartbeat channels and event queue are shared
+// by all of them.
+func NewRPCPluginServer(server *rpc.Server, base *RPCServerDriver, newDriver func() drivers.Driver) *RPCPluginServer {
+	return &RPCPluginServer{
+		server:    server,
+		base:      base,
+		newDriver: newDriver,
+		services:  make(map[string]*RPCServerDriver),
+		refs:      make(map[string]int),
+	}
+}
+
+// DriverServiceName returns the name of the RPC service for a machine.
+func DriverServiceName(machineName string) string {
+	return "RPCServerDriver." + machineName
+}
+
+// NewDriver makes sure there's a driver for the machine and replies with
+// the name of its service.  Asking again for the same machine returns the
+// same driver, until every client asking for it called CloseDriver.
+func (s *RPCPluginServer) NewDriver(machineName string, reply *string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	name := DriverServiceName(machineName)
+
+	if rpcd, ok := s.services[name]; !ok {
+		rpcd = &RPCServerDriver{
+			ActualDriver:  s.newDriver(),
+			CloseCh:       s.base.CloseCh,
+			HeartbeatCh:   s.base.HeartbeatCh,
+			Events:        s.base.Events,
+			APIVersion:    s.base.APIVersion,
+			clientVersion: s.base.getClientVersion(),
+		}
+		if err := s.server.RegisterName(name, rpcd); err != nil {
+			return err
+		}
+		s.services[name] = rpcd
+	} else if s.refs[name] == 0 {
+		rpcd.ActualDriver = s.newDriver()
+		rpcd.versionLock.Lock()
+		rpcd.clientVersion = s.base.getClientVersion()
+		rpcd.versionLock.Unlock()
+	}
+	s.refs[name]++
+
+	*reply = name
+
+	return nil
+}
+
+// CloseDriver tells that a client is done with the driver of a service
+// returned by NewDriver.  The driver is dropped once no client uses it.
+func (s *RPCPluginServer) CloseDriver(serviceName string, _ *struct{}) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	rpcd, ok := s.services[serviceName]
+	if !ok || s.refs[serviceName] == 0 {
+		return fmt.Errorf("No driver is open for %s", serviceName)
+	}
+
+	s.refs[serviceName]--
+	if s.refs[serviceName] == 0 {
+		rpcd.ActualDriver = errdriver.NewDriver(s.base.ActualDriver.DriverName())
+	}
+
+	return nil
+}
+
 func (r *RPCServerDriver) Close(_, _ *struct{}) error {
 	r.CloseCh <- true
 	return nil
 }
 
+// GetVersion reports the API version every plugin speaks, so that clients
+// which predate NegotiateVersion keep working.
 func (r *RPCServerDriver) GetVersion(_ *struct{}, reply *int) error {
-	*reply = version.APIVersion
+	*reply = version.MinAPIVersion
+	return nil
+}
+
+// NegotiateVersion replies with the highest API version spoken by both the
+// client and the plugin, which the plugin then speaks.
+func (r *RPCServerDriver) NegotiateVersion(clientVersion int, reply *int) error {
+	*reply = r.APIVersion
+	if clientVersion < *reply {
+		*reply = clientVersion
+	}
+
+	r.versionLock.Lock()
+	r.clientVersion = *reply
+	r.versionLock.Unlock()
+
 	return nil
 }
 
+func (r *RPCServerDriver) getClientVersion() int {
+	r.versionLock.Lock()
+	defer r.versionLock.Unlock()
+
+	if r.clientVersion < version.MinAPIVersion {
+		return version.MinAPIVersion
+	}
+	return r.clientVersion
+}
+
 func (r *RPCServerDriver) GetConfigRaw(_ *struct{}, reply *[]byte) error {
 	driverData, err := json.Marshal(r.ActualDriver)
 	if err != nil {
@@ -99,17 +281,102 @@ func (r *RPCServerDriver) GetConfigRaw(_ *struct{}, reply *[]byte) error {
 	return nil
 }
 
+func (r *RPCServerDriver) GetCapabilities(_ *struct{}, reply *drivers.Capabilities) error {
+	*reply = drivers.GetCapabilities(r.ActualDriver)
+	return nil
+}
+
+func (r *RPCServerDriver) GetCommands(_ *struct{}, reply *[]drivers.Command) error {
+	*reply = drivers.GetCommands(r.ActualDriver)
+	return nil
+}
+
+// RunCommandArgs are the arguments of RunCommand.
+type RunCommandArgs struct {
+	Name  string
+	Flags RPCFlags
+	Args  []string
+}
+
+func (r *RPCServerDriver) RunCommand(args *RunCommandArgs, reply *string) error {
+	runner, ok := r.ActualDriver.(drivers.CommandRunner)
+	if !ok || drivers.FindCommand(runner.GetCommands(), args.Name) == nil {
+		return fmt.Errorf("Driver %q has no command %q", r.ActualDriver.DriverName(), args.Name)
+	}
+
+	output, err := runner.RunCommand(args.Name, args.Flags, args.Args)
+	*reply = output
+	return mcnerror.Encode(err)
+}
+
 func (r *RPCServerDriver) GetCreateFlags(_ *struct{}, reply *[]mcnflag.Flag) error {
 	*reply = r.ActualDriver.GetCreateFlags()
+	if r.getClientVersion() < typedFlagsAPIVersion {
+		*reply = toV1Flags(*reply)
+	}
 	return nil
 }
 
+// typedFlagsAPIVersion is the first API version whose clients know the
+// duration, float, enum, secret and file path flags.
+const typedFlagsAPIVersion = 2
+
+// toV1Flags replaces the flags of the types which clients of API version 1
+// can't decode with string flags.  The values come back as strings, which
+// RPCFlags reads as durations and floats as well.
+func toV1Flags(flags []mcnflag.Flag) []mcnflag.Flag {
+	v1Flags := []mcnflag.Flag{}
+
+	for _, flag := range flags {
+		switch v := flag.(type) {
+		case mcnflag.DurationFlag:
+			flag = &v
+		case mcnflag.FloatFlag:
+			flag = &v
+		case mcnflag.EnumFlag:
+			flag = &v
+		case mcnflag.SecretFlag:
+			flag = &v
+		case mcnflag.FilePathFlag:
+			flag = &v
+		}
+
+		switch v := flag.(type) {
+		case *mcnflag.DurationFlag:
+			flag = mcnflag.StringFlag{Name: v.Name, Usage: v.Usage, EnvVar: v.EnvVar, Value: v.Value.String()}
+		case *mcnflag.FloatFlag:
+			flag = mcnflag.StringFlag{Name: v.Name, Usage: v.Usage, EnvVar: v.EnvVar, Value: strconv.FormatFloat(v.Value, 'g', -1, 64)}
+		case *mcnflag.EnumFlag:
+			flag = mcnflag.StringFlag{Name: v.Name, Usage: v.Usage, EnvVar: v.EnvVar, Value: v.Value}
+		case *mcnflag.SecretFlag:
+			flag = mcnflag.StringFlag{Name: v.Name, Usage: v.Usage, EnvVar: v.EnvVar}
+		case *mcnflag.FilePathFlag:
+			flag = mcnflag.StringFlag{Name: v.Name, Usage: v.Usage, EnvVar: v.EnvVar, Value: v.Value}
+		}
+
+		v1Flags = append(v1Flags, flag)
+	}
+
+	return v1Flags
+}
+
 func (r *RPCServerDriver) SetConfigRaw(data []byte, _ *struct{}) error {
-	return json.Unmarshal(data, &r.ActualDriver)
+	if err := json.Unmarshal(data, &r.ActualDriver); err != nil {
+		return err
+	}
+
+	// The plugin masks the secrets in its logs as well.
+	log.AddSecret(drivers.GetSecrets(r.ActualDriver)...)
+	return nil
+}
+
+func (r *RPCServerDriver) GetSecrets(_ *struct{}, reply *[]string) error {
+	*reply = drivers.GetSecrets(r.ActualDriver)
+	return nil
 }
 
 func (r *RPCServerDriver) Create(_, _ *struct{}) error {
-	return r.ActualDriver.Create()
+	return mcnerror.Encode(r.ActualDriver.Create())
 }
 
 func (r *RPCServerDriver) DriverName(_ *struct{}, reply *string) error {
@@ -120,7 +387,7 @@ func (r *RPCServerDriver) DriverName(_ *struct{}, reply *string) error {
 func (r *RPCServerDriver) GetIP(_ *struct{}, reply *string) error {
 	ip, err := r.ActualDriver.GetIP()
 	*reply = ip
-	return err
+	return mcnerror.Encode(err)
 }
 
 func (r *RPCServerDriver) GetMachineName(_ *struct{}, reply *string) error {
@@ -131,7 +398,7 @@ func (r *RPCServerDriver) GetMachineName(_ *struct{}, reply *string) error {
 func (r *RPCServerDriver) GetSSHHostname(_ *struct{}, reply *string) error {
 	hostname, err := r.ActualDriver.GetSSHHostname()
 	*reply = hostname
-	return err
+	return mcnerror.Encode(err)
 }
 
 func (r *RPCServerDriver) GetSSHKeyPath(_ *struct{}, reply *string) error {
@@ -143,7 +410,7 @@ func (r *RPCServerDriver) GetSSHKeyPath(_ *struct{}, reply *string) error {
 func (r *RPCServerDriver) GetSSHPort(_ *struct{}, reply *int) error {
 	port, err := r.ActualDriver.GetSSHPort()
 	*reply = port
-	return err
+	return mcnerror.Encode(err)
 }
 
 func (r *RPCServerDriver) GetSSHUsername(_ *struct{}, reply *string) error {
@@ -154,41 +421,61 @@ func (r *RPCServerDriver) GetSSHUsername(_ *struct{}, reply *string) error {
 func (r *RPCServerDriver) GetURL(_ *struct{}, reply *string) error {
 	info, err := r.ActualDriver.GetURL()
 	*reply = info
-	return err
+	return mcnerror.Encode(err)
 }
 
 func (r *RPCServerDriver) GetState(_ *struct{}, reply *state.State) error {
 	s, err := r.ActualDriver.GetState()
 	*reply = s
-	return err
+	return mcnerror.Encode(err)
 }
 
 func (r *RPCServerDriver) Kill(_ *struct{}, _ *struct{}) error {
-	return r.ActualDriver.Kill()
+	return mcnerror.Encode(r.ActualDriver.Kill())
 }
 
 func (r *RPCServerDriver) PreCreateCheck(_ *struct{}, _ *struct{}) error {
-	return r.ActualDriver.PreCreateCheck()
+	return mcnerror.Encode(r.ActualDriver.PreCreateCheck())
 }
 
 func (r *RPCServerDriver) Remove(_ *struct{}, _ *struct{}) error {
-	return r.ActualDriver.Remove()
+	return mcnerror.Encode(r.ActualDriver.Remove())
 }
 
 func (r *RPCServerDriver) Restart(_ *struct{}, _ *struct{}) error {
-	return r.ActualDriver.Restart()
+	return mcnerror.Encode(r.ActualDriver.Restart())
 }
 
 func (r *RPCServerDriver) SetConfigFromFlags(flags *drivers.DriverOptions, _ *struct{}) error {
-	return r.ActualDriver.SetConfigFromFlags(*flags)
+	// The plugin masks the secrets in its logs as well.
+	for _, flag := range r.ActualDriver.GetCreateFlags() {
+		if mcnflag.IsSecret(flag) {
+			log.AddSecret((*flags).String(flag.String()))
+		}
+	}
+
+	return mcnerror.Encode(r.ActualDriver.SetConfigFromFlags(*flags))
 }
 
 func (r *RPCServerDriver) Start(_ *struct{}, _ *struct{}) error {
-	return r.ActualDriver.Start()
+	return mcnerror.Encode(r.ActualDriver.Start())
 }
 
 func (r *RPCServerDriver) Stop(_ *struct{}, _ *struct{}) error {
-	return r.ActualDriver.Stop()
+	return mcnerror.Encode(r.ActualDriver.Stop())
+}
+
+// WatchEvents replies with the events published since the last call,
+// waiting a little for some if there are none, unless asked to flush them.
+// Once it has been called, the log records of the plugin are sent as
+// events too.
+func (r *RPCServerDriver) WatchEvents(args *WatchEventsArgs, reply *[]events.Event) error {
+	timeout := watchEventsTimeout
+	if args.Flush {
+		timeout = 0
+	}
+	*reply = r.Events.Next(timeout)
+	return nil
 }
 
 func (r *RPCServerDriver) Heartbeat(_ *struct{}, _ *struct{}) error {
@@ -0,0 +1,288 @@
+package rpcdriver
+
+import (
+	"net"
+	"net/rpc"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnflag"
+	"github.com/docker/machine/libmachine/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestPluginServer(t *testing.T) *rpc.Client {
+	newDriver := func() drivers.Driver {
+		return &fakedriver.Driver{}
+	}
+
+	server := rpc.NewServer()
+	rpcd := NewRPCServerDriver(newDriver())
+	rpcd.APIVersion = version.APIVersion
+
+	assert.NoError(t, server.Register(rpcd))
+	assert.NoError(t, server.Register(NewRPCPluginServer(server, rpcd, newDriver)))
+
+	serverConn, clientConn := net.Pipe()
+	go server.ServeConn(serverConn)
+
+	return rpc.NewClient(clientConn)
+}
+
+func TestNegotiateVersion(t *testing.T) {
+	client := newTestPluginServer(t)
+	defer client.Close()
+
+	var apiVersion int
+
+	assert.NoError(t, client.Call("RPCServerDriver.NegotiateVersion", version.APIVersion, &apiVersion))
+	assert.Equal(t, version.APIVersion, apiVersion)
+
+	assert.NoError(t, client.Call("RPCServerDriver.NegotiateVersion", 1, &apiVersion))
+	assert.Equal(t, 1, apiVersion)
+
+	assert.NoError(t, client.Call("RPCServerDriver.GetVersion", struct{}{}, &apiVersion))
+	assert.Equal(t, version.MinAPIVersion, apiVersion)
+}
+
+type typedFlagsDriver struct {
+	*fakedriver.Driver
+}
+
+func (d *typedFlagsDriver) GetCreateFlags() []mcnflag.Flag {
+	return []mcnflag.Flag{
+		mcnflag.IntFlag{Name: "cpus", Value: 1},
+		mcnflag.DurationFlag{Name: "timeout", Value: time.Minute},
+		mcnflag.FloatFlag{Name: "ratio", Value: 0.5},
+		mcnflag.EnumFlag{Name: "size", Value: "small", Allowed: []string{"small", "large"}},
+		&mcnflag.SecretFlag{Name: "token", EnvVar: "TOKEN"},
+		mcnflag.FilePathFlag{Name: "script", Value: "/tmp/script"},
+	}
+}
+
+func TestGetCreateFlagsForV1Clients(t *testing.T) {
+	server := rpc.NewServer()
+	rpcd := NewRPCServerDriver(&typedFlagsDriver{&fakedriver.Driver{}})
+	rpcd.APIVersion = version.APIVersion
+	assert.NoError(t, server.Register(rpcd))
+
+	serverConn, clientConn := net.Pipe()
+	go server.ServeConn(serverConn)
+
+	client := rpc.NewClient(clientConn)
+	defer client.Close()
+
+	// Clients which don't negotiate a version speak version 1.
+	var flags []mcnflag.Flag
+	assert.NoError(t, client.Call("RPCServerDriver.GetCreateFlags", struct{}{}, &flags))
+	assert.Equal(t, []mcnflag.Flag{
+		&mcnflag.IntFlag{Name: "cpus", Value: 1},
+		&mcnflag.StringFlag{Name: "timeout", Value: "1m0s"},
+		&mcnflag.StringFlag{Name: "ratio", Value: "0.5"},
+		&mcnflag.StringFlag{Name: "size", Value: "small"},
+		&mcnflag.StringFlag{Name: "token", EnvVar: "TOKEN"},
+		&mcnflag.StringFlag{Name: "script", Value: "/tmp/script"},
+	}, flags)
+
+	var apiVersion int
+	assert.NoError(t, client.Call("RPCServerDriver.NegotiateVersion", version.APIVersion, &apiVersion))
+
+	flags = nil
+	assert.NoError(t, client.Call("RPCServerDriver.GetCreateFlags", struct{}{}, &flags))
+	assert.Equal(t, &mcnflag.DurationFlag{Name: "timeout", Value: time.Minute}, flags[1])
+	assert.Equal(t, &mcnflag.SecretFlag{Name: "token", EnvVar: "TOKEN"}, flags[4])
+}
+
+func TestRPCFlagsFromV1Clients(t *testing.T) {
+	flags := RPCFlags{Values: map[string]interface{}{"timeout": "1m0s", "ratio": "0.5"}}
+
+	assert.Equal(t, time.Minute, flags.Duration("timeout"))
+	assert.Equal(t, 0.5, flags.Float("ratio"))
+}
+
+func TestPluginServerServesSeveralMachines(t *testing.T) {
+	client := newTestPluginServer(t)
+	defer client.Close()
+
+	var fooService, barService, fooAgain string
+
+	assert.NoError(t, client.Call("RPCPluginServer.NewDriver", "foo", &fooService))
+	assert.NoError(t, client.Call("RPCPluginServer.NewDriver", "bar", &barService))
+	assert.NoError(t, client.Call("RPCPluginServer.NewDriver", "foo", &fooAgain))
+
+	assert.Equal(t, DriverServiceName("foo"), fooService)
+	assert.Equal(t, DriverServiceName("bar"), barService)
+	assert.Equal(t, fooService, fooAgain)
+
+	foo := &InternalClient{RPCClient: client, ServiceName: fooService}
+	bar := &InternalClient{RPCClient: client, ServiceName: barService}
+
+	assert.NoError(t, foo.Call("RPCServerDriver.SetConfigRaw", []byte(`{"MockName":"foo"}`), nil))
+	assert.NoError(t, bar.Call("RPCServerDriver.SetConfigRaw", []byte(`{"MockName":"bar"}`), nil))
+
+	var name string
+
+	assert.NoError(t, foo.Call("RPCServerDriver.GetMachineName", struct{}{}, &name))
+	assert.Equal(t, "foo", name)
+
+	assert.NoError(t, bar.Call("RPCServerDriver.GetMachineName", struct{}{}, &name))
+	assert.Equal(t, "bar", name)
+
+	// The default service is left alone.
+	assert.NoError(t, client.Call("RPCServerDriver.GetMachineName", struct{}{}, &name))
+	assert.Equal(t, "", name)
+}
+
+func TestPluginServerCloseDriver(t *testing.T) {
+	client := newTestPluginServer(t)
+	defer client.Close()
+
+	var service string
+	assert.NoError(t, client.Call("RPCPluginServer.NewDriver", "foo", &service))
+	assert.NoError(t, client.Call("RPCPluginServer.NewDriver", "foo", &service))
+
+	foo := &InternalClient{RPCClient: client, ServiceName: service}
+	assert.NoError(t, foo.Call("RPCServerDriver.SetConfigRaw", []byte(`{"MockName":"foo"}`), nil))
+
+	// The driver is kept until both clients are done with it.
+	var name string
+	assert.NoError(t, client.Call("RPCPluginServer.CloseDriver", service, nil))
+	assert.NoError(t, foo.Call("RPCServerDriver.GetMachineName", struct{}{}, &name))
+	assert.Equal(t, "foo", name)
+
+	assert.NoError(t, client.Call("RPCPluginServer.CloseDriver", service, nil))
+	assert.Error(t, foo.Call("RPCServerDriver.GetURL", struct{}{}, &name))
+	assert.Error(t, client.Call("RPCPluginServer.CloseDriver", service, nil))
+
+	// Asking for the machine again gives a new driver.
+	assert.NoError(t, client.Call("RPCPluginServer.NewDriver", "foo", &service))
+	assert.NoError(t, foo.Call("RPCServerDriver.GetMachineName", struct{}{}, &name))
+	assert.Equal(t, "", name)
+}
+
+func TestMachineNameFromConfig(t *testing.T) {
+	assert.Equal(t, "foo", machineNameFromConfig([]byte(`{"MachineName":"foo","StorePath":"/tmp"}`)))
+	assert.Equal(t, "", machineNameFromConfig([]byte(`{}`)))
+	assert.Equal(t, "", machineNameFromConfig([]byte(`not json`)))
+}
+
+type commandsDriver struct {
+	*fakedriver.Driver
+}
+
+func (d *commandsDriver) GetCommands() []drivers.Command {
+	return []drivers.Command{
+		{
+			Name:  "echo",
+			Usage: "Print the arguments",
+			Flags: []mcnflag.Flag{
+				mcnflag.StringFlag{Name: "prefix", Value: ">"},
+			},
+		},
+	}
+}
+
+func (d *commandsDriver) RunCommand(name string, flags drivers.DriverOptions, args []string) (string, error) {
+	return flags.String("prefix") + " " + strings.Join(args, " "), nil
+}
+
+func TestDriverCommands(t *testing.T) {
+	server := rpc.NewServer()
+	assert.NoError(t, server.Register(NewRPCServerDriver(&commandsDriver{&fakedriver.Driver{}})))
+
+	serverConn, clientConn := net.Pipe()
+	go server.ServeConn(serverConn)
+
+	client := rpc.NewClient(clientConn)
+	defer client.Close()
+
+	var commands []drivers.Command
+	assert.NoError(t, client.Call("RPCServerDriver.GetCommands", struct{}{}, &commands))
+	assert.Equal(t, []drivers.Command{
+		{
+			Name:  "echo",
+			Usage: "Print the arguments",
+			Flags: []mcnflag.Flag{
+				&mcnflag.StringFlag{Name: "prefix", Value: ">"},
+			},
+		},
+	}, commands)
+
+	var output string
+	args := &RunCommandArgs{
+		Name:  "echo",
+		Flags: RPCFlags{Values: map[string]interface{}{"prefix": "$"}},
+		Args:  []string{"hello", "world"},
+	}
+	assert.NoError(t, client.Call("RPCServerDriver.RunCommand", args, &output))
+	assert.Equal(t, "$ hello world", output)
+
+	args.Name = "nope"
+	assert.EqualError(t, client.Call("RPCServerDriver.RunCommand", args, &output), `Driver "Driver" has no command "nope"`)
+}
+
+func TestDriverWithoutCommands(t *testing.T) {
+	client := newTestPluginServer(t)
+	defer client.Close()
+
+	var commands []drivers.Command
+	assert.NoError(t, client.Call("RPCServerDriver.GetCommands", struct{}{}, &commands))
+	assert.Empty(t, commands)
+}
+
+type secretsDriver struct {
+	*fakedriver.Driver
+	Token string
+}
+
+func (d *secretsDriver) GetSecrets() []string {
+	return []string{d.Token}
+}
+
+func TestDriverSecretsMaskedOnLoad(t *testing.T) {
+	server := rpc.NewServer()
+	assert.NoError(t, server.Register(NewRPCServerDriver(&secretsDriver{Driver: &fakedriver.Driver{}})))
+
+	serverConn, clientConn := net.Pipe()
+	go server.ServeConn(serverConn)
+
+	client := rpc.NewClient(clientConn)
+	defer client.Close()
+
+	assert.NoError(t, client.Call("RPCServerDriver.SetConfigRaw", []byte(`{"Token":"l0aded-t0ken"}`), nil))
+	assert.Equal(t, "--token "+log.Mask, log.Redact("--token l0aded-t0ken"))
+
+	var secrets []string
+	assert.NoError(t, client.Call("RPCServerDriver.GetSecrets", struct{}{}, &secrets))
+	assert.Equal(t, []string{"l0aded-t0ken"}, secrets)
+}
+
+func TestDriverWithoutSecrets(t *testing.T) {
+	client := newTestPluginServer(t)
+	defer client.Close()
+
+	var secrets []string
+	assert.NoError(t, client.Call("RPCServerDriver.GetSecrets", struct{}{}, &secrets))
+	assert.Empty(t, secrets)
+}
+
+func TestRPCClientDriverResolveStorePath(t *testing.T) {
+	driver := &RPCClientDriver{config: []byte(`{"MachineName":"foo","StorePath":"/store"}`)}
+	assert.Equal(t, filepath.Join("/store", "machines", "foo", "known_hosts"), driver.ResolveStorePath("known_hosts"))
+
+	driver = &RPCClientDriver{config: []byte(`{}`)}
+	assert.Equal(t, "", driver.ResolveStorePath("known_hosts"))
+}
+
+func TestRPCClientDriverUseSSHAgent(t *testing.T) {
+	driver := &RPCClientDriver{config: []byte(`{"MachineName":"foo","SSHAgent":true}`)}
+	assert.True(t, driver.UseSSHAgent())
+
+	driver = &RPCClientDriver{config: []byte(`{"MachineName":"foo"}`)}
+	assert.False(t, driver.UseSSHAgent())
+}
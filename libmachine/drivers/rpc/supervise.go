@@ -0,0 +1,238 @@
+package rpcdriver
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnerror"
+)
+
+var (
+	// How long a plugin gets to exit once it has been asked to, or once
+	// its connection has gone away, before it is killed.
+	pluginExitTimeout = 2 * time.Second
+
+	// running are all the plugin processes started by this process, so
+	// that none of them outlives it.
+	runningLock = &sync.Mutex{}
+	running     = make(map[*pluginProcess]bool)
+
+	// idempotentCalls are the calls which are retried with a restarted
+	// plugin if the plugin exits while serving them.
+	idempotentCalls = map[string]bool{
+		"RPCServerDriver.DriverName":         true,
+		"RPCServerDriver.GetCapabilities":    true,
+		"RPCServerDriver.GetCommands":        true,
+		"RPCServerDriver.GetConfigRaw":       true,
+		"RPCServerDriver.GetCreateFlags":     true,
+		"RPCServerDriver.GetIP":              true,
+		"RPCServerDriver.GetMachineName":     true,
+		"RPCServerDriver.GetSecrets":         true,
+		"RPCServerDriver.GetSSHHostname":     true,
+		"RPCServerDriver.GetSSHKeyPath":      true,
+		"RPCServerDriver.GetSSHPort":         true,
+		"RPCServerDriver.GetSSHUsername":     true,
+		"RPCServerDriver.GetState":           true,
+		"RPCServerDriver.GetURL":             true,
+		"RPCServerDriver.GlobalArtifactPath": true,
+		"RPCServerDriver.LocalArtifactPath":  true,
+		"RPCServerDriver.SetConfigRaw":       true,
+	}
+)
+
+// ErrPluginExited is returned by calls to a plugin which exited while it
+// was still in use.
+type ErrPluginExited struct {
+	DriverName string
+	Err        error
+	Stderr     []string
+}
+
+func (e ErrPluginExited) Error() string {
+	msg := fmt.Sprintf("The plugin for driver %q exited unexpectedly", e.DriverName)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if len(e.Stderr) > 0 {
+		msg += "\nLast output of the plugin:\n    " + strings.Join(e.Stderr, "\n    ")
+	}
+	return msg
+}
+
+func (e ErrPluginExited) ExitCode() int {
+	return mcnerror.ExitCodeDriverUnavailable
+}
+
+// watch waits for the plugin process to exit and makes sure that nothing
+// uses it afterwards.
+func (p *pluginProcess) watch() {
+	err := p.plugin.Wait()
+
+	p.lock.Lock()
+	if !p.closing {
+		p.exitErr = ErrPluginExited{
+			DriverName: p.driverName,
+			Err:        err,
+			Stderr:     p.plugin.Stderr(),
+		}
+		log.Debugf("Plugin for driver %q exited: %s", p.driverName, err)
+	}
+	p.lock.Unlock()
+
+	p.forget()
+	close(p.exitedCh)
+
+	// Fail the calls still waiting for an answer.
+	p.client.RPCClient.Close()
+}
+
+func isConnectionError(err error) bool {
+	if err == rpc.ErrShutdown || err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+	_, ok := err.(net.Error)
+	return ok
+}
+
+// exited tells why the plugin process went away if err means that it did,
+// or returns nil otherwise.
+func (p *pluginProcess) exited(err error) error {
+	if !isConnectionError(err) {
+		return nil
+	}
+
+	select {
+	case <-p.exitedCh:
+	case <-time.After(pluginExitTimeout):
+		// The plugin is still running, but we can't talk to it any more.
+		log.Debugf("Lost the connection to the plugin for driver %q, killing it", p.driverName)
+		p.plugin.Kill()
+		<-p.exitedCh
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.exitErr
+}
+
+// CloseAllPlugins shuts down every plugin process started by this process.
+// It is meant to be called before the process exits.
+func CloseAllPlugins() {
+	runningLock.Lock()
+	processes := []*pluginProcess{}
+	for p := range running {
+		processes = append(processes, p)
+	}
+	runningLock.Unlock()
+
+	var wg sync.WaitGroup
+	for _, p := range processes {
+		wg.Add(1)
+		go func(p *pluginProcess) {
+			defer wg.Done()
+			if err := p.close(); err != nil {
+				log.Debugf("Error closing plugin for driver %q: %s", p.driverName, err)
+			}
+		}(p)
+	}
+	wg.Wait()
+}
+
+// call makes a call to the plugin.  If the plugin has exited, calls which
+// are safe to repeat are retried once with a restarted plugin, which gets
+// the last known configuration of the driver first.
+func (c *RPCClientDriver) call(method string, args interface{}, reply interface{}) error {
+	c.lock.Lock()
+	client, process := c.Client, c.process
+	c.lock.Unlock()
+
+	err := client.Call(method, args, reply)
+	if err != nil {
+		exitErr := process.exited(err)
+		if exitErr == nil {
+			if !idempotentCalls[method] {
+				// A failed call may have changed the configuration half
+				// way, which is what a restarted plugin would need.
+				// Successful ones are picked up by the GetConfigRaw made
+				// whenever the host is saved.
+				c.refreshConfig(client)
+			}
+			return err
+		}
+
+		if !idempotentCalls[method] {
+			return exitErr
+		}
+
+		if err := c.restart(process); err != nil {
+			log.Debugf("Error restarting the plugin for driver %q: %s", c.driverName, err)
+			return exitErr
+		}
+
+		c.lock.Lock()
+		client = c.Client
+		c.lock.Unlock()
+
+		if err := client.Call(method, args, reply); err != nil {
+			return err
+		}
+	}
+
+	switch method {
+	case "RPCServerDriver.SetConfigRaw":
+		c.setConfig(args.([]byte))
+	case "RPCServerDriver.GetConfigRaw":
+		c.setConfig(*reply.(*[]byte))
+	}
+
+	return nil
+}
+
+func (c *RPCClientDriver) refreshConfig(client *InternalClient) {
+	var data []byte
+	if err := client.Call("RPCServerDriver.GetConfigRaw", struct{}{}, &data); err != nil {
+		log.Debugf("Error refreshing driver configuration: %s", err)
+		return
+	}
+	c.setConfig(data)
+}
+
+func (c *RPCClientDriver) setConfig(data []byte) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.config = data
+}
+
+// restart replaces the crashed plugin process, unless another call has
+// already done so.
+func (c *RPCClientDriver) restart(crashed *pluginProcess) error {
+	c.restartLock.Lock()
+	defer c.restartLock.Unlock()
+
+	c.lock.Lock()
+	current, config := c.process, c.config
+	c.lock.Unlock()
+
+	if current != crashed {
+		return nil
+	}
+
+	log.Warnf("The plugin for driver %q exited unexpectedly, restarting it", c.driverName)
+
+	if err := c.connect(config); err != nil {
+		return err
+	}
+
+	if err := crashed.release(); err != nil {
+		log.Debugf("Error closing the crashed plugin for driver %q: %s", c.driverName, err)
+	}
+
+	return nil
+}
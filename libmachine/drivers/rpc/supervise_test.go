@@ -0,0 +1,164 @@
+package rpcdriver_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/drivers"
+	"github.com/docker/machine/libmachine/drivers/plugin"
+	"github.com/docker/machine/libmachine/drivers/plugin/localbinary"
+	"github.com/docker/machine/libmachine/drivers/rpc"
+	"github.com/docker/machine/libmachine/events"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/stretchr/testify/assert"
+)
+
+const crashDirEnvKey = "MACHINE_TEST_CRASH_DIR"
+
+// crashingDriver exits the plugin when a file named after the method being
+// called exists in the crash directory.
+type crashingDriver struct {
+	*fakedriver.Driver
+}
+
+func crash(method string) {
+	path := filepath.Join(os.Getenv(crashDirEnvKey), method)
+	if _, err := os.Stat(path); err == nil {
+		os.Remove(path)
+		fmt.Fprintf(os.Stderr, "%s went boom\n", method)
+		os.Exit(3)
+	}
+}
+
+func (d *crashingDriver) GetURL() (string, error) {
+	crash("GetURL")
+	return d.Driver.GetURL()
+}
+
+// Create reports progress, so that events can be tested too.
+func (d *crashingDriver) Create() error {
+	crash("Create")
+	events.Progress(d.MachineName, "Creating", 50, "Half way there")
+	log.Info("Created")
+	return d.Driver.Create()
+}
+
+// GetIP tells which plugin process serves the machine.
+func (d *crashingDriver) GetIP() (string, error) {
+	return fmt.Sprint(os.Getpid()), nil
+}
+
+func (d *crashingDriver) Start() error {
+	crash("Start")
+	return d.Driver.Start()
+}
+
+// TestMain lets the test binary double as a driver plugin.
+func TestMain(m *testing.M) {
+	if os.Getenv(crashDirEnvKey) != "" && os.Getenv(localbinary.PluginEnvKey) == localbinary.PluginEnvVal {
+		plugin.RegisterDriverFactory(func() drivers.Driver {
+			return &crashingDriver{&fakedriver.Driver{}}
+		})
+		return
+	}
+
+	os.Exit(m.Run())
+}
+
+func setupCrashingPlugin(t *testing.T) (string, func()) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test binary can't be linked as a plugin on Windows")
+	}
+
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+
+	pluginDir := filepath.Join(dir, "drivers")
+	crashDir := filepath.Join(dir, "crash")
+	assert.NoError(t, os.Mkdir(pluginDir, 0700))
+	assert.NoError(t, os.Mkdir(crashDir, 0700))
+
+	binary, err := filepath.Abs(os.Args[0])
+	assert.NoError(t, err)
+	assert.NoError(t, os.Symlink(binary, filepath.Join(pluginDir, localbinary.BinaryName("crashing"))))
+
+	localbinary.PluginDir = pluginDir
+	os.Setenv(crashDirEnvKey, crashDir)
+
+	return crashDir, func() {
+		rpcdriver.CloseAllPlugins()
+		localbinary.PluginDir = ""
+		os.Unsetenv(crashDirEnvKey)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestPluginRestartedForIdempotentCalls(t *testing.T) {
+	crashDir, cleanup := setupCrashingPlugin(t)
+	defer cleanup()
+
+	d, err := rpcdriver.NewRPCClientDriver([]byte(`{"MachineName":"foo","MockURL":"tcp://1.2.3.4:2376"}`), "crashing")
+	assert.NoError(t, err)
+	defer d.Close()
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(crashDir, "GetURL"), nil, 0600))
+
+	// The restarted plugin must have been given the configuration again.
+	url, err := d.GetURL()
+	assert.NoError(t, err)
+	assert.Equal(t, "tcp://1.2.3.4:2376", url)
+}
+
+func TestPluginExitReported(t *testing.T) {
+	crashDir, cleanup := setupCrashingPlugin(t)
+	defer cleanup()
+
+	d, err := rpcdriver.NewRPCClientDriver([]byte(`{"MachineName":"foo"}`), "crashing")
+	assert.NoError(t, err)
+	defer d.Close()
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(crashDir, "Start"), nil, 0600))
+
+	err = d.Start()
+
+	exitErr, ok := err.(rpcdriver.ErrPluginExited)
+	if assert.True(t, ok, "unexpected error %v", err) {
+		assert.Equal(t, "crashing", exitErr.DriverName)
+		assert.Contains(t, exitErr.Stderr, "Start went boom")
+		assert.Contains(t, exitErr.Error(), "exit status 3")
+	}
+}
+
+func TestPluginSharedByConcurrentMachines(t *testing.T) {
+	_, cleanup := setupCrashingPlugin(t)
+	defer cleanup()
+
+	var wg sync.WaitGroup
+	pids := make([]string, 4)
+	for i := range pids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			d, err := rpcdriver.NewRPCClientDriver([]byte(fmt.Sprintf(`{"MachineName":"machine%d"}`, i)), "crashing")
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer d.Close()
+
+			pids[i], err = d.GetIP()
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, pid := range pids {
+		assert.Equal(t, pids[0], pid)
+	}
+}
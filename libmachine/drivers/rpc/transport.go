@@ -0,0 +1,218 @@
+package rpcdriver
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+	"os"
+	"strings"
+
+	"github.com/docker/machine/libmachine/log"
+)
+
+const (
+	unixScheme = "unix://"
+	tcpScheme  = "tcp://"
+
+	// jsonSchemePrefix marks the address of a plugin which speaks the JSON
+	// protocol, e.g. jsonrpc+unix:///path/to/plugin.sock.
+	jsonSchemePrefix = "jsonrpc+"
+)
+
+var errInvalidToken = errors.New("Invalid plugin token")
+
+// authClientCodec is the gob codec of net/rpc, except that every request is
+// preceded by the token the plugin was launched with.
+type authClientCodec struct {
+	rwc    io.ReadWriteCloser
+	dec    *gob.Decoder
+	enc    *gob.Encoder
+	encBuf *bufio.Writer
+	token  string
+}
+
+// NewAuthClientCodec returns a codec which sends the token with every call.
+func NewAuthClientCodec(conn io.ReadWriteCloser, token string) rpc.ClientCodec {
+	encBuf := bufio.NewWriter(conn)
+	return &authClientCodec{
+		rwc:    conn,
+		dec:    gob.NewDecoder(conn),
+		enc:    gob.NewEncoder(encBuf),
+		encBuf: encBuf,
+		token:  token,
+	}
+}
+
+func (c *authClientCodec) WriteRequest(r *rpc.Request, body interface{}) error {
+	if err := c.enc.Encode(c.token); err != nil {
+		return err
+	}
+	if err := c.enc.Encode(r); err != nil {
+		return err
+	}
+	if err := c.enc.Encode(body); err != nil {
+		return err
+	}
+	return c.encBuf.Flush()
+}
+
+func (c *authClientCodec) ReadResponseHeader(r *rpc.Response) error {
+	return c.dec.Decode(r)
+}
+
+func (c *authClientCodec) ReadResponseBody(body interface{}) error {
+	return c.dec.Decode(body)
+}
+
+func (c *authClientCodec) Close() error {
+	return c.rwc.Close()
+}
+
+func validToken(token, expected string) bool {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+// authServerCodec checks the token of every call and drops the connection
+// as soon as one doesn't match.
+type authServerCodec struct {
+	rwc    io.ReadWriteCloser
+	dec    *gob.Decoder
+	enc    *gob.Encoder
+	encBuf *bufio.Writer
+	token  string
+	closed bool
+}
+
+// NewAuthServerCodec returns a codec which only accepts calls carrying the
+// token.
+func NewAuthServerCodec(conn io.ReadWriteCloser, token string) rpc.ServerCodec {
+	encBuf := bufio.NewWriter(conn)
+	return &authServerCodec{
+		rwc:    conn,
+		dec:    gob.NewDecoder(conn),
+		enc:    gob.NewEncoder(encBuf),
+		encBuf: encBuf,
+		token:  token,
+	}
+}
+
+func (c *authServerCodec) ReadRequestHeader(r *rpc.Request) error {
+	var token string
+	if err := c.dec.Decode(&token); err != nil {
+		return err
+	}
+
+	if !validToken(token, c.token) {
+		return errInvalidToken
+	}
+
+	return c.dec.Decode(r)
+}
+
+func (c *authServerCodec) ReadRequestBody(body interface{}) error {
+	return c.dec.Decode(body)
+}
+
+func (c *authServerCodec) WriteResponse(r *rpc.Response, body interface{}) error {
+	if err := c.enc.Encode(r); err != nil {
+		if c.encBuf.Flush() == nil {
+			// Couldn't encode the header, which should never happen.
+			c.Close()
+		}
+		return err
+	}
+	if err := c.enc.Encode(body); err != nil {
+		if c.encBuf.Flush() == nil {
+			// Was a gob problem encoding the body but the header has been
+			// written, so shut down the connection to signal that the
+			// connection is broken.
+			c.Close()
+		}
+		return err
+	}
+	return c.encBuf.Flush()
+}
+
+func (c *authServerCodec) Close() error {
+	if c.closed {
+		// Only call c.rwc.Close once; otherwise the semantics are undefined.
+		return nil
+	}
+	c.closed = true
+	return c.rwc.Close()
+}
+
+// Listen opens the listener of a plugin server.  The server listens on a
+// Unix socket at socketPath which only the current user can use, or on a
+// random local TCP port if socketPath is empty.  The returned address is
+// what the plugin prints for the client.
+func Listen(socketPath string) (net.Listener, string, error) {
+	if socketPath == "" {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, "", err
+		}
+		return listener, tcpScheme + listener.Addr().String(), nil
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		listener.Close()
+		return nil, "", err
+	}
+
+	return listener, unixScheme + socketPath, nil
+}
+
+// ServeAuthenticated serves the calls which carry the token on the
+// connections accepted by the listener, until the listener is closed.
+func ServeAuthenticated(listener net.Listener, server *rpc.Server, token string) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go server.ServeCodec(NewAuthServerCodec(conn, token))
+	}
+}
+
+// DialPlugin connects to a plugin server at the address it printed.  The
+// address tells which protocol the plugin speaks: Go plugins use gob, other
+// plugins prefix the address with "jsonrpc+" to use JSON.  Plugins which
+// predate authenticated transports print a bare TCP address and serve HTTP
+// RPC without a token.
+func DialPlugin(addr, token string) (*rpc.Client, error) {
+	newCodec := NewAuthClientCodec
+	if strings.HasPrefix(addr, jsonSchemePrefix) {
+		newCodec = NewJSONClientCodec
+		addr = strings.TrimPrefix(addr, jsonSchemePrefix)
+	}
+
+	network := ""
+
+	switch {
+	case strings.HasPrefix(addr, unixScheme):
+		network, addr = "unix", strings.TrimPrefix(addr, unixScheme)
+	case strings.HasPrefix(addr, tcpScheme):
+		network, addr = "tcp", strings.TrimPrefix(addr, tcpScheme)
+	default:
+		log.Debugf("Plugin at %s doesn't support authentication, falling back to HTTP RPC", addr)
+		return rpc.DialHTTP("tcp", addr)
+	}
+
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("Error dialing plugin server: %s", err)
+	}
+
+	return rpc.NewClientWithCodec(newCodec(conn, token)), nil
+}
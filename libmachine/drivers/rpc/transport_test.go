@@ -0,0 +1,97 @@
+package rpcdriver
+
+import (
+	"io/ioutil"
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T) *rpc.Server {
+	server := rpc.NewServer()
+	assert.NoError(t, server.Register(NewRPCServerDriver(&fakedriver.Driver{MockName: "foo"})))
+	return server
+}
+
+func TestAuthCodecAcceptsToken(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	go newTestServer(t).ServeCodec(NewAuthServerCodec(serverConn, "secret"))
+
+	client := rpc.NewClientWithCodec(NewAuthClientCodec(clientConn, "secret"))
+	defer client.Close()
+
+	var name string
+	assert.NoError(t, client.Call("RPCServerDriver.GetMachineName", struct{}{}, &name))
+	assert.Equal(t, "foo", name)
+
+	// Every call carries the token, not just the first one.
+	assert.NoError(t, client.Call("RPCServerDriver.GetMachineName", struct{}{}, &name))
+}
+
+func TestAuthCodecRejectsWrongToken(t *testing.T) {
+	serverConn, clientConn := net.Pipe()
+	go newTestServer(t).ServeCodec(NewAuthServerCodec(serverConn, "secret"))
+
+	client := rpc.NewClientWithCodec(NewAuthClientCodec(clientConn, "guess"))
+	defer client.Close()
+
+	var config []byte
+	assert.Error(t, client.Call("RPCServerDriver.GetConfigRaw", struct{}{}, &config))
+	assert.Empty(t, config)
+}
+
+func TestListenUnixSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no Unix sockets on Windows")
+	}
+
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	socketPath := filepath.Join(dir, "plugin.sock")
+
+	listener, addr, err := Listen(socketPath)
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	assert.Equal(t, "unix://"+socketPath, addr)
+
+	info, err := os.Stat(socketPath)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	go ServeAuthenticated(listener, newTestServer(t), "secret")
+
+	client, err := DialPlugin(addr, "secret")
+	assert.NoError(t, err)
+	defer client.Close()
+
+	var name string
+	assert.NoError(t, client.Call("RPCServerDriver.GetMachineName", struct{}{}, &name))
+	assert.Equal(t, "foo", name)
+}
+
+func TestListenTCPFallback(t *testing.T) {
+	listener, addr, err := Listen("")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	assert.Equal(t, "tcp://"+listener.Addr().String(), addr)
+
+	go ServeAuthenticated(listener, newTestServer(t), "secret")
+
+	client, err := DialPlugin(addr, "secret")
+	assert.NoError(t, err)
+	defer client.Close()
+
+	var name string
+	assert.NoError(t, client.Call("RPCServerDriver.GetMachineName", struct{}{}, &name))
+	assert.Equal(t, "foo", name)
+}
@@ -0,0 +1,24 @@
+package drivers
+
+// SecretHolder is implemented by drivers whose configuration holds secrets,
+// the values of their SecretFlags, so that they are kept out of the logs
+// whenever the machine is loaded, not only when it's created.
+//
+// GetSecrets is called once the configuration is loaded, both in Machine and
+// in the plugin, and everything it returns is masked in their logs from then
+// on.  It returns the fields of the configuration as they are: values which
+// aren't set, or are too short to be told apart from ordinary words, are
+// ignored.  A driver embedding another one adds its secrets to those of the
+// embedded driver.
+type SecretHolder interface {
+	// GetSecrets returns the secrets of the configuration, e.g. API keys.
+	GetSecrets() []string
+}
+
+// GetSecrets returns the secrets held by the driver, if any.
+func GetSecrets(d Driver) []string {
+	if holder, ok := d.(SecretHolder); ok {
+		return holder.GetSecrets()
+	}
+	return []string{}
+}
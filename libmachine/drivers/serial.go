@@ -16,8 +16,10 @@ var stdLock = &sync.Mutex{}
 // up against VirtualBox's own locking mechanisms.
 //
 // It would be preferable to simply have a lock around, say, the VBoxManage
-// command, but with our current one-server-process-per-machine model it is
-// impossible to dictate this locking on the server side.
+// command.  Plugins for API version 2 serve all the machines of a driver
+// from one process, but older ones still run one server process per
+// machine, where it is impossible to dictate this locking on the server
+// side.
 type SerialDriver struct {
 	Driver
 	sync.Locker
@@ -56,6 +58,39 @@ func (d *SerialDriver) GetCreateFlags() []mcnflag.Flag {
 	return d.Driver.GetCreateFlags()
 }
 
+// GetCapabilities returns the actions the driver supports
+func (d *SerialDriver) GetCapabilities() Capabilities {
+	d.Lock()
+	defer d.Unlock()
+	return GetCapabilities(d.Driver)
+}
+
+// ResolveStorePath returns the path of a file of the machine, or "" if the
+// wrapped driver can't tell where the files of the machine are.
+func (d *SerialDriver) ResolveStorePath(file string) string {
+	resolver, ok := d.Driver.(StorePathResolver)
+	if !ok {
+		return ""
+	}
+	return resolver.ResolveStorePath(file)
+}
+
+// GetSSHJumpHost returns the jump host of the wrapped driver, if it has
+// one.
+func (d *SerialDriver) GetSSHJumpHost() (string, string) {
+	getter, ok := d.Driver.(SSHJumpHostGetter)
+	if !ok {
+		return "", ""
+	}
+	return getter.GetSSHJumpHost()
+}
+
+// UseSSHAgent tells if the wrapped driver logs in with the keys of the
+// running SSH agent.
+func (d *SerialDriver) UseSSHAgent() bool {
+	return UseSSHAgent(d.Driver)
+}
+
 // GetIP returns an IP or hostname that this host is available at
 // e.g. 1.2.3.4 or docker-host-d60b70a14d3a.cloudapp.net
 func (d *SerialDriver) GetIP() (string, error) {
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/docker/machine/libmachine/mcnflag"
+	"github.com/docker/machine/libmachine/ssh"
 	"github.com/docker/machine/libmachine/state"
 	"github.com/stretchr/testify/assert"
 )
@@ -294,3 +295,76 @@ func TestSerialDriverStop(t *testing.T) {
 
 	assert.Equal(t, []string{"Lock", "Stop", "Unlock"}, callRecorder.calls)
 }
+
+type MockStoreDriver struct {
+	*MockDriver
+}
+
+func (d *MockStoreDriver) ResolveStorePath(file string) string {
+	return "/store/machines/" + d.machineName + "/" + file
+}
+
+func TestSerialDriverKnownHostsPath(t *testing.T) {
+	callRecorder := &CallRecorder{}
+
+	driver := newSerialDriverWithLock(&MockStoreDriver{&MockDriver{machineName: "default", calls: callRecorder}}, &MockLocker{calls: callRecorder})
+	assert.Equal(t, "/store/machines/default/known_hosts", KnownHostsPath(driver))
+
+	driver = newSerialDriverWithLock(&MockStoreDriver{&MockDriver{calls: callRecorder}}, &MockLocker{calls: callRecorder})
+	assert.Equal(t, "", KnownHostsPath(driver))
+
+	driver = newSerialDriverWithLock(&MockDriver{machineName: "default", calls: callRecorder}, &MockLocker{calls: callRecorder})
+	assert.Equal(t, "", KnownHostsPath(driver))
+}
+
+type MockJumpDriver struct {
+	*MockDriver
+	jumpHost, jumpKeyPath string
+}
+
+func (d *MockJumpDriver) GetSSHJumpHost() (string, string) {
+	return d.jumpHost, d.jumpKeyPath
+}
+
+func TestSerialDriverGetSSHJumpHost(t *testing.T) {
+	callRecorder := &CallRecorder{}
+	machine := &MockDriver{sshUsername: "docker", sshKeyPath: "/store/id_rsa", calls: callRecorder}
+
+	driver := newSerialDriverWithLock(&MockJumpDriver{MockDriver: machine, jumpHost: "bastion"}, &MockLocker{calls: callRecorder})
+	jump, err := GetSSHJumpHost(driver)
+	assert.NoError(t, err)
+	assert.Equal(t, &ssh.JumpHost{User: "docker", Hostname: "bastion", Port: 22, Keys: []string{"/store/id_rsa"}}, jump)
+
+	driver = newSerialDriverWithLock(&MockJumpDriver{MockDriver: machine, jumpHost: "ubuntu@bastion:2222", jumpKeyPath: "/keys/bastion"}, &MockLocker{calls: callRecorder})
+	jump, err = GetSSHJumpHost(driver)
+	assert.NoError(t, err)
+	assert.Equal(t, &ssh.JumpHost{User: "ubuntu", Hostname: "bastion", Port: 2222, Keys: []string{"/keys/bastion"}}, jump)
+
+	driver = newSerialDriverWithLock(&MockJumpDriver{MockDriver: machine}, &MockLocker{calls: callRecorder})
+	jump, err = GetSSHJumpHost(driver)
+	assert.NoError(t, err)
+	assert.Nil(t, jump)
+
+	driver = newSerialDriverWithLock(machine, &MockLocker{calls: callRecorder})
+	jump, err = GetSSHJumpHost(driver)
+	assert.NoError(t, err)
+	assert.Nil(t, jump)
+}
+
+type MockAgentDriver struct {
+	*MockDriver
+}
+
+func (d *MockAgentDriver) UseSSHAgent() bool {
+	return true
+}
+
+func TestSerialDriverUseSSHAgent(t *testing.T) {
+	callRecorder := &CallRecorder{}
+
+	driver := newSerialDriverWithLock(&MockAgentDriver{&MockDriver{calls: callRecorder}}, &MockLocker{calls: callRecorder})
+	assert.True(t, UseSSHAgent(driver))
+
+	driver = newSerialDriverWithLock(&MockDriver{calls: callRecorder}, &MockLocker{calls: callRecorder})
+	assert.False(t, UseSSHAgent(driver))
+}
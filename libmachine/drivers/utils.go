@@ -1,14 +1,31 @@
 package drivers
 
 import (
+	"errors"
 	"fmt"
+	"io"
 
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/mcnutils"
 	"github.com/docker/machine/libmachine/ssh"
+	"github.com/docker/machine/libmachine/timing"
 )
 
+const knownHostsFile = "known_hosts"
+
 func GetSSHClientFromDriver(d Driver) (ssh.Client, error) {
+	return getSSHClient(d, false, mcnutils.GetBackoffPolicy(d.DriverName()))
+}
+
+// GetSSHClientForwardingAgent returns an SSH client of the machine which
+// lets the commands run on it use the running SSH agent.
+func GetSSHClientForwardingAgent(d Driver) (ssh.Client, error) {
+	return getSSHClient(d, true, mcnutils.GetBackoffPolicy(d.DriverName()))
+}
+
+// getSSHClient returns an SSH client of the machine which retries dialing
+// it following backoff.
+func getSSHClient(d Driver, forwardAgent bool, backoff *mcnutils.BackoffPolicy) (ssh.Client, error) {
 	address, err := d.GetSSHHostname()
 	if err != nil {
 		return nil, err
@@ -19,8 +36,19 @@ func GetSSHClientFromDriver(d Driver) (ssh.Client, error) {
 		return nil, err
 	}
 
+	jump, err := GetSSHJumpHost(d)
+	if err != nil {
+		return nil, err
+	}
+
 	auth := &ssh.Auth{
-		Keys: []string{d.GetSSHKeyPath()},
+		Keys:         []string{d.GetSSHKeyPath()},
+		Agent:        UseSSHAgent(d),
+		ForwardAgent: forwardAgent,
+		KnownHosts:   KnownHostsPath(d),
+		HostKeyAlias: HostKeyAlias(d),
+		Jump:         jump,
+		Backoff:      backoff,
 	}
 
 	client, err := ssh.NewClient(d.GetSSHUsername(), address, port, auth)
@@ -28,6 +56,81 @@ func GetSSHClientFromDriver(d Driver) (ssh.Client, error) {
 
 }
 
+// SSHAgentUser is implemented by drivers whose machines can be logged in to
+// with the keys of the running SSH agent, as all the drivers embedding
+// BaseDriver can.
+type SSHAgentUser interface {
+	UseSSHAgent() bool
+}
+
+// UseSSHAgent tells if the machine is logged in to with the keys of the
+// running SSH agent.
+func UseSSHAgent(d Driver) bool {
+	user, ok := d.(SSHAgentUser)
+	return ok && user.UseSSHAgent()
+}
+
+// SSHJumpHostGetter is implemented by drivers whose machines can be reached
+// through a jump host, as all the drivers embedding BaseDriver can.
+type SSHJumpHostGetter interface {
+	GetSSHJumpHost() (string, string)
+}
+
+// GetSSHJumpHost returns the jump host the machine is reached through, or
+// nil if it's reached directly.  The user and the key default to the ones
+// of the machine.
+func GetSSHJumpHost(d Driver) (*ssh.JumpHost, error) {
+	getter, ok := d.(SSHJumpHostGetter)
+	if !ok {
+		return nil, nil
+	}
+
+	spec, keyPath := getter.GetSSHJumpHost()
+	if spec == "" {
+		return nil, nil
+	}
+
+	jump, err := ssh.ParseJumpHost(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	if jump.User == "" {
+		jump.User = d.GetSSHUsername()
+	}
+
+	if keyPath == "" {
+		keyPath = d.GetSSHKeyPath()
+	}
+	jump.Keys = []string{keyPath}
+
+	return jump, nil
+}
+
+// StorePathResolver is implemented by drivers which know where the files of
+// their machine are kept, as all the drivers embedding BaseDriver do.
+type StorePathResolver interface {
+	ResolveStorePath(file string) string
+}
+
+// KnownHostsPath returns the known_hosts file where the SSH host key of the
+// machine is recorded, or "" if the driver can't tell where the files of
+// the machine are.
+func KnownHostsPath(d Driver) string {
+	resolver, ok := d.(StorePathResolver)
+	if !ok || d.GetMachineName() == "" {
+		return ""
+	}
+	return resolver.ResolveStorePath(knownHostsFile)
+}
+
+// HostKeyAlias returns the name the SSH host key of the machine is recorded
+// under in its known_hosts file, so that the key is checked whatever address
+// the machine gets.
+func HostKeyAlias(d Driver) string {
+	return "docker-machine:" + d.GetMachineName()
+}
+
 func RunSSHCommandFromDriver(d Driver, command string) (string, error) {
 	client, err := GetSSHClientFromDriver(d)
 	if err != nil {
@@ -39,31 +142,75 @@ func RunSSHCommandFromDriver(d Driver, command string) (string, error) {
 	output, err := client.Output(command)
 	log.Debugf("SSH cmd err, output: %v: %s", err, output)
 	if err != nil {
-		return "", fmt.Errorf(`Something went wrong running an SSH command!
+		return "", errors.New(log.Redact(fmt.Sprintf(`Something went wrong running an SSH command!
 command : %s
 err     : %v
 output  : %s
-`, command, err, output)
+`, command, err, output)))
 	}
 
 	return output, nil
 }
 
-func sshAvailableFunc(d Driver) func() bool {
-	return func() bool {
+// RunSSHCommand runs a command on the machine, and returns what it printed
+// on its standard output and error apart, and its exit status.  If the
+// command fails, the error is an *ssh.ExitError.
+func RunSSHCommand(d Driver, command string) (*ssh.Result, error) {
+	client, err := GetSSHClientFromDriver(d)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debugf("About to run SSH command:\n%s", command)
+
+	result, err := client.Run(command)
+	if result != nil {
+		log.Debugf("SSH cmd err, exit status, stdout, stderr: %v: %d: %s: %s", err, result.ExitStatus, result.Stdout, result.Stderr)
+	}
+
+	return result, err
+}
+
+// StreamSSHCommand runs a command on the machine, writing what it prints on
+// its standard output and error to the writers as it runs.  If the command
+// fails, the error is an *ssh.ExitError.
+func StreamSSHCommand(d Driver, command string, stdout, stderr io.Writer) error {
+	client, err := GetSSHClientFromDriver(d)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("About to stream SSH command:\n%s", command)
+
+	return client.Stream(command, stdout, stderr)
+}
+
+// sshAvailableFunc tries to run a command on the machine once, WaitForSSH
+// does the retrying.
+func sshAvailableFunc(d Driver) func() error {
+	return func() error {
 		log.Debug("Getting to WaitForSSH function...")
-		if _, err := RunSSHCommandFromDriver(d, "exit 0"); err != nil {
+		client, err := getSSHClient(d, false, mcnutils.ConstantBackoffPolicy(1, 0))
+		if err != nil {
+			return err
+		}
+
+		if _, err := client.Output("exit 0"); err != nil {
 			log.Debugf("Error getting ssh command 'exit 0' : %s", err)
-			return false
+			return err
 		}
-		return true
+		return nil
 	}
 }
 
+// WaitForSSH waits until an SSH command can be run on the machine, following
+// the backoff policy configured for the driver.
 func WaitForSSH(d Driver) error {
-	// Try to dial SSH for 30 seconds before timing out.
-	if err := mcnutils.WaitFor(sshAvailableFunc(d)); err != nil {
-		return fmt.Errorf("Too many retries waiting for SSH to be available.  Last error: %s", err)
+	defer timing.Track("drivers.WaitForSSH")()
+
+	if err := mcnutils.RetryWithDriverPolicy(d.DriverName(), sshAvailableFunc(d)); err != nil {
+		// The error of the backoff policy tells the last error already.
+		return fmt.Errorf("Error waiting for SSH to be available: %s", err)
 	}
 	return nil
 }
@@ -0,0 +1,71 @@
+package drivers
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/docker/machine/libmachine/mcnutils"
+	"github.com/docker/machine/libmachine/ssh"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitForSSHError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	mcnutils.SetDriverBackoffPolicy("waitforssh", mcnutils.ConstantBackoffPolicy(2, time.Millisecond))
+	defer mcnutils.SetDriverBackoffPolicy("waitforssh", nil)
+
+	d := &MockDriver{calls: &CallRecorder{}, driverName: "waitforssh", sshHostname: "127.0.0.1", sshPort: port, sshUsername: "docker"}
+
+	err = WaitForSSH(d)
+	if assert.Error(t, err) {
+		assert.True(t, strings.HasPrefix(err.Error(), "Error waiting for SSH to be available: Maximum number of retries (2) exceeded"), err.Error())
+		assert.Equal(t, 1, strings.Count(strings.ToLower(err.Error()), "last error"), err.Error())
+	}
+}
+
+func TestWaitForSSHDialsOncePerAttempt(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	// The handshake fails once the connection is counted.
+	var dials int32
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&dials, 1)
+			conn.Close()
+		}
+	}()
+
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	keyPath := filepath.Join(dir, "id_rsa")
+	assert.NoError(t, ssh.GenerateSSHKey(keyPath))
+
+	ssh.SetDefaultClient(ssh.Native)
+	defer ssh.SetDefaultClient(ssh.External)
+
+	mcnutils.SetDriverBackoffPolicy("waitforssh", mcnutils.ConstantBackoffPolicy(3, time.Millisecond))
+	defer mcnutils.SetDriverBackoffPolicy("waitforssh", nil)
+
+	d := &MockDriver{calls: &CallRecorder{}, driverName: "waitforssh", sshHostname: "127.0.0.1", sshPort: listener.Addr().(*net.TCPAddr).Port, sshKeyPath: keyPath, sshUsername: "docker"}
+
+	assert.Error(t, WaitForSSH(d))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&dials))
+}
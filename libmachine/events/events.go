@@ -0,0 +1,110 @@
+// Package events lets drivers report what they are doing while an
+// operation runs, e.g. how much of an ISO has been copied, to whoever
+// subscribed.  Events reported by driver plugins are sent to the client
+// over RPC and published there, so subscribers get the same events whether
+// a driver runs in the process or as a plugin.
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Kind tells what an event is about.
+type Kind string
+
+const (
+	// KindProgress is the progress of a phase of a long operation.
+	KindProgress Kind = "progress"
+
+	// KindLog is a log record of a driver plugin.
+	KindLog Kind = "log"
+)
+
+// UnknownPercent is the Percent of a phase whose progress can't be told.
+const UnknownPercent = -1
+
+// Event is something a driver reports while it works.
+type Event struct {
+	Kind        Kind
+	Time        time.Time
+	MachineName string
+
+	// Phase is what's in progress, e.g. "Copying ISO".
+	Phase string
+
+	// Percent is how much of the phase is done, from 0 to 100, or
+	// UnknownPercent.
+	Percent int
+
+	// Level is the level of a log record: debug, info, warn or error.
+	Level string
+
+	Message string
+}
+
+// Handler is called with every event published.  Handlers must not block.
+type Handler func(Event)
+
+type subscriber struct {
+	id      int
+	handler Handler
+}
+
+var (
+	lock        = &sync.Mutex{}
+	subscribers = []subscriber{}
+	nextID      = 0
+
+	// now is a variable so that tests can control the clock.
+	now = time.Now
+)
+
+// Subscribe calls handler with every event published until the returned
+// function is called.
+func Subscribe(handler Handler) func() {
+	lock.Lock()
+	defer lock.Unlock()
+
+	id := nextID
+	nextID++
+	subscribers = append(subscribers, subscriber{id, handler})
+
+	return func() {
+		lock.Lock()
+		defer lock.Unlock()
+		for i, s := range subscribers {
+			if s.id == id {
+				subscribers = append(subscribers[:i:i], subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish hands the event to every subscriber, in the order they
+// subscribed.
+func Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = now()
+	}
+
+	lock.Lock()
+	subscribed := subscribers
+	lock.Unlock()
+
+	for _, s := range subscribed {
+		s.handler(event)
+	}
+}
+
+// Progress publishes the progress of a phase of an operation on a machine.
+func Progress(machineName, phase string, percent int, message string) {
+	Publish(Event{
+		Kind:        KindProgress,
+		MachineName: machineName,
+		Phase:       phase,
+		Percent:     percent,
+		Message:     message,
+	})
+}
@@ -0,0 +1,44 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubscribe(t *testing.T) {
+	now = func() time.Time { return time.Unix(42, 0) }
+	defer func() { now = time.Now }()
+
+	received := []string{}
+	unsubscribeFirst := Subscribe(func(e Event) {
+		received = append(received, "first "+e.Phase)
+	})
+	unsubscribeSecond := Subscribe(func(e Event) {
+		received = append(received, "second "+e.Phase)
+		assert.Equal(t, time.Unix(42, 0), e.Time)
+		assert.Equal(t, KindProgress, e.Kind)
+		assert.Equal(t, "default", e.MachineName)
+	})
+	defer unsubscribeSecond()
+
+	Progress("default", "Copying", 10, "")
+	unsubscribeFirst()
+	Progress("default", "Booting", UnknownPercent, "")
+
+	assert.Equal(t, []string{"first Copying", "second Copying", "second Booting"}, received)
+}
+
+func TestPublishKeepsTime(t *testing.T) {
+	var received Event
+	unsubscribe := Subscribe(func(e Event) {
+		received = e
+	})
+	defer unsubscribe()
+
+	Publish(Event{Kind: KindLog, Time: time.Unix(42, 0), Level: "info", Message: "Created"})
+
+	assert.Equal(t, time.Unix(42, 0), received.Time)
+	assert.Equal(t, "Created", received.Message)
+}
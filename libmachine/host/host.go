@@ -3,13 +3,18 @@ package host
 import (
 	"errors"
 	"fmt"
+	"net"
+	"os"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/docker/machine/libmachine/auth"
 	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/engine"
-	"github.com/docker/machine/libmachine/mcnutils"
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnerror"
 	"github.com/docker/machine/libmachine/provision"
 	"github.com/docker/machine/libmachine/provision/pkgaction"
 	"github.com/docker/machine/libmachine/provision/serviceaction"
@@ -31,6 +36,19 @@ type Host struct {
 	HostOptions   *Options
 	Name          string
 	RawDriver     []byte
+	// LastTransition is the most recent state change made to the host by
+	// machine.  It is nil for hosts which have not been acted on yet.
+	LastTransition *Transition
+}
+
+// Transition records a state the host was moved to, when, and why.
+type Transition struct {
+	State     state.State
+	Reason    string
+	Timestamp time.Time
+	// Pid is the process which creates, provisions or removes the host,
+	// for the lifecycle states.
+	Pid int `json:",omitempty"`
 }
 
 type Options struct {
@@ -56,55 +74,208 @@ func (h *Host) RunSSHCommand(command string) (string, error) {
 	return drivers.RunSSHCommandFromDriver(h.Driver, command)
 }
 
+// SupportsAction returns an error if the host's driver is not able to
+// perform the given action.
+func (h *Host) SupportsAction(capability drivers.Capability) error {
+	if !drivers.GetCapabilities(h.Driver).Has(capability) {
+		return mcnerror.ErrUnsupportedAction{
+			Name:       h.Name,
+			DriverName: h.DriverName,
+			Action:     string(capability),
+		}
+	}
+	return nil
+}
+
 func (h *Host) CreateSSHClient() (ssh.Client, error) {
-	addr, err := h.Driver.GetSSHHostname()
-	if err != nil {
+	if err := h.SupportsAction(drivers.CapabilitySSH); err != nil {
 		return ssh.ExternalClient{}, err
 	}
 
-	port, err := h.Driver.GetSSHPort()
-	if err != nil {
+	return drivers.GetSSHClientFromDriver(h.Driver)
+}
+
+// CreateSSHClientForwardingAgent returns an SSH client of the host which
+// lets the commands run on it use the running SSH agent.
+func (h *Host) CreateSSHClientForwardingAgent() (ssh.Client, error) {
+	if err := h.SupportsAction(drivers.CapabilitySSH); err != nil {
 		return ssh.ExternalClient{}, err
 	}
 
-	auth := &ssh.Auth{
-		Keys: []string{h.Driver.GetSSHKeyPath()},
+	return drivers.GetSSHClientForwardingAgent(h.Driver)
+}
+
+// ResetHostKey forgets the SSH host key recorded for the host, so that the
+// key it presents next is trusted, e.g. once it was recreated.
+func (h *Host) ResetHostKey() error {
+	path := drivers.KnownHostsPath(h.Driver)
+	if path == "" {
+		return fmt.Errorf("The driver of %q doesn't tell where the SSH host key is recorded", h.Name)
+	}
+
+	log.Infof("Forgetting the SSH host key of %q", h.Name)
+
+	// Keys recorded before machines had aliases are only known by address,
+	// which can't be told if the machine is stopped.
+	addrs := []string{}
+	if hostname, err := h.Driver.GetSSHHostname(); err != nil {
+		log.Debugf("Error getting the SSH address of %q: %s", h.Name, err)
+	} else if port, err := h.Driver.GetSSHPort(); err != nil {
+		log.Debugf("Error getting the SSH port of %q: %s", h.Name, err)
+	} else if hostname != "" {
+		addrs = append(addrs, net.JoinHostPort(hostname, strconv.Itoa(port)))
+	}
+
+	return ssh.ForgetHostKey(path, drivers.HostKeyAlias(h.Driver), addrs...)
+}
+
+// SetTransition records that the host was moved to the given state.  The
+// caller is responsible for saving the host to the store.
+func (h *Host) SetTransition(s state.State, reason string) {
+	h.LastTransition = &Transition{
+		State:     s,
+		Reason:    reason,
+		Timestamp: time.Now(),
+	}
+
+	if s.IsLifecycle() {
+		h.LastTransition.Pid = os.Getpid()
+	}
+}
+
+// ClearStaleTransition moves the host to Error if it was left creating,
+// provisioning or removing by a process which has gone away, e.g. killed,
+// so that it can be acted on again.  It returns whether it did.
+func (h *Host) ClearStaleTransition() bool {
+	s := h.LifecycleState()
+	if s == state.None || processExists(h.LastTransition.Pid) {
+		return false
+	}
+
+	log.Debugf("Machine %q was left %s by process %d, which is gone", h.Name, strings.ToLower(s.String()), h.LastTransition.Pid)
+	h.Interrupt()
+
+	return true
+}
+
+// Interrupt moves the host to Error because machine stopped creating,
+// provisioning or removing it before it was done.
+func (h *Host) Interrupt() {
+	s := h.LifecycleState()
+	if s == state.None {
+		return
+	}
+
+	h.SetTransition(state.Error, fmt.Sprintf("Interrupted while %s", strings.ToLower(s.String())))
+}
+
+// LifecycleState returns the state recorded while machine is creating,
+// provisioning or removing the host, or state.None otherwise.
+func (h *Host) LifecycleState() state.State {
+	if h.LastTransition != nil && h.LastTransition.State.IsLifecycle() {
+		return h.LastTransition.State
 	}
+	return state.None
+}
 
-	return ssh.NewClient(h.Driver.GetSSHUsername(), addr, port, auth)
+// GetState returns the lifecycle state of the host if machine is working on
+// it, or else the state reported by the driver.
+func (h *Host) GetState() (state.State, error) {
+	if s := h.LifecycleState(); s != state.None {
+		return s, nil
+	}
+	return h.Driver.GetState()
 }
 
-func (h *Host) runActionForState(action func() error, desiredState state.State) error {
-	if drivers.MachineInState(h.Driver, desiredState)() {
+// runActionForState runs the action if the host may move through the
+// transitional state to the desired state, and records the outcome.
+func (h *Host) runActionForState(action func() error, transitionalState, desiredState state.State, reason string) error {
+	// If we can't tell which state the machine is in, let the driver try
+	// anyway, as it did before transitions were checked.
+	currentState, err := h.GetState()
+	if err != nil {
+		currentState = state.None
+	}
+
+	if currentState == desiredState {
 		return fmt.Errorf("Machine %q is already %s.", h.Name, strings.ToLower(desiredState.String()))
 	}
 
+	if !state.CanTransition(currentState, transitionalState) {
+		return mcnerror.ErrInvalidTransition{
+			Name: h.Name,
+			From: currentState,
+			To:   transitionalState,
+		}
+	}
+
+	h.SetTransition(transitionalState, reason)
+
 	if err := action(); err != nil {
+		h.SetTransition(state.Error, err.Error())
+		return err
+	}
+
+	if err := drivers.WaitForState(h.Driver, desiredState); err != nil {
+		h.SetTransition(state.Error, err.Error())
 		return err
 	}
 
-	return mcnutils.WaitFor(drivers.MachineInState(h.Driver, desiredState))
+	h.SetTransition(desiredState, reason)
+
+	return nil
 }
 
 func (h *Host) Start() error {
-	return h.runActionForState(h.Driver.Start, state.Running)
+	if err := h.SupportsAction(drivers.CapabilityStart); err != nil {
+		return err
+	}
+
+	return h.runActionForState(h.Driver.Start, state.Starting, state.Running, "Machine started")
 }
 
 func (h *Host) Stop() error {
-	return h.runActionForState(h.Driver.Stop, state.Stopped)
+	if err := h.SupportsAction(drivers.CapabilityStop); err != nil {
+		return err
+	}
+
+	return h.runActionForState(h.Driver.Stop, state.Stopping, state.Stopped, "Machine stopped")
 }
 
 func (h *Host) Kill() error {
-	return h.runActionForState(h.Driver.Kill, state.Stopped)
+	if err := h.SupportsAction(drivers.CapabilityKill); err != nil {
+		return err
+	}
+
+	return h.runActionForState(h.Driver.Kill, state.Stopping, state.Stopped, "Machine killed")
 }
 
 func (h *Host) Restart() error {
+	capabilities := drivers.GetCapabilities(h.Driver)
+
+	// Drivers which cannot stop and start a machine on their own may still
+	// know how to restart it, e.g. generic does so over SSH.
+	if !capabilities.Has(drivers.CapabilityStop) || !capabilities.Has(drivers.CapabilityStart) {
+		if err := h.SupportsAction(drivers.CapabilityRestart); err != nil {
+			return err
+		}
+
+		if err := h.Driver.Restart(); err != nil {
+			h.SetTransition(state.Error, err.Error())
+			return err
+		}
+
+		h.SetTransition(state.Running, "Machine restarted")
+
+		return nil
+	}
+
 	if drivers.MachineInState(h.Driver, state.Running)() {
 		if err := h.Stop(); err != nil {
 			return err
 		}
 
-		if err := mcnutils.WaitFor(drivers.MachineInState(h.Driver, state.Stopped)); err != nil {
+		if err := drivers.WaitForState(h.Driver, state.Stopped); err != nil {
 			return err
 		}
 	}
@@ -113,7 +284,7 @@ func (h *Host) Restart() error {
 		return err
 	}
 
-	if err := mcnutils.WaitFor(drivers.MachineInState(h.Driver, state.Running)); err != nil {
+	if err := drivers.WaitForState(h.Driver, state.Running); err != nil {
 		return err
 	}
 
@@ -130,6 +301,10 @@ func (h *Host) Upgrade() error {
 		return errMachineMustBeRunningForUpgrade
 	}
 
+	if err := h.SupportsAction(drivers.CapabilitySSH); err != nil {
+		return err
+	}
+
 	provisioner, err := provision.DetectProvisioner(h.Driver)
 	if err != nil {
 		return err
@@ -150,6 +325,10 @@ func (h *Host) GetURL() (string, error) {
 }
 
 func (h *Host) ConfigureAuth() error {
+	if err := h.SupportsAction(drivers.CapabilitySSH); err != nil {
+		return err
+	}
+
 	provisioner, err := provision.DetectProvisioner(h.Driver)
 	if err != nil {
 		return err
@@ -1,9 +1,16 @@
 package host
 
 import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 
-	_ "github.com/docker/machine/drivers/none"
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/drivers/none"
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/state"
 )
 
 func TestValidateHostnameValid(t *testing.T) {
@@ -35,3 +42,156 @@ func TestValidateHostnameInvalid(t *testing.T) {
 		}
 	}
 }
+
+func TestSupportsAction(t *testing.T) {
+	h := &Host{
+		Name:       "test",
+		DriverName: "none",
+		Driver:     none.NewDriver("test", "/tmp/artifacts"),
+	}
+
+	err := h.Start()
+	if _, ok := err.(mcnerror.ErrUnsupportedAction); !ok {
+		t.Fatalf("Expected an unsupported action error, got %v", err)
+	}
+
+	if _, err := h.CreateSSHClient(); err == nil {
+		t.Fatal("Expected creating an SSH client for a none host to fail")
+	}
+}
+
+func TestStartRecordsTransition(t *testing.T) {
+	h := &Host{
+		Name:   "test",
+		Driver: &fakedriver.Driver{MockState: state.Stopped},
+	}
+
+	if err := h.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	if h.LastTransition == nil || h.LastTransition.State != state.Running {
+		t.Fatalf("Expected the last transition to be to Running, got %+v", h.LastTransition)
+	}
+
+	if h.LastTransition.Timestamp.IsZero() || h.LastTransition.Reason == "" {
+		t.Fatalf("Expected the transition to have a timestamp and reason, got %+v", h.LastTransition)
+	}
+}
+
+func TestStartRefusedWhileStopping(t *testing.T) {
+	h := &Host{
+		Name:   "test",
+		Driver: &fakedriver.Driver{MockState: state.Stopping},
+	}
+
+	err := h.Start()
+	if _, ok := err.(mcnerror.ErrInvalidTransition); !ok {
+		t.Fatalf("Expected an invalid transition error, got %v", err)
+	}
+
+	if h.LastTransition != nil {
+		t.Fatalf("Expected no transition to be recorded, got %+v", h.LastTransition)
+	}
+}
+
+func TestLifecycleStateOverridesDriver(t *testing.T) {
+	h := &Host{
+		Name:   "test",
+		Driver: &fakedriver.Driver{MockState: state.Running},
+	}
+
+	h.SetTransition(state.Provisioning, "Provisioning machine")
+
+	if s, _ := h.GetState(); s != state.Provisioning {
+		t.Fatalf("Expected state Provisioning, got %s", s)
+	}
+
+	if err := h.Stop(); err == nil {
+		t.Fatal("Expected stopping a machine being provisioned to fail")
+	}
+
+	h.SetTransition(state.Running, "Machine created")
+
+	if s, _ := h.GetState(); s != state.Running {
+		t.Fatalf("Expected state Running, got %s", s)
+	}
+}
+
+func TestClearStaleTransition(t *testing.T) {
+	h := &Host{
+		Name:   "test",
+		Driver: &fakedriver.Driver{MockState: state.Stopped},
+	}
+
+	// The process creating the machine is this one.
+	h.SetTransition(state.Creating, "Creating machine")
+	if h.ClearStaleTransition() {
+		t.Fatal("Expected the transition of a running process to be kept")
+	}
+
+	// The test binary runs no test and exits.
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	h.LastTransition.Pid = cmd.Process.Pid
+
+	if !h.ClearStaleTransition() {
+		t.Fatal("Expected the transition of an exited process to be cleared")
+	}
+	if h.LastTransition.State != state.Error || h.LastTransition.Reason != "Interrupted while creating" {
+		t.Fatalf("Expected the machine to be in error, got %+v", h.LastTransition)
+	}
+
+	if err := h.Start(); err != nil {
+		t.Fatalf("Expected the machine to start again, got %s", err)
+	}
+}
+
+func TestResetHostKey(t *testing.T) {
+	storePath, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(storePath)
+
+	h := &Host{
+		Name:   "test",
+		Driver: none.NewDriver("test", storePath),
+	}
+
+	knownHosts := filepath.Join(storePath, "machines", "test", "known_hosts")
+	if err := os.MkdirAll(filepath.Dir(knownHosts), 0700); err != nil {
+		t.Fatal(err)
+	}
+	// The key of the jump host is kept.
+	jumpLine := "bastion.example.com ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBDFjdTkj5se/2Myk1L2SfR9vDaBx1SuUz7Lc51fVCXQ3O3HCJQD6UEL0H6sdB8wGd4erw03eWY5BQ9WA0dDFbHg=\n"
+	machineLine := "docker-machine:test,192.168.99.100 ecdsa-sha2-nistp256 AAAAE2VjZHNhLXNoYTItbmlzdHAyNTYAAAAIbmlzdHAyNTYAAABBBDFjdTkj5se/2Myk1L2SfR9vDaBx1SuUz7Lc51fVCXQ3O3HCJQD6UEL0H6sdB8wGd4erw03eWY5BQ9WA0dDFbHg=\n"
+	if err := ioutil.WriteFile(knownHosts, []byte(machineLine+jumpLine), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.ResetHostKey(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(knownHosts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != jumpLine {
+		t.Fatalf("Expected only the jump host key to be left, got %q", data)
+	}
+
+	os.Remove(knownHosts)
+	if err := h.ResetHostKey(); err != nil {
+		t.Fatalf("Expected resetting an unknown host key to succeed, got %v", err)
+	}
+
+	// The key of a machine without a store path can't be forgotten.
+	h.Driver = none.NewDriver("", storePath)
+	if err := h.ResetHostKey(); err == nil {
+		t.Fatal("Expected an error when the known_hosts file is unknown")
+	}
+}
@@ -0,0 +1,25 @@
+//go:build !windows
+// +build !windows
+
+package host
+
+import (
+	"os"
+	"syscall"
+)
+
+// processExists returns whether a process is running, even if it belongs to
+// another user.
+func processExists(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,19 @@
+package host
+
+import "os"
+
+// processExists returns whether a process is running.  Finding a process
+// opens it on Windows, which fails once it has exited.
+func processExists(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	p.Release()
+	return true
+}
@@ -8,10 +8,12 @@ import (
 	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnerror"
 	"github.com/docker/machine/libmachine/mcnutils"
 	"github.com/docker/machine/libmachine/persist"
 	"github.com/docker/machine/libmachine/provision"
 	"github.com/docker/machine/libmachine/state"
+	"github.com/docker/machine/libmachine/timing"
 )
 
 func GetDefaultStore() *persist.Filestore {
@@ -27,56 +29,91 @@ func GetDefaultStore() *persist.Filestore {
 // Create is the wrapper method which covers all of the boilerplate around
 // actually creating, provisioning, and persisting an instance in the store.
 func Create(store persist.Store, h *host.Host) error {
+	defer timing.Track("libmachine.Create")()
+
 	if err := cert.BootstrapCertificates(h.HostOptions.AuthOptions); err != nil {
 		return fmt.Errorf("Error generating certificates: %s", err)
 	}
 
 	log.Info("Running pre-create checks...")
 
-	if err := h.Driver.PreCreateCheck(); err != nil {
+	stopTiming := timing.Track("libmachine.Create.PreCreateCheck")
+	err := h.Driver.PreCreateCheck()
+	stopTiming()
+	if err != nil {
 		return fmt.Errorf("Error with pre-create check: %s", err)
 	}
 
+	h.SetTransition(state.Creating, "Creating machine")
+
 	if err := store.Save(h); err != nil {
 		return fmt.Errorf("Error saving host to store before attempting creation: %s", err)
 	}
 
+	if err := create(store, h); err != nil {
+		// Don't leave the host looking like it is still being created.
+		h.SetTransition(state.Error, err.Error())
+		if saveErr := store.Save(h); saveErr != nil {
+			log.Debugf("Error saving failed host %q to store: %s", h.Name, saveErr)
+		}
+		return err
+	}
+
+	h.SetTransition(state.Running, "Machine created")
+
+	log.Debug("Reticulating splines...")
+
+	return nil
+}
+
+// create runs the driver and then waits for and provisions the machine.
+func create(store persist.Store, h *host.Host) error {
 	log.Info("Creating machine...")
 
-	if err := h.Driver.Create(); err != nil {
+	stopTiming := timing.Track("libmachine.Create.Driver")
+	err := h.Driver.Create()
+	stopTiming()
+	if err != nil {
 		return fmt.Errorf("Error in driver during machine creation: %s", err)
 	}
 
+	h.SetTransition(state.Provisioning, "Provisioning machine")
+
 	if err := store.Save(h); err != nil {
 		return fmt.Errorf("Error saving host to store after attempting creation: %s", err)
 	}
 
-	// TODO: Not really a fan of just checking "none" here.
-	if h.Driver.DriverName() != "none" {
+	// Machines which can't be reached over SSH (e.g. "none") can't be
+	// provisioned either.
+	if drivers.GetCapabilities(h.Driver).Has(drivers.CapabilitySSH) {
 		log.Info("Waiting for machine to be running, this may take a few minutes...")
-		if err := mcnutils.WaitFor(drivers.MachineInState(h.Driver, state.Running)); err != nil {
+		stopTiming = timing.Track("libmachine.Create.WaitForRunning")
+		err = drivers.WaitForState(h.Driver, state.Running)
+		stopTiming()
+		if err != nil {
 			return fmt.Errorf("Error waiting for machine to be running: %s", err)
 		}
 
 		log.Info("Machine is running, waiting for SSH to be available...")
 		if err := drivers.WaitForSSH(h.Driver); err != nil {
-			return fmt.Errorf("Error waiting for SSH: %s", err)
+			return mcnerror.ErrSSHUnreachable{Name: h.Name, Err: err}
 		}
 
 		log.Info("Detecting operating system of created instance...")
 		provisioner, err := provision.DetectProvisioner(h.Driver)
 		if err != nil {
-			return fmt.Errorf("Error detecting OS: %s", err)
+			return mcnerror.ErrProvisioningFailed{Name: h.Name, Err: fmt.Errorf("Error detecting OS: %s", err)}
 		}
 
 		log.Info("Provisioning created instance...")
-		if err := provisioner.Provision(*h.HostOptions.SwarmOptions, *h.HostOptions.AuthOptions, *h.HostOptions.EngineOptions); err != nil {
-			return fmt.Errorf("Error running provisioning: %s", err)
+		stopTiming = timing.Track("libmachine.Create.Provision")
+		err = provisioner.Provision(*h.HostOptions.SwarmOptions, *h.HostOptions.AuthOptions, *h.HostOptions.EngineOptions)
+		stopTiming()
+		if err != nil {
+			return mcnerror.ErrProvisioningFailed{Name: h.Name, Err: err}
 		}
 	}
 
-	log.Debug("Reticulating splines...")
-
 	return nil
 }
 
@@ -122,3 +122,37 @@ func WithField(fieldName string, field interface{}) Logger {
 func WithFields(fields Fields) Logger {
 	return l.WithFields(fields)
 }
+
+// The levels of log records.
+const (
+	DebugLevel = "debug"
+	InfoLevel  = "info"
+	WarnLevel  = "warn"
+	ErrorLevel = "error"
+)
+
+// Record is a log message, as handed to the handler set with SetHandler.
+type Record struct {
+	Level   string
+	Message string
+}
+
+var (
+	handlerLock = &sync.Mutex{}
+	handler     func(Record)
+)
+
+// SetHandler sends every log message to handler instead of writing it out,
+// or writes them out again if handler is nil.  Driver plugins use it to
+// send their logs to the client.
+func SetHandler(h func(Record)) {
+	handlerLock.Lock()
+	defer handlerLock.Unlock()
+	handler = h
+}
+
+func getHandler() func(Record) {
+	handlerLock.Lock()
+	defer handlerLock.Unlock()
+	return handler
+}
@@ -17,3 +17,30 @@ func TestStandardLoggerWithFields(t *testing.T) {
 		t.Fatalf("Expected %q, got %q", expectedOutFields, withFieldsStandardLogger.fieldOut)
 	}
 }
+
+func TestSetHandler(t *testing.T) {
+	records := []Record{}
+	SetHandler(func(r Record) {
+		records = append(records, r)
+	})
+	defer SetHandler(nil)
+
+	Infof("Copying %s", "ISO")
+	Warn("Slow disk")
+	WithField("machine", "default").Error("Failed")
+
+	expected := []Record{
+		{Level: InfoLevel, Message: "Copying ISO"},
+		{Level: WarnLevel, Message: "Slow disk"},
+		{Level: ErrorLevel, Message: "Failed\t\t machine=default"},
+	}
+
+	if len(records) != len(expected) {
+		t.Fatalf("Expected %d records, got %+v", len(expected), records)
+	}
+	for i := range expected {
+		if records[i] != expected[i] {
+			t.Fatalf("Expected %+v, got %+v", expected[i], records[i])
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package log
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask is what is written out in place of a secret.
+const Mask = "****"
+
+// Values shorter than minSecretLength aren't masked: they turn up everywhere,
+// so masking them would garble the logs while hiding next to nothing.
+const minSecretLength = 4
+
+var (
+	secretsLock = &sync.RWMutex{}
+	secrets     = []string{}
+
+	privateKeyRegexp  = regexp.MustCompile(`(?s)(-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----).*?(-----END [A-Z0-9 ]*PRIVATE KEY-----)`)
+	urlPasswordRegexp = regexp.MustCompile(`(://[^/:@\s]+:)[^/@\s]+@`)
+)
+
+// AddSecret marks values, such as API keys and passwords, as secrets: they
+// are masked in all that is logged from then on, and by Redact.  The values
+// are still used as they are everywhere else, e.g. in the commands run.
+// Values of less than a few characters are ignored.
+func AddSecret(values ...string) {
+	secretsLock.Lock()
+	defer secretsLock.Unlock()
+
+	for _, value := range values {
+		if len(value) < minSecretLength || containsSecret(value) {
+			continue
+		}
+		secrets = append(secrets, value)
+	}
+
+	// A secret holding another one must be masked first, as a whole.
+	sort.Sort(byLength(secrets))
+}
+
+func containsSecret(value string) bool {
+	for _, secret := range secrets {
+		if secret == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact masks the secrets of a message: the values marked with AddSecret,
+// private keys and the passwords of URLs.  It's for the messages which don't
+// go through the log, such as errors.
+func Redact(message string) string {
+	secretsLock.RLock()
+	for _, secret := range secrets {
+		message = strings.Replace(message, secret, Mask, -1)
+	}
+	secretsLock.RUnlock()
+
+	message = privateKeyRegexp.ReplaceAllString(message, "$1\n"+Mask+"\n$2")
+	return urlPasswordRegexp.ReplaceAllString(message, "${1}"+Mask+"@")
+}
+
+type byLength []string
+
+func (s byLength) Len() int           { return len(s) }
+func (s byLength) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byLength) Less(i, j int) bool { return len(s[i]) > len(s[j]) }
@@ -16,100 +16,107 @@ type StandardLogger struct {
 	mu        *sync.Mutex
 }
 
-func (t StandardLogger) log(args ...interface{}) {
-	defer t.mu.Unlock()
-	t.mu.Lock()
-	fmt.Fprint(t.OutWriter, args...)
-	fmt.Fprint(t.OutWriter, t.fieldOut, "\n")
-	t.fieldOut = ""
+func (t StandardLogger) log(level string, args ...interface{}) {
+	t.write(level, t.OutWriter, fmt.Sprint(args...)+t.fieldOut)
 }
 
-func (t StandardLogger) logf(fmtString string, args ...interface{}) {
-	defer t.mu.Unlock()
-	t.mu.Lock()
-	fmt.Fprintf(t.OutWriter, fmtString, args...)
-	fmt.Fprint(t.OutWriter, "\n")
-	t.fieldOut = ""
+func (t StandardLogger) logf(level string, fmtString string, args ...interface{}) {
+	t.write(level, t.OutWriter, fmt.Sprintf(fmtString, args...))
 }
 
-func (t StandardLogger) err(args ...interface{}) {
-	defer t.mu.Unlock()
-	t.mu.Lock()
-	fmt.Fprint(t.ErrWriter, args...)
-	fmt.Fprint(t.ErrWriter, t.fieldOut, "\n")
-	t.fieldOut = ""
+func (t StandardLogger) err(level string, args ...interface{}) {
+	t.write(level, t.ErrWriter, fmt.Sprint(args...)+t.fieldOut)
+}
+
+func (t StandardLogger) errf(level string, fmtString string, args ...interface{}) {
+	t.write(level, t.ErrWriter, fmt.Sprintf(fmtString, args...)+t.fieldOut)
 }
 
-func (t StandardLogger) errf(fmtString string, args ...interface{}) {
+// write hands the message, its secrets masked, to the handler set with
+// SetHandler, if any, or writes it out.
+func (t StandardLogger) write(level string, w io.Writer, message string) {
+	message = Redact(message)
+
+	if handler := getHandler(); handler != nil {
+		handler(Record{Level: level, Message: message})
+		return
+	}
+
+	t.writeOut(level, w, message)
+}
+
+func (t StandardLogger) writeOut(level string, w io.Writer, message string) {
+	if level == WarnLevel {
+		fmt.Print("WARNING >>> ")
+	}
+
 	defer t.mu.Unlock()
 	t.mu.Lock()
-	fmt.Fprintf(t.ErrWriter, fmtString, args...)
-	fmt.Fprint(t.ErrWriter, t.fieldOut, "\n")
-	t.fieldOut = ""
+	fmt.Fprint(w, message, "\n")
 }
 
 func (t StandardLogger) Debug(args ...interface{}) {
 	if IsDebug {
-		t.err(args...)
+		t.err(DebugLevel, args...)
 	}
 }
 
 func (t StandardLogger) Debugf(fmtString string, args ...interface{}) {
 	if IsDebug {
-		t.errf(fmtString, args...)
+		t.errf(DebugLevel, fmtString, args...)
 	}
 }
 
 func (t StandardLogger) Error(args ...interface{}) {
-	t.err(args...)
+	t.err(ErrorLevel, args...)
 }
 
 func (t StandardLogger) Errorf(fmtString string, args ...interface{}) {
-	t.errf(fmtString, args...)
+	t.errf(ErrorLevel, fmtString, args...)
 }
 
 func (t StandardLogger) Errorln(args ...interface{}) {
-	t.err(args...)
+	t.err(ErrorLevel, args...)
 }
 
 func (t StandardLogger) Info(args ...interface{}) {
-	t.log(args...)
+	t.log(InfoLevel, args...)
 }
 
 func (t StandardLogger) Infof(fmtString string, args ...interface{}) {
-	t.logf(fmtString, args...)
+	t.logf(InfoLevel, fmtString, args...)
 }
 
 func (t StandardLogger) Infoln(args ...interface{}) {
-	t.log(args...)
+	t.log(InfoLevel, args...)
 }
 
+// Fatal and Fatalf always write the message out, as nothing would be left to
+// handle it once the process has exited.
 func (t StandardLogger) Fatal(args ...interface{}) {
-	t.err(args...)
+	t.writeOut(ErrorLevel, t.ErrWriter, Redact(fmt.Sprint(args...)+t.fieldOut))
 	os.Exit(1)
 }
 
 func (t StandardLogger) Fatalf(fmtString string, args ...interface{}) {
-	t.errf(fmtString, args...)
+	t.writeOut(ErrorLevel, t.ErrWriter, Redact(fmt.Sprintf(fmtString, args...)+t.fieldOut))
 	os.Exit(1)
 }
 
 func (t StandardLogger) Print(args ...interface{}) {
-	t.log(args...)
+	t.log(InfoLevel, args...)
 }
 
 func (t StandardLogger) Printf(fmtString string, args ...interface{}) {
-	t.logf(fmtString, args...)
+	t.logf(InfoLevel, fmtString, args...)
 }
 
 func (t StandardLogger) Warn(args ...interface{}) {
-	fmt.Print("WARNING >>> ")
-	t.log(args...)
+	t.log(WarnLevel, args...)
 }
 
 func (t StandardLogger) Warnf(fmtString string, args ...interface{}) {
-	fmt.Print("WARNING >>> ")
-	t.logf(fmtString, args...)
+	t.logf(WarnLevel, fmtString, args...)
 }
 
 func (t StandardLogger) WithFields(fields Fields) Logger {
@@ -0,0 +1,128 @@
+package mcnerror
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Errors only cross the plugin RPC boundary as strings, so typed errors are
+// encoded into a tagged JSON string by the plugin and decoded back into
+// their original type by the client.
+const encodedErrorPrefix = "mcnerror:"
+
+type encodedError struct {
+	// Context is what Wrapf added to the error, if anything.
+	Context    string `json:",omitempty"`
+	Kind       string
+	Name       string `json:",omitempty"`
+	DriverName string `json:",omitempty"`
+	Action     string `json:",omitempty"`
+	Cause      string `json:",omitempty"`
+}
+
+func causeString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func causeError(msg string) error {
+	if msg == "" {
+		return nil
+	}
+	return errors.New(msg)
+}
+
+// Encode converts a typed error into an error whose message carries enough
+// information for Decode to restore it.  Other errors are returned as is.
+func Encode(err error) error {
+	var enc encodedError
+
+	switch e := Cause(err).(type) {
+	case ErrHostDoesNotExist:
+		enc = encodedError{Kind: "HostDoesNotExist", Name: e.Name}
+	case ErrHostAlreadyExists:
+		enc = encodedError{Kind: "HostAlreadyExists", Name: e.Name}
+	case ErrHostNotRunning:
+		enc = encodedError{Kind: "HostNotRunning", Name: e.Name}
+	case ErrDriverUnavailable:
+		enc = encodedError{Kind: "DriverUnavailable", DriverName: e.DriverName}
+	case ErrUnsupportedAction:
+		enc = encodedError{Kind: "UnsupportedAction", Name: e.Name, DriverName: e.DriverName, Action: e.Action}
+	case ErrSSHUnreachable:
+		enc = encodedError{Kind: "SSHUnreachable", Name: e.Name, Cause: causeString(e.Err)}
+	case ErrProvisioningFailed:
+		enc = encodedError{Kind: "ProvisioningFailed", Name: e.Name, Cause: causeString(e.Err)}
+	default:
+		return err
+	}
+
+	enc.Context = wrapContext(err)
+
+	data, jsonErr := json.Marshal(enc)
+	if jsonErr != nil {
+		return err
+	}
+
+	return errors.New(encodedErrorPrefix + string(data))
+}
+
+// Decode restores an error produced by Encode.  Errors which were not
+// encoded are returned as is.
+func Decode(err error) error {
+	if err == nil || !strings.HasPrefix(err.Error(), encodedErrorPrefix) {
+		return err
+	}
+
+	var enc encodedError
+	if jsonErr := json.Unmarshal([]byte(strings.TrimPrefix(err.Error(), encodedErrorPrefix)), &enc); jsonErr != nil {
+		return err
+	}
+
+	decoded := decodeKind(enc)
+	if decoded == nil {
+		return err
+	}
+
+	if enc.Context != "" {
+		return Wrapf(decoded, "%s", enc.Context)
+	}
+	return decoded
+}
+
+// wrapContext returns the messages added to an error with Wrapf, joined the
+// way wrappedError prints them.
+func wrapContext(err error) string {
+	msgs := []string{}
+	for {
+		wrapped, ok := err.(wrappedError)
+		if !ok {
+			return strings.Join(msgs, ": ")
+		}
+		msgs = append(msgs, wrapped.msg)
+		err = wrapped.err
+	}
+}
+
+func decodeKind(enc encodedError) error {
+	switch enc.Kind {
+	case "HostDoesNotExist":
+		return ErrHostDoesNotExist{Name: enc.Name}
+	case "HostAlreadyExists":
+		return ErrHostAlreadyExists{Name: enc.Name}
+	case "HostNotRunning":
+		return ErrHostNotRunning{Name: enc.Name}
+	case "DriverUnavailable":
+		return ErrDriverUnavailable{DriverName: enc.DriverName}
+	case "UnsupportedAction":
+		return ErrUnsupportedAction{Name: enc.Name, DriverName: enc.DriverName, Action: enc.Action}
+	case "SSHUnreachable":
+		return ErrSSHUnreachable{Name: enc.Name, Err: causeError(enc.Cause)}
+	case "ProvisioningFailed":
+		return ErrProvisioningFailed{Name: enc.Name, Err: causeError(enc.Cause)}
+	}
+
+	return nil
+}
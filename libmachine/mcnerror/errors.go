@@ -3,12 +3,90 @@ package mcnerror
 import (
 	"errors"
 	"fmt"
+	"strings"
+
+	"github.com/docker/machine/libmachine/state"
+)
+
+// Exit codes returned by the docker-machine CLI.  These are part of the
+// public interface and must not be renumbered.
+const (
+	ExitCodeGeneric            = 1
+	ExitCodeUsage              = 2
+	ExitCodeHostDoesNotExist   = 3
+	ExitCodeHostAlreadyExists  = 4
+	ExitCodeHostNotRunning     = 5
+	ExitCodeDriverUnavailable  = 6
+	ExitCodeUnsupportedAction  = 7
+	ExitCodeSSHUnreachable     = 8
+	ExitCodeProvisioningFailed = 9
+	ExitCodeCertInvalid        = 10
+	ExitCodeInvalidTransition  = 11
 )
 
 var (
 	ErrInvalidHostname = errors.New("Invalid hostname specified. Allowed hostname chars are: 0-9a-zA-Z . -")
 )
 
+// ExitCoder is implemented by errors which map to a specific exit code.
+type ExitCoder interface {
+	error
+	ExitCode() int
+}
+
+// ExitCode returns the exit code for an error, looking through any context
+// added with Wrapf.
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	cause := Cause(err)
+
+	if cause == ErrInvalidHostname {
+		return ExitCodeUsage
+	}
+
+	if coder, ok := cause.(ExitCoder); ok {
+		return coder.ExitCode()
+	}
+
+	return ExitCodeGeneric
+}
+
+type wrappedError struct {
+	msg string
+	err error
+}
+
+func (e wrappedError) Error() string {
+	return fmt.Sprintf("%s: %s", e.msg, e.err)
+}
+
+// Wrapf adds context to an error in the same way as
+// fmt.Errorf("...: %s", err) would, but keeps the original error reachable
+// through Cause.
+func Wrapf(err error, format string, args ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	return wrappedError{
+		msg: fmt.Sprintf(format, args...),
+		err: err,
+	}
+}
+
+// Cause returns the original error underneath any context added with Wrapf.
+func Cause(err error) error {
+	for {
+		wrapped, ok := err.(wrappedError)
+		if !ok {
+			return err
+		}
+		err = wrapped.err
+	}
+}
+
 type ErrHostDoesNotExist struct {
 	Name string
 }
@@ -17,6 +95,10 @@ func (e ErrHostDoesNotExist) Error() string {
 	return fmt.Sprintf("Host does not exist: %q", e.Name)
 }
 
+func (e ErrHostDoesNotExist) ExitCode() int {
+	return ExitCodeHostDoesNotExist
+}
+
 type ErrHostAlreadyExists struct {
 	Name string
 }
@@ -24,3 +106,100 @@ type ErrHostAlreadyExists struct {
 func (e ErrHostAlreadyExists) Error() string {
 	return fmt.Sprintf("Host already exists: %q", e.Name)
 }
+
+func (e ErrHostAlreadyExists) ExitCode() int {
+	return ExitCodeHostAlreadyExists
+}
+
+// ErrHostNotRunning is returned when an action requires a running host.
+// Drivers do not always know the name of the host, so it may be empty.
+type ErrHostNotRunning struct {
+	Name string
+}
+
+func (e ErrHostNotRunning) Error() string {
+	if e.Name == "" {
+		return "Host is not running"
+	}
+	return fmt.Sprintf("Host is not running: %q", e.Name)
+}
+
+func (e ErrHostNotRunning) ExitCode() int {
+	return ExitCodeHostNotRunning
+}
+
+// ErrDriverUnavailable is returned when the plugin binary for a driver can
+// not be found, and by every call to the driver of a host whose plugin is
+// missing.
+type ErrDriverUnavailable struct {
+	DriverName string
+}
+
+func (e ErrDriverUnavailable) Error() string {
+	return fmt.Sprintf("Driver %q not found. Do you have the plugin binary accessible in your PATH, or installed with \"docker-machine driver install\"?", e.DriverName)
+}
+
+func (e ErrDriverUnavailable) ExitCode() int {
+	return ExitCodeDriverUnavailable
+}
+
+type ErrUnsupportedAction struct {
+	Name       string
+	DriverName string
+	Action     string
+}
+
+func (e ErrUnsupportedAction) Error() string {
+	return fmt.Sprintf("Driver %q does not support %q on host %q", e.DriverName, e.Action, e.Name)
+}
+
+func (e ErrUnsupportedAction) ExitCode() int {
+	return ExitCodeUnsupportedAction
+}
+
+// ErrSSHUnreachable is returned when we gave up trying to reach a host
+// over SSH.
+type ErrSSHUnreachable struct {
+	Name string
+	Err  error
+}
+
+func (e ErrSSHUnreachable) Error() string {
+	return fmt.Sprintf("Unable to reach host %q over SSH: %s", e.Name, e.Err)
+}
+
+func (e ErrSSHUnreachable) ExitCode() int {
+	return ExitCodeSSHUnreachable
+}
+
+// ErrProvisioningFailed is returned when a host was created but could not
+// be provisioned.
+type ErrProvisioningFailed struct {
+	Name string
+	Err  error
+}
+
+func (e ErrProvisioningFailed) Error() string {
+	return fmt.Sprintf("Error provisioning host %q: %s", e.Name, e.Err)
+}
+
+func (e ErrProvisioningFailed) ExitCode() int {
+	return ExitCodeProvisioningFailed
+}
+
+// ErrInvalidTransition is returned when an action would move a host into a
+// state it cannot reach from its current one, e.g. starting it while it is
+// still stopping.
+type ErrInvalidTransition struct {
+	Name string
+	From state.State
+	To   state.State
+}
+
+func (e ErrInvalidTransition) Error() string {
+	return fmt.Sprintf("Host %q is %s and cannot be moved to %s", e.Name, strings.ToLower(e.From.String()), strings.ToLower(e.To.String()))
+}
+
+func (e ErrInvalidTransition) ExitCode() int {
+	return ExitCodeInvalidTransition
+}
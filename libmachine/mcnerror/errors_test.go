@@ -0,0 +1,51 @@
+package mcnerror
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExitCode(t *testing.T) {
+	assert.Equal(t, 0, ExitCode(nil))
+	assert.Equal(t, ExitCodeGeneric, ExitCode(errors.New("foo")))
+	assert.Equal(t, ExitCodeUsage, ExitCode(ErrInvalidHostname))
+	assert.Equal(t, ExitCodeHostDoesNotExist, ExitCode(ErrHostDoesNotExist{Name: "foo"}))
+	assert.Equal(t, ExitCodeSSHUnreachable, ExitCode(ErrSSHUnreachable{Name: "foo", Err: errors.New("timeout")}))
+}
+
+func TestWrapf(t *testing.T) {
+	err := Wrapf(Wrapf(ErrHostNotRunning{Name: "foo"}, "Error getting URL"), "Error loading %q", "foo")
+
+	assert.EqualError(t, err, `Error loading "foo": Error getting URL: Host is not running: "foo"`)
+	assert.Equal(t, ErrHostNotRunning{Name: "foo"}, Cause(err))
+	assert.Equal(t, ExitCodeHostNotRunning, ExitCode(err))
+	assert.Nil(t, Wrapf(nil, "Error"))
+}
+
+func TestEncodeDecode(t *testing.T) {
+	errs := []error{
+		ErrHostDoesNotExist{Name: "foo"},
+		ErrHostAlreadyExists{Name: "foo"},
+		ErrHostNotRunning{},
+		ErrDriverUnavailable{DriverName: "bar"},
+		ErrUnsupportedAction{Name: "foo", DriverName: "none", Action: "ssh"},
+		ErrSSHUnreachable{Name: "foo", Err: errors.New("connection refused")},
+		ErrProvisioningFailed{Name: "foo", Err: errors.New("no space left on device")},
+	}
+
+	for _, err := range errs {
+		assert.Equal(t, err, Decode(Encode(err)))
+	}
+
+	plain := errors.New("plain")
+	assert.Equal(t, plain, Encode(plain))
+	assert.Equal(t, plain, Decode(plain))
+
+	// The context added with Wrapf crosses the boundary as well.
+	wrapped := Wrapf(Wrapf(ErrHostNotRunning{Name: "foo"}, "Error getting URL"), "Error loading %q", "foo")
+	decoded := Decode(Encode(wrapped))
+	assert.EqualError(t, decoded, wrapped.Error())
+	assert.Equal(t, ErrHostNotRunning{Name: "foo"}, Cause(decoded))
+}
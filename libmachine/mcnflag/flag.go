@@ -1,6 +1,9 @@
 package mcnflag
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 type Flag interface {
 	fmt.Stringer
@@ -8,10 +11,11 @@ type Flag interface {
 }
 
 type StringFlag struct {
-	Name   string
-	Usage  string
-	EnvVar string
-	Value  string
+	Name     string
+	Usage    string
+	EnvVar   string
+	Value    string
+	Required bool
 }
 
 // TODO: Could this be done more succinctly using embedding?
@@ -24,10 +28,11 @@ func (f StringFlag) Default() interface{} {
 }
 
 type StringSliceFlag struct {
-	Name   string
-	Usage  string
-	EnvVar string
-	Value  []string
+	Name     string
+	Usage    string
+	EnvVar   string
+	Value    []string
+	Required bool
 }
 
 // TODO: Could this be done more succinctly using embedding?
@@ -40,10 +45,11 @@ func (f StringSliceFlag) Default() interface{} {
 }
 
 type IntFlag struct {
-	Name   string
-	Usage  string
-	EnvVar string
-	Value  int
+	Name     string
+	Usage    string
+	EnvVar   string
+	Value    int
+	Required bool
 }
 
 // TODO: Could this be done more succinctly using embedding?
@@ -69,3 +75,87 @@ func (f BoolFlag) String() string {
 func (f BoolFlag) Default() interface{} {
 	return nil
 }
+
+type DurationFlag struct {
+	Name     string
+	Usage    string
+	EnvVar   string
+	Value    time.Duration
+	Required bool
+}
+
+func (f DurationFlag) String() string {
+	return f.Name
+}
+
+func (f DurationFlag) Default() interface{} {
+	return f.Value
+}
+
+type FloatFlag struct {
+	Name     string
+	Usage    string
+	EnvVar   string
+	Value    float64
+	Required bool
+}
+
+func (f FloatFlag) String() string {
+	return f.Name
+}
+
+func (f FloatFlag) Default() interface{} {
+	return f.Value
+}
+
+// EnumFlag is a string flag which only takes one of the Allowed values.
+type EnumFlag struct {
+	Name     string
+	Usage    string
+	EnvVar   string
+	Value    string
+	Allowed  []string
+	Required bool
+}
+
+func (f EnumFlag) String() string {
+	return f.Name
+}
+
+func (f EnumFlag) Default() interface{} {
+	return f.Value
+}
+
+// SecretFlag is a string flag, e.g. an API key, whose value is never shown.
+// It has no default, so it can't leak from the output of "create --help".
+type SecretFlag struct {
+	Name     string
+	Usage    string
+	EnvVar   string
+	Required bool
+}
+
+func (f SecretFlag) String() string {
+	return f.Name
+}
+
+func (f SecretFlag) Default() interface{} {
+	return ""
+}
+
+// FilePathFlag is a string flag naming a file which must exist.
+type FilePathFlag struct {
+	Name     string
+	Usage    string
+	EnvVar   string
+	Value    string
+	Required bool
+}
+
+func (f FilePathFlag) String() string {
+	return f.Name
+}
+
+func (f FilePathFlag) Default() interface{} {
+	return f.Value
+}
@@ -0,0 +1,182 @@
+package mcnflag
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/docker/machine/libmachine/mcnerror"
+)
+
+// ErrInvalidFlag is returned when the value given to a driver flag isn't
+// acceptable.
+type ErrInvalidFlag struct {
+	Name   string
+	Reason string
+}
+
+func (e ErrInvalidFlag) Error() string {
+	return fmt.Sprintf("Invalid value for --%s: %s", e.Name, e.Reason)
+}
+
+func (e ErrInvalidFlag) ExitCode() int {
+	return mcnerror.ExitCodeUsage
+}
+
+// deref turns the pointers to flags which come out of gob back into flags.
+func deref(flag Flag) Flag {
+	if v := reflect.ValueOf(flag); v.Kind() == reflect.Ptr && !v.IsNil() {
+		if f, ok := v.Elem().Interface().(Flag); ok {
+			return f
+		}
+	}
+	return flag
+}
+
+// IsRequired tells whether the flag must be given a value.
+func IsRequired(flag Flag) bool {
+	switch f := deref(flag).(type) {
+	case StringFlag:
+		return f.Required
+	case StringSliceFlag:
+		return f.Required
+	case IntFlag:
+		return f.Required
+	case DurationFlag:
+		return f.Required
+	case FloatFlag:
+		return f.Required
+	case EnumFlag:
+		return f.Required
+	case SecretFlag:
+		return f.Required
+	case FilePathFlag:
+		return f.Required
+	}
+	return false
+}
+
+// IsSecret tells whether the value of the flag must be kept out of the logs.
+func IsSecret(flag Flag) bool {
+	_, ok := deref(flag).(SecretFlag)
+	return ok
+}
+
+// EnvVar returns the environment variable the flag can be given with, or ""
+// if there is none.
+func EnvVar(flag Flag) string {
+	switch f := deref(flag).(type) {
+	case StringFlag:
+		return f.EnvVar
+	case StringSliceFlag:
+		return f.EnvVar
+	case IntFlag:
+		return f.EnvVar
+	case BoolFlag:
+		return f.EnvVar
+	case DurationFlag:
+		return f.EnvVar
+	case FloatFlag:
+		return f.EnvVar
+	case EnumFlag:
+		return f.EnvVar
+	case SecretFlag:
+		return f.EnvVar
+	case FilePathFlag:
+		return f.EnvVar
+	}
+	return ""
+}
+
+// HasDefault tells whether the flag has a default value, which a required
+// flag is satisfied with.  The zero value of the flag's type isn't one.
+func HasDefault(flag Flag) bool {
+	switch v := flag.Default().(type) {
+	case int:
+		return v != 0
+	case float64:
+		return v != 0
+	case time.Duration:
+		return v != 0
+	}
+	return !isMissing(flag.Default())
+}
+
+// isMissing tells whether a value leaves a flag without a value.  Numbers
+// always count, 0 included: a required number flag which wasn't set is left
+// out of the values.
+func isMissing(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []string:
+		return len(v) == 0
+	}
+	return false
+}
+
+// Validate checks the values given to the flags, by flag name: required
+// flags must have a value, enum flags one of their allowed values and file
+// path flags must name an existing file.  The values of the required flags
+// which weren't set and have no default must be left out.
+func Validate(flags []Flag, values map[string]interface{}) error {
+	for _, flag := range flags {
+		name := flag.String()
+		value := values[name]
+
+		if IsRequired(flag) && isMissing(value) {
+			return ErrInvalidFlag{Name: name, Reason: "the flag is required"}
+		}
+
+		str := valueString(value)
+		if str == "" {
+			continue
+		}
+
+		switch f := deref(flag).(type) {
+		case EnumFlag:
+			if !contains(f.Allowed, str) {
+				return ErrInvalidFlag{
+					Name:   name,
+					Reason: fmt.Sprintf("%q is not one of %s", str, strings.Join(f.Allowed, ", ")),
+				}
+			}
+		case FilePathFlag:
+			if _, err := os.Stat(str); err != nil {
+				return ErrInvalidFlag{Name: name, Reason: err.Error()}
+			}
+		}
+	}
+
+	return nil
+}
+
+// valueString returns the value given to a flag as a string, whatever its
+// type, e.g. a number for an enum flag given in JSON.
+func valueString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case *string:
+		if v == nil {
+			return ""
+		}
+		return *v
+	}
+	return fmt.Sprint(value)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package mcnflag
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRequired(t *testing.T) {
+	flags := []Flag{
+		&StringFlag{Name: "region", Required: true},
+		IntFlag{Name: "cpus", Required: true},
+		DurationFlag{Name: "timeout", Required: true},
+		StringSliceFlag{Name: "tags"},
+	}
+
+	err := Validate(flags, map[string]interface{}{"region": "", "cpus": 1, "timeout": time.Second})
+	assert.EqualError(t, err, "Invalid value for --region: the flag is required")
+	assert.Equal(t, mcnerror.ExitCodeUsage, err.(ErrInvalidFlag).ExitCode())
+
+	err = Validate(flags, map[string]interface{}{"region": "eu", "timeout": time.Second})
+	assert.EqualError(t, err, "Invalid value for --cpus: the flag is required")
+
+	err = Validate(flags, map[string]interface{}{"region": "eu", "cpus": 1, "timeout": time.Second})
+	assert.NoError(t, err)
+
+	// Numbers set to 0 are given.
+	err = Validate(flags, map[string]interface{}{"region": "eu", "cpus": 0, "timeout": time.Duration(0)})
+	assert.NoError(t, err)
+}
+
+func TestHasDefault(t *testing.T) {
+	assert.True(t, HasDefault(IntFlag{Name: "cpus", Value: 1}))
+	assert.False(t, HasDefault(IntFlag{Name: "cpus"}))
+	assert.True(t, HasDefault(&FloatFlag{Name: "ratio", Value: 0.5}))
+	assert.False(t, HasDefault(&FloatFlag{Name: "ratio"}))
+	assert.True(t, HasDefault(EnumFlag{Name: "size", Value: "small"}))
+	assert.False(t, HasDefault(SecretFlag{Name: "token"}))
+	assert.False(t, HasDefault(BoolFlag{Name: "debug"}))
+}
+
+func TestValidateEnum(t *testing.T) {
+	flags := []Flag{EnumFlag{Name: "size", Allowed: []string{"small", "large"}}}
+
+	assert.NoError(t, Validate(flags, map[string]interface{}{"size": "large"}))
+	assert.NoError(t, Validate(flags, map[string]interface{}{"size": ""}))
+	assert.EqualError(t, Validate(flags, map[string]interface{}{"size": "huge"}), `Invalid value for --size: "huge" is not one of small, large`)
+
+	// Values which aren't strings are checked too.
+	huge := "huge"
+	assert.Error(t, Validate(flags, map[string]interface{}{"size": &huge}))
+	assert.EqualError(t, Validate(flags, map[string]interface{}{"size": 3}), `Invalid value for --size: "3" is not one of small, large`)
+
+	numbers := []Flag{EnumFlag{Name: "cpus", Allowed: []string{"1", "2"}}}
+	assert.NoError(t, Validate(numbers, map[string]interface{}{"cpus": 2}))
+}
+
+func TestValidateFilePath(t *testing.T) {
+	file, err := ioutil.TempFile("", "mcnflag-test")
+	assert.NoError(t, err)
+	file.Close()
+	defer os.Remove(file.Name())
+
+	flags := []Flag{&FilePathFlag{Name: "key"}}
+
+	assert.NoError(t, Validate(flags, map[string]interface{}{"key": file.Name()}))
+	assert.Error(t, Validate(flags, map[string]interface{}{"key": file.Name() + ".missing"}))
+
+	missing := file.Name() + ".missing"
+	assert.Error(t, Validate(flags, map[string]interface{}{"key": &missing}))
+}
+
+func TestIsSecret(t *testing.T) {
+	assert.True(t, IsSecret(SecretFlag{Name: "api-key"}))
+	assert.True(t, IsSecret(&SecretFlag{Name: "api-key"}))
+	assert.False(t, IsSecret(StringFlag{Name: "region"}))
+	assert.False(t, IsSecret(&StringFlag{Name: "region"}))
+}
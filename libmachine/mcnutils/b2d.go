@@ -12,6 +12,7 @@ import (
 	"path/filepath"
 	"regexp"
 
+	"github.com/docker/machine/libmachine/events"
 	"github.com/docker/machine/libmachine/log"
 )
 
@@ -133,6 +134,12 @@ func removeFileIfExists(name string) error {
 
 // DownloadISO downloads boot2docker ISO image for the given tag and save it at dest.
 func (b *B2dUtils) DownloadISO(dir, file, isoURL string) error {
+	return b.downloadISO(dir, file, isoURL, "")
+}
+
+// downloadISO is DownloadISO reporting the progress of the download as
+// events of the machine.
+func (b *B2dUtils) downloadISO(dir, file, isoURL, machineName string) error {
 	u, err := url.Parse(isoURL)
 
 	var src io.ReadCloser
@@ -152,8 +159,9 @@ func (b *B2dUtils) DownloadISO(dir, file, isoURL string) error {
 
 		src = &ReaderWithProgress{
 			ReadCloser:     s.Body,
-			out:            os.Stdout,
 			expectedLength: s.ContentLength,
+			machineName:    machineName,
+			phase:          "Downloading " + file,
 		}
 	}
 
@@ -195,12 +203,16 @@ func (b *B2dUtils) DownloadISO(dir, file, isoURL string) error {
 	return nil
 }
 
+// ReaderWithProgress prints how much of the expected length has been read
+// to out, if set, and publishes it as progress events of the phase.
 type ReaderWithProgress struct {
 	io.ReadCloser
 	out                io.Writer
 	bytesTransferred   int64
 	expectedLength     int64
 	nextPercentToPrint int64
+	machineName        string
+	phase              string
 }
 
 func (r *ReaderWithProgress) Read(p []byte) (int, error) {
@@ -211,10 +223,15 @@ func (r *ReaderWithProgress) Read(p []byte) (int, error) {
 		percentage := r.bytesTransferred * 100 / r.expectedLength
 
 		for percentage >= r.nextPercentToPrint {
-			if r.nextPercentToPrint%10 == 0 {
-				fmt.Fprintf(r.out, "%d%%", r.nextPercentToPrint)
-			} else if r.nextPercentToPrint%2 == 0 {
-				fmt.Fprint(r.out, ".")
+			if r.out != nil {
+				if r.nextPercentToPrint%10 == 0 {
+					fmt.Fprintf(r.out, "%d%%", r.nextPercentToPrint)
+				} else if r.nextPercentToPrint%2 == 0 {
+					fmt.Fprint(r.out, ".")
+				}
+			}
+			if r.phase != "" {
+				events.Progress(r.machineName, r.phase, int(r.nextPercentToPrint), "")
 			}
 			r.nextPercentToPrint += 2
 		}
@@ -224,7 +241,9 @@ func (r *ReaderWithProgress) Read(p []byte) (int, error) {
 }
 
 func (r *ReaderWithProgress) Close() error {
-	fmt.Fprintln(r.out)
+	if r.out != nil {
+		fmt.Fprintln(r.out)
+	}
 	return r.ReadCloser.Close()
 }
 
@@ -263,7 +282,7 @@ func (b *B2dUtils) CopyIsoToMachineDir(isoURL, machineName string) error {
 	// By default just copy the existing "cached" iso to
 	// the machine's directory...
 	if isoURL == "" {
-		if err := b.copyDefaultIsoToMachine(machineIsoPath); err != nil {
+		if err := b.copyDefaultIsoToMachine(machineIsoPath, machineName); err != nil {
 			return err
 		}
 	} else {
@@ -271,7 +290,7 @@ func (b *B2dUtils) CopyIsoToMachineDir(isoURL, machineName string) error {
 		//to a direct download
 		if downloadURL, err := b.GetLatestBoot2DockerReleaseURL(isoURL); err == nil {
 			log.Infof("Downloading %s from %s...", b.isoFilename, downloadURL)
-			if err := b.DownloadISO(machineDir, b.isoFilename, downloadURL); err != nil {
+			if err := b.downloadISO(machineDir, b.isoFilename, downloadURL, machineName); err != nil {
 				return err
 			}
 		} else {
@@ -282,7 +301,7 @@ func (b *B2dUtils) CopyIsoToMachineDir(isoURL, machineName string) error {
 	return nil
 }
 
-func (b *B2dUtils) copyDefaultIsoToMachine(machineIsoPath string) error {
+func (b *B2dUtils) copyDefaultIsoToMachine(machineIsoPath, machineName string) error {
 	if _, err := os.Stat(b.commonIsoPath); os.IsNotExist(err) {
 		log.Info("No default boot2docker iso found locally, downloading the latest release...")
 		if err := b.DownloadLatestBoot2Docker(""); err != nil {
@@ -290,7 +309,7 @@ func (b *B2dUtils) copyDefaultIsoToMachine(machineIsoPath string) error {
 		}
 	}
 
-	if err := CopyFile(b.commonIsoPath, machineIsoPath); err != nil {
+	if err := copyFile(b.commonIsoPath, machineIsoPath, machineName, "Copying "+b.isoFilename); err != nil {
 		return err
 	}
 
@@ -10,6 +10,7 @@ import (
 
 	"bytes"
 
+	"github.com/docker/machine/libmachine/events"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -110,3 +111,34 @@ func TestReaderWithProgress(t *testing.T) {
 	readerWithProgress.Close()
 	assert.Equal(t, "0%....10%....20%....30%....40%....50%....60%....70%....80%....90%....100%\n", output.String())
 }
+
+func TestReaderWithProgressEvents(t *testing.T) {
+	percents := []int{}
+	unsubscribe := events.Subscribe(func(e events.Event) {
+		assert.Equal(t, events.KindProgress, e.Kind)
+		assert.Equal(t, "default", e.MachineName)
+		assert.Equal(t, "Copying boot2docker.iso", e.Phase)
+		percents = append(percents, e.Percent)
+	})
+	defer unsubscribe()
+
+	readCloser := MockReadCloser{blockLengths: []int{5, 45, 50}}
+	buffer := make([]byte, 100)
+
+	readerWithProgress := ReaderWithProgress{
+		ReadCloser:     &readCloser,
+		expectedLength: 100,
+		machineName:    "default",
+		phase:          "Copying boot2docker.iso",
+	}
+
+	readerWithProgress.Read(buffer)
+	assert.Equal(t, []int{0, 2, 4}, percents)
+
+	readerWithProgress.Read(buffer)
+	readerWithProgress.Read(buffer)
+	assert.Len(t, percents, 51)
+	assert.Equal(t, 100, percents[50])
+
+	assert.NoError(t, readerWithProgress.Close())
+}
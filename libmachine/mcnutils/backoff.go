@@ -0,0 +1,205 @@
+package mcnutils
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrConditionNotMet is the error recorded for an attempt when a
+	// boolean condition being waited on was simply not true yet.
+	ErrConditionNotMet = errors.New("Condition not met")
+
+	backoffLock     = &sync.RWMutex{}
+	defaultBackoff  = DefaultBackoffPolicy()
+	driverBackoffs  = make(map[string]*BackoffPolicy)
+	backoffRand     = rand.New(rand.NewSource(time.Now().UnixNano()))
+	backoffRandLock = &sync.Mutex{}
+
+	// sleep is a variable so that tests do not actually have to wait.
+	sleep = time.Sleep
+)
+
+// BackoffPolicy describes how an operation which may fail transiently (e.g.
+// dialing SSH on a freshly booted instance) is retried.
+//
+// The interval between attempts starts at InitialInterval, is multiplied by
+// Multiplier after every failed attempt and is capped at MaxInterval. Each
+// interval is randomized by +/- RandomizationFactor to avoid many clients
+// retrying in lock step. Retrying stops as soon as either MaxElapsedTime has
+// passed or MaxAttempts attempts were made, whichever comes first. A zero
+// MaxElapsedTime or MaxAttempts means there is no such limit.
+type BackoffPolicy struct {
+	InitialInterval     time.Duration
+	MaxInterval         time.Duration
+	Multiplier          float64
+	RandomizationFactor float64
+	MaxElapsedTime      time.Duration
+	MaxAttempts         int
+}
+
+// ErrRetriesExhausted is returned when an operation kept failing until the
+// backoff policy gave up on it.
+type ErrRetriesExhausted struct {
+	Attempts int
+	Elapsed  time.Duration
+	LastErr  error
+}
+
+func (e ErrRetriesExhausted) Error() string {
+	msg := fmt.Sprintf("Maximum number of retries (%d) exceeded after %s", e.Attempts, e.Elapsed)
+	if e.LastErr != nil && e.LastErr != ErrConditionNotMet {
+		msg = fmt.Sprintf("%s, last error: %s", msg, e.LastErr)
+	}
+	return msg
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+// StopRetrying wraps an error returned from a retried operation to indicate
+// that it is not transient. Retry returns the wrapped error immediately
+// instead of trying again.
+func StopRetrying(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err}
+}
+
+// DefaultBackoffPolicy returns the built-in policy: start polling quickly
+// so that fast local VMs are not slowed down, back off to at most 10s
+// between attempts, and give up after 3 minutes.
+func DefaultBackoffPolicy() *BackoffPolicy {
+	return &BackoffPolicy{
+		InitialInterval:     500 * time.Millisecond,
+		MaxInterval:         10 * time.Second,
+		Multiplier:          1.5,
+		RandomizationFactor: 0.2,
+		MaxElapsedTime:      3 * time.Minute,
+	}
+}
+
+// ConstantBackoffPolicy returns a policy which waits the same interval
+// between attempts and gives up after maxAttempts attempts.
+func ConstantBackoffPolicy(maxAttempts int, interval time.Duration) *BackoffPolicy {
+	return &BackoffPolicy{
+		InitialInterval: interval,
+		MaxInterval:     interval,
+		Multiplier:      1,
+		MaxAttempts:     maxAttempts,
+	}
+}
+
+// SetDefaultBackoffPolicy replaces the policy used by WaitFor and by every
+// driver which does not have a policy of its own.
+func SetDefaultBackoffPolicy(p *BackoffPolicy) {
+	backoffLock.Lock()
+	defer backoffLock.Unlock()
+	defaultBackoff = p
+}
+
+// SetDriverBackoffPolicy sets the policy used when waiting on machines
+// created with the named driver.  Passing a nil policy reverts the driver
+// to the default policy.
+func SetDriverBackoffPolicy(driverName string, p *BackoffPolicy) {
+	backoffLock.Lock()
+	defer backoffLock.Unlock()
+	if p == nil {
+		delete(driverBackoffs, driverName)
+		return
+	}
+	driverBackoffs[driverName] = p
+}
+
+// GetBackoffPolicy returns the policy for the named driver, falling back
+// to the default policy.
+func GetBackoffPolicy(driverName string) *BackoffPolicy {
+	backoffLock.RLock()
+	defer backoffLock.RUnlock()
+	if p, ok := driverBackoffs[driverName]; ok {
+		return p
+	}
+	return defaultBackoff
+}
+
+func (p *BackoffPolicy) randomize(interval time.Duration) time.Duration {
+	if p.RandomizationFactor <= 0 {
+		return interval
+	}
+
+	backoffRandLock.Lock()
+	r := backoffRand.Float64()
+	backoffRandLock.Unlock()
+
+	delta := p.RandomizationFactor * float64(interval)
+	min := float64(interval) - delta
+	return time.Duration(min + r*2*delta)
+}
+
+func (p *BackoffPolicy) next(interval time.Duration) time.Duration {
+	if p.Multiplier > 1 {
+		interval = time.Duration(float64(interval) * p.Multiplier)
+	}
+	if p.MaxInterval > 0 && interval > p.MaxInterval {
+		interval = p.MaxInterval
+	}
+	return interval
+}
+
+// Retry calls f until it returns nil, waiting between attempts as dictated
+// by the policy.  If the policy gives up, an ErrRetriesExhausted carrying
+// the error from the last attempt is returned.  Errors wrapped with
+// StopRetrying are returned straight away.
+func (p *BackoffPolicy) Retry(f func() error) error {
+	start := time.Now()
+	interval := p.InitialInterval
+
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil {
+			return nil
+		}
+
+		if perr, ok := err.(permanentError); ok {
+			return perr.err
+		}
+
+		elapsed := time.Since(start)
+		if (p.MaxAttempts > 0 && attempt >= p.MaxAttempts) ||
+			(p.MaxElapsedTime > 0 && elapsed >= p.MaxElapsedTime) {
+			return ErrRetriesExhausted{
+				Attempts: attempt,
+				Elapsed:  elapsed,
+				LastErr:  err,
+			}
+		}
+
+		wait := p.randomize(interval)
+		if p.MaxElapsedTime > 0 && elapsed+wait > p.MaxElapsedTime {
+			wait = p.MaxElapsedTime - elapsed
+		}
+
+		sleep(wait)
+
+		interval = p.next(interval)
+	}
+}
+
+// WaitFor calls f until it returns true, using the policy.
+func (p *BackoffPolicy) WaitFor(f func() bool) error {
+	return p.Retry(func() error {
+		if f() {
+			return nil
+		}
+		return ErrConditionNotMet
+	})
+}
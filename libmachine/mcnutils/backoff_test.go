@@ -0,0 +1,139 @@
+package mcnutils
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func withFakeSleep(f func(slept *[]time.Duration)) {
+	slept := []time.Duration{}
+	sleep = func(d time.Duration) {
+		slept = append(slept, d)
+	}
+	defer func() {
+		sleep = time.Sleep
+	}()
+	f(&slept)
+}
+
+func TestRetrySucceedsEventually(t *testing.T) {
+	withFakeSleep(func(slept *[]time.Duration) {
+		attempts := 0
+		policy := &BackoffPolicy{
+			InitialInterval: time.Second,
+			MaxInterval:     3 * time.Second,
+			Multiplier:      2,
+			MaxAttempts:     10,
+		}
+
+		err := policy.Retry(func() error {
+			attempts++
+			if attempts < 4 {
+				return errors.New("not yet")
+			}
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 4, attempts)
+		assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}, *slept)
+	})
+}
+
+func TestRetryReportsLastError(t *testing.T) {
+	withFakeSleep(func(slept *[]time.Duration) {
+		attempts := 0
+		err := ConstantBackoffPolicy(3, time.Second).Retry(func() error {
+			attempts++
+			return errors.New("connection refused")
+		})
+
+		exhausted, ok := err.(ErrRetriesExhausted)
+		assert.True(t, ok)
+		assert.Equal(t, 3, exhausted.Attempts)
+		assert.EqualError(t, exhausted.LastErr, "connection refused")
+		assert.Contains(t, err.Error(), "last error: connection refused")
+		assert.Len(t, *slept, 2)
+	})
+}
+
+func TestRetryStopRetrying(t *testing.T) {
+	withFakeSleep(func(slept *[]time.Duration) {
+		attempts := 0
+		err := ConstantBackoffPolicy(5, time.Second).Retry(func() error {
+			attempts++
+			return StopRetrying(errors.New("fatal"))
+		})
+
+		assert.EqualError(t, err, "fatal")
+		assert.Equal(t, 1, attempts)
+		assert.Empty(t, *slept)
+	})
+}
+
+func TestRetryRandomization(t *testing.T) {
+	policy := &BackoffPolicy{
+		RandomizationFactor: 0.5,
+	}
+
+	for i := 0; i < 100; i++ {
+		d := policy.randomize(10 * time.Second)
+		assert.True(t, d >= 5*time.Second && d <= 15*time.Second)
+	}
+}
+
+func TestRetryMaxElapsedTime(t *testing.T) {
+	policy := &BackoffPolicy{
+		InitialInterval: 10 * time.Millisecond,
+		Multiplier:      1,
+		MaxElapsedTime:  50 * time.Millisecond,
+	}
+
+	start := time.Now()
+	err := policy.WaitFor(func() bool {
+		return false
+	})
+
+	assert.IsType(t, ErrRetriesExhausted{}, err)
+	assert.NotContains(t, err.Error(), "last error")
+	assert.True(t, time.Since(start) < time.Second)
+}
+
+func TestWaitForSpecificOrError(t *testing.T) {
+	withFakeSleep(func(slept *[]time.Duration) {
+		err := WaitForSpecificOrError(func() (bool, error) {
+			return false, nil
+		}, 4, 2*time.Second)
+
+		assert.Contains(t, err.Error(), "Maximum number of retries (4) exceeded")
+		assert.Equal(t, []time.Duration{2 * time.Second, 2 * time.Second, 2 * time.Second}, *slept)
+	})
+}
+
+func TestWaitForSpecificNoAttempts(t *testing.T) {
+	called := false
+	err := WaitForSpecificOrError(func() (bool, error) {
+		called = true
+		return true, nil
+	}, 0, time.Second)
+
+	assert.IsType(t, ErrRetriesExhausted{}, err)
+	assert.Contains(t, err.Error(), "Maximum number of retries (0) exceeded")
+	assert.False(t, called)
+
+	assert.Error(t, WaitForSpecific(func() bool { return true }, -1, time.Second))
+}
+
+func TestDriverBackoffPolicy(t *testing.T) {
+	policy := ConstantBackoffPolicy(1, time.Second)
+
+	SetDriverBackoffPolicy("foo", policy)
+	assert.Equal(t, policy, GetBackoffPolicy("foo"))
+	assert.Equal(t, defaultBackoff, GetBackoffPolicy("bar"))
+
+	SetDriverBackoffPolicy("foo", nil)
+	assert.Equal(t, defaultBackoff, GetBackoffPolicy("foo"))
+}
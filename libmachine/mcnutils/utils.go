@@ -4,7 +4,6 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
-	"fmt"
 	"io"
 	"os"
 	"runtime"
@@ -43,27 +42,43 @@ func GetUsername() string {
 }
 
 func CopyFile(src, dst string) error {
-	in, err := os.Open(src)
+	return copyFile(src, dst, "", "")
+}
+
+// copyFile is CopyFile publishing the progress of the copy as events of the
+// phase, if one is given.
+func copyFile(src, dst, machineName, phase string) error {
+	fi, err := os.Stat(src)
 	if err != nil {
 		return err
 	}
 
-	defer in.Close()
-
-	out, err := os.Create(dst)
+	in, err := os.Open(src)
 	if err != nil {
 		return err
 	}
 
-	if _, err = io.Copy(out, in); err != nil {
-		return err
+	var reader io.ReadCloser = in
+	if phase != "" && fi.Size() > 0 {
+		reader = &ReaderWithProgress{
+			ReadCloser:     in,
+			expectedLength: fi.Size(),
+			machineName:    machineName,
+			phase:          phase,
+		}
 	}
 
-	fi, err := os.Stat(src)
+	defer reader.Close()
+
+	out, err := os.Create(dst)
 	if err != nil {
 		return err
 	}
 
+	if _, err = io.Copy(out, reader); err != nil {
+		return err
+	}
+
 	if err := os.Chmod(dst, fi.Mode()); err != nil {
 		return err
 	}
@@ -71,18 +86,24 @@ func CopyFile(src, dst string) error {
 	return nil
 }
 
+// WaitForSpecificOrError calls f until it returns true, at most maxAttempts
+// times and waitInterval apart.  An error returned by f aborts the wait.  f
+// isn't called if maxAttempts isn't positive, the wait fails straight away.
 func WaitForSpecificOrError(f func() (bool, error), maxAttempts int, waitInterval time.Duration) error {
-	for i := 0; i < maxAttempts; i++ {
+	if maxAttempts <= 0 {
+		return ErrRetriesExhausted{}
+	}
+
+	return ConstantBackoffPolicy(maxAttempts, waitInterval).Retry(func() error {
 		stop, err := f()
 		if err != nil {
-			return err
+			return StopRetrying(err)
 		}
-		if stop {
-			return nil
+		if !stop {
+			return ErrConditionNotMet
 		}
-		time.Sleep(waitInterval)
-	}
-	return fmt.Errorf("Maximum number of retries (%d) exceeded", maxAttempts)
+		return nil
+	})
 }
 
 func WaitForSpecific(f func() bool, maxAttempts int, waitInterval time.Duration) error {
@@ -91,8 +112,16 @@ func WaitForSpecific(f func() bool, maxAttempts int, waitInterval time.Duration)
 	}, maxAttempts, waitInterval)
 }
 
+// WaitFor calls f until it returns true, following the default backoff
+// policy.
 func WaitFor(f func() bool) error {
-	return WaitForSpecific(f, 60, 3*time.Second)
+	return GetBackoffPolicy("").WaitFor(f)
+}
+
+// RetryWithDriverPolicy calls f until it returns nil, following the backoff
+// policy configured for the named driver.
+func RetryWithDriverPolicy(driverName string, f func() error) error {
+	return GetBackoffPolicy(driverName).Retry(f)
 }
 
 func DumpVal(vals ...interface{}) {
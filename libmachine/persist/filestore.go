@@ -7,7 +7,9 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
+	"github.com/docker/machine/drivers/none"
 	"github.com/docker/machine/libmachine/auth"
 	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/drivers/rpc"
@@ -15,10 +17,23 @@ import (
 	"github.com/docker/machine/libmachine/host"
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/mcnerror"
+	"github.com/docker/machine/libmachine/state"
 	"github.com/docker/machine/libmachine/swarm"
 	"github.com/docker/machine/libmachine/version"
 )
 
+// inTransition are the machines this process saved while creating,
+// provisioning or removing them.
+var (
+	inTransitionLock sync.Mutex
+	inTransition     = make(map[storedHost]bool)
+)
+
+type storedHost struct {
+	store Filestore
+	name  string
+}
+
 type Filestore struct {
 	Path             string
 	CaCertPath       string
@@ -65,12 +80,24 @@ func (s Filestore) Save(host *host.Host) error {
 		return err
 	}
 
-	return s.saveToFile(data, filepath.Join(hostPath, "config.json"))
+	if err := s.saveToFile(data, filepath.Join(hostPath, "config.json")); err != nil {
+		return err
+	}
+
+	setInTransition(s, host.Name, host.LifecycleState() != state.None)
+
+	return nil
 }
 
 func (s Filestore) Remove(name string) error {
 	hostPath := filepath.Join(s.getMachinesDir(), name)
-	return os.RemoveAll(hostPath)
+	if err := os.RemoveAll(hostPath); err != nil {
+		return err
+	}
+
+	setInTransition(s, name, false)
+
+	return nil
 }
 
 func (s Filestore) List() ([]*host.Host, error) {
@@ -126,6 +153,10 @@ func (s Filestore) loadConfig(h *host.Host) error {
 
 	h.Name = name
 
+	// A process killed while it was creating, provisioning or removing the
+	// host would leave it in that state forever.
+	h.ClearStaleTransition()
+
 	// If we end up performing a migration, we should save afterwards so we don't have to do it again on subsequent invocations.
 	if migrationPerformed {
 		if err := s.saveToFile(data, filepath.Join(s.getMachinesDir(), h.Name, "config.json.bak")); err != nil {
@@ -194,3 +225,81 @@ func (s Filestore) NewHost(driver drivers.Driver) (*host.Host, error) {
 		HostOptions:   hostOptions,
 	}, nil
 }
+
+func setInTransition(s Filestore, name string, saved bool) {
+	inTransitionLock.Lock()
+	defer inTransitionLock.Unlock()
+
+	if saved {
+		inTransition[storedHost{s, name}] = true
+	} else {
+		delete(inTransition, storedHost{s, name})
+	}
+}
+
+// InterruptTransitions moves the machines this process saved while creating,
+// provisioning or removing them to Error.  It is meant to be called when the
+// process is about to exit before it is done with them, e.g. on SIGINT.
+// The machines are saved without asking their driver for its configuration,
+// as its plugin may already be gone.
+func InterruptTransitions() {
+	inTransitionLock.Lock()
+	hosts := []storedHost{}
+	for sh := range inTransition {
+		hosts = append(hosts, sh)
+	}
+	inTransitionLock.Unlock()
+
+	for _, sh := range hosts {
+		if err := sh.store.interrupt(sh.name); err != nil {
+			log.Debugf("Error saving interrupted machine %q: %s", sh.name, err)
+		}
+	}
+}
+
+// rawDriver keeps the driver configuration of a host as it was saved.
+type rawDriver struct {
+	*none.Driver
+	data []byte
+}
+
+func (r *rawDriver) UnmarshalJSON(data []byte) error {
+	r.data = append([]byte(nil), data...)
+	return nil
+}
+
+func (r *rawDriver) MarshalJSON() ([]byte, error) {
+	return r.data, nil
+}
+
+// interrupt moves a host this process saved to Error, leaving the rest of
+// its configuration untouched.
+func (s Filestore) interrupt(name string) error {
+	path := filepath.Join(s.getMachinesDir(), name, "config.json")
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	h := &host.Host{
+		Driver: &rawDriver{Driver: none.NewDriver(name, s.Path)},
+	}
+	if err := json.Unmarshal(data, h); err != nil {
+		return err
+	}
+
+	h.Interrupt()
+
+	if data, err = json.MarshalIndent(h, "", "    "); err != nil {
+		return err
+	}
+
+	if err := s.saveToFile(data, path); err != nil {
+		return err
+	}
+
+	setInTransition(s, name, false)
+
+	return nil
+}
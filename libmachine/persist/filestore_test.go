@@ -11,6 +11,7 @@ import (
 	"github.com/docker/machine/commands/mcndirs"
 	_ "github.com/docker/machine/drivers/none"
 	"github.com/docker/machine/libmachine/hosttest"
+	"github.com/docker/machine/libmachine/state"
 )
 
 func cleanup() {
@@ -183,3 +184,39 @@ func TestStoreLoad(t *testing.T) {
 		t.Fatalf("GetURL is not %q, got %q", expectedURL, actualURL)
 	}
 }
+
+func TestInterruptTransitions(t *testing.T) {
+	defer cleanup()
+
+	store := getTestStore()
+
+	h, err := hosttest.GetDefaultTestHost()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.RawDriver = []byte(`{"URL":"unix:///foo/baz"}`)
+	h.SetTransition(state.Provisioning, "Provisioning machine")
+	if err := store.Save(h); err != nil {
+		t.Fatal(err)
+	}
+
+	InterruptTransitions()
+
+	h, err = store.Load(h.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h.LastTransition.State != state.Error || h.LastTransition.Reason != "Interrupted while provisioning" {
+		t.Fatalf("Expected the machine to be interrupted, got %+v", h.LastTransition)
+	}
+
+	if string(h.RawDriver) != `{"URL":"unix:///foo/baz"}` {
+		t.Fatalf("Expected the driver configuration to be kept, got %s", h.RawDriver)
+	}
+
+	if len(inTransition) != 0 {
+		t.Fatalf("Expected no machine left in transition, got %v", inTransition)
+	}
+}
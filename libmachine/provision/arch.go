@@ -12,6 +12,7 @@ import (
 	"github.com/docker/machine/libmachine/mcnutils"
 	"github.com/docker/machine/libmachine/provision/pkgaction"
 	"github.com/docker/machine/libmachine/provision/serviceaction"
+	"github.com/docker/machine/libmachine/shell"
 	"github.com/docker/machine/libmachine/swarm"
 )
 
@@ -47,7 +48,7 @@ func (provisioner *ArchProvisioner) Service(name string, action serviceaction.Se
 		return err
 	}
 
-	command := fmt.Sprintf("sudo systemctl %s %s", action.String(), name)
+	command := shell.Join("sudo", "systemctl", action.String(), name)
 
 	if _, err := provisioner.SSHCommand(command); err != nil {
 		return err
@@ -81,9 +82,7 @@ func (provisioner *ArchProvisioner) Package(name string, action pkgaction.Packag
 		pacmanOpts = pacmanOpts + "y"
 	}
 
-	pacmanOpts = pacmanOpts + " --noconfirm --noprogressbar"
-
-	command := fmt.Sprintf("sudo -E pacman %s %s", pacmanOpts, name)
+	command := shell.Join("sudo", "-E", "pacman", pacmanOpts, "--noconfirm", "--noprogressbar", name)
 
 	log.Debugf("package: action=%s name=%s", action.String(), name)
 
@@ -177,13 +176,13 @@ func (provisioner *ArchProvisioner) GenerateDockerOptions(dockerPort int) (*Dock
 	provisioner.EngineOptions.Labels = append(provisioner.EngineOptions.Labels, driverNameLabel)
 
 	engineConfigTmpl := `[Service]
-ExecStart=/usr/bin/docker -d -H tcp://0.0.0.0:{{.DockerPort}} -H unix:///var/run/docker.sock --storage-driver {{.EngineOptions.StorageDriver}} --tlsverify --tlscacert {{.AuthOptions.CaCertRemotePath}} --tlscert {{.AuthOptions.ServerCertRemotePath}} --tlskey {{.AuthOptions.ServerKeyRemotePath}} {{ range .EngineOptions.Labels }}--label {{.}} {{ end }}{{ range .EngineOptions.InsecureRegistry }}--insecure-registry {{.}} {{ end }}{{ range .EngineOptions.RegistryMirror }}--registry-mirror {{.}} {{ end }}{{ range .EngineOptions.ArbitraryFlags }}--{{.}} {{ end }}
+ExecStart=/usr/bin/docker -d -H tcp://0.0.0.0:{{.DockerPort}} -H unix:///var/run/docker.sock --storage-driver {{ systemd .EngineOptions.StorageDriver }} --tlsverify --tlscacert {{ systemd .AuthOptions.CaCertRemotePath }} --tlscert {{ systemd .AuthOptions.ServerCertRemotePath }} --tlskey {{ systemd .AuthOptions.ServerKeyRemotePath }} {{ range .EngineOptions.Labels }}--label {{ systemd . }} {{ end }}{{ range .EngineOptions.InsecureRegistry }}--insecure-registry {{ systemd . }} {{ end }}{{ range .EngineOptions.RegistryMirror }}--registry-mirror {{ systemd . }} {{ end }}{{ range .EngineOptions.ArbitraryFlags }}{{ systemd (print "--" .) }} {{ end }}
 MountFlags=slave
 LimitNOFILE=1048576
 LimitNPROC=1048576
 LimitCORE=infinity
 `
-	t, err := template.New("engineConfig").Parse(engineConfigTmpl)
+	t, err := template.New("engineConfig").Funcs(engineConfigFuncs).Parse(engineConfigTmpl)
 	if err != nil {
 		return nil, err
 	}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"path"
 	"text/template"
@@ -17,6 +18,8 @@ import (
 	"github.com/docker/machine/libmachine/mcnutils"
 	"github.com/docker/machine/libmachine/provision/pkgaction"
 	"github.com/docker/machine/libmachine/provision/serviceaction"
+	"github.com/docker/machine/libmachine/shell"
+	"github.com/docker/machine/libmachine/ssh"
 	"github.com/docker/machine/libmachine/state"
 	"github.com/docker/machine/libmachine/swarm"
 )
@@ -42,7 +45,7 @@ type Boot2DockerProvisioner struct {
 }
 
 func (provisioner *Boot2DockerProvisioner) Service(name string, action serviceaction.ServiceAction) error {
-	_, err := provisioner.SSHCommand(fmt.Sprintf("sudo /etc/init.d/%s %s", name, action.String()))
+	_, err := provisioner.SSHCommand(shell.Join("sudo", "/etc/init.d/"+name, action.String()))
 	return err
 }
 
@@ -78,7 +81,7 @@ func (provisioner *Boot2DockerProvisioner) upgradeIso() error {
 		return err
 	}
 
-	if err := mcnutils.WaitFor(drivers.MachineInState(provisioner.Driver, state.Stopped)); err != nil {
+	if err := drivers.WaitForState(provisioner.Driver, state.Stopped); err != nil {
 		return err
 	}
 
@@ -98,7 +101,7 @@ func (provisioner *Boot2DockerProvisioner) upgradeIso() error {
 		return err
 	}
 
-	return mcnutils.WaitFor(drivers.MachineInState(provisioner.Driver, state.Running))
+	return drivers.WaitForState(provisioner.Driver, state.Running)
 }
 
 func (provisioner *Boot2DockerProvisioner) Package(name string, action pkgaction.PackageAction) error {
@@ -115,8 +118,8 @@ func (provisioner *Boot2DockerProvisioner) Hostname() (string, error) {
 }
 
 func (provisioner *Boot2DockerProvisioner) SetHostname(hostname string) error {
-	if _, err := provisioner.SSHCommand(fmt.Sprintf(
-		"sudo /usr/bin/sethostname %s && echo %q | sudo tee /var/lib/boot2docker/etc/hostname",
+	if _, err := provisioner.SSHCommand(shell.Sprintf(
+		"sudo /usr/bin/sethostname %s && printf '%%s\\n' %s | sudo tee /var/lib/boot2docker/etc/hostname",
 		hostname,
 		hostname,
 	)); err != nil {
@@ -144,23 +147,23 @@ func (provisioner *Boot2DockerProvisioner) GenerateDockerOptions(dockerPort int)
 
 	engineConfigTmpl := `
 EXTRA_ARGS='
-{{ range .EngineOptions.Labels }}--label {{.}}
-{{ end }}{{ range .EngineOptions.InsecureRegistry }}--insecure-registry {{.}}
-{{ end }}{{ range .EngineOptions.RegistryMirror }}--registry-mirror {{.}}
-{{ end }}{{ range .EngineOptions.ArbitraryFlags }}--{{.}}
+{{ range .EngineOptions.Labels }}--label {{ escape . }}
+{{ end }}{{ range .EngineOptions.InsecureRegistry }}--insecure-registry {{ escape . }}
+{{ end }}{{ range .EngineOptions.RegistryMirror }}--registry-mirror {{ escape . }}
+{{ end }}{{ range .EngineOptions.ArbitraryFlags }}--{{ escape . }}
 {{ end }}
 '
-CACERT={{.AuthOptions.CaCertRemotePath}}
+CACERT={{ quote .AuthOptions.CaCertRemotePath }}
 DOCKER_HOST='-H tcp://0.0.0.0:{{.DockerPort}}'
-DOCKER_STORAGE={{.EngineOptions.StorageDriver}}
+DOCKER_STORAGE={{ quote .EngineOptions.StorageDriver }}
 DOCKER_TLS=auto
-SERVERKEY={{.AuthOptions.ServerKeyRemotePath}}
-SERVERCERT={{.AuthOptions.ServerCertRemotePath}}
+SERVERKEY={{ quote .AuthOptions.ServerKeyRemotePath }}
+SERVERCERT={{ quote .AuthOptions.ServerCertRemotePath }}
 
-{{range .EngineOptions.Env}}export \"{{ printf "%q" . }}\"
+{{range .EngineOptions.Env}}export {{ quote . }}
 {{end}}
 `
-	t, err := template.New("engineConfig").Parse(engineConfigTmpl)
+	t, err := template.New("engineConfig").Funcs(engineConfigFuncs).Parse(engineConfigTmpl)
 	if err != nil {
 		return nil, err
 	}
@@ -272,6 +275,14 @@ func (provisioner *Boot2DockerProvisioner) SSHCommand(args string) (string, erro
 	return drivers.RunSSHCommandFromDriver(provisioner.Driver, args)
 }
 
+func (provisioner *Boot2DockerProvisioner) RunSSHCommand(args string) (*ssh.Result, error) {
+	return drivers.RunSSHCommand(provisioner.Driver, args)
+}
+
+func (provisioner *Boot2DockerProvisioner) StreamSSHCommand(args string, stdout, stderr io.Writer) error {
+	return drivers.StreamSSHCommand(provisioner.Driver, args, stdout, stderr)
+}
+
 func (provisioner *Boot2DockerProvisioner) GetDriver() drivers.Driver {
 	return provisioner.Driver
 }
@@ -2,13 +2,13 @@ package provision
 
 import (
 	"bytes"
-	"fmt"
 	"net/url"
 	"strings"
 	"text/template"
 
 	"github.com/docker/machine/libmachine/auth"
 	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/shell"
 	"github.com/docker/machine/libmachine/swarm"
 )
 
@@ -24,29 +24,68 @@ type SwarmCommandContext struct {
 	SwarmImage    string
 }
 
+const (
+	swarmMasterCmdTemplate = `sudo docker run -d \
+--restart=always \
+{{range .Env}} -e {{quote .}}{{end}} \
+--name swarm-agent-master \
+-p {{quote .Port}}:{{quote .Port}} \
+-v {{quote .DockerDir}}:{{quote .DockerDir}} \
+{{quote .SwarmImage}} \
+manage \
+--tlsverify \
+--tlscacert={{quote .AuthOptions.CaCertRemotePath}} \
+--tlscert={{quote .AuthOptions.ServerCertRemotePath}} \
+--tlskey={{quote .AuthOptions.ServerKeyRemotePath}} \
+-H {{quote .SwarmOptions.Host}} \
+--strategy {{quote .SwarmOptions.Strategy}} {{range .SwarmOptions.ArbitraryFlags}} --{{quote .}}{{end}} {{quote .SwarmOptions.Discovery}}
+`
+
+	swarmWorkerCmdTemplate = `sudo docker run -d \
+--restart=always \
+{{range .Env}} -e {{quote .}}{{end}} \
+--name swarm-agent \
+{{quote .SwarmImage}} \
+join --advertise {{quote .IP}}:{{.DockerPort}} {{quote .SwarmOptions.Discovery}}
+`
+)
+
 // Wrapper function to generate a docker run swarm command (manage or join)
 // from a template/context and execute it.
 func runSwarmCommandFromTemplate(p Provisioner, cmdTmpl string, swarmCmdContext SwarmCommandContext) error {
-	var (
-		executedCmdTmpl bytes.Buffer
-	)
-
-	parsedMasterCmdTemplate, err := template.New("swarmMasterCmd").Parse(cmdTmpl)
+	command, err := swarmCommand(cmdTmpl, swarmCmdContext)
 	if err != nil {
 		return err
 	}
 
-	parsedMasterCmdTemplate.Execute(&executedCmdTmpl, swarmCmdContext)
+	log.Debugf("The swarm command being run is: %s", command)
 
-	log.Debugf("The swarm command being run is: %s", executedCmdTmpl.String())
-
-	if _, err := p.SSHCommand(executedCmdTmpl.String()); err != nil {
+	if _, err := p.SSHCommand(command); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// swarmCommand generates a docker run swarm command.  The values are quoted,
+// the options of swarm come from the user.
+func swarmCommand(cmdTmpl string, swarmCmdContext SwarmCommandContext) (string, error) {
+	var (
+		executedCmdTmpl bytes.Buffer
+	)
+
+	parsedCmdTemplate, err := template.New("swarmCmd").Funcs(template.FuncMap{"quote": shell.Quote}).Parse(cmdTmpl)
+	if err != nil {
+		return "", err
+	}
+
+	if err := parsedCmdTemplate.Execute(&executedCmdTmpl, swarmCmdContext); err != nil {
+		return "", err
+	}
+
+	return executedCmdTmpl.String(), nil
+}
+
 func configureSwarm(p Provisioner, swarmOptions swarm.Options, authOptions auth.Options) error {
 	if !swarmOptions.IsSwarm {
 		return nil
@@ -82,34 +121,10 @@ func configureSwarm(p Provisioner, swarmOptions swarm.Options, authOptions auth.
 	}
 
 	// First things first, get the swarm image.
-	if _, err := p.SSHCommand(fmt.Sprintf("sudo docker pull %s", swarmOptions.Image)); err != nil {
+	if _, err := p.SSHCommand(shell.Join("sudo", "docker", "pull", swarmOptions.Image)); err != nil {
 		return err
 	}
 
-	swarmMasterCmdTemplate := `sudo docker run -d \
---restart=always \
-{{range .Env}} -e {{.}}{{end}} \
---name swarm-agent-master \
--p {{.Port}}:{{.Port}} \
--v {{.DockerDir}}:{{.DockerDir}} \
-{{.SwarmImage}} \
-manage \
---tlsverify \
---tlscacert={{.AuthOptions.CaCertRemotePath}} \
---tlscert={{.AuthOptions.ServerCertRemotePath}} \
---tlskey={{.AuthOptions.ServerKeyRemotePath}} \
--H {{.SwarmOptions.Host}} \
---strategy {{.SwarmOptions.Strategy}} {{range .SwarmOptions.ArbitraryFlags}} --{{.}}{{end}} {{.SwarmOptions.Discovery}}
-`
-
-	swarmWorkerCmdTemplate := `sudo docker run -d \
---restart=always \
-{{range .Env}} -e {{.}}{{end}} \
---name swarm-agent \
-{{.SwarmImage}} \
-join --advertise {{.IP}}:{{.DockerPort}} {{.SwarmOptions.Discovery}}
-`
-
 	if swarmOptions.Master {
 		log.Debug("Launching swarm master")
 		if err := runSwarmCommandFromTemplate(p, swarmMasterCmdTemplate, swarmCmdContext); err != nil {
@@ -0,0 +1,82 @@
+package provision
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/swarm"
+	"github.com/stretchr/testify/assert"
+)
+
+// runShellArgs runs a command with sh, docker replaced by printf, and
+// returns the arguments docker would have got.
+func runShellArgs(t *testing.T, command string) []string {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not found")
+	}
+
+	command = strings.Replace(command, "sudo docker", `printf '%s\0'`, 1)
+	output, err := exec.Command(sh, "-c", command).Output()
+	if err != nil {
+		t.Fatalf("Error running %q: %s", command, err)
+	}
+
+	return strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00")
+}
+
+func TestSwarmCommandQuoting(t *testing.T) {
+	context := SwarmCommandContext{
+		Env:        []string{"TOKEN=it's $(reboot)"},
+		DockerDir:  "/etc/docker",
+		DockerPort: 2376,
+		IP:         "10.0.0.2",
+		Port:       "3376",
+		AuthOptions: auth.Options{
+			CaCertRemotePath:     "/etc/docker/ca.pem",
+			ServerCertRemotePath: "/etc/docker/server.pem",
+			ServerKeyRemotePath:  "/etc/docker/server-key.pem",
+		},
+		SwarmOptions: swarm.Options{
+			Host:           "tcp://0.0.0.0:3376",
+			Strategy:       "spread",
+			ArbitraryFlags: []string{"label=a b; reboot"},
+			Discovery:      "token://`reboot`",
+		},
+		SwarmImage: "swarm:latest",
+	}
+
+	command, err := swarmCommand(swarmMasterCmdTemplate, context)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"run", "-d",
+		"--restart=always",
+		"-e", "TOKEN=it's $(reboot)",
+		"--name", "swarm-agent-master",
+		"-p", "3376:3376",
+		"-v", "/etc/docker:/etc/docker",
+		"swarm:latest",
+		"manage",
+		"--tlsverify",
+		"--tlscacert=/etc/docker/ca.pem",
+		"--tlscert=/etc/docker/server.pem",
+		"--tlskey=/etc/docker/server-key.pem",
+		"-H", "tcp://0.0.0.0:3376",
+		"--strategy", "spread",
+		"--label=a b; reboot",
+		"token://`reboot`",
+	}, runShellArgs(t, command))
+
+	command, err = swarmCommand(swarmWorkerCmdTemplate, context)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"run", "-d",
+		"--restart=always",
+		"-e", "TOKEN=it's $(reboot)",
+		"--name", "swarm-agent",
+		"swarm:latest",
+		"join", "--advertise", "10.0.0.2:2376", "token://`reboot`",
+	}, runShellArgs(t, command))
+}
@@ -11,15 +11,16 @@ import (
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/provision/pkgaction"
 	"github.com/docker/machine/libmachine/provision/serviceaction"
+	"github.com/docker/machine/libmachine/shell"
 	"github.com/docker/machine/libmachine/swarm"
 )
 
 const (
-	hostTmpl = `sudo tee /var/tmp/hostname.yml << EOF
-#cloud-config
+	// The hostname is a double-quoted YAML string, whose escapes are the
+	// ones of Go.
+	hostTmpl = `#cloud-config
 
-hostname: %s
-EOF
+hostname: %q
 `
 )
 
@@ -50,7 +51,7 @@ func (provisioner *CoreOSProvisioner) Service(name string, action serviceaction.
 		return err
 	}
 
-	command := fmt.Sprintf("sudo systemctl %s %s", action.String(), name)
+	command := shell.Join("sudo", "systemctl", action.String(), name)
 
 	if _, err := provisioner.SSHCommand(command); err != nil {
 		return err
@@ -69,7 +70,7 @@ func (provisioner *CoreOSProvisioner) Service(name string, action serviceaction.
 func (provisioner *CoreOSProvisioner) SetHostname(hostname string) error {
 	log.Debugf("SetHostname: %s", hostname)
 
-	if _, err := provisioner.SSHCommand(fmt.Sprintf(hostTmpl, hostname)); err != nil {
+	if err := WriteFile(provisioner, "/var/tmp/hostname.yml", []byte(fmt.Sprintf(hostTmpl, hostname)), 0644); err != nil {
 		return err
 	}
 
@@ -99,13 +100,13 @@ EnvironmentFile=-/run/flannel_docker_opts.env
 MountFlags=slave
 LimitNOFILE=1048576
 LimitNPROC=1048576
-ExecStart=/usr/lib/coreos/dockerd --daemon --host=unix:///var/run/docker.sock --host=tcp://0.0.0.0:{{.DockerPort}} --tlsverify --tlscacert {{.AuthOptions.CaCertRemotePath}} --tlscert {{.AuthOptions.ServerCertRemotePath}} --tlskey {{.AuthOptions.ServerKeyRemotePath}}{{ range .EngineOptions.Labels }} --label {{.}}{{ end }}{{ range .EngineOptions.InsecureRegistry }} --insecure-registry {{.}}{{ end }}{{ range .EngineOptions.RegistryMirror }} --registry-mirror {{.}}{{ end }}{{ range .EngineOptions.ArbitraryFlags }} --{{.}}{{ end }} \$DOCKER_OPTS \$DOCKER_OPT_BIP \$DOCKER_OPT_MTU \$DOCKER_OPT_IPMASQ
+ExecStart=/usr/lib/coreos/dockerd --daemon --host=unix:///var/run/docker.sock --host=tcp://0.0.0.0:{{.DockerPort}} --tlsverify --tlscacert {{ systemd .AuthOptions.CaCertRemotePath }} --tlscert {{ systemd .AuthOptions.ServerCertRemotePath }} --tlskey {{ systemd .AuthOptions.ServerKeyRemotePath }}{{ range .EngineOptions.Labels }} --label {{ systemd . }}{{ end }}{{ range .EngineOptions.InsecureRegistry }} --insecure-registry {{ systemd . }}{{ end }}{{ range .EngineOptions.RegistryMirror }} --registry-mirror {{ systemd . }}{{ end }}{{ range .EngineOptions.ArbitraryFlags }} {{ systemd (print "--" .) }}{{ end }} $DOCKER_OPTS $DOCKER_OPT_BIP $DOCKER_OPT_MTU $DOCKER_OPT_IPMASQ
 
 [Install]
 WantedBy=multi-user.target
 `
 
-	t, err := template.New("engineConfig").Parse(engineConfigTmpl)
+	t, err := template.New("engineConfig").Funcs(engineConfigFuncs).Parse(engineConfigTmpl)
 	if err != nil {
 		return nil, err
 	}
@@ -12,6 +12,7 @@ import (
 	"github.com/docker/machine/libmachine/mcnutils"
 	"github.com/docker/machine/libmachine/provision/pkgaction"
 	"github.com/docker/machine/libmachine/provision/serviceaction"
+	"github.com/docker/machine/libmachine/shell"
 	"github.com/docker/machine/libmachine/swarm"
 )
 
@@ -45,7 +46,7 @@ func (provisioner *DebianProvisioner) Service(name string, action serviceaction.
 		return err
 	}
 
-	command := fmt.Sprintf("sudo systemctl -f %s %s", action.String(), name)
+	command := shell.Join("sudo", "systemctl", "-f", action.String(), name)
 
 	if _, err := provisioner.SSHCommand(command); err != nil {
 		return err
@@ -100,7 +101,7 @@ func (provisioner *DebianProvisioner) Package(name string, action pkgaction.Pack
 		}
 	}
 
-	command := fmt.Sprintf("DEBIAN_FRONTEND=noninteractive sudo -E apt-get %s -y  %s", packageAction, name)
+	command := "DEBIAN_FRONTEND=noninteractive " + shell.Join("sudo", "-E", "apt-get", packageAction, "-y", name)
 
 	log.Debugf("package: action=%s name=%s", action.String(), name)
 
@@ -189,17 +190,17 @@ func (provisioner *DebianProvisioner) GenerateDockerOptions(dockerPort int) (*Do
 	provisioner.EngineOptions.Labels = append(provisioner.EngineOptions.Labels, driverNameLabel)
 
 	engineConfigTmpl := `[Service]
-ExecStart=/usr/bin/docker -d -H tcp://0.0.0.0:{{.DockerPort}} -H unix:///var/run/docker.sock --storage-driver {{.EngineOptions.StorageDriver}} --tlsverify --tlscacert {{.AuthOptions.CaCertRemotePath}} --tlscert {{.AuthOptions.ServerCertRemotePath}} --tlskey {{.AuthOptions.ServerKeyRemotePath}} {{ range .EngineOptions.Labels }}--label {{.}} {{ end }}{{ range .EngineOptions.InsecureRegistry }}--insecure-registry {{.}} {{ end }}{{ range .EngineOptions.RegistryMirror }}--registry-mirror {{.}} {{ end }}{{ range .EngineOptions.ArbitraryFlags }}--{{.}} {{ end }}
+ExecStart=/usr/bin/docker -d -H tcp://0.0.0.0:{{.DockerPort}} -H unix:///var/run/docker.sock --storage-driver {{ systemd .EngineOptions.StorageDriver }} --tlsverify --tlscacert {{ systemd .AuthOptions.CaCertRemotePath }} --tlscert {{ systemd .AuthOptions.ServerCertRemotePath }} --tlskey {{ systemd .AuthOptions.ServerKeyRemotePath }} {{ range .EngineOptions.Labels }}--label {{ systemd . }} {{ end }}{{ range .EngineOptions.InsecureRegistry }}--insecure-registry {{ systemd . }} {{ end }}{{ range .EngineOptions.RegistryMirror }}--registry-mirror {{ systemd . }} {{ end }}{{ range .EngineOptions.ArbitraryFlags }}{{ systemd (print "--" .) }} {{ end }}
 MountFlags=slave
 LimitNOFILE=1048576
 LimitNPROC=1048576
 LimitCORE=infinity
-Environment={{range .EngineOptions.Env}}{{ printf "%q" . }} {{end}}
+Environment={{range .EngineOptions.Env}}{{ systemdEnv . }} {{end}}
 
 [Install]
 WantedBy=multi-user.target
 `
-	t, err := template.New("engineConfig").Parse(engineConfigTmpl)
+	t, err := template.New("engineConfig").Funcs(engineConfigFuncs).Parse(engineConfigTmpl)
 	if err != nil {
 		return nil, err
 	}
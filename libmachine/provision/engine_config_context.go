@@ -1,8 +1,11 @@
 package provision
 
 import (
+	"text/template"
+
 	"github.com/docker/machine/libmachine/auth"
 	"github.com/docker/machine/libmachine/engine"
+	"github.com/docker/machine/libmachine/shell"
 )
 
 type EngineConfigContext struct {
@@ -11,3 +14,15 @@ type EngineConfigContext struct {
 	EngineOptions    engine.Options
 	DockerOptionsDir string
 }
+
+// engineConfigFuncs are the functions of the engine configurations: quote
+// makes a value one word and escape puts it between single quotes, for the
+// configurations sourced by a shell, while systemd makes a value one word
+// of the command line of a systemd unit and systemdEnv one assignment of
+// its environment.
+var engineConfigFuncs = template.FuncMap{
+	"quote":      shell.Quote,
+	"escape":     shell.Escape,
+	"systemd":    shell.SystemdQuote,
+	"systemdEnv": shell.SystemdQuoteEnv,
+}
@@ -3,11 +3,15 @@ package provision
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"strings"
 	"text/template"
 
 	"github.com/docker/machine/libmachine/auth"
 	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/engine"
+	"github.com/docker/machine/libmachine/shell"
+	"github.com/docker/machine/libmachine/ssh"
 	"github.com/docker/machine/libmachine/swarm"
 )
 
@@ -28,26 +32,36 @@ func (provisioner *GenericProvisioner) Hostname() (string, error) {
 }
 
 func (provisioner *GenericProvisioner) SetHostname(hostname string) error {
-	if _, err := provisioner.SSHCommand(fmt.Sprintf(
-		"sudo hostname %s && echo %q | sudo tee /etc/hostname",
+	if _, err := provisioner.SSHCommand(shell.Sprintf(
+		"sudo hostname %s && printf '%%s\\n' %s | sudo tee /etc/hostname",
 		hostname,
 		hostname,
 	)); err != nil {
 		return err
 	}
 
-	// ubuntu/debian use 127.0.1.1 for non "localhost" loopback hostnames: https://www.debian.org/doc/manuals/debian-reference/ch05.en.html#_the_hostname_resolution
-	if _, err := provisioner.SSHCommand(fmt.Sprintf(
-		"if grep -xq 127.0.1.1.* /etc/hosts; then sudo sed -i 's/^127.0.1.1.*/127.0.1.1 %s/g' /etc/hosts; else echo '127.0.1.1 %s' | sudo tee -a /etc/hosts; fi",
-		hostname,
-		hostname,
-	)); err != nil {
+	if _, err := provisioner.SSHCommand(etcHostsCommand(hostname)); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// etcHostsCommand maps 127.0.1.1 to the hostname in /etc/hosts, since
+// ubuntu/debian use 127.0.1.1 for non "localhost" loopback hostnames: https://www.debian.org/doc/manuals/debian-reference/ch05.en.html#_the_hostname_resolution
+func etcHostsCommand(hostname string) string {
+	entry := "127.0.1.1 " + hostname
+
+	// The entry is the replacement of a sed expression as well.
+	replacement := strings.NewReplacer(`\`, `\\`, "/", `\/`, "&", `\&`, "\n", `\n`).Replace(entry)
+
+	return shell.Sprintf(
+		"if grep -xq '127.0.1.1.*' /etc/hosts; then sudo sed -i %s /etc/hosts; else printf '%%s\\n' %s | sudo tee -a /etc/hosts; fi",
+		"s/^127.0.1.1.*/"+replacement+"/g",
+		entry,
+	)
+}
+
 func (provisioner *GenericProvisioner) GetDockerOptionsDir() string {
 	return provisioner.DockerOptionsDir
 }
@@ -56,6 +70,14 @@ func (provisioner *GenericProvisioner) SSHCommand(args string) (string, error) {
 	return drivers.RunSSHCommandFromDriver(provisioner.Driver, args)
 }
 
+func (provisioner *GenericProvisioner) RunSSHCommand(args string) (*ssh.Result, error) {
+	return drivers.RunSSHCommand(provisioner.Driver, args)
+}
+
+func (provisioner *GenericProvisioner) StreamSSHCommand(args string, stdout, stderr io.Writer) error {
+	return drivers.StreamSSHCommand(provisioner.Driver, args, stdout, stderr)
+}
+
 func (provisioner *GenericProvisioner) CompatibleWithHost() bool {
 	return provisioner.OsReleaseInfo.ID == provisioner.OsReleaseID
 }
@@ -84,21 +106,21 @@ func (provisioner *GenericProvisioner) GenerateDockerOptions(dockerPort int) (*D
 DOCKER_OPTS='
 -H tcp://0.0.0.0:{{.DockerPort}}
 -H unix:///var/run/docker.sock
---storage-driver {{.EngineOptions.StorageDriver}}
+--storage-driver {{ escape .EngineOptions.StorageDriver }}
 --tlsverify
---tlscacert {{.AuthOptions.CaCertRemotePath}}
---tlscert {{.AuthOptions.ServerCertRemotePath}}
---tlskey {{.AuthOptions.ServerKeyRemotePath}}
-{{ range .EngineOptions.Labels }}--label {{.}}
-{{ end }}{{ range .EngineOptions.InsecureRegistry }}--insecure-registry {{.}}
-{{ end }}{{ range .EngineOptions.RegistryMirror }}--registry-mirror {{.}}
-{{ end }}{{ range .EngineOptions.ArbitraryFlags }}--{{.}}
+--tlscacert {{ escape .AuthOptions.CaCertRemotePath }}
+--tlscert {{ escape .AuthOptions.ServerCertRemotePath }}
+--tlskey {{ escape .AuthOptions.ServerKeyRemotePath }}
+{{ range .EngineOptions.Labels }}--label {{ escape . }}
+{{ end }}{{ range .EngineOptions.InsecureRegistry }}--insecure-registry {{ escape . }}
+{{ end }}{{ range .EngineOptions.RegistryMirror }}--registry-mirror {{ escape . }}
+{{ end }}{{ range .EngineOptions.ArbitraryFlags }}--{{ escape . }}
 {{ end }}
 '
-{{range .EngineOptions.Env}}export \"{{ printf "%q" . }}\"
+{{range .EngineOptions.Env}}export {{ quote . }}
 {{end}}
 `
-	t, err := template.New("engineConfig").Parse(engineConfigTmpl)
+	t, err := template.New("engineConfig").Funcs(engineConfigFuncs).Parse(engineConfigTmpl)
 	if err != nil {
 		return nil, err
 	}
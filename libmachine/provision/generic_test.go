@@ -0,0 +1,119 @@
+package provision
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/machine/drivers/fakedriver"
+	"github.com/docker/machine/libmachine/auth"
+	"github.com/docker/machine/libmachine/engine"
+	"github.com/stretchr/testify/assert"
+)
+
+func runShell(t *testing.T, command string) string {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not found")
+	}
+
+	output, err := exec.Command(sh, "-c", command).CombinedOutput()
+	if err != nil {
+		t.Fatalf("Error running %q: %s: %s", command, err, output)
+	}
+
+	return string(output)
+}
+
+func TestEtcHostsCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	hosts := filepath.Join(dir, "hosts")
+	command := func(hostname string) string {
+		command := strings.Replace(etcHostsCommand(hostname), "/etc/hosts", hosts, -1)
+		return strings.Replace(command, "sudo ", "", -1)
+	}
+
+	// The entry is added...
+	assert.NoError(t, ioutil.WriteFile(hosts, []byte("127.0.0.1 localhost\n"), 0644))
+	runShell(t, command("it's/$(touch pwned)&`id`\\"))
+
+	data, err := ioutil.ReadFile(hosts)
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1 localhost\n127.0.1.1 it's/$(touch pwned)&`id`\\\n", string(data))
+
+	// ...and replaced.
+	runShell(t, command("a & b"))
+
+	data, err = ioutil.ReadFile(hosts)
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1 localhost\n127.0.1.1 a & b\n", string(data))
+
+	_, err = os.Stat("pwned")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestGenerateDockerOptionsGenericQuoting(t *testing.T) {
+	p := &GenericProvisioner{
+		Driver: &fakedriver.Driver{},
+		EngineOptions: engine.Options{
+			StorageDriver:  "aufs",
+			Labels:         []string{"owner=it's"},
+			ArbitraryFlags: []string{"log-opt='$(reboot)'"},
+			Env:            []string{"HTTP_PROXY=http://proxy:3128/?a=1&b='$HOME'"},
+		},
+		AuthOptions: auth.Options{
+			CaCertRemotePath:     "/etc/docker/ca.pem",
+			ServerCertRemotePath: "/etc/docker/server.pem",
+			ServerKeyRemotePath:  "/etc/docker/server-key.pem",
+		},
+	}
+
+	dockerCfg, err := p.GenerateDockerOptions(2376)
+	assert.NoError(t, err)
+
+	// The configuration is sourced by a shell.
+	output := runShell(t, dockerCfg.EngineOptions+`printf '%s\n' "$HTTP_PROXY" $DOCKER_OPTS`)
+	assert.Equal(t, []string{
+		"http://proxy:3128/?a=1&b='$HOME'",
+		"-H", "tcp://0.0.0.0:2376",
+		"-H", "unix:///var/run/docker.sock",
+		"--storage-driver", "aufs",
+		"--tlsverify",
+		"--tlscacert", "/etc/docker/ca.pem",
+		"--tlscert", "/etc/docker/server.pem",
+		"--tlskey", "/etc/docker/server-key.pem",
+		"--label", "owner=it's",
+		"--label", "provider=Driver",
+		"--log-opt='$(reboot)'",
+	}, strings.Split(strings.TrimSuffix(output, "\n"), "\n"))
+}
+
+func TestGenerateDockerOptionsSystemdQuoting(t *testing.T) {
+	p := NewDebianProvisioner(&fakedriver.Driver{}).(*DebianProvisioner)
+	p.EngineOptions = engine.Options{
+		StorageDriver:    "aufs",
+		Labels:           []string{"owner=it's 100% $USER"},
+		InsecureRegistry: []string{`registry:5000\`},
+		RegistryMirror:   []string{"http://mirror/\"a b\""},
+		ArbitraryFlags:   []string{"log-opt=tag=%n"},
+		Env:              []string{"HTTP_PROXY=http://proxy:3128/?a=$HOME&b=50%"},
+	}
+	p.AuthOptions = auth.Options{
+		CaCertRemotePath:     "/etc/docker/ca.pem",
+		ServerCertRemotePath: "/etc/docker/my server.pem",
+		ServerKeyRemotePath:  "/etc/docker/server-key.pem",
+	}
+
+	dockerCfg, err := p.GenerateDockerOptions(2376)
+	assert.NoError(t, err)
+
+	// The unit is read by systemd, which expands % and $ even in quotes.
+	assert.Contains(t, dockerCfg.EngineOptions, `ExecStart=/usr/bin/docker -d -H tcp://0.0.0.0:2376 -H unix:///var/run/docker.sock --storage-driver aufs --tlsverify --tlscacert /etc/docker/ca.pem --tlscert "/etc/docker/my server.pem" --tlskey /etc/docker/server-key.pem --label "owner=it's 100%% $$USER" --label provider=Driver --insecure-registry "registry:5000\\" --registry-mirror "http://mirror/\"a b\"" "--log-opt=tag=%%n" `+"\n")
+	assert.Contains(t, dockerCfg.EngineOptions, `Environment="HTTP_PROXY=http://proxy:3128/?a=$HOME&b=50%%" `+"\n")
+}
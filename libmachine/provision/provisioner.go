@@ -2,6 +2,7 @@ package provision
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/docker/machine/libmachine/auth"
 	"github.com/docker/machine/libmachine/drivers"
@@ -9,6 +10,7 @@ import (
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/provision/pkgaction"
 	"github.com/docker/machine/libmachine/provision/serviceaction"
+	"github.com/docker/machine/libmachine/ssh"
 	"github.com/docker/machine/libmachine/swarm"
 )
 
@@ -54,6 +56,16 @@ type Provisioner interface {
 	// Short-hand for accessing an SSH command from the driver.
 	SSHCommand(args string) (string, error)
 
+	// RunSSHCommand runs a command as SSHCommand does, and returns what it
+	// printed on its standard output and error apart, and its exit status.
+	// If the command fails, the error is an *ssh.ExitError.
+	RunSSHCommand(args string) (*ssh.Result, error)
+
+	// StreamSSHCommand runs a command as SSHCommand does, writing what it
+	// prints to the writers as it runs.  If the command fails, the error is
+	// an *ssh.ExitError.
+	StreamSSHCommand(args string, stdout, stderr io.Writer) error
+
 	// Set the OS Release info depending on how it's represented
 	// internally
 	SetOsReleaseInfo(info *OsRelease)
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"net/http"
+	"path"
 	"strings"
 
 	"github.com/docker/machine/commands/mcndirs"
@@ -14,6 +15,7 @@ import (
 	"github.com/docker/machine/libmachine/mcnutils"
 	"github.com/docker/machine/libmachine/provision/pkgaction"
 	"github.com/docker/machine/libmachine/provision/serviceaction"
+	"github.com/docker/machine/libmachine/shell"
 	"github.com/docker/machine/libmachine/state"
 	"github.com/docker/machine/libmachine/swarm"
 )
@@ -21,12 +23,10 @@ import (
 const (
 	versionsURL  = "http://releases.rancher.com/os/versions.yml"
 	isoURL       = "https://github.com/rancherio/os/releases/download/%s/machine-rancheros.iso"
-	hostnameTmpl = `sudo mkdir -p /var/lib/rancher/conf/cloud-config.d/  
-sudo tee /var/lib/rancher/conf/cloud-config.d/machine-hostname.yml << EOF
-#cloud-config
+	hostnameDir  = "/var/lib/rancher/conf/cloud-config.d"
+	hostnameTmpl = `#cloud-config
 
-hostname: %s
-EOF
+hostname: %q
 `
 )
 
@@ -52,7 +52,7 @@ type RancherProvisioner struct {
 }
 
 func (provisioner *RancherProvisioner) Service(name string, action serviceaction.ServiceAction) error {
-	command := fmt.Sprintf("sudo system-docker %s %s", action.String(), name)
+	command := shell.Join("sudo", "system-docker", action.String(), name)
 
 	if _, err := provisioner.SSHCommand(command); err != nil {
 		return err
@@ -78,7 +78,7 @@ func (provisioner *RancherProvisioner) Package(name string, action pkgaction.Pac
 		packageAction = "upgrade"
 	}
 
-	command := fmt.Sprintf("sudo rancherctl service %s %s", packageAction, name)
+	command := shell.Join("sudo", "rancherctl", "service", packageAction, name)
 
 	if _, err := provisioner.SSHCommand(command); err != nil {
 		return err
@@ -137,7 +137,11 @@ func (provisioner *RancherProvisioner) SetHostname(hostname string) error {
 		return err
 	}
 
-	if _, err := provisioner.SSHCommand(fmt.Sprintf(hostnameTmpl, hostname)); err != nil {
+	if _, err := provisioner.SSHCommand(shell.Join("sudo", "mkdir", "-p", hostnameDir)); err != nil {
+		return err
+	}
+
+	if err := WriteFile(provisioner, path.Join(hostnameDir, "machine-hostname.yml"), []byte(fmt.Sprintf(hostnameTmpl, hostname)), 0644); err != nil {
 		return err
 	}
 
@@ -170,7 +174,7 @@ func (provisioner *RancherProvisioner) upgradeIso() error {
 		return err
 	}
 
-	if err := mcnutils.WaitFor(drivers.MachineInState(provisioner.Driver, state.Stopped)); err != nil {
+	if err := drivers.WaitForState(provisioner.Driver, state.Stopped); err != nil {
 		return err
 	}
 
@@ -203,7 +207,7 @@ func (provisioner *RancherProvisioner) upgradeIso() error {
 		return err
 	}
 
-	return mcnutils.WaitFor(drivers.MachineInState(provisioner.Driver, state.Running))
+	return drivers.WaitForState(provisioner.Driver, state.Running)
 }
 
 func (provisioner *RancherProvisioner) getLatestISOURL() (string, error) {
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"text/template"
 
 	"github.com/docker/machine/libmachine/auth"
@@ -13,6 +14,7 @@ import (
 	"github.com/docker/machine/libmachine/mcnutils"
 	"github.com/docker/machine/libmachine/provision/pkgaction"
 	"github.com/docker/machine/libmachine/provision/serviceaction"
+	"github.com/docker/machine/libmachine/shell"
 	"github.com/docker/machine/libmachine/ssh"
 	"github.com/docker/machine/libmachine/swarm"
 )
@@ -28,12 +30,12 @@ enabled=1
 gpgkey=https://yum.dockerproject.org/gpg
 `
 	engineConfigTemplate = `[Service]
-ExecStart=/usr/bin/docker -d -H tcp://0.0.0.0:{{.DockerPort}} -H unix:///var/run/docker.sock --storage-driver {{.EngineOptions.StorageDriver}} --tlsverify --tlscacert {{.AuthOptions.CaCertRemotePath}} --tlscert {{.AuthOptions.ServerCertRemotePath}} --tlskey {{.AuthOptions.ServerKeyRemotePath}} {{ range .EngineOptions.Labels }}--label {{.}} {{ end }}{{ range .EngineOptions.InsecureRegistry }}--insecure-registry {{.}} {{ end }}{{ range .EngineOptions.RegistryMirror }}--registry-mirror {{.}} {{ end }}{{ range .EngineOptions.ArbitraryFlags }}--{{.}} {{ end }}
+ExecStart=/usr/bin/docker -d -H tcp://0.0.0.0:{{.DockerPort}} -H unix:///var/run/docker.sock --storage-driver {{ systemd .EngineOptions.StorageDriver }} --tlsverify --tlscacert {{ systemd .AuthOptions.CaCertRemotePath }} --tlscert {{ systemd .AuthOptions.ServerCertRemotePath }} --tlskey {{ systemd .AuthOptions.ServerKeyRemotePath }} {{ range .EngineOptions.Labels }}--label {{ systemd . }} {{ end }}{{ range .EngineOptions.InsecureRegistry }}--insecure-registry {{ systemd . }} {{ end }}{{ range .EngineOptions.RegistryMirror }}--registry-mirror {{ systemd . }} {{ end }}{{ range .EngineOptions.ArbitraryFlags }}{{ systemd (print "--" .) }} {{ end }}
 MountFlags=slave
 LimitNOFILE=1048576
 LimitNPROC=1048576
 LimitCORE=infinity
-Environment={{range .EngineOptions.Env}}{{ printf "%q" . }} {{end}}
+Environment={{range .EngineOptions.Env}}{{ systemdEnv . }} {{end}}
 `
 )
 
@@ -66,10 +68,12 @@ type RedHatProvisioner struct {
 	GenericProvisioner
 }
 
-func (provisioner *RedHatProvisioner) SSHCommand(args string) (string, error) {
+// ttyClient returns the SSH client of the machine, set to allocate a tty:
+// redhat needs one for sudo.
+func (provisioner *RedHatProvisioner) ttyClient() (ssh.Client, error) {
 	client, err := drivers.GetSSHClientFromDriver(provisioner.Driver)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// redhat needs "-t" for tty allocation on ssh therefore we check for the
@@ -79,31 +83,70 @@ func (provisioner *RedHatProvisioner) SSHCommand(args string) (string, error) {
 	switch c := client.(type) {
 	case ssh.ExternalClient:
 		c.BaseArgs = append(c.BaseArgs, "-tt")
-		client = c
+		return c, nil
 	case ssh.NativeClient:
-		return c.OutputWithPty(args)
+		return nativeTTYClient{c}, nil
+	}
+
+	return client, nil
+}
+
+// nativeTTYClient is a native client which allocates a tty.
+type nativeTTYClient struct {
+	ssh.NativeClient
+}
+
+func (c nativeTTYClient) Output(command string) (string, error) {
+	return c.OutputWithPty(command)
+}
+
+func (c nativeTTYClient) Run(command string) (*ssh.Result, error) {
+	return c.RunWithPty(command)
+}
+
+func (c nativeTTYClient) Stream(command string, stdout, stderr io.Writer) error {
+	return c.StreamWithPty(command, stdout, stderr)
+}
+
+func (provisioner *RedHatProvisioner) SSHCommand(args string) (string, error) {
+	client, err := provisioner.ttyClient()
+	if err != nil {
+		return "", err
 	}
 
 	return client.Output(args)
 }
 
+func (provisioner *RedHatProvisioner) RunSSHCommand(args string) (*ssh.Result, error) {
+	client, err := provisioner.ttyClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Run(args)
+}
+
+func (provisioner *RedHatProvisioner) StreamSSHCommand(args string, stdout, stderr io.Writer) error {
+	client, err := provisioner.ttyClient()
+	if err != nil {
+		return err
+	}
+
+	return client.Stream(args, stdout, stderr)
+}
+
 func (provisioner *RedHatProvisioner) SetHostname(hostname string) error {
 	// we have to have SetHostname here as well to use the RedHat provisioner
 	// SSHCommand to add the tty allocation
-	if _, err := provisioner.SSHCommand(fmt.Sprintf(
-		"sudo hostname %s && echo %q | sudo tee /etc/hostname",
+	if _, err := provisioner.SSHCommand(shell.Sprintf(
+		"sudo hostname %s && printf '%%s\\n' %s | sudo tee /etc/hostname",
 		hostname,
 		hostname,
 	)); err != nil {
 		return err
 	}
 
-	// ubuntu/debian use 127.0.1.1 for non "localhost" loopback hostnames: https://www.debian.org/doc/manuals/debian-reference/ch05.en.html#_the_hostname_resolution
-	if _, err := provisioner.SSHCommand(fmt.Sprintf(
-		"if grep -xq 127.0.1.1.* /etc/hosts; then sudo sed -i 's/^127.0.1.1.*/127.0.1.1 %s/g' /etc/hosts; else echo '127.0.1.1 %s' | sudo tee -a /etc/hosts; fi",
-		hostname,
-		hostname,
-	)); err != nil {
+	if _, err := provisioner.SSHCommand(etcHostsCommand(hostname)); err != nil {
 		return err
 	}
 
@@ -126,7 +169,7 @@ func (provisioner *RedHatProvisioner) Service(name string, action serviceaction.
 		}
 	}
 
-	command := fmt.Sprintf("sudo systemctl %s %s", action.String(), name)
+	command := shell.Join("sudo", "systemctl", action.String(), name)
 
 	if _, err := provisioner.SSHCommand(command); err != nil {
 		return err
@@ -147,7 +190,7 @@ func (provisioner *RedHatProvisioner) Package(name string, action pkgaction.Pack
 		packageAction = "upgrade"
 	}
 
-	command := fmt.Sprintf("sudo -E yum %s -y %s", packageAction, name)
+	command := shell.Join("sudo", "-E", "yum", packageAction, "-y", name)
 
 	if _, err := provisioner.SSHCommand(command); err != nil {
 		return err
@@ -260,7 +303,7 @@ func (provisioner *RedHatProvisioner) GenerateDockerOptions(dockerPort int) (*Do
 
 	// systemd / redhat will not load options if they are on newlines
 	// instead, it just continues with a different set of options; yeah...
-	t, err := template.New("engineConfig").Parse(engineConfigTemplate)
+	t, err := template.New("engineConfig").Funcs(engineConfigFuncs).Parse(engineConfigTemplate)
 	if err != nil {
 		return nil, err
 	}
@@ -321,10 +364,7 @@ func (provisioner *RedHatProvisioner) ConfigurePackageList() error {
 		return err
 	}
 
-	// we cannot use %q here as it combines the newlines in the formatting
-	// on transport causing yum to not use the repo
-	packageCmd := fmt.Sprintf("echo \"%s\" | sudo tee /etc/yum.repos.d/docker.repo", buf.String())
-	if _, err := provisioner.SSHCommand(packageCmd); err != nil {
+	if err := WriteFile(provisioner, "/etc/yum.repos.d/docker.repo", buf.Bytes(), 0644); err != nil {
 		return err
 	}
 
@@ -12,6 +12,7 @@ import (
 	"github.com/docker/machine/libmachine/mcnutils"
 	"github.com/docker/machine/libmachine/provision/pkgaction"
 	"github.com/docker/machine/libmachine/provision/serviceaction"
+	"github.com/docker/machine/libmachine/shell"
 	"github.com/docker/machine/libmachine/swarm"
 )
 
@@ -89,7 +90,7 @@ func (provisioner *SUSEProvisioner) Service(name string, action serviceaction.Se
 		}
 	}
 
-	command := fmt.Sprintf("sudo systemctl %s %s", action.String(), name)
+	command := shell.Join("sudo", "systemctl", action.String(), name)
 
 	if _, err := provisioner.SSHCommand(command); err != nil {
 		return err
@@ -110,7 +111,7 @@ func (provisioner *SUSEProvisioner) Package(name string, action pkgaction.Packag
 		packageAction = "upgrade"
 	}
 
-	command := fmt.Sprintf("sudo -E zypper -n %s %s", packageAction, name)
+	command := shell.Join("sudo", "-E", "zypper", "-n", packageAction, name)
 
 	if _, err := provisioner.SSHCommand(command); err != nil {
 		return err
@@ -198,7 +199,7 @@ func (provisioner *SUSEProvisioner) GenerateDockerOptions(dockerPort int) (*Dock
 	)
 
 	// remove existing
-	if _, err := provisioner.SSHCommand(fmt.Sprintf("sudo rm %s", configPath)); err != nil {
+	if _, err := provisioner.SSHCommand(shell.Join("sudo", "rm", configPath)); err != nil {
 		return nil, err
 	}
 
@@ -206,9 +207,9 @@ func (provisioner *SUSEProvisioner) GenerateDockerOptions(dockerPort int) (*Dock
 	provisioner.EngineOptions.Labels = append(provisioner.EngineOptions.Labels, driverNameLabel)
 
 	engineConfigTmpl := `# File automatically generated by docker-machine
-DOCKER_OPTS=' -H tcp://0.0.0.0:{{.DockerPort}} {{ if .EngineOptions.StorageDriver }} --storage-driver {{.EngineOptions.StorageDriver}} {{ end }} --tlsverify --tlscacert {{.AuthOptions.CaCertRemotePath}} --tlscert {{.AuthOptions.ServerCertRemotePath}} --tlskey {{.AuthOptions.ServerKeyRemotePath}} {{ range .EngineOptions.Labels }}--label {{.}} {{ end }}{{ range .EngineOptions.InsecureRegistry }}--insecure-registry {{.}} {{ end }}{{ range .EngineOptions.RegistryMirror }}--registry-mirror {{.}} {{ end }}{{ range .EngineOptions.ArbitraryFlags }}--{{.}} {{ end }}'
+DOCKER_OPTS=' -H tcp://0.0.0.0:{{.DockerPort}} {{ if .EngineOptions.StorageDriver }} --storage-driver {{ escape .EngineOptions.StorageDriver }} {{ end }} --tlsverify --tlscacert {{ escape .AuthOptions.CaCertRemotePath }} --tlscert {{ escape .AuthOptions.ServerCertRemotePath }} --tlskey {{ escape .AuthOptions.ServerKeyRemotePath }} {{ range .EngineOptions.Labels }}--label {{ escape . }} {{ end }}{{ range .EngineOptions.InsecureRegistry }}--insecure-registry {{ escape . }} {{ end }}{{ range .EngineOptions.RegistryMirror }}--registry-mirror {{ escape . }} {{ end }}{{ range .EngineOptions.ArbitraryFlags }}--{{ escape . }} {{ end }}'
 `
-	t, err := template.New("engineConfig").Parse(engineConfigTmpl)
+	t, err := template.New("engineConfig").Funcs(engineConfigFuncs).Parse(engineConfigTmpl)
 	if err != nil {
 		return nil, err
 	}
@@ -10,6 +10,7 @@ import (
 	"github.com/docker/machine/libmachine/mcnutils"
 	"github.com/docker/machine/libmachine/provision/pkgaction"
 	"github.com/docker/machine/libmachine/provision/serviceaction"
+	"github.com/docker/machine/libmachine/shell"
 	"github.com/docker/machine/libmachine/swarm"
 )
 
@@ -38,7 +39,7 @@ type UbuntuProvisioner struct {
 }
 
 func (provisioner *UbuntuProvisioner) Service(name string, action serviceaction.ServiceAction) error {
-	command := fmt.Sprintf("sudo service %s %s", name, action.String())
+	command := shell.Join("sudo", "service", name, action.String())
 
 	if _, err := provisioner.SSHCommand(command); err != nil {
 		return err
@@ -93,7 +94,7 @@ func (provisioner *UbuntuProvisioner) Package(name string, action pkgaction.Pack
 		}
 	}
 
-	command := fmt.Sprintf("DEBIAN_FRONTEND=noninteractive sudo -E apt-get %s -y  %s", packageAction, name)
+	command := "DEBIAN_FRONTEND=noninteractive " + shell.Join("sudo", "-E", "apt-get", packageAction, "-y", name)
 
 	log.Debugf("package: action=%s name=%s", action.String(), name)
 
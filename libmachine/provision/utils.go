@@ -1,9 +1,12 @@
 package provision
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/url"
+	"os"
 	"path"
 	"path/filepath"
 	"regexp"
@@ -13,9 +16,13 @@ import (
 
 	"github.com/docker/machine/libmachine/auth"
 	"github.com/docker/machine/libmachine/cert"
+	"github.com/docker/machine/libmachine/drivers"
 	"github.com/docker/machine/libmachine/log"
 	"github.com/docker/machine/libmachine/mcnutils"
 	"github.com/docker/machine/libmachine/provision/serviceaction"
+	"github.com/docker/machine/libmachine/shell"
+	"github.com/docker/machine/libmachine/ssh"
+	"github.com/docker/machine/libmachine/timing"
 )
 
 type DockerOptions struct {
@@ -24,18 +31,66 @@ type DockerOptions struct {
 }
 
 func installDockerGeneric(p Provisioner, baseURL string) error {
+	defer timing.Track("provision.InstallDocker")()
+
 	// install docker - until cloudinit we use ubuntu everywhere so we
-	// just install it using the docker repos
-	if output, err := p.SSHCommand(fmt.Sprintf("if ! type docker; then curl -sSL %s | sh -; fi", baseURL)); err != nil {
-		return fmt.Errorf("error installing docker: %s\n", output)
+	// just install it using the docker repos.  The install takes a while,
+	// its output is logged as it goes.
+	stdout := &logWriter{prefix: "Installing Docker"}
+	stderr := &logWriter{prefix: "Installing Docker"}
+	errOutput := &bytes.Buffer{}
+
+	err := p.StreamSSHCommand(shell.Sprintf("if ! type docker; then curl -sSL %s | sh -; fi", baseURL), stdout, io.MultiWriter(stderr, errOutput))
+	stdout.Flush()
+	stderr.Flush()
+
+	if exitErr, ok := err.(*ssh.ExitError); ok && errOutput.Len() > 0 {
+		exitErr.Stderr = strings.TrimSpace(errOutput.String())
+	}
+	if err != nil {
+		return fmt.Errorf("error installing docker: %s", err)
 	}
 
 	return nil
 }
 
+// logWriter logs the lines written to it at the debug level, so that the
+// output of long commands shows up while they run.
+type logWriter struct {
+	prefix  string
+	partial []byte
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.log(w.partial[:i])
+		w.partial = w.partial[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// Flush logs the last line, if it didn't end with a newline.
+func (w *logWriter) Flush() {
+	if len(w.partial) > 0 {
+		w.log(w.partial)
+		w.partial = nil
+	}
+}
+
+func (w *logWriter) log(line []byte) {
+	log.Debugf("%s: %s", w.prefix, bytes.TrimRight(line, "\r"))
+}
+
 func makeDockerOptionsDir(p Provisioner) error {
 	dockerDir := p.GetDockerOptionsDir()
-	if _, err := p.SSHCommand(fmt.Sprintf("sudo mkdir -p %s", dockerDir)); err != nil {
+	if _, err := p.SSHCommand(shell.Join("sudo", "mkdir", "-p", dockerDir)); err != nil {
 		return err
 	}
 
@@ -56,6 +111,8 @@ func setRemoteAuthOptions(p Provisioner) auth.Options {
 }
 
 func ConfigureAuth(p Provisioner) error {
+	defer timing.Track("provision.ConfigureAuth")()
+
 	var (
 		err error
 	)
@@ -129,20 +186,16 @@ func ConfigureAuth(p Provisioner) error {
 
 	log.Info("Copying certs to the remote machine...")
 
-	// printf will choke if we don't pass a format string because of the
-	// dashes, so that's the reason for the '%%s'
-	certTransferCmdFmt := "printf '%%s' '%s' | sudo tee %s"
-
 	// These ones are for Jessie and Mike <3 <3 <3
-	if _, err := p.SSHCommand(fmt.Sprintf(certTransferCmdFmt, string(caCert), authOptions.CaCertRemotePath)); err != nil {
+	if err := WriteFile(p, authOptions.CaCertRemotePath, caCert, 0644); err != nil {
 		return err
 	}
 
-	if _, err := p.SSHCommand(fmt.Sprintf(certTransferCmdFmt, string(serverCert), authOptions.ServerCertRemotePath)); err != nil {
+	if err := WriteFile(p, authOptions.ServerCertRemotePath, serverCert, 0644); err != nil {
 		return err
 	}
 
-	if _, err := p.SSHCommand(fmt.Sprintf(certTransferCmdFmt, string(serverKey), authOptions.ServerKeyRemotePath)); err != nil {
+	if err := WriteFile(p, authOptions.ServerKeyRemotePath, serverKey, 0600); err != nil {
 		return err
 	}
 
@@ -171,7 +224,7 @@ func ConfigureAuth(p Provisioner) error {
 
 	log.Info("Setting Docker configuration on the remote daemon...")
 
-	if _, err = p.SSHCommand(fmt.Sprintf("printf %%s \"%s\" | sudo tee %s", dkrcfg.EngineOptions, dkrcfg.EngineOptionsPath)); err != nil {
+	if err := WriteFile(p, dkrcfg.EngineOptionsPath, []byte(dkrcfg.EngineOptions), 0644); err != nil {
 		return err
 	}
 
@@ -179,9 +232,25 @@ func ConfigureAuth(p Provisioner) error {
 		return err
 	}
 
+	addToDockerGroup(p.SSHCommand)
+
 	return waitForDocker(p, dockerPort)
 }
 
+// dockerGroupCommand adds the SSH user, unless it's root or already there,
+// to the docker group: usermod where there's one, busybox's addgroup on
+// boot2docker and the like.
+const dockerGroupCommand = `if [ "$(id -u)" -ne 0 ] && ! id -nG | grep -qw docker; then sudo usermod -aG docker "$(id -un)" || sudo addgroup "$(id -un)" docker; fi`
+
+// addToDockerGroup lets the SSH user use the Docker socket, which the
+// tunnels to the machine forward to.  It's not needed otherwise, so failing
+// to is only a warning.
+func addToDockerGroup(sshCommand func(string) (string, error)) {
+	if _, err := sshCommand(dockerGroupCommand); err != nil {
+		log.Warnf("Error adding the SSH user to the docker group, tunnels to the machine won't be allowed to use the Docker socket: %s", err)
+	}
+}
+
 func matchNetstatOut(reDaemonListening, netstatOut string) bool {
 	// TODO: I would really prefer this be a Scanner directly on
 	// the STDOUT of the executed command than to do all the string
@@ -201,24 +270,71 @@ func matchNetstatOut(reDaemonListening, netstatOut string) bool {
 	return false
 }
 
-func checkDaemonUp(p Provisioner, dockerPort int) func() bool {
+func checkDaemonUp(p Provisioner, dockerPort int) func() error {
 	reDaemonListening := fmt.Sprintf(":%d.*LISTEN", dockerPort)
-	return func() bool {
+	return func() error {
 		// HACK: Check netstat's output to see if anyone's listening on the Docker API port.
-		netstatOut, err := p.SSHCommand("netstat -a")
-		if err != nil {
+		result, err := p.RunSSHCommand("netstat -a")
+		if result == nil {
 			log.Warnf("Error running SSH command: %s", err)
-			return false
+			return err
+		}
+
+		if result.ExitStatus != 0 {
+			return fmt.Errorf("Error checking if the Docker daemon listens, netstat exited with status %d: %s", result.ExitStatus, result.Stderr)
 		}
 
-		return matchNetstatOut(reDaemonListening, netstatOut)
+		if !matchNetstatOut(reDaemonListening, result.Stdout) {
+			return fmt.Errorf("Docker daemon is not listening on port %d yet", dockerPort)
+		}
+
+		return nil
 	}
 }
 
 func waitForDocker(p Provisioner, dockerPort int) error {
-	if err := mcnutils.WaitForSpecific(checkDaemonUp(p, dockerPort), 5, 3*time.Second); err != nil {
+	if err := mcnutils.RetryWithDriverPolicy(p.GetDriver().DriverName(), checkDaemonUp(p, dockerPort)); err != nil {
 		return NewErrDaemonAvailable(err)
 	}
 
 	return nil
 }
+
+// WriteFile writes a file of the machine, owned by root.  The content is
+// copied over SSH rather than on a command line, where it would have to be
+// quoted and would show up in the logs, unless the machine has no scp to
+// receive it.
+func WriteFile(p Provisioner, dest string, content []byte, mode os.FileMode) error {
+	client, err := drivers.GetSSHClientFromDriver(p.GetDriver())
+	if err != nil {
+		return err
+	}
+
+	return writeFile(client, p.SSHCommand, dest, content, mode)
+}
+
+// writeFile copies the content to a temporary file, which only root can
+// then move into place: the SSH user may not be allowed to write there.
+func writeFile(client ssh.Client, sshCommand func(string) (string, error), dest string, content []byte, mode os.FileMode) error {
+	tmp := fmt.Sprintf("/tmp/docker-machine-%d-%s", time.Now().UnixNano(), path.Base(dest))
+
+	log.Debugf("Copying %d bytes to %s through %s", len(content), dest, tmp)
+
+	if err := client.Upload(bytes.NewReader(content), int64(len(content)), tmp, 0600); err != nil {
+		// Some machines have no scp binary: the content is then written by
+		// tee, in a command run like any other by the provisioner.
+		log.Debugf("Error copying %s with scp, writing it with tee instead: %s", dest, err)
+		if _, err := sshCommand(shell.Sprintf("umask 077 && printf '%%s' %s | sudo tee %s > /dev/null", string(content), tmp)); err != nil {
+			return fmt.Errorf("Error copying %s to the machine: %s", dest, err)
+		}
+	}
+
+	if _, err := sshCommand(shell.Sprintf("sudo chown 0:0 %s && sudo chmod %o %s && sudo mv %s %s", tmp, uint32(mode.Perm()), tmp, tmp, dest)); err != nil {
+		if _, rmErr := sshCommand(shell.Join("sudo", "rm", "-f", tmp)); rmErr != nil {
+			log.Debugf("Error removing %s: %s", tmp, rmErr)
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+// Package shell builds the commands run on the machines over SSH.  The
+// commands are run by a POSIX shell: the values which come from the user or
+// the machine are quoted, so that they are passed as they are whatever they
+// hold.  So are the values of the systemd units written on the machines,
+// whose quoting rules differ.
+package shell
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Quote returns a string as one word of the shell.  It's left as it is if
+// the shell wouldn't interpret any of its characters, and single-quoted
+// otherwise.
+func Quote(s string) string {
+	if s == "" {
+		return "''"
+	}
+
+	if strings.IndexFunc(s, isUnsafe) < 0 {
+		return s
+	}
+
+	return "'" + Escape(s) + "'"
+}
+
+// Escape escapes a string to be put between single quotes, where the shell
+// interprets nothing but the single quote ending them.
+func Escape(s string) string {
+	return strings.Replace(s, "'", `'\''`, -1)
+}
+
+func isUnsafe(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return false
+	}
+	return !strings.ContainsRune("@%+=:,./-_", r)
+}
+
+// Join returns a simple command, its name and arguments each quoted.
+func Join(args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = Quote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// Sprintf formats a command: the format is shell, which is run as it is,
+// and the string arguments are quoted.  The other arguments, such as
+// numbers, are formatted as they are.
+func Sprintf(format string, args ...interface{}) string {
+	quoted := make([]interface{}, len(args))
+	for i, arg := range args {
+		if s, ok := arg.(string); ok {
+			arg = Quote(s)
+		}
+		quoted[i] = arg
+	}
+	return fmt.Sprintf(format, quoted...)
+}
+
+// SystemdQuote returns a string as one word of a command line of a systemd
+// unit, such as ExecStart.  It's left as it is if systemd wouldn't interpret
+// any of its characters, and double-quoted otherwise: systemd expands the
+// specifiers starting with % and the variables starting with $ even there,
+// and unescapes the backslashes as C does.
+func SystemdQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, isUnsafeSystemd) < 0 {
+		return s
+	}
+
+	return `"` + systemdEscape(s, true) + `"`
+}
+
+// SystemdQuoteEnv returns a VAR=value assignment of the Environment setting
+// of a systemd unit, double-quoted.  Only the specifiers are expanded there,
+// not the variables.
+func SystemdQuoteEnv(s string) string {
+	return `"` + systemdEscape(s, false) + `"`
+}
+
+func isUnsafeSystemd(r rune) bool {
+	return r == '%' || isUnsafe(r)
+}
+
+func systemdEscape(s string, variables bool) string {
+	var buf bytes.Buffer
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '"':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case r == '\n':
+			buf.WriteString(`\n`)
+		case r == '\t':
+			buf.WriteString(`\t`)
+		case r < ' ' || r == 0x7f:
+			fmt.Fprintf(&buf, `\x%02x`, r)
+		case r == '%':
+			buf.WriteString("%%")
+		case r == '$' && variables:
+			buf.WriteString("$$")
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,206 @@
+package shell
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var hostileArgs = []string{
+	"",
+	" ",
+	"it's",
+	"'",
+	"''",
+	`\'`,
+	`"double quoted"`,
+	`back\slash`,
+	"$(touch /tmp/pwned)",
+	"`touch /tmp/pwned`",
+	"${HOME}",
+	"$HOME",
+	"a; touch /tmp/pwned",
+	"a && touch /tmp/pwned",
+	"a | tee /tmp/pwned",
+	"a > /tmp/pwned",
+	"line\nbreak",
+	"tab\there",
+	"*",
+	"~",
+	"!",
+	"#comment",
+	"-n",
+	"label=it's a trap'; touch /tmp/pwned; echo '",
+	"héllo wörld",
+	"100%",
+	"%h",
+	"$$",
+	"trailing\\",
+	"bell\x07",
+	";",
+}
+
+// runShell runs a command with sh and returns the arguments printf got.
+func runShell(t *testing.T, command string) []string {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not found")
+	}
+
+	output, err := exec.Command(sh, "-c", command).Output()
+	if err != nil {
+		t.Fatalf("Error running %q: %s", command, err)
+	}
+
+	return strings.Split(strings.TrimSuffix(string(output), "\x00"), "\x00")
+}
+
+func TestQuote(t *testing.T) {
+	var tests = []struct {
+		arg      string
+		expected string
+	}{
+		{"/etc/docker/ca.pem", "/etc/docker/ca.pem"},
+		{"provider=virtualbox", "provider=virtualbox"},
+		{"swarm:latest", "swarm:latest"},
+		{"", "''"},
+		{"/tmp/it's here", `'/tmp/it'\''s here'`},
+		{"$(reboot)", "'$(reboot)'"},
+		{"a b", "'a b'"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, Quote(test.arg))
+	}
+}
+
+func TestQuoteHostile(t *testing.T) {
+	for _, arg := range hostileArgs {
+		assert.Equal(t, []string{arg}, runShell(t, `printf '%s\0' `+Quote(arg)), "quoting %q", arg)
+	}
+}
+
+func TestEscapeHostile(t *testing.T) {
+	for _, arg := range hostileArgs {
+		assert.Equal(t, []string{"[" + arg + "]"}, runShell(t, `printf '%s\0' '[`+Escape(arg)+`]'`), "escaping %q", arg)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	assert.Equal(t, "sudo hostname 'my host'", Join("sudo", "hostname", "my host"))
+
+	args := append([]string{"printf", `%s\0`}, hostileArgs...)
+	assert.Equal(t, hostileArgs, runShell(t, Join(args...)))
+}
+
+func TestSprintf(t *testing.T) {
+	assert.Equal(t, "sudo chmod 644 '/tmp/a file' && sudo mv '/tmp/a file' /etc/docker", Sprintf("sudo chmod %o %s && sudo mv %s %s", 0644, "/tmp/a file", "/tmp/a file", "/etc/docker"))
+
+	for _, arg := range hostileArgs {
+		assert.Equal(t, []string{arg, arg}, runShell(t, Sprintf(`printf '%%s\0' %s && printf '%%s\0' %s`, arg, arg)), "formatting %q", arg)
+	}
+}
+
+// systemdWords splits a line of a systemd unit into words as systemd does:
+// the specifiers are expanded, the words unquoted and unescaped, then the
+// variables expanded if asked.  No specifier nor variable is defined, so
+// that one left in by the quoting fails the test.
+func systemdWords(t *testing.T, line string, variables bool) []string {
+	if strings.Contains(strings.Replace(line, "%%", "", -1), "%") {
+		t.Fatalf("Specifier left in %q", line)
+	}
+	line = strings.Replace(line, "%%", "%", -1)
+
+	words := []string{}
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" {
+			return words
+		}
+
+		word := ""
+		quoted := line[0] == '"'
+		if quoted {
+			line = line[1:]
+		}
+
+		for {
+			if line == "" {
+				if quoted {
+					t.Fatalf("Unterminated quote after %q", word)
+				}
+				break
+			}
+
+			c := line[0]
+			if (quoted && c == '"') || (!quoted && (c == ' ' || c == '\t')) {
+				if quoted {
+					line = line[1:]
+				}
+				break
+			}
+
+			if c == '\\' {
+				value, _, tail, err := strconv.UnquoteChar(line, '"')
+				if err != nil {
+					t.Fatalf("Invalid escape in %q: %s", line, err)
+				}
+				word += string(value)
+				line = tail
+				continue
+			}
+
+			word += line[:1]
+			line = line[1:]
+		}
+
+		if variables {
+			if strings.Contains(strings.Replace(word, "$$", "", -1), "$") {
+				t.Fatalf("Variable left in %q", word)
+			}
+			word = strings.Replace(word, "$$", "$", -1)
+		}
+
+		words = append(words, word)
+	}
+}
+
+func TestSystemdQuote(t *testing.T) {
+	var tests = []struct {
+		arg      string
+		expected string
+	}{
+		{"/etc/docker/ca.pem", "/etc/docker/ca.pem"},
+		{"provider=virtualbox", "provider=virtualbox"},
+		{"", `""`},
+		{"a b", `"a b"`},
+		{"100%", `"100%%"`},
+		{"$HOME", `"$$HOME"`},
+		{`say "hi"\`, `"say \"hi\"\\"`},
+		{"line\nbreak", `"line\nbreak"`},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, SystemdQuote(test.arg))
+	}
+}
+
+func TestSystemdQuoteHostile(t *testing.T) {
+	line := "ExecStart=/usr/bin/docker"
+	for _, arg := range hostileArgs {
+		assert.Equal(t, []string{arg}, systemdWords(t, SystemdQuote(arg), true), "quoting %q", arg)
+		line += " " + SystemdQuote(arg)
+	}
+
+	assert.Equal(t, append([]string{"ExecStart=/usr/bin/docker"}, hostileArgs...), systemdWords(t, line, true))
+}
+
+func TestSystemdQuoteEnvHostile(t *testing.T) {
+	for _, arg := range hostileArgs {
+		assignment := "HTTP_PROXY=" + arg
+		assert.Equal(t, []string{assignment}, systemdWords(t, SystemdQuoteEnv(assignment), false), "quoting %q", assignment)
+	}
+}
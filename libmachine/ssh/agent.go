@@ -0,0 +1,207 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/docker/docker/pkg/term"
+	"github.com/docker/machine/libmachine/log"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+var (
+	// PassphrasePrompt asks for the passphrase of an encrypted private key.
+	// By default, it's read from the terminal.
+	PassphrasePrompt = terminalPassphrasePrompt
+
+	errNoAgent = errors.New("SSH agent not available, SSH_AUTH_SOCK is not set")
+
+	agentLock   = &sync.Mutex{}
+	agentClient agent.Agent
+
+	// signers holds the keys already read, so that the passphrase of a key
+	// is asked once.
+	signersLock = &sync.Mutex{}
+	signers     = make(map[string]ssh.Signer)
+)
+
+// getAgent connects to the running SSH agent, once: the connection is
+// shared by the clients authenticating with it and the forwarded agents.
+func getAgent() (agent.Agent, error) {
+	agentLock.Lock()
+	defer agentLock.Unlock()
+
+	if agentClient != nil {
+		return agentClient, nil
+	}
+
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil, errNoAgent
+	}
+
+	conn, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil, fmt.Errorf("Error connecting to the SSH agent: %s", err)
+	}
+
+	agentClient = agent.NewClient(conn)
+	return agentClient, nil
+}
+
+// publicKeySigners returns the keys of the running SSH agent, if asked to,
+// then the ones of private key files.  A key file whose public key the agent
+// holds isn't read, so that its passphrase isn't asked for nothing, and the
+// agent is enough if the file doesn't exist.
+func publicKeySigners(useAgent bool, keys []string) ([]ssh.Signer, error) {
+	var keySigners []ssh.Signer
+
+	if useAgent {
+		agentSigners, err := agentSigners()
+		if err != nil {
+			log.Debugf("Not authenticating with the SSH agent: %s", err)
+		}
+		keySigners = append(keySigners, agentSigners...)
+	}
+
+	for _, k := range keys {
+		if hasPublicKey(keySigners, k+".pub") {
+			continue
+		}
+
+		signer, err := readPrivateKey(k)
+		if os.IsNotExist(err) && useAgent {
+			log.Debugf("Not authenticating with %s: %s", k, err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		keySigners = append(keySigners, signer)
+	}
+
+	return keySigners, nil
+}
+
+func agentSigners() ([]ssh.Signer, error) {
+	a, err := getAgent()
+	if err != nil {
+		return nil, err
+	}
+	return a.Signers()
+}
+
+// hasPublicKey tells if one of the signers has the public key of a file.
+func hasPublicKey(keySigners []ssh.Signer, path string) bool {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	key, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return false
+	}
+
+	for _, signer := range keySigners {
+		if bytes.Equal(signer.PublicKey().Marshal(), key.Marshal()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readPrivateKey reads a private key file, asking for its passphrase if it's
+// encrypted.
+func readPrivateKey(path string) (ssh.Signer, error) {
+	signersLock.Lock()
+	defer signersLock.Unlock()
+
+	if signer, ok := signers[path]; ok {
+		return signer, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := parsePrivateKey(path, data)
+	if err != nil {
+		return nil, err
+	}
+
+	signers[path] = signer
+	return signer, nil
+}
+
+func parsePrivateKey(path string, data []byte) (ssh.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block != nil && block.Type == "OPENSSH PRIVATE KEY" {
+		var promptErr error
+		signer, err := parseOpenSSHPrivateKey(block.Bytes, func() ([]byte, error) {
+			passphrase, err := PassphrasePrompt(path)
+			promptErr = err
+			return passphrase, err
+		})
+		if promptErr != nil {
+			return nil, promptErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Error reading %s: %s", path, err)
+		}
+		return signer, nil
+	}
+
+	if block == nil || !x509.IsEncryptedPEMBlock(block) {
+		return ssh.ParsePrivateKey(data)
+	}
+
+	passphrase, err := PassphrasePrompt(path)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.DecryptPEMBlock(block, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("Error decrypting %s: %s", path, err)
+	}
+
+	return ssh.ParsePrivateKey(pem.EncodeToMemory(&pem.Block{
+		Type:  block.Type,
+		Bytes: der,
+	}))
+}
+
+func terminalPassphrasePrompt(path string) ([]byte, error) {
+	fd := os.Stdin.Fd()
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("The SSH key %s is encrypted, add it to the SSH agent or run from a terminal to enter its passphrase", path)
+	}
+
+	fmt.Fprintf(os.Stderr, "Enter passphrase for key '%s': ", path)
+	passphrase, err := terminal.ReadPassword(int(fd))
+	fmt.Fprintln(os.Stderr)
+
+	return passphrase, err
+}
+
+// forwardAgent lets the sessions of a connection which request it use the
+// running SSH agent.
+func forwardAgent(client *ssh.Client) error {
+	a, err := getAgent()
+	if err != nil {
+		return err
+	}
+	return agent.ForwardToAgent(client, a)
+}
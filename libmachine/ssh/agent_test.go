@@ -0,0 +1,249 @@
+package ssh
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+const testPassphrase = "correct horse battery staple"
+
+// newTestEncryptedKey writes an RSA key encrypted with testPassphrase, and
+// its public key next to it.
+func newTestEncryptedKey(t *testing.T, dir string) (string, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key), []byte(testPassphrase), x509.PEMCipherAES128)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "id_rsa")
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	publicKey, err := ssh.NewPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(path+".pub", ssh.MarshalAuthorizedKey(publicKey), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path, key
+}
+
+// stubPassphrasePrompt counts how many times the passphrase is asked.
+func stubPassphrasePrompt(passphrase string) (*int, func()) {
+	asked := 0
+	PassphrasePrompt = func(path string) ([]byte, error) {
+		asked++
+		return []byte(passphrase), nil
+	}
+
+	return &asked, func() {
+		PassphrasePrompt = terminalPassphrasePrompt
+
+		signersLock.Lock()
+		signers = make(map[string]ssh.Signer)
+		signersLock.Unlock()
+	}
+}
+
+// startTestAgent serves an agent holding a key on a socket pointed to by
+// SSH_AUTH_SOCK.
+func startTestAgent(t *testing.T, dir string, key interface{}) func() {
+	keyring := agent.NewKeyring()
+	if err := keyring.Add(key, nil, "test key"); err != nil {
+		t.Fatal(err)
+	}
+
+	sock := filepath.Join(dir, "agent.sock")
+	listener, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, conn)
+		}
+	}()
+
+	oldSock := os.Getenv("SSH_AUTH_SOCK")
+	os.Setenv("SSH_AUTH_SOCK", sock)
+
+	return func() {
+		os.Setenv("SSH_AUTH_SOCK", oldSock)
+		listener.Close()
+
+		agentLock.Lock()
+		agentClient = nil
+		agentLock.Unlock()
+	}
+}
+
+func TestNativeClientEncryptedKey(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	defer CloseAll()
+
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	keyPath, key := newTestEncryptedKey(t, dir)
+	publicKey, err := ssh.NewPublicKey(&key.PublicKey)
+	assert.NoError(t, err)
+	s.AuthorizeKey(publicKey)
+
+	asked, restore := stubPassphrasePrompt(testPassphrase)
+	defer restore()
+
+	client, err := NewNativeClient(testUser, "127.0.0.1", s.port, &Auth{Keys: []string{keyPath}})
+	assert.NoError(t, err)
+
+	output, err := client.Output("hostname")
+	assert.NoError(t, err)
+	assert.Equal(t, "ran hostname", output)
+
+	// The key is decrypted once.
+	CloseAll()
+	_, err = client.Output("hostname")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, *asked)
+}
+
+func TestNativeClientWrongPassphrase(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	defer CloseAll()
+
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	keyPath, _ := newTestEncryptedKey(t, dir)
+
+	_, restore := stubPassphrasePrompt("wrong")
+	defer restore()
+
+	_, err = NewNativeClient(testUser, "127.0.0.1", s.port, &Auth{Keys: []string{keyPath}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Error decrypting "+keyPath)
+}
+
+func TestPassphrasePromptError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	keyPath, _ := newTestEncryptedKey(t, dir)
+
+	_, restore := stubPassphrasePrompt("")
+	defer restore()
+	PassphrasePrompt = func(path string) ([]byte, error) {
+		return nil, errors.New("no terminal")
+	}
+
+	_, err = readPrivateKey(keyPath)
+	assert.EqualError(t, err, "no terminal")
+}
+
+func TestNativeClientAgent(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	defer CloseAll()
+
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	keyPath, key := newTestEncryptedKey(t, dir)
+	publicKey, err := ssh.NewPublicKey(&key.PublicKey)
+	assert.NoError(t, err)
+	s.AuthorizeKey(publicKey)
+
+	defer startTestAgent(t, dir, key)()
+
+	asked, restore := stubPassphrasePrompt(testPassphrase)
+	defer restore()
+
+	// The key the agent holds isn't decrypted.
+	client, err := NewNativeClient(testUser, "127.0.0.1", s.port, &Auth{Agent: true, Keys: []string{keyPath}})
+	assert.NoError(t, err)
+
+	output, err := client.Output("hostname")
+	assert.NoError(t, err)
+	assert.Equal(t, "ran hostname", output)
+	assert.Equal(t, 0, *asked)
+
+	// Without the agent, it is.
+	client, err = NewNativeClient(testUser, "127.0.0.1", s.port, &Auth{Keys: []string{keyPath}})
+	assert.NoError(t, err)
+
+	_, err = client.Output("hostname")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, *asked)
+}
+
+func TestNativeClientForwardAgent(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	defer CloseAll()
+
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, key := newTestEncryptedKey(t, dir)
+	defer startTestAgent(t, dir, key)()
+
+	publicKey, err := ssh.NewPublicKey(&key.PublicKey)
+	assert.NoError(t, err)
+
+	client, err := NewNativeClient(testUser, "127.0.0.1", s.port, &Auth{Passwords: []string{testPassword}, ForwardAgent: true})
+	assert.NoError(t, err)
+
+	output, err := client.Output("ssh-add -l")
+	assert.NoError(t, err)
+	assert.Equal(t, Fingerprint(publicKey)+" test key\n", output)
+
+	client, err = NewNativeClient(testUser, "127.0.0.1", s.port, &Auth{Passwords: []string{testPassword}})
+	assert.NoError(t, err)
+
+	output, err = client.Output("ssh-add -l")
+	assert.Error(t, err)
+	assert.Equal(t, "Could not open a connection to your authentication agent.", output)
+}
+
+func TestExternalClientForwardAgent(t *testing.T) {
+	client, err := NewExternalClient("/usr/bin/ssh", "docker", "localhost", 22, &Auth{ForwardAgent: true})
+	assert.NoError(t, err)
+	assert.Contains(t, strings.Join(client.BaseArgs, " "), "-o ForwardAgent=yes docker@localhost")
+
+	client, err = NewExternalClient("/usr/bin/ssh", "docker", "localhost", 22, &Auth{})
+	assert.NoError(t, err)
+	assert.NotContains(t, strings.Join(client.BaseArgs, " "), "ForwardAgent")
+}
@@ -2,9 +2,11 @@ package ssh
 
 import (
 	"fmt"
-	"io/ioutil"
+	"io"
+	"net"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 
 	"github.com/docker/docker/pkg/term"
@@ -17,22 +19,98 @@ import (
 type Client interface {
 	Output(command string) (string, error)
 	Shell(args ...string) error
+
+	// Run runs a command and returns what it printed on its standard
+	// output and error apart, and its exit status.  If it fails, the error
+	// is an *ExitError, returned with the result.
+	Run(command string) (*Result, error)
+
+	// Stream runs a command, writing what it prints on its standard output
+	// and error to the writers as it runs.  If it fails, the error is an
+	// *ExitError.
+	Stream(command string, stdout, stderr io.Writer) error
+
+	// Upload writes the size bytes of a reader to a file of the host,
+	// created with the given mode.
+	Upload(src io.Reader, size int64, dest string, mode os.FileMode) error
+
+	// Download writes the content of a file of the host to a writer.
+	Download(src string, dest io.Writer) error
+
+	// Forward listens on a local address and forwards the connections it
+	// accepts to an address of the host, e.g. DockerSocket, until the
+	// tunnel is closed.
+	Forward(local, remote Addr) (Tunnel, error)
+
+	// Close releases the connection of the client, if it keeps one.  The
+	// client connects again if it is used afterwards.
+	Close() error
 }
 
 type ExternalClient struct {
 	BaseArgs   []string
 	BinaryPath string
+
+	// knownHosts is where the key of the host at addr, known by alias, is
+	// recorded before running ssh, which checks it strictly.  It's learned
+	// through the jump host if there is one.
+	knownHosts string
+	alias      string
+	addr       string
+	jump       *NativeClient
 }
 
+// NativeClient runs commands over connections which are kept open and
+// shared by all the native clients of a host, see Close and CloseAll.
 type NativeClient struct {
 	Config   ssh.ClientConfig
 	Hostname string
 	Port     int
+
+	// poolKey is the connection of the pool the client uses.
+	poolKey string
+
+	// jump is the client of the jump host the connection goes through.
+	jump *NativeClient
+
+	forwardAgent bool
+
+	// backoff is how dialing the host is retried.
+	backoff *mcnutils.BackoffPolicy
 }
 
 type Auth struct {
 	Passwords []string
-	Keys      []string
+
+	// Keys are private key files.  The passphrase of the encrypted ones is
+	// asked with PassphrasePrompt by the native client.
+	Keys []string
+
+	// Agent tells to authenticate with the keys of the running SSH agent
+	// too, before the key files.
+	Agent bool
+
+	// ForwardAgent lets the commands run on the host use the running SSH
+	// agent.
+	ForwardAgent bool
+
+	// KnownHosts is the known_hosts file where the host key is recorded
+	// on first use and checked afterwards.  Host keys aren't checked if
+	// it's empty.
+	KnownHosts string
+
+	// HostKeyAlias is the name the host key is recorded under in
+	// KnownHosts, along with the address of the host, e.g. the name of a
+	// machine.  Only that key is accepted, whatever address the host gets.
+	HostKeyAlias string
+
+	// Jump is the host to connect through, if the host can't be reached
+	// directly.
+	Jump *JumpHost
+
+	// Backoff is how the native client retries dialing the host, following
+	// the default policy if it's nil.
+	Backoff *mcnutils.BackoffPolicy
 }
 
 type ClientType string
@@ -50,8 +128,6 @@ var (
 	baseSSHArgs = []string{
 		"-o", "PasswordAuthentication=no",
 		"-o", "IdentitiesOnly=yes",
-		"-o", "StrictHostKeyChecking=no",
-		"-o", "UserKnownHostsFile=/dev/null",
 		"-o", "LogLevel=quiet", // suppress "Warning: Permanently added '[localhost]:2022' (ECDSA) to the list of known hosts."
 		"-o", "ConnectionAttempts=3", // retry 3 times if SSH connection fails
 		"-o", "ConnectTimeout=10", // timeout after 10 seconds
@@ -95,11 +171,23 @@ func NewNativeClient(user, host string, port int, auth *Auth) (Client, error) {
 		return nil, fmt.Errorf("Error getting config for native Go SSH: %s", err)
 	}
 
-	return NativeClient{
-		Config:   config,
-		Hostname: host,
-		Port:     port,
-	}, nil
+	client := NativeClient{
+		Config:       config,
+		Hostname:     host,
+		Port:         port,
+		poolKey:      poolKey(user, host, port, auth),
+		forwardAgent: auth.ForwardAgent,
+		backoff:      auth.Backoff,
+	}
+
+	if auth.Jump != nil {
+		client.jump, err = newJumpClient(auth.Jump, auth.KnownHosts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return client, nil
 }
 
 func NewNativeConfig(user string, auth *Auth) (ssh.ClientConfig, error) {
@@ -107,74 +195,107 @@ func NewNativeConfig(user string, auth *Auth) (ssh.ClientConfig, error) {
 		authMethods []ssh.AuthMethod
 	)
 
-	for _, k := range auth.Keys {
-		key, err := ioutil.ReadFile(k)
-		if err != nil {
-			return ssh.ClientConfig{}, err
-		}
-
-		privateKey, err := ssh.ParsePrivateKey(key)
-		if err != nil {
-			return ssh.ClientConfig{}, err
-		}
+	signers, err := publicKeySigners(auth.Agent, auth.Keys)
+	if err != nil {
+		return ssh.ClientConfig{}, err
+	}
 
-		authMethods = append(authMethods, ssh.PublicKeys(privateKey))
+	// The client tries the "publickey" method once, with all the keys.
+	if len(signers) > 0 {
+		authMethods = append(authMethods, ssh.PublicKeys(signers...))
 	}
 
 	for _, p := range auth.Passwords {
 		authMethods = append(authMethods, ssh.Password(p))
 	}
 
-	return ssh.ClientConfig{
+	config := ssh.ClientConfig{
 		User: user,
 		Auth: authMethods,
-	}, nil
-}
+	}
 
-func (client NativeClient) dialSuccess() bool {
-	if _, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", client.Hostname, client.Port), &client.Config); err != nil {
-		log.Debugf("Error dialing TCP: %s", err)
-		return false
+	if auth.KnownHosts != "" {
+		config.HostKeyCallback = knownHostsCallback(auth.KnownHosts, auth.HostKeyAlias)
 	}
-	return true
+
+	return config, nil
 }
 
-func (client NativeClient) session(command string) (*ssh.Session, error) {
-	if err := mcnutils.WaitFor(client.dialSuccess); err != nil {
-		return nil, fmt.Errorf("Error attempting SSH client dial: %s", err)
+func (client NativeClient) Output(command string) (string, error) {
+	session, err := client.session()
+	if err != nil {
+		return "", err
 	}
 
-	conn, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", client.Hostname, client.Port), &client.Config)
+	output, err := session.CombinedOutput(command)
+	defer session.Close()
+
+	return string(output), err
+}
+
+func (client NativeClient) Run(command string) (*Result, error) {
+	return runCommand(client.Stream, command)
+}
+
+func (client NativeClient) Stream(command string, stdout, stderr io.Writer) error {
+	session, err := client.session()
 	if err != nil {
-		return nil, fmt.Errorf("Mysterious error dialing TCP for SSH (we already succeeded at least once) : %s", err)
+		return err
 	}
+	defer session.Close()
+
+	session.Stdout = stdout
+	session.Stderr = stderr
 
-	return conn.NewSession()
+	return nativeExitError(command, session.Run(command))
 }
 
-func (client NativeClient) Output(command string) (string, error) {
-	session, err := client.session(command)
+func (client NativeClient) OutputWithPty(command string) (string, error) {
+	session, err := client.session()
 	if err != nil {
-		return "", nil
+		return "", err
 	}
 
-	output, err := session.CombinedOutput(command)
 	defer session.Close()
 
+	if err := requestPty(session); err != nil {
+		return "", err
+	}
+
+	output, err := session.CombinedOutput(command)
+
 	return string(output), err
 }
 
-func (client NativeClient) OutputWithPty(command string) (string, error) {
-	session, err := client.session(command)
+// RunWithPty is Run with a tty, see StreamWithPty.
+func (client NativeClient) RunWithPty(command string) (*Result, error) {
+	return runCommand(client.StreamWithPty, command)
+}
+
+// StreamWithPty is Stream with a tty, which the hosts whose sudo requires
+// one need.  The command prints everything on its standard output then.
+func (client NativeClient) StreamWithPty(command string, stdout, stderr io.Writer) error {
+	session, err := client.session()
 	if err != nil {
-		return "", nil
+		return err
+	}
+	defer session.Close()
+
+	if err := requestPty(session); err != nil {
+		return err
 	}
 
-	fd := int(os.Stdin.Fd())
+	session.Stdout = stdout
+	session.Stderr = stderr
+
+	return nativeExitError(command, session.Run(command))
+}
 
-	termWidth, termHeight, err := terminal.GetSize(fd)
+// requestPty requests a tty of the size of the terminal, if there's one.
+func requestPty(session *ssh.Session) error {
+	termWidth, termHeight, err := terminal.GetSize(int(os.Stdin.Fd()))
 	if err != nil {
-		return "", err
+		termWidth, termHeight = 80, 24
 	}
 
 	modes := ssh.TerminalModes{
@@ -185,26 +306,14 @@ func (client NativeClient) OutputWithPty(command string) (string, error) {
 
 	// request tty -- fixes error with hosts that use
 	// "Defaults requiretty" in /etc/sudoers - I'm looking at you RedHat
-	if err := session.RequestPty("xterm", termHeight, termWidth, modes); err != nil {
-		return "", err
-	}
-
-	output, err := session.CombinedOutput(command)
-	defer session.Close()
-
-	return string(output), err
+	return session.RequestPty("xterm", termHeight, termWidth, modes)
 }
 
 func (client NativeClient) Shell(args ...string) error {
 	var (
 		termWidth, termHeight int
 	)
-	conn, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", client.Hostname, client.Port), &client.Config)
-	if err != nil {
-		return err
-	}
-
-	session, err := conn.NewSession()
+	session, err := client.session()
 	if err != nil {
 		return err
 	}
@@ -258,9 +367,31 @@ func (client NativeClient) Shell(args ...string) error {
 func NewExternalClient(sshBinaryPath, user, host string, port int, auth *Auth) (ExternalClient, error) {
 	client := ExternalClient{
 		BinaryPath: sshBinaryPath,
+		knownHosts: auth.KnownHosts,
+		alias:      auth.HostKeyAlias,
+		addr:       net.JoinHostPort(host, strconv.Itoa(port)),
 	}
 
-	args := append(baseSSHArgs, fmt.Sprintf("%s@%s", user, host))
+	args := append([]string{}, baseSSHArgs...)
+	args = append(args, KnownHostsArgs(auth.KnownHosts)...)
+
+	if auth.Jump != nil {
+		args = append(args, ProxyCommandArgs(sshBinaryPath, auth.Jump, auth.KnownHosts)...)
+
+		if auth.KnownHosts != "" {
+			jump, err := newJumpClient(auth.Jump, auth.KnownHosts)
+			if err != nil {
+				return ExternalClient{}, err
+			}
+			client.jump = jump
+		}
+	}
+
+	if auth.ForwardAgent {
+		args = append(args, "-o", "ForwardAgent=yes")
+	}
+
+	args = append(args, fmt.Sprintf("%s@%s", user, host))
 
 	// Specify which private keys to use to authorize the SSH request.
 	for _, privateKeyPath := range auth.Keys {
@@ -275,18 +406,80 @@ func NewExternalClient(sshBinaryPath, user, host string, port int, auth *Auth) (
 	return client, nil
 }
 
+// KnownHostsArgs are the options of the ssh and scp binaries to check host
+// keys against known_hosts files, or not to check them if there are none.
+func KnownHostsArgs(knownHosts ...string) []string {
+	files := []string{}
+	for _, path := range knownHosts {
+		if path == "" {
+			continue
+		}
+		if strings.ContainsAny(path, " \t") {
+			path = strconv.Quote(path)
+		}
+		files = append(files, path)
+	}
+
+	if len(files) == 0 {
+		return []string{
+			"-o", "StrictHostKeyChecking=no",
+			"-o", "UserKnownHostsFile=/dev/null",
+		}
+	}
+
+	return []string{
+		"-o", "StrictHostKeyChecking=yes",
+		"-o", "CheckHostIP=no",
+		"-o", "UserKnownHostsFile=" + strings.Join(files, " "),
+	}
+}
+
 func getSSHCmd(binaryPath string, args ...string) *exec.Cmd {
 	return exec.Command(binaryPath, args...)
 }
 
+// recordHostKey records the key of the host the first time it's connected
+// to, since ssh then refuses to connect to unknown hosts.
+func (client ExternalClient) recordHostKey() error {
+	if client.knownHosts == "" {
+		return nil
+	}
+	return recordHostKey(client.knownHosts, client.alias, client.addr, client.jump)
+}
+
 func (client ExternalClient) Output(command string) (string, error) {
+	if err := client.recordHostKey(); err != nil {
+		return "", err
+	}
+
 	args := append(client.BaseArgs, command)
 	cmd := getSSHCmd(client.BinaryPath, args...)
 	output, err := cmd.CombinedOutput()
 	return string(output), err
 }
 
+func (client ExternalClient) Run(command string) (*Result, error) {
+	return runCommand(client.Stream, command)
+}
+
+func (client ExternalClient) Stream(command string, stdout, stderr io.Writer) error {
+	if err := client.recordHostKey(); err != nil {
+		return err
+	}
+
+	args := append(append([]string{}, client.BaseArgs...), command)
+	cmd := getSSHCmd(client.BinaryPath, args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	return externalExitError(command, cmd.Run())
+}
+
 func (client ExternalClient) Shell(args ...string) error {
+	if err := client.recordHostKey(); err != nil {
+		return err
+	}
+
 	args = append(client.BaseArgs, args...)
 	cmd := getSSHCmd(client.BinaryPath, args...)
 
@@ -298,3 +491,9 @@ func (client ExternalClient) Shell(args ...string) error {
 
 	return cmd.Run()
 }
+
+// Close does nothing: each command of the external client runs its own ssh
+// process.
+func (client ExternalClient) Close() error {
+	return nil
+}
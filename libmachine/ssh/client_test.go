@@ -2,6 +2,7 @@ package ssh
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -43,3 +44,96 @@ func TestGetSSHCmdArgs(t *testing.T) {
 		assert.Equal(t, cmd.Args, c.expectedArgs)
 	}
 }
+
+func newTestNativeClient(t *testing.T, s *testServer) NativeClient {
+	client, err := NewNativeClient(testUser, "127.0.0.1", s.port, &Auth{Passwords: []string{testPassword}})
+	assert.NoError(t, err)
+	return client.(NativeClient)
+}
+
+func TestNativeClientSharesConnection(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	defer CloseAll()
+
+	first := newTestNativeClient(t, s)
+	second := newTestNativeClient(t, s)
+
+	output, err := first.Output("hostname")
+	assert.NoError(t, err)
+	assert.Equal(t, "ran hostname", output)
+
+	output, err = second.Output("uptime")
+	assert.NoError(t, err)
+	assert.Equal(t, "ran uptime", output)
+
+	output, err = first.Output("fail please")
+	assert.Error(t, err)
+	assert.Equal(t, "ran fail please", output)
+
+	assert.Equal(t, 1, s.Connections())
+}
+
+func TestNativeClientReconnects(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	defer CloseAll()
+
+	client := newTestNativeClient(t, s)
+
+	_, err := client.Output("sudo reboot")
+	assert.NoError(t, err)
+
+	s.DropConnections()
+
+	output, err := client.Output("hostname")
+	assert.NoError(t, err)
+	assert.Equal(t, "ran hostname", output)
+
+	assert.Equal(t, 2, s.Connections())
+}
+
+func TestNativeClientDropsHungConnection(t *testing.T) {
+	defer func(timeout time.Duration) { responseTimeout = timeout }(responseTimeout)
+	responseTimeout = 100 * time.Millisecond
+
+	s := newTestServer(t)
+	defer s.Close()
+	defer CloseAll()
+
+	client := newTestNativeClient(t, s)
+
+	_, err := client.Output("hostname")
+	assert.NoError(t, err)
+
+	s.HangConnections()
+
+	output, err := client.Output("hostname")
+	assert.NoError(t, err)
+	assert.Equal(t, "ran hostname", output)
+
+	assert.Equal(t, 2, s.Connections())
+}
+
+func TestNativeClientClose(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	defer CloseAll()
+
+	client := newTestNativeClient(t, s)
+
+	_, err := client.Output("hostname")
+	assert.NoError(t, err)
+	assert.NoError(t, client.Close())
+	assert.NoError(t, client.Close())
+
+	_, err = client.Output("hostname")
+	assert.NoError(t, err)
+
+	CloseAll()
+
+	_, err = client.Output("hostname")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 3, s.Connections())
+}
@@ -0,0 +1,172 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"io"
+
+	"filippo.io/edwards25519"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// The version of golang.org/x/crypto/ssh in use predates Ed25519 keys, and
+// the version of Go the standard library's crypto/ed25519: the keys are
+// marshaled and parsed here, in the formats of OpenSSH, and the signatures
+// of RFC 8032 are made on edwards25519.
+
+const (
+	keyAlgoEd25519 = "ssh-ed25519"
+
+	ed25519SeedSize       = 32
+	ed25519PublicKeySize  = 32
+	ed25519PrivateKeySize = 64
+	ed25519SignatureSize  = 64
+)
+
+type ed25519PublicKey []byte
+
+// ed25519PrivateKey is the seed of the key followed by its public key, as
+// OpenSSH stores it.
+type ed25519PrivateKey []byte
+
+// generateEd25519Key returns a key made from a seed read from rand.
+func generateEd25519Key(rand io.Reader) (ed25519PrivateKey, error) {
+	seed := make([]byte, ed25519SeedSize)
+	if _, err := io.ReadFull(rand, seed); err != nil {
+		return nil, err
+	}
+
+	return newEd25519Key(seed), nil
+}
+
+func newEd25519Key(seed []byte) ed25519PrivateKey {
+	s, _ := ed25519Expand(seed)
+	public := new(edwards25519.Point).ScalarBaseMult(s).Bytes()
+
+	return ed25519PrivateKey(append(append([]byte{}, seed...), public...))
+}
+
+// ed25519Expand returns the secret scalar of a seed, and the prefix the
+// nonces of the signatures are derived from.
+func ed25519Expand(seed []byte) (*edwards25519.Scalar, []byte) {
+	h := sha512.Sum512(seed)
+	return edwards25519.NewScalar().SetBytesWithClamping(h[:32]), h[32:]
+}
+
+// ed25519Hash reduces the SHA-512 of its arguments to a scalar.
+func ed25519Hash(parts ...[]byte) *edwards25519.Scalar {
+	h := sha512.New()
+	for _, part := range parts {
+		h.Write(part)
+	}
+	return edwards25519.NewScalar().SetUniformBytes(h.Sum(nil))
+}
+
+func (k ed25519PrivateKey) public() ed25519PublicKey {
+	return ed25519PublicKey(k[ed25519SeedSize:])
+}
+
+func (k ed25519PrivateKey) sign(message []byte) []byte {
+	s, prefix := ed25519Expand(k[:ed25519SeedSize])
+
+	r := ed25519Hash(prefix, message)
+	R := new(edwards25519.Point).ScalarBaseMult(r).Bytes()
+
+	// S = H(R || A || M) * s + r
+	S := edwards25519.NewScalar().MultiplyAdd(ed25519Hash(R, k.public(), message), s, r)
+
+	return append(R, S.Bytes()...)
+}
+
+func (k ed25519PublicKey) verify(message, sig []byte) bool {
+	if len(k) != ed25519PublicKeySize || len(sig) != ed25519SignatureSize {
+		return false
+	}
+
+	A, err := new(edwards25519.Point).SetBytes(k)
+	if err != nil {
+		return false
+	}
+
+	S, err := edwards25519.NewScalar().SetCanonicalBytes(sig[32:])
+	if err != nil {
+		return false
+	}
+
+	// R = S * B - H(R || A || M) * A
+	minusA := new(edwards25519.Point).Negate(A)
+	R := new(edwards25519.Point).VarTimeDoubleScalarBaseMult(ed25519Hash(sig[:32], k, message), minusA, S)
+
+	return bytes.Equal(sig[:32], R.Bytes())
+}
+
+func (k ed25519PublicKey) Type() string {
+	return keyAlgoEd25519
+}
+
+func (k ed25519PublicKey) Marshal() []byte {
+	return gossh.Marshal(&struct {
+		Name string
+		Key  []byte
+	}{keyAlgoEd25519, []byte(k)})
+}
+
+func (k ed25519PublicKey) Verify(data []byte, sig *gossh.Signature) error {
+	if sig.Format != keyAlgoEd25519 {
+		return fmt.Errorf("ssh: signature type %s for key type %s", sig.Format, keyAlgoEd25519)
+	}
+	if !k.verify(data, sig.Blob) {
+		return errors.New("ssh: signature did not verify")
+	}
+	return nil
+}
+
+type ed25519Signer ed25519PrivateKey
+
+func (s ed25519Signer) PublicKey() gossh.PublicKey {
+	return ed25519PrivateKey(s).public()
+}
+
+func (s ed25519Signer) Sign(rand io.Reader, data []byte) (*gossh.Signature, error) {
+	return &gossh.Signature{
+		Format: keyAlgoEd25519,
+		Blob:   ed25519PrivateKey(s).sign(data),
+	}, nil
+}
+
+type openSSHEd25519PrivateKey struct {
+	Pub     []byte
+	Priv    []byte
+	Comment string
+	Pad     []byte `ssh:"rest"`
+}
+
+// marshalEd25519PrivateKey returns an unencrypted Ed25519 private key in
+// the OpenSSH format, without the PEM armor.
+func marshalEd25519PrivateKey(key ed25519PrivateKey) ([]byte, error) {
+	pub := key.public()
+	return marshalOpenSSHPrivateKey(pub, &openSSHEd25519PrivateKey{
+		Pub:  pub,
+		Priv: key,
+	})
+}
+
+func parseEd25519PrivateKey(data []byte) (gossh.Signer, error) {
+	var key openSSHEd25519PrivateKey
+	if err := gossh.Unmarshal(data, &key); err != nil {
+		return nil, err
+	}
+
+	if len(key.Priv) != ed25519PrivateKeySize {
+		return nil, errors.New("invalid Ed25519 private key")
+	}
+
+	// The public key is recorded after the seed, check it's the right one.
+	if !bytes.Equal(newEd25519Key(key.Priv[:ed25519SeedSize]), key.Priv) {
+		return nil, errors.New("invalid Ed25519 private key, its public key doesn't match")
+	}
+
+	return ed25519Signer(key.Priv), nil
+}
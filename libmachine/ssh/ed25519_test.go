@@ -0,0 +1,51 @@
+package ssh
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The test vectors of section 7.1 of RFC 8032.
+var ed25519Vectors = []struct {
+	seed, public, message, signature string
+}{
+	{
+		"9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60",
+		"d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a",
+		"",
+		"e5564300c360ac729086e2cc806e828a84877f1eb8e5d974d873e065224901555fb8821590a33bacc61e39701cf9b46bd25bf5f0595bbe24655141438e7a100b",
+	},
+	{
+		"4ccd089b28ff96da9db6c346ec114e0f5b8a319f35aba624da8cf6ed4fb8a6fb",
+		"3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c",
+		"72",
+		"92a009a9f0d4cab8720e820b5f642540a2b27b5416503f8fb3762223ebdb69da085ac1e43e15996e458f3613d0f11d8c387b2eaeb4302aeeb00d291612bb0c00",
+	},
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestEd25519Vectors(t *testing.T) {
+	for _, vector := range ed25519Vectors {
+		key := newEd25519Key(decodeHex(t, vector.seed))
+		message := decodeHex(t, vector.message)
+
+		assert.Equal(t, vector.public, hex.EncodeToString(key.public()))
+
+		signature := key.sign(message)
+		assert.Equal(t, vector.signature, hex.EncodeToString(signature))
+		assert.True(t, key.public().verify(message, signature))
+
+		signature[0] ^= 1
+		assert.False(t, key.public().verify(message, signature))
+		assert.False(t, key.public().verify(append(message, 0), key.sign(message)))
+	}
+}
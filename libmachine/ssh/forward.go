@@ -0,0 +1,548 @@
+package ssh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnutils"
+	"golang.org/x/crypto/ssh"
+)
+
+// DockerSocket is where the Docker daemon of a machine listens locally,
+// besides its TCP port.
+const DockerSocket = "/var/run/docker.sock"
+
+var (
+	errTunnelClosed = errors.New("SSH tunnel closed")
+
+	tunnelsLock = &sync.Mutex{}
+
+	// tunnels holds the open tunnels, for CloseAll to close them.
+	tunnels = make(map[Tunnel]bool)
+)
+
+// Addr is an address connections are forwarded from or to: a TCP
+// host:port, or the path of a Unix socket.
+type Addr struct {
+	Net     string
+	Address string
+}
+
+// ParseAddr parses an address given like DOCKER_HOST is, as tcp://host:port
+// or unix:///path.
+func ParseAddr(spec string) (Addr, error) {
+	u, err := url.Parse(spec)
+	if err != nil {
+		return Addr{}, fmt.Errorf("Invalid address %q: %s", spec, err)
+	}
+
+	switch u.Scheme {
+	case "tcp":
+		if _, _, err := net.SplitHostPort(u.Host); err != nil || u.Path != "" {
+			return Addr{}, fmt.Errorf("Invalid address %q, expected tcp://host:port", spec)
+		}
+		return Addr{Net: "tcp", Address: u.Host}, nil
+	case "unix":
+		if u.Host != "" || u.Path == "" {
+			return Addr{}, fmt.Errorf("Invalid address %q, expected unix:///path", spec)
+		}
+		return Addr{Net: "unix", Address: u.Path}, nil
+	}
+
+	return Addr{}, fmt.Errorf("Invalid address %q, expected tcp://host:port or unix:///path", spec)
+}
+
+func (a Addr) Network() string {
+	return a.Net
+}
+
+func (a Addr) String() string {
+	return a.Address
+}
+
+// URL returns the address as DOCKER_HOST expects it.
+func (a Addr) URL() string {
+	return fmt.Sprintf("%s://%s", a.Net, a.Address)
+}
+
+// Tunnel forwards the connections to a local address to an address of a
+// host, until it's closed.
+type Tunnel interface {
+	// Addr is the local address, with the port which was picked if the
+	// one asked for was 0.
+	Addr() Addr
+
+	// Wait blocks until the tunnel stops, and tells why.  It returns nil
+	// if the tunnel was closed.
+	Wait() error
+
+	Close() error
+}
+
+func addTunnel(t Tunnel) {
+	tunnelsLock.Lock()
+	defer tunnelsLock.Unlock()
+	tunnels[t] = true
+}
+
+func removeTunnel(t Tunnel) {
+	tunnelsLock.Lock()
+	defer tunnelsLock.Unlock()
+	delete(tunnels, t)
+}
+
+// closeTunnels closes every open tunnel.
+func closeTunnels() {
+	tunnelsLock.Lock()
+	open := tunnels
+	tunnels = make(map[Tunnel]bool)
+	tunnelsLock.Unlock()
+
+	for t := range open {
+		if err := t.Close(); err != nil {
+			log.Debugf("Error closing SSH tunnel: %s", err)
+		}
+	}
+}
+
+// removeStaleSocket removes the Unix socket left behind by a tunnel which
+// didn't stop cleanly, as ssh does with StreamLocalBindUnlink.  Other files
+// are left alone.
+func removeStaleSocket(local Addr) error {
+	if local.Net != "unix" {
+		return nil
+	}
+
+	fi, err := os.Lstat(local.Address)
+	if err != nil || fi.Mode()&os.ModeSocket == 0 {
+		return nil
+	}
+
+	return os.Remove(local.Address)
+}
+
+// nativeTunnel accepts the local connections and forwards each of them over
+// the connection shared by the native clients of the host.
+type nativeTunnel struct {
+	client   NativeClient
+	listener net.Listener
+	remote   Addr
+	done     chan struct{}
+	err      error
+
+	lock   sync.Mutex
+	closed bool
+	conns  map[io.Closer]bool
+}
+
+func (client NativeClient) Forward(local, remote Addr) (Tunnel, error) {
+	// Connect first, so that the tunnel isn't opened to a host which can't
+	// be reached.
+	if _, err := client.conn().get(client.dial); err != nil {
+		return nil, err
+	}
+
+	if err := removeStaleSocket(local); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen(local.Net, local.Address)
+	if err != nil {
+		return nil, fmt.Errorf("Error listening on %s: %s", local.URL(), err)
+	}
+
+	// Only the user can connect to the socket, as with the ssh binary.
+	if local.Net == "unix" {
+		if err := os.Chmod(local.Address, 0600); err != nil {
+			listener.Close()
+			return nil, err
+		}
+	}
+
+	t := &nativeTunnel{
+		client:   client,
+		listener: listener,
+		remote:   remote,
+		done:     make(chan struct{}),
+		conns:    make(map[io.Closer]bool),
+	}
+
+	addTunnel(t)
+	go t.serve()
+
+	return t, nil
+}
+
+func (t *nativeTunnel) Addr() Addr {
+	addr := t.listener.Addr()
+	return Addr{Net: addr.Network(), Address: addr.String()}
+}
+
+func (t *nativeTunnel) serve() {
+	defer close(t.done)
+
+	for {
+		conn, err := t.listener.Accept()
+		if err != nil {
+			t.lock.Lock()
+			if !t.closed {
+				t.err = err
+			}
+			t.lock.Unlock()
+			return
+		}
+
+		go t.forward(conn)
+	}
+}
+
+// forward copies a local connection to the remote address and back, until
+// either end closes it.
+func (t *nativeTunnel) forward(local net.Conn) {
+	remote, err := t.client.dialRemote(t.remote)
+	if err != nil {
+		log.Debugf("Error forwarding a connection to %s: %s", t.remote.URL(), err)
+		local.Close()
+		return
+	}
+
+	if !t.track(local, remote) {
+		local.Close()
+		remote.Close()
+		return
+	}
+	defer t.untrack(local, remote)
+
+	done := make(chan struct{})
+	go func() {
+		io.Copy(remote, local)
+		closeWrite(remote)
+		close(done)
+	}()
+
+	io.Copy(local, remote)
+	closeWrite(local)
+	<-done
+
+	local.Close()
+	remote.Close()
+}
+
+// track records the connections being forwarded, unless the tunnel was
+// closed meanwhile.
+func (t *nativeTunnel) track(conns ...io.Closer) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.closed {
+		return false
+	}
+
+	for _, c := range conns {
+		t.conns[c] = true
+	}
+	return true
+}
+
+func (t *nativeTunnel) untrack(conns ...io.Closer) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, c := range conns {
+		delete(t.conns, c)
+	}
+}
+
+func (t *nativeTunnel) Wait() error {
+	<-t.done
+	return t.err
+}
+
+// Close stops accepting connections and closes the ones being forwarded.
+func (t *nativeTunnel) Close() error {
+	t.lock.Lock()
+	if t.closed {
+		t.lock.Unlock()
+		return nil
+	}
+	t.closed = true
+	conns := t.conns
+	t.conns = nil
+	t.lock.Unlock()
+
+	removeTunnel(t)
+
+	err := t.listener.Close()
+	for c := range conns {
+		c.Close()
+	}
+
+	<-t.done
+	return err
+}
+
+// closeWrite tells the other end of a connection that nothing more will be
+// written, if the connection can.
+func closeWrite(conn io.Closer) {
+	if c, ok := conn.(interface {
+		CloseWrite() error
+	}); ok {
+		c.CloseWrite()
+	}
+}
+
+// dialRemote opens a connection to an address from the host of the client,
+// over the connection it shares with the other clients.  If the shared
+// connection turns out to be broken, it is dialed again once.
+func (client NativeClient) dialRemote(remote Addr) (net.Conn, error) {
+	shared := client.conn()
+
+	conn, err := shared.get(client.dial)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := openRemote(conn, remote)
+	if _, refused := err.(*ssh.OpenChannelError); err == nil || refused {
+		return c, err
+	}
+
+	log.Debugf("Error connecting to %s through %s:%d, reconnecting: %s", remote.URL(), client.Hostname, client.Port, err)
+	shared.drop(conn)
+	conn.Close()
+
+	conn, err = shared.get(client.dial)
+	if err != nil {
+		return nil, err
+	}
+
+	return openRemote(conn, remote)
+}
+
+func openRemote(conn *ssh.Client, remote Addr) (net.Conn, error) {
+	if remote.Net != "unix" {
+		return conn.Dial(remote.Net, remote.Address)
+	}
+
+	// The version of golang.org/x/crypto/ssh in use can't connect to Unix
+	// sockets: the channel of OpenSSH for it is opened here.
+	channel, requests, err := conn.OpenChannel("direct-streamlocal@openssh.com", ssh.Marshal(&struct {
+		SocketPath string
+		Reserved0  string
+		Reserved1  uint32
+	}{remote.Address, "", 0}))
+	if err != nil {
+		return nil, err
+	}
+	go ssh.DiscardRequests(requests)
+
+	return &channelConn{Channel: channel, remote: remote}, nil
+}
+
+// channelConn is a channel connected to a Unix socket of the host, as a
+// net.Conn.
+type channelConn struct {
+	ssh.Channel
+	remote Addr
+}
+
+func (c *channelConn) LocalAddr() net.Addr {
+	return Addr{Net: "unix"}
+}
+
+func (c *channelConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func (c *channelConn) SetDeadline(t time.Time) error {
+	return errors.New("ssh: deadline not supported")
+}
+
+func (c *channelConn) SetReadDeadline(t time.Time) error {
+	return c.SetDeadline(t)
+}
+
+func (c *channelConn) SetWriteDeadline(t time.Time) error {
+	return c.SetDeadline(t)
+}
+
+// externalTunnel is an ssh process which forwards the connections.
+type externalTunnel struct {
+	cmd   *exec.Cmd
+	local Addr
+	done  chan struct{}
+	err   error
+
+	lock   sync.Mutex
+	closed bool
+}
+
+func (client ExternalClient) Forward(local, remote Addr) (Tunnel, error) {
+	if err := client.recordHostKey(); err != nil {
+		return nil, err
+	}
+
+	local, err := pickPort(local)
+	if err != nil {
+		return nil, err
+	}
+
+	args := append([]string{}, client.BaseArgs...)
+	args = append(args, forwardArgs(local, remote)...)
+
+	cmd := getSSHCmd(client.BinaryPath, args...)
+	log.Debug(cmd)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	t := &externalTunnel{
+		cmd:   cmd,
+		local: local,
+		done:  make(chan struct{}),
+	}
+
+	go func() {
+		err := cmd.Wait()
+		t.lock.Lock()
+		if !t.closed {
+			t.err = externalTunnelError(local, err, stderr.String())
+		}
+		t.lock.Unlock()
+		close(t.done)
+	}()
+
+	// ssh doesn't tell when the forwarding is set up: the local address
+	// is tried until it accepts connections or ssh gives up.
+	if err := mcnutils.WaitForSpecificOrError(t.listening, int(dialTimeout/(100*time.Millisecond)), 100*time.Millisecond); err != nil {
+		t.Close()
+		return nil, err
+	}
+
+	addTunnel(t)
+
+	return t, nil
+}
+
+// externalTunnelError tells why ssh stopped forwarding, with what it printed
+// if anything.
+func externalTunnelError(local Addr, err error, stderr string) error {
+	if err == nil {
+		err = errTunnelClosed
+	}
+	if stderr = strings.TrimSpace(stderr); stderr != "" {
+		return fmt.Errorf("Error forwarding %s over SSH: %s: %s", local.URL(), err, stderr)
+	}
+	return fmt.Errorf("Error forwarding %s over SSH: %s", local.URL(), err)
+}
+
+// listening tells if ssh listens on the local address, or why it stopped.
+func (t *externalTunnel) listening() (bool, error) {
+	select {
+	case <-t.done:
+		return false, t.err
+	default:
+	}
+
+	conn, err := net.DialTimeout(t.local.Net, t.local.Address, time.Second)
+	if err != nil {
+		return false, nil
+	}
+	conn.Close()
+
+	return true, nil
+}
+
+func (t *externalTunnel) Addr() Addr {
+	return t.local
+}
+
+func (t *externalTunnel) Wait() error {
+	<-t.done
+	return t.err
+}
+
+func (t *externalTunnel) Close() error {
+	t.lock.Lock()
+	if t.closed {
+		t.lock.Unlock()
+		return nil
+	}
+	t.closed = true
+	t.lock.Unlock()
+
+	removeTunnel(t)
+
+	err := t.cmd.Process.Kill()
+	<-t.done
+
+	if err := removeStaleSocket(t.local); err != nil {
+		log.Debugf("Error removing %s: %s", t.local.Address, err)
+	}
+
+	return err
+}
+
+// pickPort picks a free local port when port 0 is asked for, since ssh
+// wouldn't tell which one it picked.
+func pickPort(local Addr) (Addr, error) {
+	if local.Net != "tcp" {
+		return local, nil
+	}
+
+	host, port, err := net.SplitHostPort(local.Address)
+	if err != nil || port != "0" {
+		return local, err
+	}
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		return Addr{}, fmt.Errorf("Error picking a port on %s: %s", host, err)
+	}
+	defer listener.Close()
+
+	return Addr{Net: "tcp", Address: listener.Addr().String()}, nil
+}
+
+// forwardArgs are the options of the ssh binary to forward a local address
+// to an address of the host, and nothing else.
+func forwardArgs(local, remote Addr) []string {
+	return []string{
+		"-N",
+		"-o", "ExitOnForwardFailure=yes",
+		"-o", "StreamLocalBindUnlink=yes",
+		"-o", "StreamLocalBindMask=0177",
+		"-L", forwardSpec(local) + ":" + forwardSpec(remote),
+	}
+}
+
+// forwardSpec is how -L takes an address: [host:]port or a socket path.
+func forwardSpec(a Addr) string {
+	if a.Net == "unix" {
+		return a.Address
+	}
+
+	host, port, err := net.SplitHostPort(a.Address)
+	if err != nil {
+		return a.Address
+	}
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+
+	return host + ":" + port
+}
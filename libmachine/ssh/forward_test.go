@@ -0,0 +1,259 @@
+package ssh
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+// newTestEchoServer listens on an address, standing for the Docker socket,
+// and sends back what it's sent.
+func newTestEchoServer(t *testing.T, network, addr string) net.Listener {
+	listener, err := net.Listen(network, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+
+	return listener
+}
+
+// assertEcho checks that what is written to an address comes back.
+func assertEcho(t *testing.T, addr Addr) {
+	conn, err := net.Dial(addr.Net, addr.Address)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("ping"))
+	assert.NoError(t, err)
+
+	reply := make([]byte, 4)
+	_, err = io.ReadFull(conn, reply)
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", string(reply))
+}
+
+func TestParseAddr(t *testing.T) {
+	var tests = []struct {
+		spec     string
+		expected Addr
+		err      string
+	}{
+		{"tcp://127.0.0.1:2375", Addr{Net: "tcp", Address: "127.0.0.1:2375"}, ""},
+		{"tcp://[::1]:0", Addr{Net: "tcp", Address: "[::1]:0"}, ""},
+		{"unix:///tmp/docker.sock", Addr{Net: "unix", Address: "/tmp/docker.sock"}, ""},
+		{"tcp://127.0.0.1", Addr{}, `Invalid address "tcp://127.0.0.1", expected tcp://host:port`},
+		{"unix://docker.sock", Addr{}, `Invalid address "unix://docker.sock", expected unix:///path`},
+		{"ssh://127.0.0.1:22", Addr{}, `Invalid address "ssh://127.0.0.1:22", expected tcp://host:port or unix:///path`},
+	}
+
+	for _, test := range tests {
+		addr, err := ParseAddr(test.spec)
+		if test.err != "" {
+			assert.EqualError(t, err, test.err)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, addr)
+		assert.Equal(t, test.spec, addr.URL())
+	}
+}
+
+func TestForwardArgs(t *testing.T) {
+	assert.Equal(t, []string{
+		"-N",
+		"-o", "ExitOnForwardFailure=yes",
+		"-o", "StreamLocalBindUnlink=yes",
+		"-o", "StreamLocalBindMask=0177",
+		"-L", "127.0.0.1:2375:/var/run/docker.sock",
+	}, forwardArgs(Addr{Net: "tcp", Address: "127.0.0.1:2375"}, Addr{Net: "unix", Address: DockerSocket}))
+
+	assert.Equal(t, "[::1]:2375:localhost:2376", forwardArgs(Addr{Net: "tcp", Address: "[::1]:2375"}, Addr{Net: "tcp", Address: "localhost:2376"})[8])
+	assert.Equal(t, "/tmp/docker.sock:/var/run/docker.sock", forwardArgs(Addr{Net: "unix", Address: "/tmp/docker.sock"}, Addr{Net: "unix", Address: DockerSocket})[8])
+}
+
+func TestNativeClientForward(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	defer CloseAll()
+
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	remote := Addr{Net: "unix", Address: filepath.Join(dir, "docker.sock")}
+	echo := newTestEchoServer(t, remote.Net, remote.Address)
+	defer echo.Close()
+
+	client, err := NewNativeClient(testUser, "127.0.0.1", s.port, &Auth{Passwords: []string{testPassword}})
+	assert.NoError(t, err)
+
+	local := Addr{Net: "unix", Address: filepath.Join(dir, "local.sock")}
+	tunnel, err := client.Forward(local, remote)
+	assert.NoError(t, err)
+	assert.Equal(t, local, tunnel.Addr())
+
+	fi, err := os.Stat(local.Address)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	assertEcho(t, local)
+	assertEcho(t, local)
+	assert.Equal(t, []string{remote.Address, remote.Address}, s.Forwarded())
+
+	// The connections are forwarded again once the machine rebooted.
+	s.DropConnections()
+	assertEcho(t, local)
+
+	assert.NoError(t, tunnel.Close())
+	assert.NoError(t, tunnel.Wait())
+
+	_, err = os.Stat(local.Address)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestNativeClientForwardTCP(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	defer CloseAll()
+
+	echo := newTestEchoServer(t, "tcp", "127.0.0.1:0")
+	defer echo.Close()
+
+	client, err := NewNativeClient(testUser, "127.0.0.1", s.port, &Auth{Passwords: []string{testPassword}})
+	assert.NoError(t, err)
+
+	tunnel, err := client.Forward(Addr{Net: "tcp", Address: "127.0.0.1:0"}, Addr{Net: "tcp", Address: echo.Addr().String()})
+	assert.NoError(t, err)
+	defer tunnel.Close()
+
+	assert.NotEqual(t, "127.0.0.1:0", tunnel.Addr().Address)
+	assertEcho(t, tunnel.Addr())
+}
+
+func TestNativeClientForwardStaleSocket(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	defer CloseAll()
+
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	client, err := NewNativeClient(testUser, "127.0.0.1", s.port, &Auth{Passwords: []string{testPassword}})
+	assert.NoError(t, err)
+
+	// A regular file isn't replaced.
+	local := Addr{Net: "unix", Address: filepath.Join(dir, "local.sock")}
+	assert.NoError(t, ioutil.WriteFile(local.Address, []byte{}, 0600))
+
+	_, err = client.Forward(local, Addr{Net: "unix", Address: DockerSocket})
+	assert.Error(t, err)
+
+	// A socket nobody listens on anymore is.
+	assert.NoError(t, os.Remove(local.Address))
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: local.Address, Net: "unix"})
+	assert.NoError(t, err)
+	listener.SetUnlinkOnClose(false)
+	listener.Close()
+
+	tunnel, err := client.Forward(local, Addr{Net: "unix", Address: DockerSocket})
+	assert.NoError(t, err)
+
+	// The tunnel outlives the connections it fails to forward.
+	conn, err := net.Dial(local.Net, local.Address)
+	assert.NoError(t, err)
+	_, err = conn.Read(make([]byte, 1))
+	assert.Equal(t, io.EOF, err)
+	conn.Close()
+
+	CloseAll()
+	assert.NoError(t, tunnel.Wait())
+}
+
+func TestExternalClientForward(t *testing.T) {
+	binary, err := exec.LookPath("ssh")
+	if err != nil {
+		t.Skip("ssh not found")
+	}
+
+	s := newTestServer(t)
+	defer s.Close()
+
+	// OpenSSH doesn't accept the RSA host key by default anymore.
+	hostKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(hostKey)
+	assert.NoError(t, err)
+	s.lock.Lock()
+	s.config.AddHostKey(signer)
+	s.lock.Unlock()
+
+	dir, err := ioutil.TempDir("", "machine-test-")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	keyPath := filepath.Join(dir, "id_ecdsa")
+	assert.NoError(t, GenerateSSHKeyOfType(keyPath, KeyTypeECDSA))
+	publicKey, err := ioutil.ReadFile(keyPath + ".pub")
+	assert.NoError(t, err)
+	key, _, _, _, err := ssh.ParseAuthorizedKey(publicKey)
+	assert.NoError(t, err)
+	s.AuthorizeKey(key)
+
+	remote := Addr{Net: "unix", Address: filepath.Join(dir, "docker.sock")}
+	echo := newTestEchoServer(t, remote.Net, remote.Address)
+	defer echo.Close()
+
+	client, err := NewExternalClient(binary, testUser, "127.0.0.1", s.port, &Auth{Keys: []string{keyPath}})
+	assert.NoError(t, err)
+
+	tunnel, err := client.Forward(Addr{Net: "tcp", Address: "127.0.0.1:0"}, remote)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.NotEqual(t, "127.0.0.1:0", tunnel.Addr().Address)
+	assertEcho(t, tunnel.Addr())
+
+	assert.NoError(t, tunnel.Close())
+	assert.NoError(t, tunnel.Wait())
+}
+
+func TestExternalClientForwardFailure(t *testing.T) {
+	binary, cleanup := newFakeSSHBinary(t)
+	defer cleanup()
+
+	client, err := NewExternalClient(binary, "docker", "localhost", 22, &Auth{})
+	assert.NoError(t, err)
+
+	// The fake ssh runs its last argument, the -L spec, which fails.
+	_, err = client.Forward(Addr{Net: "tcp", Address: "127.0.0.1:0"}, Addr{Net: "unix", Address: DockerSocket})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "Error forwarding tcp://127.0.0.1:")
+	}
+}
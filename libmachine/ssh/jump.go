@@ -0,0 +1,104 @@
+package ssh
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/docker/machine/libmachine/shell"
+)
+
+const defaultPort = 22
+
+// JumpHost is a host, also known as a bastion, which the connections to a
+// host go through when it can't be reached directly.
+type JumpHost struct {
+	User     string
+	Hostname string
+	Port     int
+	Keys     []string
+}
+
+// ParseJumpHost parses a jump host given as [user@]host[:port].  The user is
+// left empty if it isn't given, for the caller to pick one.
+func ParseJumpHost(spec string) (*JumpHost, error) {
+	jump := &JumpHost{
+		Hostname: spec,
+		Port:     defaultPort,
+	}
+
+	if i := strings.LastIndex(spec, "@"); i >= 0 {
+		jump.User, jump.Hostname = spec[:i], spec[i+1:]
+	}
+
+	if host, port, err := net.SplitHostPort(jump.Hostname); err == nil {
+		p, err := strconv.Atoi(port)
+		if err != nil || p <= 0 || p > 65535 {
+			return nil, fmt.Errorf("Invalid port in jump host %q", spec)
+		}
+		jump.Hostname, jump.Port = host, p
+	}
+
+	if jump.Hostname == "" || strings.ContainsAny(jump.Hostname, "[]@ ") {
+		return nil, fmt.Errorf("Invalid jump host %q, expected [user@]host[:port]", spec)
+	}
+
+	return jump, nil
+}
+
+func (j *JumpHost) String() string {
+	return fmt.Sprintf("%s@%s", j.User, net.JoinHostPort(j.Hostname, strconv.Itoa(j.Port)))
+}
+
+// newJumpClient returns the native client of a jump host, which checks its
+// key against the same known_hosts file as the host behind it.
+func newJumpClient(jump *JumpHost, knownHosts string) (*NativeClient, error) {
+	client, err := NewNativeClient(jump.User, jump.Hostname, jump.Port, &Auth{
+		Keys:       jump.Keys,
+		KnownHosts: knownHosts,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error getting client for jump host %s: %s", jump, err)
+	}
+
+	native := client.(NativeClient)
+	return &native, nil
+}
+
+// dialThrough opens a connection to an address from the host of the client,
+// over the connection it shares with the other clients.
+func (client NativeClient) dialThrough(addr string) (net.Conn, error) {
+	return client.dialRemote(Addr{Net: "tcp", Address: addr})
+}
+
+// dialTCP connects to an address, through the jump host if there is one.
+func dialTCP(jump *NativeClient, addr string) (net.Conn, error) {
+	if jump == nil {
+		return net.DialTimeout("tcp", addr, dialTimeout)
+	}
+	return jump.dialThrough(addr)
+}
+
+// ProxyCommandArgs are the options of the ssh and scp binaries to connect
+// through a jump host, whose key is checked against the known_hosts files.
+func ProxyCommandArgs(sshBinaryPath string, jump *JumpHost, knownHosts ...string) []string {
+	return []string{"-o", "ProxyCommand=" + proxyCommand(sshBinaryPath, jump, knownHosts...)}
+}
+
+// proxyCommand is how the ssh binary connects through the jump host: with
+// another ssh, given the same options, which forwards its standard input and
+// output to the host.
+func proxyCommand(binaryPath string, jump *JumpHost, knownHosts ...string) string {
+	args := []string{binaryPath}
+	args = append(args, baseSSHArgs...)
+	args = append(args, KnownHostsArgs(knownHosts...)...)
+
+	for _, key := range jump.Keys {
+		args = append(args, "-i", key)
+	}
+
+	args = append(args, "-p", strconv.Itoa(jump.Port), "-W", "%h:%p", fmt.Sprintf("%s@%s", jump.User, jump.Hostname))
+
+	return shell.Join(args...)
+}
@@ -0,0 +1,100 @@
+package ssh
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseJumpHost(t *testing.T) {
+	jump, err := ParseJumpHost("ubuntu@bastion.example.com:2222")
+	assert.NoError(t, err)
+	assert.Equal(t, &JumpHost{User: "ubuntu", Hostname: "bastion.example.com", Port: 2222}, jump)
+
+	jump, err = ParseJumpHost("10.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, &JumpHost{Hostname: "10.0.0.1", Port: 22}, jump)
+
+	jump, err = ParseJumpHost("ec2-user@[fd00::1]:22")
+	assert.NoError(t, err)
+	assert.Equal(t, &JumpHost{User: "ec2-user", Hostname: "fd00::1", Port: 22}, jump)
+
+	for _, spec := range []string{"", "ubuntu@", "bastion:ssh", "bastion:0", "[fd00::1", "a@b@"} {
+		_, err := ParseJumpHost(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestNativeClientThroughJumpHost(t *testing.T) {
+	bastion := newTestServer(t)
+	defer bastion.Close()
+
+	machine := newTestServer(t)
+	defer machine.Close()
+
+	defer CloseAll()
+
+	knownHosts, cleanup := newTestKnownHosts(t)
+	defer cleanup()
+
+	assert.NoError(t, os.MkdirAll(filepath.Dir(knownHosts), 0700))
+	key := filepath.Join(filepath.Dir(knownHosts), "id_rsa")
+	assert.NoError(t, GenerateSSHKey(key))
+
+	auth := &Auth{
+		Passwords:  []string{testPassword},
+		KnownHosts: knownHosts,
+		Jump:       &JumpHost{User: testUser, Hostname: "127.0.0.1", Port: bastion.port, Keys: []string{key}},
+	}
+
+	client, err := NewNativeClient(testUser, "127.0.0.1", machine.port, auth)
+	assert.NoError(t, err)
+
+	output, err := client.Output("hostname")
+	assert.NoError(t, err)
+	assert.Equal(t, "ran hostname", output)
+
+	assert.Equal(t, []string{fmt.Sprintf("127.0.0.1:%d", machine.port)}, bastion.Forwarded())
+	assert.Equal(t, 1, machine.Connections())
+
+	// Both host keys are recorded.
+	for _, port := range []int{bastion.port, machine.port} {
+		keys, err := knownHostKeys(knownHosts, knownHostsName(fmt.Sprintf("127.0.0.1:%d", port)))
+		assert.NoError(t, err)
+		assert.Len(t, keys, 1)
+	}
+
+	// The machine can be reconnected to through the same bastion
+	// connection.
+	machine.DropConnections()
+
+	_, err = client.Output("uptime")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, bastion.Connections())
+	assert.Equal(t, 2, machine.Connections())
+}
+
+func TestExternalClientThroughJumpHost(t *testing.T) {
+	auth := &Auth{
+		Keys: []string{"/store/machines/dev/id_rsa"},
+		Jump: &JumpHost{User: "ubuntu", Hostname: "bastion", Port: 2222, Keys: []string{"/home/me/.ssh/id rsa"}},
+	}
+
+	client, err := NewExternalClient("/usr/bin/ssh", "docker", "10.0.1.5", 22, auth)
+	assert.NoError(t, err)
+
+	var proxyCommand string
+	for _, arg := range client.BaseArgs {
+		if strings.HasPrefix(arg, "ProxyCommand=") {
+			proxyCommand = strings.TrimPrefix(arg, "ProxyCommand=")
+		}
+	}
+
+	assert.True(t, strings.HasPrefix(proxyCommand, "/usr/bin/ssh -o PasswordAuthentication=no"), proxyCommand)
+	assert.Contains(t, proxyCommand, "-o UserKnownHostsFile=/dev/null")
+	assert.True(t, strings.HasSuffix(proxyCommand, "-i '/home/me/.ssh/id rsa' -p 2222 -W %h:%p ubuntu@bastion"), proxyCommand)
+}
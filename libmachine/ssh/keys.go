@@ -1,6 +1,8 @@
 package ssh
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/md5"
 	"crypto/rand"
 	"crypto/rsa"
@@ -12,6 +14,8 @@ import (
 	"io"
 	"os"
 	"runtime"
+	"strconv"
+	"strings"
 
 	gossh "golang.org/x/crypto/ssh"
 )
@@ -23,38 +27,181 @@ var (
 	ErrUnableToWriteFile = errors.New("Unable to write file")
 )
 
+const (
+	KeyTypeRSA     = "rsa"
+	KeyTypeECDSA   = "ecdsa"
+	KeyTypeEd25519 = "ed25519"
+)
+
+// KeyType is the algorithm of generated keys, and their size in bits for
+// the algorithms which have several.
+type KeyType struct {
+	Algorithm string
+	Bits      int
+}
+
+// DefaultKeyType is the type of the keys generated when none is chosen.
+var DefaultKeyType = KeyType{Algorithm: KeyTypeRSA, Bits: 2048}
+
+// ParseKeyType parses a key type given as algorithm[-bits], e.g. rsa-4096,
+// ecdsa-384 or ed25519.  The size defaults to 2048 bits for RSA and 256 for
+// ECDSA, and the type to DefaultKeyType.
+func ParseKeyType(spec string) (KeyType, error) {
+	if spec == "" {
+		return DefaultKeyType, nil
+	}
+
+	keyType := KeyType{Algorithm: spec}
+	if i := strings.Index(spec, "-"); i >= 0 {
+		bits, err := strconv.Atoi(spec[i+1:])
+		if err != nil {
+			return KeyType{}, fmt.Errorf("Invalid SSH key type %q, expected algorithm[-bits]", spec)
+		}
+		keyType = KeyType{Algorithm: spec[:i], Bits: bits}
+	}
+
+	switch keyType.Algorithm {
+	case KeyTypeRSA:
+		if keyType.Bits == 0 {
+			keyType.Bits = 2048
+		}
+		if keyType.Bits < 2048 || keyType.Bits > 16384 {
+			return KeyType{}, fmt.Errorf("Invalid SSH key type %q, RSA keys have from 2048 to 16384 bits", spec)
+		}
+	case KeyTypeECDSA:
+		switch keyType.Bits {
+		case 0:
+			keyType.Bits = 256
+		case 256, 384, 521:
+		default:
+			return KeyType{}, fmt.Errorf("Invalid SSH key type %q, ECDSA keys have 256, 384 or 521 bits", spec)
+		}
+	case KeyTypeEd25519:
+		if keyType.Bits != 0 {
+			return KeyType{}, fmt.Errorf("Invalid SSH key type %q, Ed25519 keys have a fixed size", spec)
+		}
+	default:
+		return KeyType{}, fmt.Errorf("Invalid SSH key type %q, expected %s, %s or %s", spec, KeyTypeRSA, KeyTypeECDSA, KeyTypeEd25519)
+	}
+
+	return keyType, nil
+}
+
+func (t KeyType) String() string {
+	if t.Bits == 0 {
+		return t.Algorithm
+	}
+	return fmt.Sprintf("%s-%d", t.Algorithm, t.Bits)
+}
+
 type KeyPair struct {
 	PrivateKey []byte
 	PublicKey  []byte
+
+	// PrivateKeyType is the type of the PEM block of the private key, RSA
+	// if it's empty.
+	PrivateKeyType string
 }
 
-// NewKeyPair generates a new SSH keypair
+// NewKeyPair generates a new SSH keypair of the default type
 // This will return a private & public key encoded as DER.
 func NewKeyPair() (keyPair *KeyPair, err error) {
-	priv, err := rsa.GenerateKey(rand.Reader, 2048)
-	if err != nil {
-		return nil, ErrKeyGeneration
-	}
+	return NewKeyPairOfType(DefaultKeyType)
+}
 
-	if err := priv.Validate(); err != nil {
-		return nil, ErrValidation
-	}
+// NewKeyPairOfType generates a new SSH keypair of the given type.  RSA and
+// ECDSA private keys are encoded as DER, Ed25519 ones in the format of
+// OpenSSH.
+func NewKeyPairOfType(keyType KeyType) (*KeyPair, error) {
+	var (
+		priv           interface{}
+		privDer        []byte
+		privateKeyType string
+		err            error
+	)
+
+	switch keyType.Algorithm {
+	case KeyTypeRSA:
+		rsaKey, err := rsa.GenerateKey(rand.Reader, keyType.Bits)
+		if err != nil {
+			return nil, ErrKeyGeneration
+		}
+
+		if err := rsaKey.Validate(); err != nil {
+			return nil, ErrValidation
+		}
+
+		priv, privDer, privateKeyType = rsaKey, x509.MarshalPKCS1PrivateKey(rsaKey), "RSA PRIVATE KEY"
+	case KeyTypeECDSA:
+		curve, ok := ecdsaCurves[keyType.Bits]
+		if !ok {
+			return nil, ErrKeyGeneration
+		}
+
+		ecdsaKey, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			return nil, ErrKeyGeneration
+		}
+
+		privDer, err = x509.MarshalECPrivateKey(ecdsaKey)
+		if err != nil {
+			return nil, ErrKeyGeneration
+		}
+
+		priv, privateKeyType = ecdsaKey, "EC PRIVATE KEY"
+	case KeyTypeEd25519:
+		ed25519Key, err := generateEd25519Key(rand.Reader)
+		if err != nil {
+			return nil, ErrKeyGeneration
+		}
 
-	privDer := x509.MarshalPKCS1PrivateKey(priv)
+		privDer, err = marshalEd25519PrivateKey(ed25519Key)
+		if err != nil {
+			return nil, ErrKeyGeneration
+		}
+
+		priv, privateKeyType = ed25519Key, "OPENSSH PRIVATE KEY"
+	default:
+		return nil, fmt.Errorf("Unsupported SSH key type %q", keyType)
+	}
 
-	pubSSH, err := gossh.NewPublicKey(&priv.PublicKey)
+	pubSSH, err := newPublicKey(priv)
 	if err != nil {
 		return nil, ErrPublicKey
 	}
 
 	return &KeyPair{
-		PrivateKey: privDer,
-		PublicKey:  gossh.MarshalAuthorizedKey(pubSSH),
+		PrivateKey:     privDer,
+		PublicKey:      gossh.MarshalAuthorizedKey(pubSSH),
+		PrivateKeyType: privateKeyType,
 	}, nil
 }
 
+var ecdsaCurves = map[int]elliptic.Curve{
+	256: elliptic.P256(),
+	384: elliptic.P384(),
+	521: elliptic.P521(),
+}
+
+func newPublicKey(priv interface{}) (gossh.PublicKey, error) {
+	switch key := priv.(type) {
+	case *rsa.PrivateKey:
+		return gossh.NewPublicKey(&key.PublicKey)
+	case *ecdsa.PrivateKey:
+		return gossh.NewPublicKey(&key.PublicKey)
+	case ed25519PrivateKey:
+		return key.public(), nil
+	}
+	return nil, ErrPublicKey
+}
+
 // WriteToFile writes keypair to files
 func (kp *KeyPair) WriteToFile(privateKeyPath string, publicKeyPath string) error {
+	privateKeyType := kp.PrivateKeyType
+	if privateKeyType == "" {
+		privateKeyType = "RSA PRIVATE KEY"
+	}
+
 	files := []struct {
 		File  string
 		Type  string
@@ -62,7 +209,7 @@ func (kp *KeyPair) WriteToFile(privateKeyPath string, publicKeyPath string) erro
 	}{
 		{
 			File:  privateKeyPath,
-			Value: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Headers: nil, Bytes: kp.PrivateKey}),
+			Value: pem.EncodeToMemory(&pem.Block{Type: privateKeyType, Headers: nil, Bytes: kp.PrivateKey}),
 		},
 		{
 			File:  publicKeyPath,
@@ -77,6 +224,7 @@ func (kp *KeyPair) WriteToFile(privateKeyPath string, publicKeyPath string) erro
 		}
 
 		if _, err := f.Write(v.Value); err != nil {
+			f.Close()
 			return ErrUnableToWriteFile
 		}
 
@@ -84,9 +232,14 @@ func (kp *KeyPair) WriteToFile(privateKeyPath string, publicKeyPath string) erro
 		switch runtime.GOOS {
 		case "darwin", "linux":
 			if err := f.Chmod(0600); err != nil {
+				f.Close()
 				return err
 			}
 		}
+
+		if err := f.Close(); err != nil {
+			return ErrUnableToWriteFile
+		}
 	}
 
 	return nil
@@ -105,12 +258,23 @@ func (kp *KeyPair) Fingerprint() string {
 // GenerateSSHKey generates SSH keypair based on path of the private key
 // The public key would be generated to the same path with ".pub" added
 func GenerateSSHKey(path string) error {
+	return GenerateSSHKeyOfType(path, "")
+}
+
+// GenerateSSHKeyOfType is GenerateSSHKey for a key type given as
+// ParseKeyType expects it, the default type if it's empty.
+func GenerateSSHKeyOfType(path string, keyType string) error {
+	t, err := ParseKeyType(keyType)
+	if err != nil {
+		return err
+	}
+
 	if _, err := os.Stat(path); err != nil {
 		if !os.IsNotExist(err) {
 			return fmt.Errorf("Desired directory for SSH keys does not exist: %s", err)
 		}
 
-		kp, err := NewKeyPair()
+		kp, err := NewKeyPairOfType(t)
 		if err != nil {
 			return fmt.Errorf("Error generating key pair: %s", err)
 		}
@@ -0,0 +1,380 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/docker/machine/libmachine/log"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	// knownHostsLock serializes the updates of the known_hosts files.
+	knownHostsLock = &sync.Mutex{}
+
+	errHostKeyRecorded = errors.New("host key recorded")
+)
+
+// ErrHostKeyMismatch is returned when a host doesn't present the key
+// recorded the first time it was connected to.  Either the host was
+// recreated, or someone is in the middle.
+type ErrHostKeyMismatch struct {
+	Host        string
+	KnownHosts  string
+	Fingerprint string
+}
+
+func (e ErrHostKeyMismatch) Error() string {
+	return fmt.Sprintf("Host key %s of %s doesn't match the one recorded in %s. If the host was recreated, forget the old key with \"docker-machine reset-host-key\"", e.Fingerprint, e.Host, e.KnownHosts)
+}
+
+// Fingerprint returns the SHA256 fingerprint of a key, as OpenSSH prints
+// it.
+func Fingerprint(key ssh.PublicKey) string {
+	sum := sha256.Sum256(key.Marshal())
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// knownHostsName is how OpenSSH names a host in known_hosts files: the
+// port is left out if it's the default one.
+func knownHostsName(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if port == "22" {
+		return host
+	}
+	return fmt.Sprintf("[%s]:%s", host, port)
+}
+
+// knownHost is a line of a known_hosts file.
+type knownHost struct {
+	hosts []string
+	key   ssh.PublicKey
+	line  string
+}
+
+func (k knownHost) has(name string) bool {
+	for _, host := range k.hosts {
+		if host == name {
+			return true
+		}
+	}
+	return false
+}
+
+// readKnownHosts returns the lines of a known_hosts file.  Hashed host names
+// and markers are not supported, the files are only written by
+// docker-machine.
+func readKnownHosts(path string) ([]knownHost, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	known := []knownHost{}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "@") {
+			continue
+		}
+
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(fields[1]))
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing %s: %s", path, err)
+		}
+
+		known = append(known, knownHost{
+			hosts: strings.Split(fields[0], ","),
+			key:   key,
+			line:  line,
+		})
+	}
+
+	return known, scanner.Err()
+}
+
+// knownHostKeys returns the keys recorded for a host name in a known_hosts
+// file.
+func knownHostKeys(path, name string) ([]ssh.PublicKey, error) {
+	known, err := readKnownHosts(path)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []ssh.PublicKey{}
+	for _, k := range known {
+		if k.has(name) {
+			keys = append(keys, k.key)
+		}
+	}
+
+	return keys, nil
+}
+
+func knownHostLine(names []string, key ssh.PublicKey) string {
+	return fmt.Sprintf("%s %s", strings.Join(names, ","), ssh.MarshalAuthorizedKey(key))
+}
+
+func addHostKey(path string, names []string, key ssh.PublicKey) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = file.WriteString(knownHostLine(names, key))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// addHostNames adds names to the host list of a recorded line, so that a
+// machine reached through several addresses, such as the forwarded port and
+// the host-only address of VirtualBox, is known by all of them.
+func addHostNames(path string, recorded knownHost, names ...string) error {
+	known, err := readKnownHosts(path)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for _, k := range known {
+		if k.line != recorded.line {
+			fmt.Fprintln(&buf, k.line)
+			continue
+		}
+
+		hosts := k.hosts
+		for _, name := range names {
+			if !k.has(name) {
+				hosts = append(hosts, name)
+			}
+		}
+		buf.WriteString(knownHostLine(hosts, k.key))
+	}
+
+	return writeKnownHosts(path, buf.Bytes())
+}
+
+// writeKnownHosts replaces a known_hosts file in one go, so that the ssh
+// binary never reads it half written.
+func writeKnownHosts(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+
+	return err
+}
+
+// ForgetHostKey removes the keys recorded for a host from a known_hosts
+// file: the lines with its alias, and the ones with one of the addresses
+// given, recorded before hosts had aliases.  The keys of other hosts, such
+// as the jump host, are kept.
+func ForgetHostKey(path, alias string, addrs ...string) error {
+	knownHostsLock.Lock()
+	defer knownHostsLock.Unlock()
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	known, err := readKnownHosts(path)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for _, k := range known {
+		forget := alias != "" && k.has(alias)
+		for _, addr := range addrs {
+			forget = forget || k.has(knownHostsName(addr))
+		}
+
+		if !forget {
+			fmt.Fprintln(&buf, k.line)
+		}
+	}
+
+	return writeKnownHosts(path, buf.Bytes())
+}
+
+// checkHostKey trusts the key of a host on first use: it's recorded if the
+// host isn't known yet, and must match afterwards.  A host with an alias,
+// such as a machine, is known by its alias, so that only its key is accepted
+// whatever address it gets; the address is recorded along for the ssh
+// binary.
+func checkHostKey(path, alias, addr string, key ssh.PublicKey) error {
+	knownHostsLock.Lock()
+	defer knownHostsLock.Unlock()
+
+	known, err := readKnownHosts(path)
+	if err != nil {
+		return err
+	}
+
+	// Keys recorded before hosts had aliases are only known by address.
+	name := knownHostsName(addr)
+	recorded := []knownHost{}
+	for _, k := range known {
+		if alias != "" && k.has(alias) {
+			recorded = append(recorded, k)
+		}
+	}
+	if len(recorded) == 0 {
+		for _, k := range known {
+			if k.has(name) {
+				recorded = append(recorded, k)
+			}
+		}
+	}
+
+	if len(recorded) == 0 {
+		log.Debugf("Recording host key %s of %s in %s", Fingerprint(key), addr, path)
+		if alias == "" {
+			return addHostKey(path, []string{name}, key)
+		}
+		return addHostKey(path, []string{alias, name}, key)
+	}
+
+	for _, k := range recorded {
+		if !bytes.Equal(k.key.Marshal(), key.Marshal()) {
+			continue
+		}
+
+		if alias == "" || (k.has(alias) && k.has(name)) {
+			return nil
+		}
+
+		log.Debugf("Recording address %s of %s in %s", addr, alias, path)
+		return addHostNames(path, k, alias, name)
+	}
+
+	return ErrHostKeyMismatch{
+		Host:        addr,
+		KnownHosts:  path,
+		Fingerprint: Fingerprint(key),
+	}
+}
+
+func knownHostsCallback(path, alias string) func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		return checkHostKey(path, alias, hostname, key)
+	}
+}
+
+// isRecorded tells whether a key is recorded for the address, under the
+// alias if there is one.
+func isRecorded(path, alias, addr string) (bool, error) {
+	knownHostsLock.Lock()
+	defer knownHostsLock.Unlock()
+
+	known, err := readKnownHosts(path)
+	if err != nil {
+		return false, err
+	}
+
+	name := knownHostsName(addr)
+	for _, k := range known {
+		if k.has(name) && (alias == "" || k.has(alias)) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// recordHostKey makes sure a key is recorded for the address, connecting to
+// the host to learn it if needed, so that the external client can check it
+// strictly.
+func recordHostKey(path, alias, addr string, jump *NativeClient) error {
+	known, err := isRecorded(path, alias, addr)
+	if err != nil {
+		return err
+	}
+
+	if known {
+		return nil
+	}
+
+	conn, err := dialTCP(jump, addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	recorded := false
+	config := &ssh.ClientConfig{
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			if err := checkHostKey(path, alias, hostname, key); err != nil {
+				return err
+			}
+			recorded = true
+			return errHostKeyRecorded
+		},
+	}
+
+	// The handshake stops as soon as the key is known, before
+	// authenticating.
+	if _, _, _, err := ssh.NewClientConn(conn, addr, config); !recorded {
+		return fmt.Errorf("Error getting the host key of %s: %s", addr, err)
+	}
+
+	return nil
+}
+
+// RecordHostKey makes sure that the key of the host at addr, known by alias
+// if it isn't "", is recorded in the known_hosts file under that address,
+// so that the ssh and scp binaries can check it with KnownHostsArgs.  The
+// host is reached through the jump host if there is one.
+func RecordHostKey(knownHosts, alias, addr string, jump *JumpHost) error {
+	var jumpClient *NativeClient
+	if jump != nil {
+		var err error
+		jumpClient, err = newJumpClient(jump, knownHosts)
+		if err != nil {
+			return err
+		}
+	}
+
+	return recordHostKey(knownHosts, alias, addr, jumpClient)
+}
@@ -0,0 +1,196 @@
+package ssh
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func newTestKnownHosts(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return filepath.Join(dir, "machines", "default", "known_hosts"), func() { os.RemoveAll(dir) }
+}
+
+func TestKnownHostsName(t *testing.T) {
+	assert.Equal(t, "192.168.99.100", knownHostsName("192.168.99.100:22"))
+	assert.Equal(t, "[127.0.0.1]:2222", knownHostsName("127.0.0.1:2222"))
+	assert.Equal(t, "[::1]:2222", knownHostsName("[::1]:2222"))
+}
+
+func TestNativeClientChecksHostKey(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	defer CloseAll()
+
+	knownHosts, cleanup := newTestKnownHosts(t)
+	defer cleanup()
+
+	auth := &Auth{Passwords: []string{testPassword}, KnownHosts: knownHosts}
+	client, err := NewNativeClient(testUser, "127.0.0.1", s.port, auth)
+	assert.NoError(t, err)
+
+	output, err := client.Output("hostname")
+	assert.NoError(t, err)
+	assert.Equal(t, "ran hostname", output)
+
+	data, err := ioutil.ReadFile(knownHosts)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), fmt.Sprintf("[127.0.0.1]:%d ssh-rsa ", s.port)))
+
+	// The same key is accepted again.
+	CloseAll()
+	_, err = client.Output("hostname")
+	assert.NoError(t, err)
+
+	s.ChangeHostKey(t)
+	CloseAll()
+
+	_, err = client.Output("hostname")
+	mismatch, ok := err.(ErrHostKeyMismatch)
+	assert.True(t, ok, "expected a host key mismatch, got %v", err)
+	assert.Equal(t, knownHosts, mismatch.KnownHosts)
+	assert.Equal(t, fmt.Sprintf("127.0.0.1:%d", s.port), mismatch.Host)
+
+	// The recorded key is kept.
+	after, err := ioutil.ReadFile(knownHosts)
+	assert.NoError(t, err)
+	assert.Equal(t, data, after)
+
+	// Once reset, the new key is trusted.
+	assert.NoError(t, os.Remove(knownHosts))
+
+	output, err = client.Output("hostname")
+	assert.NoError(t, err)
+	assert.Equal(t, "ran hostname", output)
+}
+
+func TestRecordHostKey(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+
+	knownHosts, cleanup := newTestKnownHosts(t)
+	defer cleanup()
+
+	addr := fmt.Sprintf("127.0.0.1:%d", s.port)
+
+	assert.NoError(t, recordHostKey(knownHosts, "", addr, nil))
+	assert.Equal(t, 1, s.Connections())
+
+	keys, err := knownHostKeys(knownHosts, knownHostsName(addr))
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	// Known hosts aren't connected to again.
+	assert.NoError(t, recordHostKey(knownHosts, "", addr, nil))
+	assert.Equal(t, 1, s.Connections())
+
+	keys, err = knownHostKeys(knownHosts, "127.0.0.1")
+	assert.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func newTestHostKey(t *testing.T) ssh.PublicKey {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicKey, err := ssh.NewPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return publicKey
+}
+
+func TestCheckHostKeyByAlias(t *testing.T) {
+	knownHosts, cleanup := newTestKnownHosts(t)
+	defer cleanup()
+
+	key := newTestHostKey(t)
+	assert.NoError(t, checkHostKey(knownHosts, "docker-machine:default", "192.168.99.100:22", key))
+
+	// The machine got another address: its key is still the only one trusted,
+	// and it's known by both addresses.
+	assert.NoError(t, checkHostKey(knownHosts, "docker-machine:default", "192.168.99.101:22", key))
+	assert.NoError(t, checkHostKey(knownHosts, "docker-machine:default", "192.168.99.100:22", key))
+
+	data, err := ioutil.ReadFile(knownHosts)
+	assert.NoError(t, err)
+	assert.Equal(t, knownHostLine([]string{"docker-machine:default", "192.168.99.100", "192.168.99.101"}, key), string(data))
+
+	err = checkHostKey(knownHosts, "docker-machine:default", "192.168.99.101:22", newTestHostKey(t))
+	assert.IsType(t, ErrHostKeyMismatch{}, err)
+
+	err = checkHostKey(knownHosts, "docker-machine:default", "192.168.99.102:22", newTestHostKey(t))
+	assert.IsType(t, ErrHostKeyMismatch{}, err)
+}
+
+func TestCheckHostKeyWithoutAlias(t *testing.T) {
+	knownHosts, cleanup := newTestKnownHosts(t)
+	defer cleanup()
+
+	// Keys recorded by address only are still checked, and get the alias.
+	key := newTestHostKey(t)
+	assert.NoError(t, addHostKey(knownHosts, []string{"192.168.99.100"}, key))
+
+	err := checkHostKey(knownHosts, "docker-machine:default", "192.168.99.100:22", newTestHostKey(t))
+	assert.IsType(t, ErrHostKeyMismatch{}, err)
+
+	assert.NoError(t, checkHostKey(knownHosts, "docker-machine:default", "192.168.99.100:22", key))
+
+	recorded, err := isRecorded(knownHosts, "docker-machine:default", "192.168.99.100:22")
+	assert.NoError(t, err)
+	assert.True(t, recorded)
+	data, err := ioutil.ReadFile(knownHosts)
+	assert.NoError(t, err)
+	assert.Equal(t, knownHostLine([]string{"192.168.99.100", "docker-machine:default"}, key), string(data))
+}
+
+func TestForgetHostKey(t *testing.T) {
+	knownHosts, cleanup := newTestKnownHosts(t)
+	defer cleanup()
+
+	machineKey, legacyKey, jumpKey := newTestHostKey(t), newTestHostKey(t), newTestHostKey(t)
+	assert.NoError(t, addHostKey(knownHosts, []string{"docker-machine:default", "[127.0.0.1]:2222", "192.168.99.100"}, machineKey))
+	assert.NoError(t, addHostKey(knownHosts, []string{"192.168.99.101"}, legacyKey))
+	assert.NoError(t, addHostKey(knownHosts, []string{"bastion.example.com"}, jumpKey))
+
+	assert.NoError(t, ForgetHostKey(knownHosts, "docker-machine:default", "192.168.99.101:22"))
+
+	data, err := ioutil.ReadFile(knownHosts)
+	assert.NoError(t, err)
+	assert.Equal(t, knownHostLine([]string{"bastion.example.com"}, jumpKey), string(data))
+
+	os.Remove(knownHosts)
+	assert.NoError(t, ForgetHostKey(knownHosts, "docker-machine:default"))
+}
+
+func TestExternalClientKnownHosts(t *testing.T) {
+	client, err := NewExternalClient("/usr/bin/ssh", "docker", "localhost", 22, &Auth{KnownHosts: "/store/known_hosts"})
+	assert.NoError(t, err)
+
+	args := strings.Join(client.BaseArgs, " ")
+	assert.Contains(t, args, "-o StrictHostKeyChecking=yes")
+	assert.Contains(t, args, "-o UserKnownHostsFile=/store/known_hosts")
+	assert.NotContains(t, args, "/dev/null")
+
+	client, err = NewExternalClient("/usr/bin/ssh", "docker", "localhost", 22, &Auth{})
+	assert.NoError(t, err)
+
+	args = strings.Join(client.BaseArgs, " ")
+	assert.Contains(t, args, "-o StrictHostKeyChecking=no")
+	assert.Contains(t, args, "-o UserKnownHostsFile=/dev/null")
+}
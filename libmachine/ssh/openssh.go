@@ -0,0 +1,298 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/dchest/bcrypt_pbkdf"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// The version of golang.org/x/crypto/ssh in use predates the OpenSSH format
+// of private keys, which ssh-keygen writes by default since OpenSSH 7.8.
+
+const openSSHKeyMagic = "openssh-key-v1\x00"
+
+var errOpenSSHKeyPassphrase = errors.New("incorrect passphrase")
+
+// openSSHCipher is a cipher ssh-keygen encrypts private keys with, given a
+// key and an IV derived from the passphrase with bcrypt_pbkdf.
+type openSSHCipher struct {
+	keyLen  int
+	ivLen   int
+	tagLen  int
+	decrypt func(key, iv, data []byte) ([]byte, error)
+}
+
+var openSSHCiphers = map[string]openSSHCipher{
+	"aes128-ctr":             {16, aes.BlockSize, 0, decryptAESCTR},
+	"aes192-ctr":             {24, aes.BlockSize, 0, decryptAESCTR},
+	"aes256-ctr":             {32, aes.BlockSize, 0, decryptAESCTR},
+	"aes128-gcm@openssh.com": {16, 12, 16, decryptAESGCM},
+	"aes256-gcm@openssh.com": {32, 12, 16, decryptAESGCM},
+}
+
+func decryptAESCTR(key, iv, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	plain := make([]byte, len(data))
+	cipher.NewCTR(block, iv).XORKeyStream(plain, data)
+	return plain, nil
+}
+
+// decryptAESGCM expects the authentication tag after the data.
+func decryptAESGCM(key, iv, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := aead.Open(nil, iv, data, nil)
+	if err != nil {
+		return nil, errOpenSSHKeyPassphrase
+	}
+	return plain, nil
+}
+
+// openSSHKdfOptions are the options of the bcrypt KDF.
+type openSSHKdfOptions struct {
+	Salt   []byte
+	Rounds uint32
+}
+
+// openSSHPrivateKey is the content of an "OPENSSH PRIVATE KEY" block, see
+// PROTOCOL.key in the sources of OpenSSH.
+type openSSHPrivateKey struct {
+	CipherName   string
+	KdfName      string
+	KdfOpts      string
+	NumKeys      uint32
+	PubKey       []byte
+	PrivKeyBlock []byte
+	// Tag follows the private keys encrypted with an AEAD cipher.
+	Tag []byte `ssh:"rest"`
+}
+
+// openSSHPrivateKeyBlock starts the private part of the key, the fields
+// which follow depend on its type.
+type openSSHPrivateKeyBlock struct {
+	Check1  uint32
+	Check2  uint32
+	Keytype string
+	Rest    []byte `ssh:"rest"`
+}
+
+type openSSHRSAPrivateKey struct {
+	N       *big.Int
+	E       *big.Int
+	D       *big.Int
+	Iqmp    *big.Int
+	P       *big.Int
+	Q       *big.Int
+	Comment string
+	Pad     []byte `ssh:"rest"`
+}
+
+type openSSHECDSAPrivateKey struct {
+	Curve   string
+	Pub     []byte
+	D       *big.Int
+	Comment string
+	Pad     []byte `ssh:"rest"`
+}
+
+// marshalOpenSSHPrivateKey returns an unencrypted private key in the
+// OpenSSH format, without the PEM armor, given the fields of its type.
+func marshalOpenSSHPrivateKey(pub gossh.PublicKey, fields interface{}) ([]byte, error) {
+	var check [4]byte
+	if _, err := rand.Read(check[:]); err != nil {
+		return nil, err
+	}
+	checkInt := binary.BigEndian.Uint32(check[:])
+
+	// The block is padded to the block size of the cipher, 8 without one.
+	block := gossh.Marshal(&openSSHPrivateKeyBlock{
+		Check1:  checkInt,
+		Check2:  checkInt,
+		Keytype: pub.Type(),
+		Rest:    gossh.Marshal(fields),
+	})
+	for i := 1; len(block)%8 != 0; i++ {
+		block = append(block, byte(i))
+	}
+
+	return append([]byte(openSSHKeyMagic), gossh.Marshal(&openSSHPrivateKey{
+		CipherName:   "none",
+		KdfName:      "none",
+		NumKeys:      1,
+		PubKey:       pub.Marshal(),
+		PrivKeyBlock: block,
+	})...), nil
+}
+
+// decryptOpenSSHPrivateKey decrypts the private part of a key encrypted by
+// ssh-keygen, asking for the passphrase.
+func decryptOpenSSHPrivateKey(key openSSHPrivateKey, passphrase func() ([]byte, error)) ([]byte, error) {
+	if key.KdfName != "bcrypt" {
+		return nil, fmt.Errorf("OpenSSH private keys encrypted with the %s KDF aren't supported, add the key to the SSH agent instead", key.KdfName)
+	}
+
+	c, ok := openSSHCiphers[key.CipherName]
+	if !ok {
+		return nil, fmt.Errorf("OpenSSH private keys encrypted with %s aren't supported, add the key to the SSH agent instead", key.CipherName)
+	}
+
+	var opts openSSHKdfOptions
+	if err := gossh.Unmarshal([]byte(key.KdfOpts), &opts); err != nil {
+		return nil, err
+	}
+
+	if len(key.Tag) != c.tagLen {
+		return nil, errors.New("invalid OpenSSH private key, the authentication tag is missing")
+	}
+
+	password, err := passphrase()
+	if err != nil {
+		return nil, err
+	}
+
+	derived, err := bcrypt_pbkdf.Key(password, opts.Salt, int(opts.Rounds), c.keyLen+c.ivLen)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.decrypt(derived[:c.keyLen], derived[c.keyLen:], append(key.PrivKeyBlock, key.Tag...))
+}
+
+// parseOpenSSHPrivateKey reads an "OPENSSH PRIVATE KEY" block, which holds
+// an RSA, ECDSA or Ed25519 key for the native client to use.  The
+// passphrase is only asked for if the key is encrypted.
+func parseOpenSSHPrivateKey(data []byte, passphrase func() ([]byte, error)) (gossh.Signer, error) {
+	if !bytes.HasPrefix(data, []byte(openSSHKeyMagic)) {
+		return nil, errors.New("invalid OpenSSH private key")
+	}
+
+	var key openSSHPrivateKey
+	if err := gossh.Unmarshal(data[len(openSSHKeyMagic):], &key); err != nil {
+		return nil, err
+	}
+
+	if key.NumKeys != 1 {
+		return nil, fmt.Errorf("OpenSSH private keys holding %d keys aren't supported", key.NumKeys)
+	}
+
+	private := key.PrivKeyBlock
+	encrypted := key.CipherName != "none"
+	if encrypted {
+		var err error
+		if private, err = decryptOpenSSHPrivateKey(key, passphrase); err != nil {
+			return nil, err
+		}
+	} else if len(key.Tag) != 0 {
+		return nil, errors.New("invalid OpenSSH private key, there is data after the key")
+	}
+
+	var block openSSHPrivateKeyBlock
+	if err := gossh.Unmarshal(private, &block); err != nil {
+		if encrypted {
+			return nil, errOpenSSHKeyPassphrase
+		}
+		return nil, err
+	}
+
+	// Decrypting with the wrong passphrase gives check bytes which don't
+	// match.
+	if block.Check1 != block.Check2 {
+		if encrypted {
+			return nil, errOpenSSHKeyPassphrase
+		}
+		return nil, errors.New("invalid OpenSSH private key, the check bytes don't match")
+	}
+
+	switch block.Keytype {
+	case gossh.KeyAlgoRSA:
+		return parseOpenSSHRSAPrivateKey(block.Rest)
+	case gossh.KeyAlgoECDSA256, gossh.KeyAlgoECDSA384, gossh.KeyAlgoECDSA521:
+		return parseOpenSSHECDSAPrivateKey(block.Rest)
+	case keyAlgoEd25519:
+		return parseEd25519PrivateKey(block.Rest)
+	}
+
+	return nil, fmt.Errorf("%s keys aren't supported in the OpenSSH format, add the key to the SSH agent instead", block.Keytype)
+}
+
+func parseOpenSSHRSAPrivateKey(data []byte) (gossh.Signer, error) {
+	var key openSSHRSAPrivateKey
+	if err := gossh.Unmarshal(data, &key); err != nil {
+		return nil, err
+	}
+
+	if key.E.BitLen() > 31 {
+		return nil, errors.New("invalid RSA private key, the exponent is too large")
+	}
+
+	private := &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{
+			N: key.N,
+			E: int(key.E.Int64()),
+		},
+		D:      key.D,
+		Primes: []*big.Int{key.P, key.Q},
+	}
+	if err := private.Validate(); err != nil {
+		return nil, err
+	}
+	private.Precompute()
+
+	return gossh.NewSignerFromKey(private)
+}
+
+func parseOpenSSHECDSAPrivateKey(data []byte) (gossh.Signer, error) {
+	var key openSSHECDSAPrivateKey
+	if err := gossh.Unmarshal(data, &key); err != nil {
+		return nil, err
+	}
+
+	var curve elliptic.Curve
+	switch key.Curve {
+	case "nistp256":
+		curve = elliptic.P256()
+	case "nistp384":
+		curve = elliptic.P384()
+	case "nistp521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported ECDSA curve %s", key.Curve)
+	}
+
+	x, y := elliptic.Unmarshal(curve, key.Pub)
+	if x == nil {
+		return nil, errors.New("invalid ECDSA private key, the public point isn't on the curve")
+	}
+
+	return gossh.NewSignerFromKey(&ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: curve,
+			X:     x,
+			Y:     y,
+		},
+		D: key.D,
+	})
+}
@@ -0,0 +1,304 @@
+package ssh
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine/log"
+	"github.com/docker/machine/libmachine/mcnutils"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+var (
+	// How long connecting to the SSH server may take, like the
+	// ConnectTimeout given to the external client.
+	dialTimeout = 10 * time.Second
+
+	// How long the SSH server gets to answer a keepalive or to open a
+	// session before its connection is given up on.
+	responseTimeout = 10 * time.Second
+
+	poolLock = &sync.Mutex{}
+
+	// pool holds the connections of the native clients, by host and
+	// credentials, so that the commands run on a host share one.
+	pool = make(map[string]*sharedConn)
+)
+
+// sharedConn is an SSH connection shared by the native clients of a host.
+// It is dialed when first needed, and again once it has gone away, e.g.
+// because the machine rebooted.
+type sharedConn struct {
+	lock   sync.Mutex
+	client *ssh.Client
+}
+
+// poolKey tells which native clients can share a connection.  Passwords
+// are hashed so that they aren't kept around in one more place.
+func poolKey(user, host string, port int, auth *Auth) string {
+	passwords := sha256.New()
+	for _, password := range auth.Passwords {
+		fmt.Fprintf(passwords, "%d:%s", len(password), password)
+	}
+	key := fmt.Sprintf("%s@%s:%d keys=%s passwords=%x agent=%t forward_agent=%t known_hosts=%s", user, host, port, strings.Join(auth.Keys, ","), passwords.Sum(nil), auth.Agent, auth.ForwardAgent, auth.KnownHosts)
+	if auth.Jump != nil {
+		key += fmt.Sprintf(" jump=%s keys=%s", auth.Jump, strings.Join(auth.Jump.Keys, ","))
+	}
+	return key
+}
+
+func getSharedConn(key string) *sharedConn {
+	poolLock.Lock()
+	defer poolLock.Unlock()
+
+	conn, ok := pool[key]
+	if !ok {
+		conn = &sharedConn{}
+		pool[key] = conn
+	}
+
+	return conn
+}
+
+// get returns the connection, dialing it if needed.  A connection which
+// doesn't answer a keepalive any more, e.g. because a NAT forgot it, is
+// dialed again.
+func (c *sharedConn) get(dial func() (*ssh.Client, error)) (*ssh.Client, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.client != nil {
+		err := keepalive(c.client)
+		if err == nil {
+			return c.client, nil
+		}
+
+		log.Debugf("SSH connection to %s is broken, reconnecting: %s", c.client.RemoteAddr(), err)
+		c.client.Close()
+		c.client = nil
+	}
+
+	client, err := dial()
+	if err != nil {
+		return nil, err
+	}
+
+	c.client = client
+
+	go func() {
+		err := client.Wait()
+		log.Debugf("SSH connection to %s closed: %v", client.RemoteAddr(), err)
+		c.drop(client)
+	}()
+
+	return client, nil
+}
+
+// keepalive checks that the server still answers on the connection.
+// Servers answer requests they don't know with a failure, which is fine.
+func keepalive(conn *ssh.Client) error {
+	errCh := make(chan error, 1)
+	go func() {
+		_, _, err := conn.SendRequest("keepalive@openssh.com", true, nil)
+		errCh <- err
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-time.After(responseTimeout):
+		return fmt.Errorf("no answer to keepalive in %s", responseTimeout)
+	}
+}
+
+// openSession opens a session on the connection, closing the connection if
+// the server doesn't open it in time.
+func openSession(conn *ssh.Client) (*ssh.Session, error) {
+	type result struct {
+		session *ssh.Session
+		err     error
+	}
+
+	resultCh := make(chan result, 1)
+	go func() {
+		session, err := conn.NewSession()
+		resultCh <- result{session, err}
+	}()
+
+	select {
+	case r := <-resultCh:
+		return r.session, r.err
+	case <-time.After(responseTimeout):
+		// This makes NewSession return.
+		conn.Close()
+		return nil, fmt.Errorf("no session opened in %s", responseTimeout)
+	}
+}
+
+// drop forgets the connection if it is still the current one, so that the
+// next command dials again.
+func (c *sharedConn) drop(client *ssh.Client) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.client == client {
+		c.client = nil
+	}
+}
+
+func (c *sharedConn) close() error {
+	c.lock.Lock()
+	client := c.client
+	c.client = nil
+	c.lock.Unlock()
+
+	if client == nil {
+		return nil
+	}
+
+	return client.Close()
+}
+
+// CloseAll closes every connection of the native clients, and the tunnels.
+// Clients can still be used afterwards, they connect again.
+func CloseAll() {
+	closeTunnels()
+
+	poolLock.Lock()
+	conns := pool
+	pool = make(map[string]*sharedConn)
+	poolLock.Unlock()
+
+	for _, conn := range conns {
+		if err := conn.close(); err != nil {
+			log.Debugf("Error closing SSH connection: %s", err)
+		}
+	}
+}
+
+func dialSSH(addr string, config *ssh.ClientConfig, jump *NativeClient) (*ssh.Client, error) {
+	conn, err := dialTCP(jump, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+func (client NativeClient) dial() (*ssh.Client, error) {
+	var (
+		conn       *ssh.Client
+		hostKeyErr error
+	)
+
+	// A host key which doesn't match won't match any better on the next
+	// attempt, and the handshake error doesn't tell it apart.
+	config := client.Config
+	if callback := config.HostKeyCallback; callback != nil {
+		config.HostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			hostKeyErr = callback(hostname, remote, key)
+			return hostKeyErr
+		}
+	}
+
+	backoff := client.backoff
+	if backoff == nil {
+		backoff = mcnutils.GetBackoffPolicy("")
+	}
+
+	err := backoff.Retry(func() error {
+		var err error
+		conn, err = dialSSH(net.JoinHostPort(client.Hostname, strconv.Itoa(client.Port)), &config, client.jump)
+		if err != nil {
+			log.Debugf("Error dialing TCP: %s", err)
+		}
+		if hostKeyErr != nil {
+			return mcnutils.StopRetrying(hostKeyErr)
+		}
+		return err
+	})
+	if hostKeyErr != nil {
+		return nil, hostKeyErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error attempting SSH client dial: %s", err)
+	}
+
+	if client.forwardAgent {
+		if err := forwardAgent(conn); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("Error forwarding the SSH agent: %s", err)
+		}
+	}
+
+	return conn, nil
+}
+
+func (client NativeClient) conn() *sharedConn {
+	key := client.poolKey
+	if key == "" {
+		key = poolKey(client.Config.User, client.Hostname, client.Port, &Auth{})
+	}
+	return getSharedConn(key)
+}
+
+// session opens a session on the shared connection of the host, which
+// forwards the SSH agent if the client does.
+func (client NativeClient) session() (*ssh.Session, error) {
+	session, err := client.newSession()
+	if err != nil || !client.forwardAgent {
+		return session, err
+	}
+
+	if err := agent.RequestAgentForwarding(session); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("Error forwarding the SSH agent: %s", err)
+	}
+
+	return session, nil
+}
+
+// newSession opens a session on the shared connection of the host.  If the
+// connection turns out to be broken, it is dialed again once.
+func (client NativeClient) newSession() (*ssh.Session, error) {
+	shared := client.conn()
+
+	conn, err := shared.get(client.dial)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := openSession(conn)
+	if err == nil {
+		return session, nil
+	}
+
+	log.Debugf("Error opening SSH session, reconnecting: %s", err)
+	shared.drop(conn)
+	conn.Close()
+
+	conn, err = shared.get(client.dial)
+	if err != nil {
+		return nil, err
+	}
+
+	return openSession(conn)
+}
+
+// Close closes the connection the client shares with the other native
+// clients of the host.  They connect again if they are used afterwards.
+func (client NativeClient) Close() error {
+	return client.conn().close()
+}
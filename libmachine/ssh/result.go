@@ -0,0 +1,106 @@
+package ssh
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"syscall"
+
+	"github.com/docker/machine/libmachine/log"
+	"golang.org/x/crypto/ssh"
+)
+
+// exitStatusSSHError is how the ssh binary exits when it fails itself, e.g.
+// when the host can't be reached, rather than with the status of the
+// command.
+const exitStatusSSHError = 255
+
+// Result is what a command run on a host printed, and how it exited.
+type Result struct {
+	Stdout     string
+	Stderr     string
+	ExitStatus int
+}
+
+// ExitError is returned when a command ran on the host but failed, as
+// opposed to the errors connecting to the host or starting the command.  Its
+// message has the secrets of the command masked.
+type ExitError struct {
+	Command    string
+	ExitStatus int
+
+	// Signal is the signal which killed the command, if one did.
+	Signal string
+
+	// Stderr is what the command printed on its standard error, if it was
+	// captured.
+	Stderr string
+}
+
+func (e *ExitError) Error() string {
+	status := fmt.Sprintf("exit status %d", e.ExitStatus)
+	if e.Signal != "" {
+		status = "signal " + e.Signal
+	}
+
+	if e.Stderr == "" {
+		return log.Redact(fmt.Sprintf("Command %q failed with %s", e.Command, status))
+	}
+	return log.Redact(fmt.Sprintf("Command %q failed with %s: %s", e.Command, status, e.Stderr))
+}
+
+// runCommand runs a command with a streaming function, keeping its output.
+func runCommand(stream func(command string, stdout, stderr io.Writer) error, command string) (*Result, error) {
+	var stdout, stderr bytes.Buffer
+
+	err := stream(command, &stdout, &stderr)
+
+	result := &Result{
+		Stdout: stdout.String(),
+		Stderr: stderr.String(),
+	}
+
+	if exitErr, ok := err.(*ExitError); ok {
+		exitErr.Stderr = result.Stderr
+		result.ExitStatus = exitErr.ExitStatus
+	} else if err != nil {
+		return nil, err
+	}
+
+	return result, err
+}
+
+// nativeExitError tells apart the commands which failed from the sessions
+// which did.
+func nativeExitError(command string, err error) error {
+	exitErr, ok := err.(*ssh.ExitError)
+	if !ok {
+		return err
+	}
+
+	return &ExitError{
+		Command:    command,
+		ExitStatus: exitErr.ExitStatus(),
+		Signal:     exitErr.Signal(),
+	}
+}
+
+// externalExitError tells apart the commands which failed from the ssh
+// binary failing.  A command which exits with 255 is taken for the latter.
+func externalExitError(command string, err error) error {
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		return err
+	}
+
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	if !ok || status.ExitStatus() == exitStatusSSHError || status.ExitStatus() < 0 {
+		return fmt.Errorf("Error running %q over SSH: %s", command, err)
+	}
+
+	return &ExitError{
+		Command:    command,
+		ExitStatus: status.ExitStatus(),
+	}
+}
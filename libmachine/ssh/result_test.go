@@ -0,0 +1,102 @@
+package ssh
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/docker/machine/libmachine/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNativeClientRun(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	defer CloseAll()
+
+	client := newTestNativeClient(t, s)
+
+	result, err := client.Run("warn careful")
+	assert.NoError(t, err)
+	assert.Equal(t, &Result{Stderr: "careful"}, result)
+
+	result, err = client.Run("fail now")
+	assert.Equal(t, &Result{Stdout: "ran fail now", ExitStatus: 1}, result)
+	assert.Equal(t, &ExitError{Command: "fail now", ExitStatus: 1}, err)
+	assert.EqualError(t, err, `Command "fail now" failed with exit status 1`)
+}
+
+func TestNativeClientStream(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	defer CloseAll()
+
+	client := newTestNativeClient(t, s)
+
+	var stdout, stderr bytes.Buffer
+	assert.NoError(t, client.Stream("hostname", &stdout, &stderr))
+	assert.Equal(t, "ran hostname", stdout.String())
+	assert.Empty(t, stderr.String())
+
+	stdout.Reset()
+	err := client.Stream("fail", &stdout, &stderr)
+	assert.Equal(t, &ExitError{Command: "fail", ExitStatus: 1}, err)
+	assert.Equal(t, "ran fail", stdout.String())
+}
+
+// newFakeSSHBinary writes a script which runs its last argument locally,
+// as the ssh binary would on the host.
+func newFakeSSHBinary(t *testing.T) (string, func()) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake ssh binary is a shell script")
+	}
+
+	dir, err := ioutil.TempDir("", "machine-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "ssh")
+	script := "#!/bin/sh\nfor command; do :; done\nexec /bin/sh -c \"$command\"\n"
+	if err := ioutil.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestExternalClientRun(t *testing.T) {
+	binary, cleanup := newFakeSSHBinary(t)
+	defer cleanup()
+
+	client, err := NewExternalClient(binary, "docker", "localhost", 22, &Auth{})
+	assert.NoError(t, err)
+
+	result, err := client.Run("echo out; echo err >&2")
+	assert.NoError(t, err)
+	assert.Equal(t, &Result{Stdout: "out\n", Stderr: "err\n"}, result)
+
+	result, err = client.Run("echo missing >&2; exit 3")
+	assert.Equal(t, &Result{Stderr: "missing\n", ExitStatus: 3}, result)
+	assert.Equal(t, &ExitError{Command: "echo missing >&2; exit 3", ExitStatus: 3, Stderr: "missing\n"}, err)
+
+	// ssh itself failing isn't the command failing.
+	result, err = client.Run("exit 255")
+	assert.Nil(t, result)
+	assert.EqualError(t, err, `Error running "exit 255" over SSH: exit status 255`)
+
+	var stdout, stderr bytes.Buffer
+	assert.NoError(t, client.Stream("echo out; echo err >&2", &stdout, &stderr))
+	assert.Equal(t, "out\n", stdout.String())
+	assert.Equal(t, "err\n", stderr.String())
+}
+
+func TestExitErrorRedacted(t *testing.T) {
+	log.AddSecret("exit-error-s3cr3t")
+
+	err := &ExitError{Command: "login --password exit-error-s3cr3t", ExitStatus: 1, Stderr: "bad password exit-error-s3cr3t"}
+	assert.EqualError(t, err, `Command "login --password ****" failed with exit status 1: bad password ****`)
+}
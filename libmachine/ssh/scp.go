@@ -0,0 +1,231 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/docker/machine/libmachine/shell"
+)
+
+// Files are transferred with the SCP protocol: the scp binary of the host
+// is run as the sink ("scp -t") or the source ("scp -f") of a copy, and
+// talked to over the standard input and output of the command.
+
+// scpSpeaker speaks the SCP protocol with the scp command of the host.
+type scpSpeaker func(w io.Writer, r *bufio.Reader) error
+
+func scpUploadCommand(dest string) string {
+	return shell.Join("scp", "-t", dest)
+}
+
+func scpDownloadCommand(src string) string {
+	return shell.Join("scp", "-f", src)
+}
+
+// readSCPAck reads the reply of the other side to the last message.
+func readSCPAck(r *bufio.Reader) error {
+	code, err := r.ReadByte()
+	if err != nil {
+		return fmt.Errorf("Error reading scp reply: %s", err)
+	}
+
+	if code == 0 {
+		return nil
+	}
+
+	message, _ := r.ReadString('\n')
+	message = strings.TrimSpace(message)
+
+	// The message of scp says it's from scp.
+	if code == 1 || code == 2 {
+		return errors.New(message)
+	}
+
+	return fmt.Errorf("Unexpected scp reply %q", string(code)+message)
+}
+
+// scpSend sends a file to the sink.
+func scpSend(src io.Reader, size int64, dest string, mode os.FileMode) scpSpeaker {
+	return func(w io.Writer, r *bufio.Reader) error {
+		if err := readSCPAck(r); err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintf(w, "C%04o %d %s\n", mode.Perm(), size, path.Base(dest)); err != nil {
+			return err
+		}
+
+		if err := readSCPAck(r); err != nil {
+			return err
+		}
+
+		n, err := io.Copy(w, io.LimitReader(src, size))
+		if err != nil {
+			return err
+		}
+		if n != size {
+			return fmt.Errorf("Error sending %s: expected %d bytes, got %d", dest, size, n)
+		}
+
+		if _, err := w.Write([]byte{0}); err != nil {
+			return err
+		}
+
+		return readSCPAck(r)
+	}
+}
+
+// scpReceive receives a file from the source.
+func scpReceive(dest io.Writer) scpSpeaker {
+	return func(w io.Writer, r *bufio.Reader) error {
+		if _, err := w.Write([]byte{0}); err != nil {
+			return err
+		}
+
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("Error reading scp header: %s", err)
+		}
+
+		if header[0] == 1 || header[0] == 2 {
+			return errors.New(strings.TrimSpace(header[1:]))
+		}
+
+		size, err := parseSCPHeader(header)
+		if err != nil {
+			return err
+		}
+
+		if _, err := w.Write([]byte{0}); err != nil {
+			return err
+		}
+
+		n, err := io.Copy(dest, io.LimitReader(r, size))
+		if err != nil {
+			return err
+		}
+		if n != size {
+			return fmt.Errorf("Error receiving file: expected %d bytes, got %d", size, n)
+		}
+
+		if err := readSCPAck(r); err != nil {
+			return err
+		}
+
+		_, err = w.Write([]byte{0})
+		return err
+	}
+}
+
+// parseSCPHeader returns the size of the file a "C<mode> <size> <name>"
+// header announces.  Directories can't be received.
+func parseSCPHeader(header string) (int64, error) {
+	fields := strings.SplitN(strings.TrimSuffix(header, "\n"), " ", 3)
+	if len(fields) != 3 || !strings.HasPrefix(fields[0], "C") {
+		return 0, fmt.Errorf("Unexpected scp header %q", header)
+	}
+
+	return strconv.ParseInt(fields[1], 10, 64)
+}
+
+// runSCP runs an scp command on the host and speaks the protocol with it.
+func (client NativeClient) runSCP(command string, speak scpSpeaker) error {
+	session, err := client.session()
+	if err != nil {
+		return err
+	}
+
+	defer session.Close()
+
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	stderr := &bytes.Buffer{}
+	session.Stderr = stderr
+
+	if err := session.Start(command); err != nil {
+		return err
+	}
+
+	return waitSCP(speak(stdin, bufio.NewReader(stdout)), stdin, session.Wait, stderr)
+}
+
+// waitSCP closes the input of the scp command and waits for it to exit.
+// The error of the protocol comes first, the one of the command is mostly
+// its exit status.
+func waitSCP(err error, stdin io.Closer, wait func() error, stderr *bytes.Buffer) error {
+	stdin.Close()
+
+	if waitErr := wait(); err == nil && waitErr != nil {
+		err = waitErr
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			err = errors.New(message)
+		}
+	}
+
+	return err
+}
+
+// Upload writes a file of the host with its scp command.
+func (client NativeClient) Upload(src io.Reader, size int64, dest string, mode os.FileMode) error {
+	return client.runSCP(scpUploadCommand(dest), scpSend(src, size, dest, mode))
+}
+
+// Download reads a file of the host with its scp command.
+func (client NativeClient) Download(src string, dest io.Writer) error {
+	return client.runSCP(scpDownloadCommand(src), scpReceive(dest))
+}
+
+// runSCP runs an scp command on the host through the ssh binary and speaks
+// the protocol with it.
+func (client ExternalClient) runSCP(command string, speak scpSpeaker) error {
+	if err := client.recordHostKey(); err != nil {
+		return err
+	}
+
+	args := append(append([]string{}, client.BaseArgs...), command)
+	cmd := getSSHCmd(client.BinaryPath, args...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	return waitSCP(speak(stdin, bufio.NewReader(stdout)), stdin, cmd.Wait, stderr)
+}
+
+// Upload writes a file of the host with its scp command, run through ssh.
+func (client ExternalClient) Upload(src io.Reader, size int64, dest string, mode os.FileMode) error {
+	return client.runSCP(scpUploadCommand(dest), scpSend(src, size, dest, mode))
+}
+
+// Download reads a file of the host with its scp command, run through ssh.
+func (client ExternalClient) Download(src string, dest io.Writer) error {
+	return client.runSCP(scpDownloadCommand(src), scpReceive(dest))
+}
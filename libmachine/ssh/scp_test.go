@@ -0,0 +1,67 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSCPCommand(t *testing.T) {
+	assert.Equal(t, "scp -t /etc/docker/ca.pem", scpUploadCommand("/etc/docker/ca.pem"))
+	assert.Equal(t, `scp -f '/tmp/it'\''s here'`, scpDownloadCommand("/tmp/it's here"))
+	assert.Equal(t, "it's here", shellUnquote(scpDownloadCommand("it's here")[len("scp -f "):]))
+}
+
+func TestNativeClientUploadDownload(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	defer CloseAll()
+
+	client := newTestNativeClient(t, s)
+
+	content := "-----BEGIN CERTIFICATE-----\n'quoted' $(not run)\n"
+	assert.NoError(t, client.Upload(strings.NewReader(content), int64(len(content)), "/etc/docker/ca.pem", 0600))
+
+	file, ok := s.File("/etc/docker/ca.pem")
+	assert.True(t, ok)
+	assert.Equal(t, content, string(file.Data))
+	assert.EqualValues(t, 0600, file.Mode)
+
+	downloaded := &bytes.Buffer{}
+	assert.NoError(t, client.Download("/etc/docker/ca.pem", downloaded))
+	assert.Equal(t, content, downloaded.String())
+
+	err := client.Download("/etc/docker/missing.pem", &bytes.Buffer{})
+	assert.EqualError(t, err, "scp: /etc/docker/missing.pem: No such file or directory")
+
+	assert.Equal(t, 1, s.Connections())
+}
+
+func TestSCPSendRefused(t *testing.T) {
+	out := &bytes.Buffer{}
+	in := bufio.NewReader(strings.NewReader("\x00\x02scp: /etc/docker: Permission denied\n"))
+
+	err := scpSend(strings.NewReader("data"), 4, "/etc/docker/ca.pem", 0644)(out, in)
+	assert.EqualError(t, err, "scp: /etc/docker: Permission denied")
+	assert.Equal(t, "C0644 4 ca.pem\n", out.String())
+}
+
+func TestSCPSendShortReader(t *testing.T) {
+	out := &bytes.Buffer{}
+	in := bufio.NewReader(strings.NewReader("\x00\x00"))
+
+	err := scpSend(strings.NewReader("da"), 4, "/etc/docker/ca.pem", 0644)(out, in)
+	assert.EqualError(t, err, "Error sending /etc/docker/ca.pem: expected 4 bytes, got 2")
+}
+
+func TestParseSCPHeader(t *testing.T) {
+	size, err := parseSCPHeader("C0644 1234 ca.pem\n")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1234, size)
+
+	_, err = parseSCPHeader("D0755 0 docker\n")
+	assert.EqualError(t, err, `Unexpected scp header "D0755 0 docker\n"`)
+}
@@ -0,0 +1,461 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+const (
+	testUser     = "docker"
+	testPassword = "tcuser"
+)
+
+// testServer is an SSH server which runs no commands: it replies to "exec"
+// requests with the command it was asked to run, and fails those starting
+// with "fail".  "warn" prints its argument on the standard error, and
+// "ssh-add -l" lists the keys of the forwarded agent.  It speaks enough of
+// the SCP protocol to keep the files copied to it in memory, and forwards
+// connections to TCP addresses and Unix sockets.
+type testServer struct {
+	listener net.Listener
+	port     int
+
+	lock           sync.Mutex
+	config         *ssh.ServerConfig
+	authorizedKeys []ssh.PublicKey
+	files          map[string]testFile
+	forwarded      []string
+	connections    int
+	conns          []net.Conn
+	hung           map[net.Conn]bool
+}
+
+type testFile struct {
+	Mode os.FileMode
+	Data []byte
+}
+
+func newTestServer(t *testing.T) *testServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testServer{
+		listener: listener,
+		port:     listener.Addr().(*net.TCPAddr).Port,
+		config:   newTestServerConfig(t),
+		files:    make(map[string]testFile),
+		hung:     make(map[net.Conn]bool),
+	}
+
+	s.config.PublicKeyCallback = s.checkPublicKey
+
+	go s.serve()
+
+	return s
+}
+
+// newTestServerConfig returns a configuration with a new host key.
+func newTestServerConfig(t *testing.T) *ssh.ServerConfig {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(c ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if c.User() == testUser && string(password) == testPassword {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("wrong password for %q", c.User())
+		},
+	}
+	config.AddHostKey(signer)
+
+	return config
+}
+
+// checkPublicKey accepts any key of the test user, or only the authorized
+// ones if there are.
+func (s *testServer) checkPublicKey(c ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+	if c.User() != testUser {
+		return nil, fmt.Errorf("unknown user %q", c.User())
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(s.authorizedKeys) == 0 {
+		return nil, nil
+	}
+
+	for _, authorized := range s.authorizedKeys {
+		if bytes.Equal(authorized.Marshal(), key.Marshal()) {
+			return nil, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unauthorized key %s", Fingerprint(key))
+}
+
+// AuthorizeKey makes the server accept only the authorized public keys.
+func (s *testServer) AuthorizeKey(key ssh.PublicKey) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.authorizedKeys = append(s.authorizedKeys, key)
+}
+
+// ChangeHostKey makes the server present a new host key, as a recreated
+// machine would.
+func (s *testServer) ChangeHostKey(t *testing.T) {
+	config := newTestServerConfig(t)
+	config.PublicKeyCallback = s.checkPublicKey
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.config = config
+}
+
+func (s *testServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.lock.Lock()
+		s.connections++
+		s.conns = append(s.conns, conn)
+		s.lock.Unlock()
+
+		go s.handle(conn)
+	}
+}
+
+func (s *testServer) handle(conn net.Conn) {
+	s.lock.Lock()
+	config := s.config
+	s.lock.Unlock()
+
+	serverConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	go func() {
+		for req := range reqs {
+			if req.WantReply && !s.isHung(conn) {
+				req.Reply(false, nil)
+			}
+		}
+	}()
+
+	for newChannel := range chans {
+		if s.isHung(conn) {
+			continue
+		}
+
+		switch newChannel.ChannelType() {
+		case "direct-tcpip":
+			go s.forward(newChannel)
+			continue
+		case "direct-streamlocal@openssh.com":
+			go s.forwardUnix(newChannel)
+			continue
+		}
+
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only sessions and forwarding are supported")
+			continue
+		}
+
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+
+		go s.handleSession(serverConn, channel, requests)
+	}
+}
+
+func (s *testServer) handleSession(conn *ssh.ServerConn, channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+
+	agentForwarded := false
+
+	for req := range requests {
+		switch req.Type {
+		case "auth-agent-req@openssh.com":
+			agentForwarded = true
+			req.Reply(true, nil)
+		case "exec":
+			command := string(req.Payload[4:])
+			req.Reply(true, nil)
+
+			status := uint32(0)
+			switch {
+			case strings.HasPrefix(command, "scp -t "):
+				status = s.scpSink(channel, shellUnquote(command[len("scp -t "):]))
+			case strings.HasPrefix(command, "scp -f "):
+				status = s.scpSource(channel, shellUnquote(command[len("scp -f "):]))
+			case command == "ssh-add -l":
+				status = s.listAgentKeys(conn, channel, agentForwarded)
+			case strings.HasPrefix(command, "warn "):
+				fmt.Fprint(channel.Stderr(), command[len("warn "):])
+			case strings.HasPrefix(command, "fail"):
+				status = 1
+				fmt.Fprintf(channel, "ran %s", command)
+			default:
+				fmt.Fprintf(channel, "ran %s", command)
+			}
+
+			payload := make([]byte, 4)
+			binary.BigEndian.PutUint32(payload, status)
+			channel.SendRequest("exit-status", false, payload)
+			return
+		default:
+			req.Reply(req.Type == "pty-req", nil)
+		}
+	}
+}
+
+// listAgentKeys lists the keys of the agent forwarded by the client.
+func (s *testServer) listAgentKeys(conn *ssh.ServerConn, channel ssh.Channel, agentForwarded bool) uint32 {
+	if !agentForwarded {
+		fmt.Fprint(channel.Stderr(), "Could not open a connection to your authentication agent.")
+		return 2
+	}
+
+	agentChannel, reqs, err := conn.OpenChannel("auth-agent@openssh.com", nil)
+	if err != nil {
+		fmt.Fprint(channel.Stderr(), err)
+		return 2
+	}
+	defer agentChannel.Close()
+	go ssh.DiscardRequests(reqs)
+
+	keys, err := agent.NewClient(agentChannel).List()
+	if err != nil {
+		fmt.Fprint(channel.Stderr(), err)
+		return 2
+	}
+
+	for _, key := range keys {
+		fmt.Fprintf(channel, "%s %s\n", Fingerprint(key), key.Comment)
+	}
+
+	return 0
+}
+
+// forward connects a channel to the address it was opened for, as a jump
+// host does.
+func (s *testServer) forward(newChannel ssh.NewChannel) {
+	var target struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+	if err := ssh.Unmarshal(newChannel.ExtraData(), &target); err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+
+	s.connect(newChannel, "tcp", net.JoinHostPort(target.Host, fmt.Sprint(target.Port)))
+}
+
+// forwardUnix connects a channel to the Unix socket it was opened for.
+func (s *testServer) forwardUnix(newChannel ssh.NewChannel) {
+	var target struct {
+		SocketPath string
+		Reserved0  string
+		Reserved1  uint32
+	}
+	if err := ssh.Unmarshal(newChannel.ExtraData(), &target); err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+
+	s.connect(newChannel, "unix", target.SocketPath)
+}
+
+func (s *testServer) connect(newChannel ssh.NewChannel, network, addr string) {
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(requests)
+
+	s.lock.Lock()
+	s.forwarded = append(s.forwarded, addr)
+	s.lock.Unlock()
+
+	go func() {
+		io.Copy(conn, channel)
+		conn.Close()
+	}()
+	io.Copy(channel, conn)
+	channel.Close()
+}
+
+// Forwarded returns the addresses the server forwarded connections to.
+func (s *testServer) Forwarded() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string{}, s.forwarded...)
+}
+
+func shellUnquote(p string) string {
+	return strings.Replace(strings.Trim(p, "'"), `'\''`, "'", -1)
+}
+
+// scpSink receives a file as "scp -t" would, and keeps it in memory.
+func (s *testServer) scpSink(channel ssh.Channel, dest string) uint32 {
+	r := bufio.NewReader(channel)
+
+	channel.Write([]byte{0})
+
+	header, err := r.ReadString('\n')
+	if err != nil {
+		return 1
+	}
+
+	var (
+		mode os.FileMode
+		size int64
+		name string
+	)
+	if _, err := fmt.Sscanf(header, "C%o %d %s\n", &mode, &size, &name); err != nil {
+		fmt.Fprintf(channel, "\x02protocol error: %s\n", err)
+		return 1
+	}
+
+	channel.Write([]byte{0})
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 1
+	}
+	if b, err := r.ReadByte(); err != nil || b != 0 {
+		return 1
+	}
+
+	s.lock.Lock()
+	s.files[dest] = testFile{Mode: mode, Data: data}
+	s.lock.Unlock()
+
+	channel.Write([]byte{0})
+
+	return 0
+}
+
+// scpSource sends a file kept in memory as "scp -f" would.
+func (s *testServer) scpSource(channel ssh.Channel, src string) uint32 {
+	r := bufio.NewReader(channel)
+
+	if b, err := r.ReadByte(); err != nil || b != 0 {
+		return 1
+	}
+
+	s.lock.Lock()
+	file, ok := s.files[src]
+	s.lock.Unlock()
+
+	if !ok {
+		fmt.Fprintf(channel, "\x01scp: %s: No such file or directory\n", src)
+		return 1
+	}
+
+	fmt.Fprintf(channel, "C%04o %d %s\n", file.Mode, len(file.Data), path.Base(src))
+
+	if b, err := r.ReadByte(); err != nil || b != 0 {
+		return 1
+	}
+
+	channel.Write(file.Data)
+	channel.Write([]byte{0})
+
+	if b, err := r.ReadByte(); err != nil || b != 0 {
+		return 1
+	}
+
+	return 0
+}
+
+// File returns a file the server received.
+func (s *testServer) File(name string) (testFile, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	file, ok := s.files[name]
+	return file, ok
+}
+
+// Connections returns how many connections the server accepted.
+func (s *testServer) Connections() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.connections
+}
+
+// DropConnections closes the connections of the server's side, as a
+// rebooting machine would.
+func (s *testServer) DropConnections() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+// HangConnections makes the server stop answering on the connections it
+// has, like a machine which went away without closing them would.
+func (s *testServer) HangConnections() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, conn := range s.conns {
+		s.hung[conn] = true
+	}
+}
+
+func (s *testServer) isHung(conn net.Conn) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.hung[conn]
+}
+
+func (s *testServer) Close() {
+	s.listener.Close()
+	s.DropConnections()
+}
@@ -1,5 +1,10 @@
 package state
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 // State represents the state of a host
 type State int
 
@@ -13,6 +18,9 @@ const (
 	Starting
 	Error
 	Timeout
+	Creating
+	Provisioning
+	Removing
 )
 
 var states = []string{
@@ -25,6 +33,9 @@ var states = []string{
 	"Starting",
 	"Error",
 	"Timeout",
+	"Creating",
+	"Provisioning",
+	"Removing",
 }
 
 // Given a State type, returns its string representation
@@ -34,3 +45,26 @@ func (s State) String() string {
 	}
 	return ""
 }
+
+// UnmarshalJSON accepts a state either by number, as it is stored, or by
+// name.
+func (s *State) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		var n int
+		if err := json.Unmarshal(data, &n); err != nil {
+			return err
+		}
+		*s = State(n)
+		return nil
+	}
+
+	for i, stateName := range states {
+		if stateName == name {
+			*s = State(i)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Unknown state %q", name)
+}
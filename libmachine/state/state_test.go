@@ -1,6 +1,7 @@
 package state
 
 import (
+	"encoding/json"
 	"testing"
 )
 
@@ -15,3 +16,57 @@ func TestDaemonCreate(t *testing.T) {
 		t.Fatal("Error state should be 'Error'")
 	}
 }
+
+func TestCanTransition(t *testing.T) {
+	cases := []struct {
+		from, to State
+		allowed  bool
+	}{
+		{Stopped, Starting, true},
+		{Stopping, Starting, false},
+		{Starting, Stopping, true},
+		{Paused, Stopping, true},
+		{Saved, Stopping, true},
+		{Stopped, Stopping, false},
+		{Running, Stopping, true},
+		{Creating, Provisioning, true},
+		{Provisioning, Starting, false},
+		{Removing, Starting, false},
+		{Error, Starting, true},
+		{None, Stopping, true},
+	}
+
+	for _, c := range cases {
+		if CanTransition(c.from, c.to) != c.allowed {
+			t.Fatalf("Expected transition from %q to %q allowed to be %v", c.from, c.to, c.allowed)
+		}
+	}
+}
+
+func TestStateJSON(t *testing.T) {
+	// The state is stored by number, which older versions read.
+	data, err := json.Marshal(Provisioning)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "10" {
+		t.Fatalf("Expected state to marshal to its number, got %s", data)
+	}
+
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil || s != Provisioning {
+		t.Fatalf("Expected to unmarshal Provisioning, got %s (%v)", s, err)
+	}
+
+	if err := json.Unmarshal([]byte(`"Provisioning"`), &s); err != nil || s != Provisioning {
+		t.Fatalf("Expected to unmarshal Provisioning from its name, got %s (%v)", s, err)
+	}
+
+	if err := json.Unmarshal([]byte("4"), &s); err != nil || s != Stopped {
+		t.Fatalf("Expected to unmarshal Stopped from its number, got %s (%v)", s, err)
+	}
+
+	if err := json.Unmarshal([]byte(`"Bogus"`), &s); err == nil {
+		t.Fatal("Expected an error unmarshalling an unknown state")
+	}
+}
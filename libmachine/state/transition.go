@@ -0,0 +1,44 @@
+package state
+
+// transitions lists the states a machine may move to from each state.
+// Error and Timeout only mean that we failed to find out what the machine
+// was doing, so anything goes from there.
+var transitions = map[State][]State{
+	Creating:     {Provisioning, Running, Error, Removing},
+	Provisioning: {Running, Error, Removing},
+	Running:      {Stopping, Stopped, Paused, Saved, Error, Removing},
+	Paused:       {Starting, Running, Stopping, Stopped, Error, Removing},
+	Saved:        {Starting, Running, Stopping, Stopped, Error, Removing},
+	Stopped:      {Starting, Running, Error, Removing},
+	Stopping:     {Stopping, Stopped, Error, Removing},
+	Starting:     {Running, Stopping, Stopped, Error, Removing},
+	Removing:     {Removing, Error},
+}
+
+// CanTransition returns whether a machine in the from state may be moved to
+// the to state.
+func CanTransition(from, to State) bool {
+	switch from {
+	case None, Error, Timeout:
+		return true
+	}
+
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsLifecycle returns whether the state is one which machine itself tracks
+// in the store while it works on a host, rather than one reported by the
+// driver.
+func (s State) IsLifecycle() bool {
+	switch s {
+	case Creating, Provisioning, Removing:
+		return true
+	}
+	return false
+}
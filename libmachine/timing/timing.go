@@ -0,0 +1,129 @@
+package timing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// maxSpans is how many spans are kept: a long running process, e.g. one
+// polling the state of a machine, records far more.  The totals count them
+// all.
+const maxSpans = 1000
+
+var (
+	spansLock   = &sync.Mutex{}
+	spans       = []Span{}
+	totals      = []Total{}
+	totalsIndex = map[string]int{}
+
+	// now is a variable so that tests can control the clock.
+	now = time.Now
+)
+
+// Span is the time taken by a single named operation.
+type Span struct {
+	Name     string
+	Start    time.Time
+	Duration time.Duration
+}
+
+// Total is the time taken by all the spans sharing a name.
+type Total struct {
+	Name     string
+	Count    int
+	Duration time.Duration
+}
+
+// Summary is what gets written to a machine's store directory.
+type Summary struct {
+	Spans  []Span
+	Totals []Total
+}
+
+// Track starts timing the named operation and returns the function which
+// stops it, e.g.:
+//
+//	defer timing.Track("provision.ConfigureAuth")()
+func Track(name string) func() {
+	start := now()
+	return func() {
+		record(Span{
+			Name:     name,
+			Start:    start,
+			Duration: now().Sub(start),
+		})
+	}
+}
+
+func record(span Span) {
+	spansLock.Lock()
+	defer spansLock.Unlock()
+
+	if len(spans) < maxSpans {
+		spans = append(spans, span)
+	} else {
+		copy(spans, spans[1:])
+		spans[len(spans)-1] = span
+	}
+
+	i, ok := totalsIndex[span.Name]
+	if !ok {
+		i = len(totals)
+		totalsIndex[span.Name] = i
+		totals = append(totals, Total{Name: span.Name})
+	}
+	totals[i].Count++
+	totals[i].Duration += span.Duration
+}
+
+// Spans returns the last spans recorded, in the order they finished.
+func Spans() []Span {
+	spansLock.Lock()
+	defer spansLock.Unlock()
+	return append([]Span{}, spans...)
+}
+
+// Reset discards all the spans recorded so far.
+func Reset() {
+	spansLock.Lock()
+	defer spansLock.Unlock()
+	spans = []Span{}
+	totals = []Total{}
+	totalsIndex = map[string]int{}
+}
+
+// Totals adds up all the spans recorded by name, in the order each name was
+// first recorded.
+func Totals() []Total {
+	spansLock.Lock()
+	defer spansLock.Unlock()
+	return append([]Total{}, totals...)
+}
+
+// WriteSummary prints the totals as a table.
+func WriteSummary(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 5, 1, 3, ' ', 0)
+	fmt.Fprintln(tw, "OPERATION\tCOUNT\tTOTAL")
+	for _, total := range Totals() {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", total.Name, total.Count, total.Duration)
+	}
+	return tw.Flush()
+}
+
+// WriteSummaryFile saves the spans and their totals as JSON.
+func WriteSummaryFile(path string) error {
+	data, err := json.MarshalIndent(Summary{
+		Spans:  Spans(),
+		Totals: Totals(),
+	}, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0600)
+}
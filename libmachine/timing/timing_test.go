@@ -0,0 +1,115 @@
+package timing
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func withFakeClock(f func(advance func(time.Duration))) {
+	current := time.Unix(0, 0)
+	now = func() time.Time {
+		return current
+	}
+	defer func() {
+		now = time.Now
+		Reset()
+	}()
+
+	Reset()
+	f(func(d time.Duration) {
+		current = current.Add(d)
+	})
+}
+
+func TestTrack(t *testing.T) {
+	withFakeClock(func(advance func(time.Duration)) {
+		stopOuter := Track("outer")
+		advance(time.Second)
+
+		stopInner := Track("inner")
+		advance(2 * time.Second)
+		stopInner()
+
+		stopInner = Track("inner")
+		advance(3 * time.Second)
+		stopInner()
+
+		stopOuter()
+
+		spans := Spans()
+		assert.Len(t, spans, 3)
+		assert.Equal(t, "outer", spans[2].Name)
+		assert.Equal(t, 6*time.Second, spans[2].Duration)
+
+		assert.Equal(t, []Total{
+			{Name: "inner", Count: 2, Duration: 5 * time.Second},
+			{Name: "outer", Count: 1, Duration: 6 * time.Second},
+		}, Totals())
+	})
+}
+
+func TestWriteSummary(t *testing.T) {
+	withFakeClock(func(advance func(time.Duration)) {
+		stop := Track("rpc.Create")
+		advance(1500 * time.Millisecond)
+		stop()
+
+		out := &bytes.Buffer{}
+		assert.NoError(t, WriteSummary(out))
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		assert.Len(t, lines, 2)
+		assert.Equal(t, []string{"OPERATION", "COUNT", "TOTAL"}, strings.Fields(lines[0]))
+		assert.Equal(t, []string{"rpc.Create", "1", "1.5s"}, strings.Fields(lines[1]))
+	})
+}
+
+func TestWriteSummaryFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "timing")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	withFakeClock(func(advance func(time.Duration)) {
+		stop := Track("drivers.WaitForSSH")
+		advance(time.Minute)
+		stop()
+
+		path := filepath.Join(dir, "timings.json")
+		assert.NoError(t, WriteSummaryFile(path))
+
+		data, err := ioutil.ReadFile(path)
+		assert.NoError(t, err)
+
+		var summary Summary
+		assert.NoError(t, json.Unmarshal(data, &summary))
+		assert.Len(t, summary.Spans, 1)
+		assert.Equal(t, "drivers.WaitForSSH", summary.Spans[0].Name)
+		assert.Equal(t, time.Minute, summary.Spans[0].Duration)
+		assert.Equal(t, Totals(), summary.Totals)
+	})
+}
+
+func TestSpansAreCapped(t *testing.T) {
+	withFakeClock(func(advance func(time.Duration)) {
+		for i := 0; i < maxSpans+10; i++ {
+			stop := Track("rpc.GetState")
+			advance(time.Second)
+			stop()
+		}
+
+		spans := Spans()
+		assert.Len(t, spans, maxSpans)
+		assert.Equal(t, time.Unix(int64(maxSpans+9), 0), spans[maxSpans-1].Start)
+		assert.Equal(t, time.Unix(10, 0), spans[0].Start)
+
+		assert.Equal(t, []Total{{Name: "rpc.GetState", Count: maxSpans + 10, Duration: time.Duration(maxSpans+10) * time.Second}}, Totals())
+	})
+}
@@ -2,10 +2,21 @@ package version
 
 var (
 	// APIVersion dictates which version of the libmachine API this is.
-	APIVersion = 1
+	// Version 2 lets a single plugin process serve several machines.
+	APIVersion = 2
+
+	// MinAPIVersion is the oldest version of the libmachine API which
+	// plugins may still speak.
+	MinAPIVersion = 1
 
 	// ConfigVersion dictates which version of the config.json format is
 	// used. It needs to be bumped if there is a breaking change, and
 	// therefore migration, introduced to the config file format.
 	ConfigVersion = 3
 )
+
+// IsAPIVersionSupported returns whether we can talk to a plugin which
+// speaks the given API version.
+func IsAPIVersionSupported(apiVersion int) bool {
+	return apiVersion >= MinAPIVersion && apiVersion <= APIVersion
+}